@@ -13,6 +13,8 @@ import (
 
 	"github.com/tjst-t/qemu-bmc/internal/bmc"
 	"github.com/tjst-t/qemu-bmc/internal/config"
+	"github.com/tjst-t/qemu-bmc/internal/eventlog"
+	"github.com/tjst-t/qemu-bmc/internal/imagecache"
 	"github.com/tjst-t/qemu-bmc/internal/ipmi"
 	"github.com/tjst-t/qemu-bmc/internal/machine"
 	"github.com/tjst-t/qemu-bmc/internal/qemu"
@@ -38,15 +40,47 @@ func main() {
 
 	var qmpClient qmp.Client
 	var m *machine.Machine
+	var processLog *eventlog.Log
+
+	mediaSlots, err := machine.ParseMediaSlots(cfg.VirtualMedia)
+	if err != nil {
+		log.Fatalf("Invalid VIRTUAL_MEDIA: %v", err)
+	}
+	mediaCache, err := imagecache.New(cfg.MediaCacheDir, cfg.MediaCacheSize)
+	if err != nil {
+		log.Printf("Virtual media cache disabled, QEMU opens HTTP(S) images itself: %v", err)
+	}
+	var mediaVerifier *imagecache.Verifier
+	if cfg.MediaTrustKeys != "" {
+		if mediaVerifier, err = imagecache.LoadVerifier(cfg.MediaTrustKeys); err != nil {
+			log.Fatalf("Invalid MEDIA_TRUSTED_KEYS: %v", err)
+		}
+	}
 
 	if len(qemuArgs) > 0 {
 		// Process management mode
 		log.Printf("Process management mode: managing QEMU lifecycle")
 
-		cmdArgs, err := qemu.BuildCommandLine(qemuArgs, qemu.BuildOptions{
-			QMPSocketPath: cfg.QMPSocket,
-			SerialAddr:    cfg.SerialAddr,
-		})
+		ovmf := qemu.OVMF{
+			Code:               cfg.OVMFCode,
+			SecureCode:         cfg.OVMFSecureCode,
+			VarsTemplate:       cfg.OVMFVarsTemplate,
+			SecureVarsTemplate: cfg.OVMFSecureVarsTemplate,
+			Vars:               cfg.OVMFVars,
+		}
+		opts := qemu.BuildOptions{
+			QMPSocketPath:    cfg.QMPSocket,
+			SerialAddr:       cfg.SerialAddr,
+			GuestAgentSocket: cfg.GuestAgentSocket,
+			FloppyDrives:     machine.FloppyCount(mediaSlots),
+		}
+		// VM_BOOT_MODE=uefi is the persistent default firmware unless the
+		// arguments bring their own
+		if fw := qemu.ParseFirmware(qemuArgs); !fw.UEFI && cfg.VMBootMode == "uefi" {
+			fw.UEFI = true
+			opts.Firmware, opts.OVMF = &fw, ovmf
+		}
+		cmdArgs, err := qemu.BuildCommandLine(qemuArgs, opts)
 		if err != nil {
 			log.Fatalf("Invalid QEMU arguments: %v", err)
 		}
@@ -54,6 +88,34 @@ func main() {
 		qmpClient = qmp.NewDisconnectedClient(cfg.QMPSocket)
 		pm := qemu.NewProcessManager(cfg.QEMUBinary, cmdArgs, qemu.DefaultCommandFactory)
 		m = machine.NewWithProcess(qmpClient, pm)
+		processLog = pm.Log()
+
+		// Report CPU and memory inventory from the command line while
+		// QEMU is off
+		hw, err := qemu.ParseHardware(cfg.QEMUBinary, cmdArgs)
+		if err != nil {
+			log.Fatalf("Invalid QEMU arguments: %v", err)
+		}
+		m.SetConfiguredInventory(machine.Inventory{
+			MachineType: hw.Machine,
+			Arch:        hw.Arch,
+			CPUModel:    hw.CPUModel,
+			CPUs:        machine.TopologyCPUs(hw.CPUs, hw.Cores, hw.Threads),
+			MemoryBytes: hw.MemoryBytes,
+		})
+		m.SetVolumeStore(cfg.VolumeDir, cfg.VolumeBus)
+		m.SetFirmwareImages(ovmf)
+		m.SetBootImages(machine.BootImages{
+			UEFIShell: cfg.UEFIShell,
+			IPXE:      cfg.IPXEEFI,
+			Dir:       cfg.BootDiskDir,
+		})
+		m.SetMediaSlots(mediaSlots)
+		m.SetImageCache(mediaCache)
+		m.SetImageVerifier(mediaVerifier)
+		if err := m.SetMediaStore(cfg.MediaState); err != nil {
+			log.Printf("Virtual media state not restored: %v", err)
+		}
 
 		if cfg.PowerOnAtStart {
 			log.Printf("Starting QEMU: %s %v", cfg.QEMUBinary, cmdArgs)
@@ -66,7 +128,6 @@ func main() {
 	} else {
 		// Legacy mode
 		log.Printf("Legacy mode: connecting to existing QEMU instance")
-		var err error
 		qmpClient, err = qmp.NewClient(cfg.QMPSocket)
 		if err != nil {
 			log.Fatalf("Failed to connect to QMP socket %s: %v", cfg.QMPSocket, err)
@@ -74,11 +135,24 @@ func main() {
 		log.Println("Connected to QMP socket")
 
 		m = machine.New(qmpClient)
+		m.SetVolumeStore(cfg.VolumeDir, cfg.VolumeBus)
+		m.SetMediaSlots(mediaSlots)
+		m.SetImageCache(mediaCache)
+		m.SetImageVerifier(mediaVerifier)
 	}
 	defer qmpClient.Close()
+	if cfg.GuestAgentSocket != "" {
+		m.SetGuestAgent(qmp.NewGuestAgent(cfg.GuestAgentSocket))
+	}
+	m.SetInletTemperature(cfg.ChassisInletTemp)
 
 	// Create BMC state
 	bmcState := bmc.NewState(cfg.IPMIUser, cfg.IPMIPass)
+	bmcState.SetLockoutPolicy(bmc.LockoutPolicy{
+		Threshold:   cfg.IPMILockoutThreshold,
+		Duration:    cfg.IPMILockoutDuration,
+		MaxDuration: cfg.IPMILockoutMaxDuration,
+	})
 
 	// Start VM IPMI server (only if configured)
 	if cfg.VMIPMIAddr != "" {
@@ -93,6 +167,7 @@ func main() {
 
 	// Start IPMI server
 	ipmiServer := ipmi.NewServer(m, bmcState, cfg.IPMIUser, cfg.IPMIPass)
+	ipmiServer.SetRateLimit(cfg.IPMIRateLimit, cfg.IPMIRateBurst)
 	go func() {
 		addr := fmt.Sprintf(":%s", cfg.IPMIPort)
 		log.Printf("Starting IPMI server on %s", addr)
@@ -102,7 +177,18 @@ func main() {
 	}()
 
 	// Start Redfish server
-	redfishServer := redfish.NewServer(m, cfg.IPMIUser, cfg.IPMIPass, cfg.VNCAddr)
+	redfishServer := redfish.NewServer(m, bmcState, cfg.IPMIUser, cfg.IPMIPass, cfg.VNCAddr)
+	redfishServer.SetSessionTimeout(cfg.RedfishSessionTimeout)
+	redfishServer.SetImageCache(mediaCache)
+	redfishServer.SetProcessLog(processLog)
+
+	// Publish machine state changes (from IPMI, Redfish or the guest) as
+	// Redfish events
+	machineEvents, _ := m.Subscribe()
+	go redfishServer.ForwardMachineEvents(machineEvents)
+	selEvents, _ := m.Subscribe()
+	go logPowerStates(bmcState, selEvents)
+	go m.WatchPowerState(make(chan struct{}), 2*time.Second)
 	addr := fmt.Sprintf(":%s", cfg.RedfishPort)
 	log.Printf("Starting Redfish server on %s", addr)
 
@@ -148,3 +234,23 @@ func main() {
 		time.Sleep(500 * time.Millisecond)
 	}
 }
+
+// logPowerStates records the system powering on and off in the SEL, as a
+// physical BMC does. Resuming a paused system is not a power on.
+func logPowerStates(state *bmc.State, events <-chan machine.Event) {
+	var prev machine.PowerState
+	for e := range events {
+		if e.Type != machine.EventPowerStateChanged {
+			continue
+		}
+		switch {
+		case e.PowerState == machine.PowerOn && prev != machine.PowerPaused:
+			state.AddPowerStateEntry(bmc.ACPIPowerStateWorking)
+		case e.PowerState == machine.PowerOff:
+			state.AddPowerStateEntry(bmc.ACPIPowerStateSoftOff)
+		case e.PowerState == machine.PowerSuspended:
+			state.AddPowerStateEntry(bmc.ACPIPowerStateSleep)
+		}
+		prev = e.PowerState
+	}
+}
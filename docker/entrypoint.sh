@@ -39,14 +39,8 @@ QEMU_ARGS="$QEMU_ARGS -boot $BOOT_PARAM"
 # Boot mode (BIOS/UEFI)
 case "${VM_BOOT_MODE:-bios}" in
     uefi)
-        # OVMF pflash for UEFI boot
-        OVMF_CODE="/usr/share/OVMF/OVMF_CODE.fd"
-        OVMF_VARS="/vm/OVMF_VARS.fd"
-        if [ ! -f "$OVMF_VARS" ]; then
-            cp /usr/share/OVMF/OVMF_VARS.fd "$OVMF_VARS"
-        fi
-        QEMU_ARGS="$QEMU_ARGS -drive if=pflash,format=raw,readonly=on,file=$OVMF_CODE"
-        QEMU_ARGS="$QEMU_ARGS -drive if=pflash,format=raw,file=$OVMF_VARS"
+        # qemu-bmc adds the OVMF pflash drives and creates the VM's
+        # OVMF_VARS copy at power on (VM_BOOT_MODE is passed through)
         ;;
     bios)
         # SGA device for serial console in BIOS mode
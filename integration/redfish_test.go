@@ -132,10 +132,19 @@ func TestRedfish_VirtualMedia(t *testing.T) {
 	env := loadTestEnv()
 	client := NewRedfishClient(env.RedfishURL, env.User, env.Pass)
 
-	// Insert media
+	// An image QEMU cannot open is refused
 	resp, err := client.Post(
 		"/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.InsertMedia",
-		map[string]string{"Image": "http://example.com/test.iso"},
+		map[string]string{"Image": "/nonexistent.iso"},
+	)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	// Insert media (an image in the QEMU container)
+	resp, err = client.Post(
+		"/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.InsertMedia",
+		map[string]string{"Image": "/test.iso"},
 	)
 	require.NoError(t, err)
 	resp.Body.Close()
@@ -147,7 +156,8 @@ func TestRedfish_VirtualMedia(t *testing.T) {
 	data, err := readJSON(resp)
 	require.NoError(t, err)
 	assert.Equal(t, true, data["Inserted"])
-	assert.Equal(t, "http://example.com/test.iso", data["Image"])
+	assert.Equal(t, "/test.iso", data["Image"])
+	assert.Equal(t, true, data["WriteProtected"])
 
 	// Eject media
 	resp, err = client.Post(
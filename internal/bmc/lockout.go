@@ -0,0 +1,169 @@
+package bmc
+
+import (
+	"time"
+)
+
+// Default authentication lockout policy.
+const (
+	DefaultLockoutThreshold   = 5
+	DefaultLockoutDuration    = 60 * time.Second
+	DefaultMaxLockoutDuration = time.Hour
+)
+
+// maxAuthFailureEntries bounds the failure tracking table so that a flood of
+// distinct source addresses cannot grow it without limit.
+const maxAuthFailureEntries = 4096
+
+// LockoutPolicy controls how repeated authentication failures are handled.
+type LockoutPolicy struct {
+	Threshold   int           // consecutive failures before lockout; 0 disables lockout
+	Duration    time.Duration // duration of the first lockout
+	MaxDuration time.Duration // upper bound for the exponential backoff
+}
+
+// authFailure tracks consecutive failures for a single user or source address.
+type authFailure struct {
+	failures    int
+	lockouts    int // consecutive lockouts, drives the exponential backoff
+	lockedUntil time.Time
+}
+
+// LockoutEvent describes a lockout triggered by RecordAuthFailure.
+type LockoutEvent struct {
+	Key      string // "user:<name>" or "addr:<source>"
+	Duration time.Duration
+}
+
+func userKey(name string) string     { return "user:" + name }
+func sourceKey(source string) string { return "addr:" + source }
+
+// SetLockoutPolicy replaces the authentication lockout policy.
+func (s *State) SetLockoutPolicy(p LockoutPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lockoutPolicy = p
+}
+
+// GetLockoutPolicy returns the current authentication lockout policy.
+func (s *State) GetLockoutPolicy() LockoutPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lockoutPolicy
+}
+
+// AuthLockedOut reports whether authentication for the given user name or
+// source address is currently locked out, and for how much longer.
+// Empty user or source values are not checked.
+func (s *State) AuthLockedOut(user, source string) (time.Duration, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := s.now()
+	var remaining time.Duration
+	for _, key := range failureKeys(user, source) {
+		if f, ok := s.authFailures[key]; ok && now.Before(f.lockedUntil) {
+			if d := f.lockedUntil.Sub(now); d > remaining {
+				remaining = d
+			}
+		}
+	}
+	return remaining, remaining > 0
+}
+
+// RecordAuthFailure counts a failed authentication attempt against the user
+// name (only if it names an existing user) and the source address. When a
+// counter reaches the lockout threshold, the key is locked out with an
+// exponentially growing duration and a Session Audit SEL entry is logged.
+// It returns the lockouts triggered by this failure, if any.
+func (s *State) RecordAuthFailure(user, source string) []LockoutEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.lockoutPolicy
+	if p.Threshold <= 0 {
+		return nil
+	}
+
+	var userID uint8
+	if user != "" {
+		userID = s.lookupUserLocked(user)
+		if userID == 0 {
+			// Don't track unknown names; an attacker could otherwise
+			// fill the table with arbitrary user names.
+			user = ""
+		}
+	}
+
+	now := s.now()
+	var events []LockoutEvent
+	for _, key := range failureKeys(user, source) {
+		f, ok := s.authFailures[key]
+		if !ok {
+			s.pruneAuthFailuresLocked(now)
+			f = &authFailure{}
+			s.authFailures[key] = f
+		}
+		if now.Before(f.lockedUntil) {
+			continue
+		}
+		// Forget earlier lockouts once the key has been quiet for longer
+		// than the maximum backoff.
+		if f.lockouts > 0 && now.Sub(f.lockedUntil) > p.MaxDuration {
+			f.lockouts = 0
+		}
+		f.failures++
+		if f.failures < p.Threshold {
+			continue
+		}
+
+		d := p.Duration << f.lockouts
+		if d > p.MaxDuration || d <= 0 {
+			d = p.MaxDuration
+		}
+		f.failures = 0
+		f.lockouts++
+		f.lockedUntil = now.Add(d)
+		events = append(events, LockoutEvent{Key: key, Duration: d})
+
+		s.addSELEntryLocked(SELEntry{
+			SensorType: SensorTypeSessionAudit,
+			EventType:  0x6F,
+			EventData:  [3]byte{SessionAuditLockout, userID, 0xFF},
+		})
+	}
+	return events
+}
+
+// RecordAuthSuccess clears the failure and lockout history for the user name
+// and source address after a successful authentication.
+func (s *State) RecordAuthSuccess(user, source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range failureKeys(user, source) {
+		delete(s.authFailures, key)
+	}
+}
+
+func failureKeys(user, source string) []string {
+	keys := make([]string, 0, 2)
+	if user != "" {
+		keys = append(keys, userKey(user))
+	}
+	if source != "" {
+		keys = append(keys, sourceKey(source))
+	}
+	return keys
+}
+
+// pruneAuthFailuresLocked drops entries that are not currently locked out
+// when the table is full. Caller must hold s.mu.
+func (s *State) pruneAuthFailuresLocked(now time.Time) {
+	if len(s.authFailures) < maxAuthFailureEntries {
+		return
+	}
+	for key, f := range s.authFailures {
+		if !now.Before(f.lockedUntil) {
+			delete(s.authFailures, key)
+		}
+	}
+}
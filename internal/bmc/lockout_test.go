@@ -0,0 +1,125 @@
+package bmc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStateWithClock returns a State whose clock is controlled by the test.
+func newTestStateWithClock() (*State, *time.Time) {
+	s := NewState("admin", "password")
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestLockout_DefaultPolicy(t *testing.T) {
+	s := NewState("admin", "password")
+	p := s.GetLockoutPolicy()
+	assert.Equal(t, DefaultLockoutThreshold, p.Threshold)
+	assert.Equal(t, DefaultLockoutDuration, p.Duration)
+	assert.Equal(t, DefaultMaxLockoutDuration, p.MaxDuration)
+}
+
+func TestLockout_UserLockedAfterThreshold(t *testing.T) {
+	s, now := newTestStateWithClock()
+	s.SetLockoutPolicy(LockoutPolicy{Threshold: 3, Duration: time.Minute, MaxDuration: time.Hour})
+
+	assert.Empty(t, s.RecordAuthFailure("admin", ""))
+	assert.Empty(t, s.RecordAuthFailure("admin", ""))
+	_, locked := s.AuthLockedOut("admin", "")
+	assert.False(t, locked)
+
+	events := s.RecordAuthFailure("admin", "")
+	require.Len(t, events, 1)
+	assert.Equal(t, "user:admin", events[0].Key)
+	assert.Equal(t, time.Minute, events[0].Duration)
+
+	remaining, locked := s.AuthLockedOut("admin", "10.0.0.1")
+	assert.True(t, locked)
+	assert.Equal(t, time.Minute, remaining)
+
+	*now = now.Add(time.Minute)
+	_, locked = s.AuthLockedOut("admin", "")
+	assert.False(t, locked)
+}
+
+func TestLockout_ExponentialBackoff(t *testing.T) {
+	s, now := newTestStateWithClock()
+	s.SetLockoutPolicy(LockoutPolicy{Threshold: 1, Duration: time.Minute, MaxDuration: 3 * time.Minute})
+
+	var got []time.Duration
+	for i := 0; i < 4; i++ {
+		events := s.RecordAuthFailure("", "10.0.0.1")
+		require.Len(t, events, 1)
+		got = append(got, events[0].Duration)
+		*now = now.Add(events[0].Duration)
+	}
+	assert.Equal(t, []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}, got)
+}
+
+func TestLockout_FailuresIgnoredWhileLocked(t *testing.T) {
+	s, _ := newTestStateWithClock()
+	s.SetLockoutPolicy(LockoutPolicy{Threshold: 1, Duration: time.Minute, MaxDuration: time.Hour})
+
+	require.Len(t, s.RecordAuthFailure("", "10.0.0.1"), 1)
+	assert.Empty(t, s.RecordAuthFailure("", "10.0.0.1"))
+}
+
+func TestLockout_SourceIndependentOfUser(t *testing.T) {
+	s, _ := newTestStateWithClock()
+	s.SetLockoutPolicy(LockoutPolicy{Threshold: 2, Duration: time.Minute, MaxDuration: time.Hour})
+
+	s.RecordAuthFailure("", "10.0.0.1")
+	s.RecordAuthFailure("", "10.0.0.1")
+
+	_, locked := s.AuthLockedOut("admin", "10.0.0.1")
+	assert.True(t, locked)
+	_, locked = s.AuthLockedOut("admin", "10.0.0.2")
+	assert.False(t, locked)
+}
+
+func TestLockout_UnknownUserNotTracked(t *testing.T) {
+	s, _ := newTestStateWithClock()
+	s.SetLockoutPolicy(LockoutPolicy{Threshold: 1, Duration: time.Minute, MaxDuration: time.Hour})
+
+	assert.Empty(t, s.RecordAuthFailure("nobody", ""))
+	_, locked := s.AuthLockedOut("nobody", "")
+	assert.False(t, locked)
+}
+
+func TestLockout_SuccessResetsCounters(t *testing.T) {
+	s, _ := newTestStateWithClock()
+	s.SetLockoutPolicy(LockoutPolicy{Threshold: 2, Duration: time.Minute, MaxDuration: time.Hour})
+
+	s.RecordAuthFailure("admin", "10.0.0.1")
+	s.RecordAuthSuccess("admin", "10.0.0.1")
+	assert.Empty(t, s.RecordAuthFailure("admin", "10.0.0.1"))
+}
+
+func TestLockout_Disabled(t *testing.T) {
+	s, _ := newTestStateWithClock()
+	s.SetLockoutPolicy(LockoutPolicy{})
+
+	for i := 0; i < 100; i++ {
+		assert.Empty(t, s.RecordAuthFailure("admin", "10.0.0.1"))
+	}
+	_, locked := s.AuthLockedOut("admin", "10.0.0.1")
+	assert.False(t, locked)
+}
+
+func TestLockout_AddsSELEntry(t *testing.T) {
+	s, _ := newTestStateWithClock()
+	s.SetLockoutPolicy(LockoutPolicy{Threshold: 1, Duration: time.Minute, MaxDuration: time.Hour})
+
+	s.RecordAuthFailure("admin", "")
+
+	entries := s.SELEntries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, SensorTypeSessionAudit, entries[0].SensorType)
+	assert.Equal(t, SessionAuditLockout, entries[0].EventData[0])
+	assert.Equal(t, uint8(2), entries[0].EventData[1], "event data 2 carries the user ID")
+}
@@ -0,0 +1,196 @@
+package bmc
+
+import "errors"
+
+// maxReceiveMessages is the depth of the system interface Receive Message Queue.
+const maxReceiveMessages = 16
+
+// ErrMessageQueueFull is returned when the Receive Message Queue is full.
+var ErrMessageQueueFull = errors.New("receive message queue full")
+
+// BMC Global Enables bits (IPMI 2.0 Table 22-3).
+const (
+	GlobalEnableReceiveMessageInterrupt uint8 = 0x01
+	GlobalEnableEventBufferInterrupt    uint8 = 0x02
+	GlobalEnableEventBuffer             uint8 = 0x04
+	GlobalEnableSystemEventLogging      uint8 = 0x08
+)
+
+// Message Flags bits (IPMI 2.0 Table 22-9).
+const (
+	MessageFlagReceiveMessageAvailable uint8 = 0x01
+	MessageFlagEventBufferFull         uint8 = 0x02
+)
+
+// ReceivedMessage is an entry in the system interface Receive Message Queue.
+type ReceivedMessage struct {
+	Channel   uint8 // channel the message arrived on
+	Privilege uint8 // privilege level of the originating session
+	Data      []byte
+}
+
+// GetGlobalEnables returns the BMC Global Enables byte.
+func (s *State) GetGlobalEnables() uint8 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.globalEnables
+}
+
+// SetGlobalEnables sets the BMC Global Enables byte. Disabling the event
+// message buffer discards any buffered event.
+func (s *State) SetGlobalEnables(v uint8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.globalEnables = v
+	if v&GlobalEnableEventBuffer == 0 && s.eventBuffer != nil {
+		s.eventBuffer = nil
+		s.notifyMessageFlagsLocked()
+	}
+}
+
+// GetMessageFlags returns the system interface Message Flags.
+func (s *State) GetMessageFlags() uint8 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.messageFlagsLocked()
+}
+
+func (s *State) messageFlagsLocked() uint8 {
+	var flags uint8
+	if len(s.receiveQueue) > 0 {
+		flags |= MessageFlagReceiveMessageAvailable
+	}
+	if s.eventBuffer != nil {
+		flags |= MessageFlagEventBufferFull
+	}
+	return flags
+}
+
+// ClearMessageFlags clears the flags in mask, flushing the Receive Message
+// Queue and/or the Event Message Buffer accordingly.
+func (s *State) ClearMessageFlags(mask uint8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := s.messageFlagsLocked()
+	if mask&MessageFlagReceiveMessageAvailable != 0 {
+		s.receiveQueue = nil
+	}
+	if mask&MessageFlagEventBufferFull != 0 {
+		s.eventBuffer = nil
+	}
+	if s.messageFlagsLocked() != before {
+		s.notifyMessageFlagsLocked()
+	}
+}
+
+// EnqueueReceiveMessage appends a message for the system software to the
+// Receive Message Queue.
+func (s *State) EnqueueReceiveMessage(msg ReceivedMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.receiveQueue) >= maxReceiveMessages {
+		return ErrMessageQueueFull
+	}
+	data := make([]byte, len(msg.Data))
+	copy(data, msg.Data)
+	msg.Data = data
+	s.receiveQueue = append(s.receiveQueue, msg)
+	if len(s.receiveQueue) == 1 {
+		s.notifyMessageFlagsLocked()
+	}
+	return nil
+}
+
+// DequeueReceiveMessage removes and returns the oldest message in the
+// Receive Message Queue. It returns false if the queue is empty.
+func (s *State) DequeueReceiveMessage() (ReceivedMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.receiveQueue) == 0 {
+		return ReceivedMessage{}, false
+	}
+	msg := s.receiveQueue[0]
+	s.receiveQueue = s.receiveQueue[1:]
+	if len(s.receiveQueue) == 0 {
+		s.receiveQueue = nil
+		s.notifyMessageFlagsLocked()
+	}
+	return msg, true
+}
+
+// ReadEventMessageBuffer removes and returns the 16-byte event in the Event
+// Message Buffer. It returns false if the buffer is empty.
+func (s *State) ReadEventMessageBuffer() ([16]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.eventBuffer == nil {
+		return [16]byte{}, false
+	}
+	ev := *s.eventBuffer
+	s.eventBuffer = nil
+	s.notifyMessageFlagsLocked()
+	return ev, true
+}
+
+// postEventLocked places a SEL-format event in the Event Message Buffer if
+// the buffer is enabled and empty. Caller must hold s.mu.
+func (s *State) postEventLocked(e SELEntry) {
+	if s.globalEnables&GlobalEnableEventBuffer == 0 || s.eventBuffer != nil {
+		return
+	}
+	ev := encodeEventMessage(e)
+	s.eventBuffer = &ev
+	s.notifyMessageFlagsLocked()
+}
+
+// encodeEventMessage formats an event in the 16-byte SEL record layout
+// returned by Read Event Message Buffer.
+func encodeEventMessage(e SELEntry) [16]byte {
+	var ev [16]byte
+	ev[0] = byte(e.RecordID)
+	ev[1] = byte(e.RecordID >> 8)
+	ev[2] = 0x02 // system event record
+	ts := uint32(e.Timestamp.Unix())
+	ev[3] = byte(ts)
+	ev[4] = byte(ts >> 8)
+	ev[5] = byte(ts >> 16)
+	ev[6] = byte(ts >> 24)
+	ev[7] = byte(e.GeneratorID)
+	ev[8] = byte(e.GeneratorID >> 8)
+	ev[9] = 0x04 // event message format revision (IPMI 2.0)
+	ev[10] = e.SensorType
+	ev[11] = e.SensorNumber
+	ev[12] = e.EventType
+	copy(ev[13:], e.EventData[:])
+	return ev
+}
+
+// SubscribeMessageFlags registers for Message Flags changes, so that each
+// system interface connection can raise or drop its attention signal.
+// Notifications are coalesced. The returned function cancels the
+// subscription.
+func (s *State) SubscribeMessageFlags() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	if s.msgFlagsSubs == nil {
+		s.msgFlagsSubs = make(map[chan struct{}]struct{})
+	}
+	s.msgFlagsSubs[ch] = struct{}{}
+	s.mu.Unlock()
+
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.msgFlagsSubs, ch)
+	}
+	return ch, cancel
+}
+
+func (s *State) notifyMessageFlagsLocked() {
+	for ch := range s.msgFlagsSubs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
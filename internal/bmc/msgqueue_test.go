@@ -0,0 +1,98 @@
+package bmc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReceiveMessageQueue(t *testing.T) {
+	s := NewState("admin", "password")
+	assert.Equal(t, uint8(0), s.GetMessageFlags())
+
+	require.NoError(t, s.EnqueueReceiveMessage(ReceivedMessage{Channel: 1, Data: []byte{0x18, 0x01}}))
+	require.NoError(t, s.EnqueueReceiveMessage(ReceivedMessage{Channel: 1, Data: []byte{0x18, 0x02}}))
+	assert.Equal(t, MessageFlagReceiveMessageAvailable, s.GetMessageFlags())
+
+	msg, ok := s.DequeueReceiveMessage()
+	require.True(t, ok)
+	assert.Equal(t, []byte{0x18, 0x01}, msg.Data, "messages are delivered in order")
+
+	_, ok = s.DequeueReceiveMessage()
+	require.True(t, ok)
+	_, ok = s.DequeueReceiveMessage()
+	assert.False(t, ok)
+	assert.Equal(t, uint8(0), s.GetMessageFlags())
+}
+
+func TestReceiveMessageQueue_Full(t *testing.T) {
+	s := NewState("admin", "password")
+	for i := 0; i < maxReceiveMessages; i++ {
+		require.NoError(t, s.EnqueueReceiveMessage(ReceivedMessage{Data: []byte{byte(i)}}))
+	}
+	assert.ErrorIs(t, s.EnqueueReceiveMessage(ReceivedMessage{}), ErrMessageQueueFull)
+
+	s.ClearMessageFlags(MessageFlagReceiveMessageAvailable)
+	assert.Equal(t, uint8(0), s.GetMessageFlags())
+}
+
+func TestEventMessageBuffer(t *testing.T) {
+	s := NewState("admin", "password")
+
+	// Disabled by default: SEL entries are not buffered
+	s.AddSELEntry(SELEntry{SensorType: SensorTypeSessionAudit})
+	_, ok := s.ReadEventMessageBuffer()
+	assert.False(t, ok)
+
+	s.SetGlobalEnables(GlobalEnableEventBuffer | GlobalEnableSystemEventLogging)
+	e := s.AddSELEntry(SELEntry{SensorType: SensorTypeSessionAudit, EventType: 0x6F, EventData: [3]byte{0x03, 0x02, 0xFF}})
+	s.AddSELEntry(SELEntry{SensorType: SensorTypeSessionAudit}) // buffer already full
+	assert.Equal(t, MessageFlagEventBufferFull, s.GetMessageFlags())
+
+	ev, ok := s.ReadEventMessageBuffer()
+	require.True(t, ok)
+	assert.Equal(t, byte(e.RecordID), ev[0])
+	assert.Equal(t, byte(0x02), ev[2], "system event record")
+	assert.Equal(t, SensorTypeSessionAudit, ev[10])
+	assert.Equal(t, []byte{0x03, 0x02, 0xFF}, ev[13:])
+	assert.Equal(t, uint8(0), s.GetMessageFlags())
+}
+
+func TestSubscribeMessageFlags(t *testing.T) {
+	s := NewState("admin", "password")
+	notify, cancel := s.SubscribeMessageFlags()
+	defer cancel()
+	other, cancelOther := s.SubscribeMessageFlags()
+
+	require.NoError(t, s.EnqueueReceiveMessage(ReceivedMessage{Data: []byte{0x01}}))
+	for _, ch := range []<-chan struct{}{notify, other} {
+		select {
+		case <-ch:
+		default:
+			t.Fatal("expected every subscriber to be notified when the queue becomes non-empty")
+		}
+	}
+
+	// Further messages do not change the flags
+	require.NoError(t, s.EnqueueReceiveMessage(ReceivedMessage{Data: []byte{0x02}}))
+	select {
+	case <-notify:
+		t.Fatal("unexpected notification")
+	default:
+	}
+
+	// A cancelled subscription is no longer notified
+	cancelOther()
+	s.ClearMessageFlags(MessageFlagReceiveMessageAvailable)
+	select {
+	case <-notify:
+	default:
+		t.Fatal("expected notification when the queue is flushed")
+	}
+	select {
+	case <-other:
+		t.Fatal("unexpected notification after cancel")
+	default:
+	}
+}
@@ -0,0 +1,92 @@
+package bmc
+
+import "time"
+
+// MaxSELEntries bounds the in-memory System Event Log. The oldest entries are
+// dropped once the limit is reached.
+const MaxSELEntries = 512
+
+// SEL sensor types (IPMI 2.0 Table 42-3) used by the BMC itself.
+const (
+	SensorTypeSystemACPIPowerState uint8 = 0x22
+	SensorTypeSessionAudit         uint8 = 0x2A
+)
+
+// System ACPI Power State sensor offsets (IPMI 2.0 Table 42-3, sensor type 0x22).
+const (
+	ACPIPowerStateWorking uint8 = 0x00 // S0/G0
+	ACPIPowerStateSleep   uint8 = 0x03 // S3, suspend to RAM
+	ACPIPowerStateSoftOff uint8 = 0x05 // S5/G2
+)
+
+// Session Audit sensor offsets (IPMI 2.0 Table 42-3, sensor type 0x2A).
+const (
+	SessionAuditActivated          uint8 = 0x00
+	SessionAuditDeactivated        uint8 = 0x01
+	SessionAuditInvalidCredentials uint8 = 0x02
+	SessionAuditLockout            uint8 = 0x03 // "Invalid password disable"
+)
+
+// SELEntry is a System Event Log record in the standard (type 0x02) format.
+type SELEntry struct {
+	RecordID     uint16
+	Timestamp    time.Time
+	GeneratorID  uint16 // 0x0020 = BMC
+	SensorType   uint8
+	SensorNumber uint8
+	EventType    uint8 // 0x6F = sensor-specific
+	EventData    [3]byte
+}
+
+// AddSELEntry appends an event to the SEL, assigning its record ID and
+// timestamp. It returns the stored entry.
+func (s *State) AddSELEntry(e SELEntry) SELEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addSELEntryLocked(e)
+}
+
+func (s *State) addSELEntryLocked(e SELEntry) SELEntry {
+	s.selNextID++
+	if s.selNextID == 0 || s.selNextID == 0xFFFF {
+		// 0x0000 and 0xFFFF are reserved record IDs
+		s.selNextID = 1
+	}
+	e.RecordID = s.selNextID
+	e.Timestamp = s.now()
+	if e.GeneratorID == 0 {
+		e.GeneratorID = 0x0020
+	}
+	if len(s.sel) >= MaxSELEntries {
+		s.sel = s.sel[1:]
+	}
+	s.sel = append(s.sel, e)
+	s.postEventLocked(e)
+	return e
+}
+
+// AddPowerStateEntry logs a transition of the system to an ACPI power
+// state such as ACPIPowerStateWorking.
+func (s *State) AddPowerStateEntry(state uint8) SELEntry {
+	return s.AddSELEntry(SELEntry{
+		SensorType: SensorTypeSystemACPIPowerState,
+		EventType:  0x6F,
+		EventData:  [3]byte{state, 0xFF, 0xFF},
+	})
+}
+
+// SELEntries returns a copy of all SEL entries, oldest first.
+func (s *State) SELEntries() []SELEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]SELEntry, len(s.sel))
+	copy(out, s.sel)
+	return out
+}
+
+// ClearSEL removes all SEL entries.
+func (s *State) ClearSEL() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sel = nil
+}
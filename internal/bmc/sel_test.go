@@ -0,0 +1,48 @@
+package bmc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSEL_AddAssignsRecordIDs(t *testing.T) {
+	s := NewState("admin", "password")
+
+	e1 := s.AddSELEntry(SELEntry{SensorType: SensorTypeSessionAudit})
+	e2 := s.AddSELEntry(SELEntry{SensorType: SensorTypeSessionAudit})
+
+	assert.Equal(t, uint16(1), e1.RecordID)
+	assert.Equal(t, uint16(2), e2.RecordID)
+	assert.Equal(t, uint16(0x0020), e1.GeneratorID, "BMC is the default generator")
+	assert.False(t, e1.Timestamp.IsZero())
+}
+
+func TestSEL_BoundedSize(t *testing.T) {
+	s := NewState("admin", "password")
+
+	for i := 0; i < MaxSELEntries+10; i++ {
+		s.AddSELEntry(SELEntry{})
+	}
+
+	entries := s.SELEntries()
+	require.Len(t, entries, MaxSELEntries)
+	assert.Equal(t, uint16(11), entries[0].RecordID, "oldest entries are dropped first")
+}
+
+func TestSEL_Clear(t *testing.T) {
+	s := NewState("admin", "password")
+	s.AddSELEntry(SELEntry{})
+	s.ClearSEL()
+	assert.Empty(t, s.SELEntries())
+}
+
+func TestSEL_AddPowerStateEntry(t *testing.T) {
+	s := NewState("admin", "password")
+	e := s.AddPowerStateEntry(ACPIPowerStateSoftOff)
+
+	assert.Equal(t, SensorTypeSystemACPIPowerState, e.SensorType)
+	assert.Equal(t, uint8(0x6F), e.EventType)
+	assert.Equal(t, [3]byte{ACPIPowerStateSoftOff, 0xFF, 0xFF}, e.EventData)
+}
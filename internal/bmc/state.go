@@ -2,12 +2,39 @@ package bmc
 
 import (
 	"crypto/subtle"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
 const maxUsers = 15
 
+// Limits of the IPMI user table (Set User Name / Set User Password).
+const (
+	MaxUserNameLength = 16
+	MaxPasswordLength = 20
+)
+
+// IPMI privilege levels (IPMI 2.0 Table 6-1).
+const (
+	PrivilegeCallback      uint8 = 0x01
+	PrivilegeUser          uint8 = 0x02
+	PrivilegeOperator      uint8 = 0x03
+	PrivilegeAdministrator uint8 = 0x04
+	PrivilegeNoAccess      uint8 = 0x0F
+)
+
+var (
+	// ErrUserExists is returned when creating a user whose name is taken.
+	ErrUserExists = errors.New("user name already exists")
+	// ErrNoFreeUserSlot is returned when all user slots are in use.
+	ErrNoFreeUserSlot = errors.New("no free user slot")
+	// ErrLastAdministrator is returned when a change would leave no
+	// enabled administrator to manage the BMC.
+	ErrLastAdministrator = errors.New("no enabled administrator would remain")
+)
+
 // UserAccess holds access settings for a single user slot.
 type UserAccess struct {
 	PrivilegeLimit uint8
@@ -19,9 +46,10 @@ type UserAccess struct {
 
 // userSlot holds the credentials and access for a single user slot.
 type userSlot struct {
-	name     string
-	password string
-	access   UserAccess
+	name       string
+	password   string
+	access     UserAccess
+	generation uint64 // changes with the name, password or access
 }
 
 // ChannelAccess represents channel access settings.
@@ -47,14 +75,41 @@ type ChannelInfo struct {
 type State struct {
 	mu            sync.RWMutex
 	users         [maxUsers + 1]userSlot // index 0 unused, 1-15 valid
+	userChanges   uint64                 // last userSlot.generation handed out
 	lanConfig     map[uint8][]byte       // parameter number → value
 	channelAccess [16]ChannelAccess      // indexed by channel (0-15)
+
+	lockoutPolicy LockoutPolicy
+	authFailures  map[string]*authFailure // keyed by "user:<name>" / "addr:<source>"
+
+	sysInfo              map[uint8][]byte // system info parameter → raw block data
+	sysInfoSetInProgress uint8
+
+	globalEnables uint8
+	receiveQueue  []ReceivedMessage
+	eventBuffer   *[16]byte
+	msgFlagsSubs  map[chan struct{}]struct{}
+
+	sel       []SELEntry
+	selNextID uint16
+
+	now func() time.Time // injectable clock for tests
 }
 
 // NewState creates a new State with a default admin user in slot 2.
 // Slot 1 is reserved as the null user (empty).
 func NewState(defaultUser, defaultPass string) *State {
-	s := &State{}
+	s := &State{
+		lockoutPolicy: LockoutPolicy{
+			Threshold:   DefaultLockoutThreshold,
+			Duration:    DefaultLockoutDuration,
+			MaxDuration: DefaultMaxLockoutDuration,
+		},
+		authFailures:  make(map[string]*authFailure),
+		sysInfo:       make(map[uint8][]byte),
+		globalEnables: GlobalEnableSystemEventLogging,
+		now:           time.Now,
+	}
 	s.users[2] = userSlot{
 		name:     defaultUser,
 		password: defaultPass,
@@ -117,7 +172,15 @@ func (s *State) SetUserName(userID uint8, name string) error {
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.users[userID].name = name
+	slot := s.users[userID]
+	slot.name = name
+	if err := s.checkAdministratorsLocked(userID, slot); err != nil {
+		return err
+	}
+	if s.users[userID].name != name {
+		s.users[userID].name = name
+		s.userChangedLocked(userID)
+	}
 	return nil
 }
 
@@ -128,7 +191,10 @@ func (s *State) SetUserPassword(userID uint8, password string) error {
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.users[userID].password = password
+	if s.users[userID].password != password {
+		s.users[userID].password = password
+		s.userChangedLocked(userID)
+	}
 	return nil
 }
 
@@ -176,7 +242,15 @@ func (s *State) SetUserAccess(channel, userID uint8, access UserAccess) error {
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.users[userID].access = access
+	slot := s.users[userID]
+	slot.access = access
+	if err := s.checkAdministratorsLocked(userID, slot); err != nil {
+		return err
+	}
+	if s.users[userID].access != access {
+		s.users[userID].access = access
+		s.userChangedLocked(userID)
+	}
 	return nil
 }
 
@@ -201,12 +275,94 @@ func (s *State) LookupUserByName(name string) (uint8, bool) {
 	}
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	userID := s.lookupUserLocked(name)
+	return userID, userID != 0
+}
+
+// lookupUserLocked returns the user ID for name, or 0 if not found.
+// Caller must hold s.mu.
+func (s *State) lookupUserLocked(name string) uint8 {
 	for i := 1; i <= maxUsers; i++ {
 		if s.users[i].name == name {
-			return uint8(i), true
+			return uint8(i)
+		}
+	}
+	return 0
+}
+
+// CreateUser stores a new user in the first free slot (2-15; slot 1 is the
+// null user) and returns its user ID.
+func (s *State) CreateUser(name, password string, access UserAccess) (uint8, error) {
+	if name == "" {
+		return 0, fmt.Errorf("user name is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lookupUserLocked(name) != 0 {
+		return 0, ErrUserExists
+	}
+	for i := 2; i <= maxUsers; i++ {
+		if s.users[i].name == "" {
+			s.users[i] = userSlot{name: name, password: password, access: access}
+			s.userChangedLocked(uint8(i))
+			return uint8(i), nil
+		}
+	}
+	return 0, ErrNoFreeUserSlot
+}
+
+// DeleteUser clears the name, password and access of the given user slot.
+func (s *State) DeleteUser(userID uint8) error {
+	if err := validateUserID(userID); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkAdministratorsLocked(userID, userSlot{}); err != nil {
+		return err
+	}
+	s.users[userID] = userSlot{}
+	s.userChangedLocked(userID)
+	return nil
+}
+
+// UserGeneration returns a value that changes whenever the name, password
+// or access of the user slot change, so that logins made with the slot can
+// be checked for being current.
+func (s *State) UserGeneration(userID uint8) uint64 {
+	if validateUserID(userID) != nil {
+		return 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.users[userID].generation
+}
+
+// userChangedLocked records a change to the user slot.
+// Caller must hold s.mu.
+func (s *State) userChangedLocked(userID uint8) {
+	s.userChanges++
+	s.users[userID].generation = s.userChanges
+}
+
+// isAdministrator reports whether u is an enabled administrator account.
+func (u userSlot) isAdministrator() bool {
+	return u.name != "" && u.access.Enabled && u.access.PrivilegeLimit == PrivilegeAdministrator
+}
+
+// checkAdministratorsLocked returns ErrLastAdministrator if replacing slot
+// userID with u would leave no enabled administrator where there was one.
+// Caller must hold s.mu.
+func (s *State) checkAdministratorsLocked(userID uint8, u userSlot) error {
+	if !s.users[userID].isAdministrator() || u.isAdministrator() {
+		return nil
+	}
+	for i := 1; i <= maxUsers; i++ {
+		if uint8(i) != userID && s.users[i].isAdministrator() {
+			return nil
 		}
 	}
-	return 0, false
+	return ErrLastAdministrator
 }
 
 // GetLANConfig returns a copy of the LAN configuration parameter value.
@@ -330,3 +330,70 @@ func TestGetChannelInfo(t *testing.T) {
 	assert.Equal(t, uint8(5), info5.ChannelNumber)
 	assert.Equal(t, uint8(0x04), info5.ChannelMedium)
 }
+
+func TestCreateDeleteUser(t *testing.T) {
+	s := NewState("admin", "password")
+
+	id, err := s.CreateUser("operator", "secret", UserAccess{PrivilegeLimit: PrivilegeOperator, Enabled: true})
+	require.NoError(t, err)
+	assert.Equal(t, uint8(3), id, "first free slot after the default admin")
+	assert.True(t, s.CheckPassword(id, "secret"))
+
+	_, err = s.CreateUser("operator", "other", UserAccess{})
+	assert.ErrorIs(t, err, ErrUserExists)
+
+	require.NoError(t, s.DeleteUser(id))
+	_, ok := s.LookupUserByName("operator")
+	assert.False(t, ok)
+	assert.False(t, s.CheckPassword(id, "secret"))
+
+	for i := 3; i <= maxUsers; i++ {
+		_, err = s.CreateUser(string(rune('a'+i)), "x", UserAccess{})
+		require.NoError(t, err)
+	}
+	_, err = s.CreateUser("overflow", "x", UserAccess{})
+	assert.ErrorIs(t, err, ErrNoFreeUserSlot)
+}
+
+func TestUserGeneration(t *testing.T) {
+	s := NewState("admin", "password")
+	gen := s.UserGeneration(2)
+
+	require.NoError(t, s.SetUserPassword(2, "password"))
+	assert.Equal(t, gen, s.UserGeneration(2), "unchanged password")
+
+	id, err := s.CreateUser("operator", "secret", UserAccess{PrivilegeLimit: PrivilegeOperator, Enabled: true})
+	require.NoError(t, err)
+	assert.Equal(t, gen, s.UserGeneration(2), "other slots are not affected")
+
+	for _, change := range []func() error{
+		func() error { return s.SetUserName(id, "op") },
+		func() error { return s.SetUserPassword(id, "changed") },
+		func() error { return s.SetUserAccess(0, id, UserAccess{PrivilegeLimit: PrivilegeOperator}) },
+		func() error { return s.DeleteUser(id) },
+	} {
+		before := s.UserGeneration(id)
+		require.NoError(t, change())
+		assert.NotEqual(t, before, s.UserGeneration(id))
+	}
+}
+
+func TestLastAdministrator(t *testing.T) {
+	s := NewState("admin", "password")
+	admin := UserAccess{PrivilegeLimit: PrivilegeAdministrator, Enabled: true}
+
+	assert.ErrorIs(t, s.DeleteUser(2), ErrLastAdministrator)
+	assert.ErrorIs(t, s.SetUserAccess(0, 2, UserAccess{PrivilegeLimit: PrivilegeAdministrator}), ErrLastAdministrator)
+	assert.ErrorIs(t, s.SetUserAccess(0, 2, UserAccess{PrivilegeLimit: PrivilegeOperator, Enabled: true}), ErrLastAdministrator)
+	assert.ErrorIs(t, s.SetUserName(2, ""), ErrLastAdministrator)
+	access, _ := s.GetUserAccess(0, 2)
+	assert.Equal(t, PrivilegeAdministrator, access.PrivilegeLimit, "a refused change leaves the account as it was")
+	assert.True(t, access.Enabled)
+
+	// With a second administrator either one can go
+	id, err := s.CreateUser("root", "secret", admin)
+	require.NoError(t, err)
+	require.NoError(t, s.SetUserAccess(0, 2, UserAccess{PrivilegeLimit: PrivilegeUser, Enabled: true}))
+	assert.ErrorIs(t, s.DeleteUser(id), ErrLastAdministrator)
+	require.NoError(t, s.DeleteUser(2))
+}
@@ -0,0 +1,145 @@
+package bmc
+
+import "fmt"
+
+// System Info parameter selectors (IPMI 2.0 Table 22-16a).
+const (
+	SysInfoSetInProgress   uint8 = 0
+	SysInfoFirmwareVersion uint8 = 1
+	SysInfoSystemName      uint8 = 2
+	SysInfoPrimaryOSName   uint8 = 3
+	SysInfoOSName          uint8 = 4
+	SysInfoOSVersion       uint8 = 5
+	SysInfoBMCURL          uint8 = 6
+)
+
+// System info string encodings (byte 0 of block 0, bits 3:0).
+const (
+	SysInfoEncodingASCII   uint8 = 0 // ASCII+Latin1
+	SysInfoEncodingUTF8    uint8 = 1
+	SysInfoEncodingUnicode uint8 = 2
+)
+
+const (
+	sysInfoBlockSize = 16
+	// maxSysInfoBlocks holds a 255-byte string: block 0 carries the encoding,
+	// the length and 14 bytes of string data, later blocks 16 bytes each.
+	maxSysInfoBlocks = 17
+)
+
+// IsSystemInfoStringParam reports whether param is one of the multi-block
+// string parameters (1-6).
+func IsSystemInfoStringParam(param uint8) bool {
+	return param >= SysInfoFirmwareVersion && param <= SysInfoBMCURL
+}
+
+// GetSystemInfoSetInProgress returns the Set In Progress state (parameter 0).
+func (s *State) GetSystemInfoSetInProgress() uint8 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sysInfoSetInProgress
+}
+
+// SetSystemInfoSetInProgress sets the Set In Progress state (parameter 0).
+// Only bits 1:0 are kept: 0=set complete, 1=set in progress, 2=commit write.
+func (s *State) SetSystemInfoSetInProgress(v uint8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sysInfoSetInProgress = v & 0x03
+}
+
+// SetSystemInfoBlock stores one 16-byte block of a string parameter.
+// data shorter than a block is zero-padded.
+func (s *State) SetSystemInfoBlock(param, setSelector uint8, data []byte) error {
+	if !IsSystemInfoStringParam(param) {
+		return fmt.Errorf("system info parameter %d not supported", param)
+	}
+	if setSelector >= maxSysInfoBlocks {
+		return fmt.Errorf("set selector %d out of range (0-%d)", setSelector, maxSysInfoBlocks-1)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	buf := s.sysInfo[param]
+	if buf == nil {
+		buf = make([]byte, maxSysInfoBlocks*sysInfoBlockSize)
+		s.sysInfo[param] = buf
+	}
+	block := buf[int(setSelector)*sysInfoBlockSize : int(setSelector+1)*sysInfoBlockSize]
+	for i := range block {
+		block[i] = 0
+	}
+	copy(block, data)
+	return nil
+}
+
+// GetSystemInfoBlock returns a copy of one 16-byte block of a string parameter.
+func (s *State) GetSystemInfoBlock(param, setSelector uint8) ([]byte, error) {
+	if !IsSystemInfoStringParam(param) {
+		return nil, fmt.Errorf("system info parameter %d not supported", param)
+	}
+	if setSelector >= maxSysInfoBlocks {
+		return nil, fmt.Errorf("set selector %d out of range (0-%d)", setSelector, maxSysInfoBlocks-1)
+	}
+	out := make([]byte, sysInfoBlockSize)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if buf := s.sysInfo[param]; buf != nil {
+		copy(out, buf[int(setSelector)*sysInfoBlockSize:])
+	}
+	return out, nil
+}
+
+// SetSystemInfoString encodes value as a UTF-8 string parameter.
+// Values longer than 255 bytes are truncated.
+func (s *State) SetSystemInfoString(param uint8, value string) error {
+	if !IsSystemInfoStringParam(param) {
+		return fmt.Errorf("system info parameter %d not supported", param)
+	}
+	if len(value) > 255 {
+		value = value[:255]
+	}
+	buf := make([]byte, maxSysInfoBlocks*sysInfoBlockSize)
+	buf[0] = SysInfoEncodingUTF8
+	buf[1] = uint8(len(value))
+	copy(buf[2:], value)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sysInfo[param] = buf
+	return nil
+}
+
+// GetSystemInfoString decodes a string parameter. It returns "" if the
+// parameter has not been set. UCS-2 (Unicode) values are decoded as
+// little-endian; ASCII+Latin1 bytes above 0x7F are mapped to Latin-1.
+func (s *State) GetSystemInfoString(param uint8) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	buf := s.sysInfo[param]
+	if buf == nil {
+		return ""
+	}
+	encoding := buf[0] & 0x0F
+	n := int(buf[1])
+	if n > len(buf)-2 {
+		n = len(buf) - 2
+	}
+	raw := buf[2 : 2+n]
+
+	switch encoding {
+	case SysInfoEncodingUnicode:
+		runes := make([]rune, 0, len(raw)/2)
+		for i := 0; i+1 < len(raw); i += 2 {
+			runes = append(runes, rune(uint16(raw[i])|uint16(raw[i+1])<<8))
+		}
+		return string(runes)
+	case SysInfoEncodingASCII:
+		runes := make([]rune, len(raw))
+		for i, b := range raw {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	default:
+		return string(raw)
+	}
+}
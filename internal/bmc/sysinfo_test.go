@@ -0,0 +1,56 @@
+package bmc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemInfo_StringRoundTrip(t *testing.T) {
+	s := NewState("admin", "password")
+
+	require.NoError(t, s.SetSystemInfoString(SysInfoSystemName, "node01.example.com"))
+	assert.Equal(t, "node01.example.com", s.GetSystemInfoString(SysInfoSystemName))
+	assert.Equal(t, "", s.GetSystemInfoString(SysInfoOSName), "unset parameters are empty")
+}
+
+func TestSystemInfo_MultiBlockString(t *testing.T) {
+	s := NewState("admin", "password")
+	name := "Ubuntu 24.04.1 LTS (Noble Numbat)" // 33 bytes: spans blocks 0-2
+
+	block0 := append([]byte{SysInfoEncodingASCII, byte(len(name))}, name[:14]...)
+	require.NoError(t, s.SetSystemInfoBlock(SysInfoOSVersion, 0, block0))
+	require.NoError(t, s.SetSystemInfoBlock(SysInfoOSVersion, 1, []byte(name[14:30])))
+	require.NoError(t, s.SetSystemInfoBlock(SysInfoOSVersion, 2, []byte(name[30:])))
+
+	assert.Equal(t, name, s.GetSystemInfoString(SysInfoOSVersion))
+
+	got, err := s.GetSystemInfoBlock(SysInfoOSVersion, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(name[14:30]), got)
+}
+
+func TestSystemInfo_UnicodeEncoding(t *testing.T) {
+	s := NewState("admin", "password")
+	block0 := []byte{SysInfoEncodingUnicode, 4, 'o', 0x00, 'k', 0x00}
+	require.NoError(t, s.SetSystemInfoBlock(SysInfoOSName, 0, block0))
+	assert.Equal(t, "ok", s.GetSystemInfoString(SysInfoOSName))
+}
+
+func TestSystemInfo_InvalidParamAndSelector(t *testing.T) {
+	s := NewState("admin", "password")
+
+	assert.Error(t, s.SetSystemInfoBlock(7, 0, nil))
+	assert.Error(t, s.SetSystemInfoBlock(SysInfoSystemName, maxSysInfoBlocks, nil))
+	_, err := s.GetSystemInfoBlock(0, 0)
+	assert.Error(t, err)
+	assert.Error(t, s.SetSystemInfoString(0xC0, "oem"))
+}
+
+func TestSystemInfo_SetInProgress(t *testing.T) {
+	s := NewState("admin", "password")
+	assert.Equal(t, uint8(0), s.GetSystemInfoSetInProgress())
+	s.SetSystemInfoSetInProgress(0x01)
+	assert.Equal(t, uint8(0x01), s.GetSystemInfoSetInProgress())
+}
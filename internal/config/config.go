@@ -1,6 +1,11 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 // Config holds the application configuration
 type Config struct {
@@ -17,6 +22,38 @@ type Config struct {
 	QEMUBinary     string // QEMU binary path for process management mode
 	PowerOnAtStart bool   // Power on VM at container start
 	VNCAddr        string // VNC TCP address for noVNC proxy
+
+	IPMILockoutThreshold   int           // failed logins before lockout (0 = disabled)
+	IPMILockoutDuration    time.Duration // first lockout duration
+	IPMILockoutMaxDuration time.Duration // cap for exponential lockout backoff
+	IPMIRateLimit          float64       // packets per second per source (0 = unlimited)
+	IPMIRateBurst          int           // packet burst per source
+
+	RedfishSessionTimeout time.Duration // idle timeout of Redfish sessions
+
+	VolumeDir string // directory for volumes created via Redfish ("" = disabled)
+	VolumeBus string // bus volumes are hot-plugged on ("" = QEMU default)
+
+	VirtualMedia string // virtual media slot types, e.g. "CD,USB,Floppy"
+	MediaState   string // file the inserted virtual media is kept in ("" = not persisted)
+
+	MediaCacheDir  string // directory HTTP(S) images are downloaded into
+	MediaCacheSize int64  // size limit of the media cache in bytes (0 = unlimited)
+	MediaTrustKeys string // PEM file of keys image signatures are checked against ("" = none)
+
+	GuestAgentSocket string // qemu-guest-agent chardev socket ("" = disabled)
+
+	ChassisInletTemp float64 // simulated inlet air temperature in degrees Celsius
+
+	OVMFCode               string // OVMF firmware code for UEFI boot
+	OVMFSecureCode         string // OVMF code with Secure Boot and SMM
+	OVMFVarsTemplate       string // empty UEFI variable store
+	OVMFSecureVarsTemplate string // UEFI variable store with Secure Boot keys enrolled
+	OVMFVars               string // the VM's UEFI variable store
+
+	UEFIShell   string // UEFI shell booted by the UefiShell boot target
+	IPXEEFI     string // iPXE EFI build booted by the UefiHttp boot target
+	BootDiskDir string // directory of the generated UefiShell/UefiHttp boot disk
 }
 
 // Load reads configuration from environment variables with defaults
@@ -35,6 +72,38 @@ func Load() *Config {
 		QEMUBinary:     getEnv("QEMU_BINARY", "qemu-system-x86_64"),
 		PowerOnAtStart: getBoolEnv("POWER_ON_AT_START", false),
 		VNCAddr:        getEnv("VNC_ADDR", "localhost:5900"),
+
+		IPMILockoutThreshold:   getIntEnv("IPMI_LOCKOUT_THRESHOLD", 5),
+		IPMILockoutDuration:    getDurationEnv("IPMI_LOCKOUT_DURATION", 60*time.Second),
+		IPMILockoutMaxDuration: getDurationEnv("IPMI_LOCKOUT_MAX_DURATION", time.Hour),
+		IPMIRateLimit:          getFloatEnv("IPMI_RATE_LIMIT", 20),
+		IPMIRateBurst:          getIntEnv("IPMI_RATE_BURST", 40),
+
+		RedfishSessionTimeout: getDurationEnv("REDFISH_SESSION_TIMEOUT", 30*time.Minute),
+
+		VolumeDir: getEnv("VOLUME_DIR", "/var/lib/qemu-bmc/volumes"),
+		VolumeBus: getEnv("VOLUME_BUS", ""),
+
+		VirtualMedia: getEnv("VIRTUAL_MEDIA", "CD"),
+		MediaState:   getEnv("MEDIA_STATE", "/var/lib/qemu-bmc/media.json"),
+
+		MediaCacheDir:  getEnv("MEDIA_CACHE_DIR", "/var/lib/qemu-bmc/media-cache"),
+		MediaCacheSize: getSizeEnv("MEDIA_CACHE_SIZE", 20<<30),
+		MediaTrustKeys: getEnv("MEDIA_TRUSTED_KEYS", ""),
+
+		GuestAgentSocket: getEnv("GUEST_AGENT_SOCK", ""),
+
+		ChassisInletTemp: getFloatEnv("CHASSIS_INLET_TEMP", 22),
+
+		OVMFCode:               getEnv("OVMF_CODE", "/usr/share/OVMF/OVMF_CODE.fd"),
+		OVMFSecureCode:         getEnv("OVMF_CODE_SECBOOT", "/usr/share/OVMF/OVMF_CODE_4M.secboot.fd"),
+		OVMFVarsTemplate:       getEnv("OVMF_VARS_TEMPLATE", "/usr/share/OVMF/OVMF_VARS.fd"),
+		OVMFSecureVarsTemplate: getEnv("OVMF_VARS_SECBOOT_TEMPLATE", "/usr/share/OVMF/OVMF_VARS_4M.ms.fd"),
+		OVMFVars:               getEnv("OVMF_VARS", "/vm/OVMF_VARS.fd"),
+
+		UEFIShell:   getEnv("UEFI_SHELL", "/usr/share/efi-shell-x64/shellx64.efi"),
+		IPXEEFI:     getEnv("IPXE_EFI", "/usr/lib/ipxe/ipxe.efi"),
+		BootDiskDir: getEnv("BOOT_DISK_DIR", "/var/lib/qemu-bmc/bootdisk"),
 	}
 }
 
@@ -56,3 +125,61 @@ func getBoolEnv(key string, defaultValue bool) bool {
 		return defaultValue
 	}
 }
+
+func getIntEnv(key string, defaultValue int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return v
+	}
+	return defaultValue
+}
+
+func getFloatEnv(key string, defaultValue float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return v
+	}
+	return defaultValue
+}
+
+// getSizeEnv parses a byte count with an optional K, M, G or T suffix
+// (powers of 1024).
+func getSizeEnv(key string, defaultValue int64) int64 {
+	value := strings.TrimSuffix(strings.ToUpper(os.Getenv(key)), "B")
+	if value == "" {
+		return defaultValue
+	}
+	shift := 0
+	switch value[len(value)-1] {
+	case 'K':
+		shift = 10
+	case 'M':
+		shift = 20
+	case 'G':
+		shift = 30
+	case 'T':
+		shift = 40
+	}
+	if shift > 0 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return defaultValue
+	}
+	return n << shift
+}
+
+// getDurationEnv parses a Go duration ("90s", "5m"). A bare integer is
+// taken as seconds.
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
+	}
+	return defaultValue
+}
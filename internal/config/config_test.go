@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -84,3 +85,148 @@ func TestLoad_PowerOnAtStart_False(t *testing.T) {
 	cfg := Load()
 	assert.Equal(t, false, cfg.PowerOnAtStart)
 }
+
+func TestLoad_IPMILockoutAndRateLimit_Defaults(t *testing.T) {
+	for _, key := range []string{"IPMI_LOCKOUT_THRESHOLD", "IPMI_LOCKOUT_DURATION", "IPMI_LOCKOUT_MAX_DURATION", "IPMI_RATE_LIMIT", "IPMI_RATE_BURST"} {
+		os.Unsetenv(key)
+	}
+	cfg := Load()
+	assert.Equal(t, 5, cfg.IPMILockoutThreshold)
+	assert.Equal(t, 60*time.Second, cfg.IPMILockoutDuration)
+	assert.Equal(t, time.Hour, cfg.IPMILockoutMaxDuration)
+	assert.Equal(t, 20.0, cfg.IPMIRateLimit)
+	assert.Equal(t, 40, cfg.IPMIRateBurst)
+}
+
+func TestLoad_IPMILockoutAndRateLimit_Custom(t *testing.T) {
+	os.Setenv("IPMI_LOCKOUT_THRESHOLD", "0")
+	os.Setenv("IPMI_LOCKOUT_DURATION", "90")
+	os.Setenv("IPMI_LOCKOUT_MAX_DURATION", "10m")
+	os.Setenv("IPMI_RATE_LIMIT", "2.5")
+	defer func() {
+		os.Unsetenv("IPMI_LOCKOUT_THRESHOLD")
+		os.Unsetenv("IPMI_LOCKOUT_DURATION")
+		os.Unsetenv("IPMI_LOCKOUT_MAX_DURATION")
+		os.Unsetenv("IPMI_RATE_LIMIT")
+	}()
+	cfg := Load()
+	assert.Equal(t, 0, cfg.IPMILockoutThreshold)
+	assert.Equal(t, 90*time.Second, cfg.IPMILockoutDuration)
+	assert.Equal(t, 10*time.Minute, cfg.IPMILockoutMaxDuration)
+	assert.Equal(t, 2.5, cfg.IPMIRateLimit)
+}
+
+func TestLoad_RedfishSessionTimeout(t *testing.T) {
+	os.Unsetenv("REDFISH_SESSION_TIMEOUT")
+	assert.Equal(t, 30*time.Minute, Load().RedfishSessionTimeout)
+
+	os.Setenv("REDFISH_SESSION_TIMEOUT", "5m")
+	defer os.Unsetenv("REDFISH_SESSION_TIMEOUT")
+	assert.Equal(t, 5*time.Minute, Load().RedfishSessionTimeout)
+}
+
+func TestLoad_Volume(t *testing.T) {
+	os.Unsetenv("VOLUME_DIR")
+	os.Unsetenv("VOLUME_BUS")
+	cfg := Load()
+	assert.Equal(t, "/var/lib/qemu-bmc/volumes", cfg.VolumeDir)
+	assert.Equal(t, "", cfg.VolumeBus)
+
+	os.Setenv("VOLUME_DIR", "/data/volumes")
+	os.Setenv("VOLUME_BUS", "hotplug0")
+	defer os.Unsetenv("VOLUME_DIR")
+	defer os.Unsetenv("VOLUME_BUS")
+	cfg = Load()
+	assert.Equal(t, "/data/volumes", cfg.VolumeDir)
+	assert.Equal(t, "hotplug0", cfg.VolumeBus)
+}
+
+func TestLoad_VirtualMedia(t *testing.T) {
+	os.Unsetenv("VIRTUAL_MEDIA")
+	assert.Equal(t, "CD", Load().VirtualMedia)
+
+	os.Setenv("VIRTUAL_MEDIA", "CD,USB,Floppy")
+	defer os.Unsetenv("VIRTUAL_MEDIA")
+	assert.Equal(t, "CD,USB,Floppy", Load().VirtualMedia)
+}
+
+func TestLoad_MediaState(t *testing.T) {
+	os.Unsetenv("MEDIA_STATE")
+	assert.Equal(t, "/var/lib/qemu-bmc/media.json", Load().MediaState)
+
+	os.Setenv("MEDIA_STATE", "/data/media.json")
+	defer os.Unsetenv("MEDIA_STATE")
+	assert.Equal(t, "/data/media.json", Load().MediaState)
+}
+
+func TestLoad_MediaCache(t *testing.T) {
+	os.Unsetenv("MEDIA_CACHE_DIR")
+	os.Unsetenv("MEDIA_CACHE_SIZE")
+	cfg := Load()
+	assert.Equal(t, "/var/lib/qemu-bmc/media-cache", cfg.MediaCacheDir)
+	assert.Equal(t, int64(20<<30), cfg.MediaCacheSize)
+
+	os.Setenv("MEDIA_CACHE_DIR", "/data/cache")
+	defer os.Unsetenv("MEDIA_CACHE_DIR")
+	defer os.Unsetenv("MEDIA_CACHE_SIZE")
+	for value, want := range map[string]int64{
+		"0":       0,
+		"1048576": 1 << 20,
+		"500M":    500 << 20,
+		"2GB":     2 << 30,
+		"1t":      1 << 40,
+		"lots":    20 << 30,
+	} {
+		os.Setenv("MEDIA_CACHE_SIZE", value)
+		cfg = Load()
+		assert.Equal(t, "/data/cache", cfg.MediaCacheDir)
+		assert.Equal(t, want, cfg.MediaCacheSize, value)
+	}
+}
+
+func TestLoad_MediaTrustKeys(t *testing.T) {
+	os.Unsetenv("MEDIA_TRUSTED_KEYS")
+	assert.Equal(t, "", Load().MediaTrustKeys)
+
+	os.Setenv("MEDIA_TRUSTED_KEYS", "/etc/qemu-bmc/trusted.pem")
+	defer os.Unsetenv("MEDIA_TRUSTED_KEYS")
+	assert.Equal(t, "/etc/qemu-bmc/trusted.pem", Load().MediaTrustKeys)
+}
+
+func TestLoad_GuestAgentSocket(t *testing.T) {
+	os.Unsetenv("GUEST_AGENT_SOCK")
+	assert.Equal(t, "", Load().GuestAgentSocket)
+
+	os.Setenv("GUEST_AGENT_SOCK", "/var/run/qemu/qga.sock")
+	defer os.Unsetenv("GUEST_AGENT_SOCK")
+	assert.Equal(t, "/var/run/qemu/qga.sock", Load().GuestAgentSocket)
+}
+
+func TestLoad_ChassisInletTemp(t *testing.T) {
+	os.Unsetenv("CHASSIS_INLET_TEMP")
+	assert.Equal(t, 22.0, Load().ChassisInletTemp)
+
+	os.Setenv("CHASSIS_INLET_TEMP", "38.5")
+	defer os.Unsetenv("CHASSIS_INLET_TEMP")
+	assert.Equal(t, 38.5, Load().ChassisInletTemp)
+}
+
+func TestLoad_OVMF(t *testing.T) {
+	os.Unsetenv("OVMF_VARS")
+	assert.Equal(t, "/vm/OVMF_VARS.fd", Load().OVMFVars)
+	assert.Equal(t, "/usr/share/OVMF/OVMF_CODE.fd", Load().OVMFCode)
+
+	os.Setenv("OVMF_VARS", "/var/lib/qemu-bmc/OVMF_VARS.fd")
+	defer os.Unsetenv("OVMF_VARS")
+	assert.Equal(t, "/var/lib/qemu-bmc/OVMF_VARS.fd", Load().OVMFVars)
+}
+
+func TestLoad_BootImages(t *testing.T) {
+	os.Unsetenv("IPXE_EFI")
+	assert.Equal(t, "/usr/lib/ipxe/ipxe.efi", Load().IPXEEFI)
+	assert.Equal(t, "/var/lib/qemu-bmc/bootdisk", Load().BootDiskDir)
+
+	os.Setenv("UEFI_SHELL", "/opt/edk2/Shell.efi")
+	defer os.Unsetenv("UEFI_SHELL")
+	assert.Equal(t, "/opt/edk2/Shell.efi", Load().UEFIShell)
+}
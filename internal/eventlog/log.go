@@ -0,0 +1,79 @@
+// Package eventlog keeps bounded in-memory logs of timestamped entries.
+package eventlog
+
+import (
+	"sync"
+	"time"
+)
+
+// Severities of log entries, as used by Redfish
+const (
+	SeverityOK       = "OK"
+	SeverityWarning  = "Warning"
+	SeverityCritical = "Critical"
+)
+
+// Entry is a log record
+type Entry struct {
+	ID          uint64 // assigned by Add, never reused
+	Created     time.Time
+	Severity    string
+	Message     string
+	MessageID   string   // message registry ID, "" for free-form messages
+	MessageArgs []string // arguments of the MessageID message
+}
+
+// Log is a bounded log. The oldest entries are dropped once it is full.
+type Log struct {
+	mu      sync.Mutex
+	max     int
+	lastID  uint64
+	entries []Entry
+	now     func() time.Time
+}
+
+// New returns a log holding at most max entries.
+func New(max int) *Log {
+	return &Log{max: max, now: time.Now}
+}
+
+// Add appends e, assigning its ID and, if unset, its creation time and
+// severity. It returns the stored entry.
+func (l *Log) Add(e Entry) Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastID++
+	e.ID = l.lastID
+	if e.Created.IsZero() {
+		e.Created = l.now()
+	}
+	if e.Severity == "" {
+		e.Severity = SeverityOK
+	}
+	if len(l.entries) >= l.max {
+		l.entries = l.entries[1:]
+	}
+	l.entries = append(l.entries, e)
+	return e
+}
+
+// Entries returns a copy of the entries, oldest first.
+func (l *Log) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]Entry, len(l.entries))
+	copy(out, l.entries)
+	return out
+}
+
+// Clear removes all entries. IDs keep counting up.
+func (l *Log) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+// Max returns the number of entries the log holds.
+func (l *Log) Max() int {
+	return l.max
+}
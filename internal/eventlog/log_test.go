@@ -0,0 +1,54 @@
+package eventlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_Add(t *testing.T) {
+	l := New(10)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	e := l.Add(Entry{Message: "started"})
+	assert.Equal(t, uint64(1), e.ID)
+	assert.Equal(t, now, e.Created)
+	assert.Equal(t, SeverityOK, e.Severity)
+
+	created := now.Add(-time.Hour)
+	e = l.Add(Entry{Message: "failed", Severity: SeverityCritical, Created: created})
+	assert.Equal(t, uint64(2), e.ID)
+	assert.Equal(t, created, e.Created)
+	assert.Equal(t, SeverityCritical, e.Severity)
+
+	entries := l.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "started", entries[0].Message)
+	assert.Equal(t, "failed", entries[1].Message)
+}
+
+func TestLog_DropsOldest(t *testing.T) {
+	l := New(2)
+	l.Add(Entry{Message: "a"})
+	l.Add(Entry{Message: "b"})
+	l.Add(Entry{Message: "c"})
+
+	entries := l.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "b", entries[0].Message)
+	assert.Equal(t, uint64(3), entries[1].ID)
+	assert.Equal(t, 2, l.Max())
+}
+
+func TestLog_Clear(t *testing.T) {
+	l := New(10)
+	l.Add(Entry{Message: "a"})
+	l.Clear()
+	assert.Empty(t, l.Entries())
+
+	// IDs are not reused after a clear
+	assert.Equal(t, uint64(2), l.Add(Entry{Message: "b"}).ID)
+}
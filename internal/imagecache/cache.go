@@ -0,0 +1,502 @@
+// Package imagecache downloads virtual media images into a local,
+// content-addressed cache so QEMU reads them from disk instead of the
+// network.
+package imagecache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache errors
+var (
+	ErrTooLarge    = errors.New("image is larger than the media cache")
+	ErrInvalidName = errors.New("invalid image name")
+	ErrNotFound    = errors.New("image not found")
+)
+
+// MaxNameLength is the longest name of an uploaded image. The entry file
+// adds ".json" to the name and must fit in 255 bytes.
+const MaxNameLength = 250
+
+// validName matches the names of uploaded images, which are used in file
+// and URL paths.
+var validName = regexp.MustCompile(fmt.Sprintf(`^[A-Za-z0-9][A-Za-z0-9._-]{0,%d}$`, MaxNameLength-1))
+
+// Layout of the cache directory. Blobs are named by the SHA-256 of their
+// content, so the same image downloaded from different URLs, or by other
+// BMCs sharing the directory, is stored once. Each URL has a small entry
+// pointing at its blob, and so has each uploaded image. Images downloaded
+// with credentials have an entry per URL and credentials, so they are
+// only served to clients presenting the same credentials.
+const (
+	blobDir   = "blobs"
+	urlDir    = "urls"
+	uploadDir = "uploads"
+	tmpDir    = "tmp"
+	tmpMaxAge = 24 * time.Hour // abandoned downloads are removed after this
+)
+
+// Upload is an image uploaded into the cache
+type Upload struct {
+	Name     string    `json:"Name"`
+	SHA256   string    `json:"SHA256"`
+	Size     int64     `json:"Size"`
+	Uploaded time.Time `json:"Uploaded"`
+	Path     string    `json:"-"` // the image file
+}
+
+// entry is the cached state of a URL
+type entry struct {
+	URL          string `json:"URL"`
+	SHA256       string `json:"SHA256"`
+	ETag         string `json:"ETag,omitempty"`
+	LastModified string `json:"LastModified,omitempty"`
+}
+
+// Cache is a directory of downloaded images limited to maxSize bytes.
+// The least recently used images are evicted first; pinned images, which
+// are in a drive, are kept.
+type Cache struct {
+	dir     string
+	maxSize int64
+	client  *http.Client
+
+	mu     sync.Mutex
+	pinned map[string]string    // holder -> blob path
+	locks  map[string]*download // entry key -> download in progress
+}
+
+// download serializes the fetches of an entry
+type download struct {
+	mu      sync.Mutex
+	waiters int // fetches holding or waiting for mu
+}
+
+// New creates the cache in dir. maxSize 0 means unlimited.
+func New(dir string, maxSize int64) (*Cache, error) {
+	for _, sub := range []string{blobDir, urlDir, uploadDir, tmpDir} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("creating media cache: %w", err)
+		}
+	}
+	c := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		client: &http.Client{Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ResponseHeaderTimeout: time.Minute,
+		}},
+		pinned: map[string]string{},
+		locks:  map[string]*download{},
+	}
+	c.removeStaleDownloads()
+	return c, nil
+}
+
+// Cacheable reports whether image is a URL the cache downloads.
+func Cacheable(image string) bool {
+	return strings.HasPrefix(image, "http://") || strings.HasPrefix(image, "https://")
+}
+
+// Fetch returns the local path of the image at url, downloading it unless
+// the cache has a current copy. A cached copy is revalidated with the
+// server when it sent an ETag or Last-Modified, and used as is when the
+// server cannot be reached. Copies downloaded with credentials are only
+// used for the same credentials. progress, if not nil, receives the
+// download progress in percent.
+func (c *Cache) Fetch(url, user, password string, progress func(percent int)) (string, error) {
+	key := entryKey(url, user, password)
+	defer c.lock(key)()
+
+	cached, ok := c.lookup(key, url)
+	if ok && cached.ETag == "" && cached.LastModified == "" {
+		return c.use(cached), nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("downloading %s: %w", url, err)
+	}
+	if user != "" || password != "" {
+		req.SetBasicAuth(user, password)
+	}
+	if ok {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ok {
+			log.Printf("Using cached %s: %v", url, err)
+			return c.use(cached), nil
+		}
+		return "", fmt.Errorf("downloading %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case ok && resp.StatusCode == http.StatusNotModified:
+		return c.use(cached), nil
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("downloading %s: %s", url, resp.Status)
+	case c.maxSize > 0 && resp.ContentLength > c.maxSize:
+		return "", fmt.Errorf("downloading %s: %w", url, ErrTooLarge)
+	}
+
+	sum, _, err := c.store(resp.Body, resp.ContentLength, progress)
+	if err != nil {
+		return "", fmt.Errorf("downloading %s: %w", url, err)
+	}
+	e := entry{
+		URL:          url,
+		SHA256:       sum,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if err := writeFile(c.urlPath(key), e); err != nil {
+		log.Printf("Saving media cache entry for %s: %v", url, err)
+	}
+	path := c.use(e)
+	c.evict(path)
+	return path, nil
+}
+
+// Pin keeps the image at path in the cache for holder, replacing what
+// holder pinned before. An empty path releases the pin.
+func (c *Cache) Pin(holder, path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if path == "" {
+		delete(c.pinned, holder)
+	} else {
+		c.pinned[holder] = path
+	}
+}
+
+// Contains reports whether path is an image in the cache.
+func (c *Cache) Contains(path string) bool {
+	if filepath.Dir(path) != filepath.Join(c.dir, blobDir) {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Store saves the image read from r as the upload name, replacing an
+// upload of that name. size is the length of the image, or -1 if unknown.
+// Uploaded images are evicted like downloaded ones.
+func (c *Cache) Store(name string, r io.Reader, size int64) (Upload, error) {
+	if !validName.MatchString(name) {
+		return Upload{}, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	if c.maxSize > 0 && size > c.maxSize {
+		return Upload{}, ErrTooLarge
+	}
+	sum, n, err := c.store(r, size, nil)
+	if err != nil {
+		return Upload{}, err
+	}
+	u := Upload{Name: name, SHA256: sum, Size: n, Uploaded: time.Now().UTC()}
+	if err := writeFile(c.uploadPath(name), u); err != nil {
+		return Upload{}, err
+	}
+	u.Path = c.blobPath(sum)
+	c.evict(u.Path)
+	return u, nil
+}
+
+// Uploads returns the uploaded images still in the cache, by name.
+func (c *Cache) Uploads() ([]Upload, error) {
+	entries, err := os.ReadDir(filepath.Join(c.dir, uploadDir))
+	if err != nil {
+		return nil, err
+	}
+	var uploads []Upload
+	for _, de := range entries {
+		name, ok := strings.CutSuffix(de.Name(), ".json")
+		if !ok {
+			continue
+		}
+		if u, ok := c.Lookup(name); ok {
+			uploads = append(uploads, u)
+		}
+	}
+	return uploads, nil
+}
+
+// Lookup returns the uploaded image name if it is in the cache.
+func (c *Cache) Lookup(name string) (Upload, bool) {
+	if !validName.MatchString(name) {
+		return Upload{}, false
+	}
+	var u Upload
+	data, err := os.ReadFile(c.uploadPath(name))
+	if err != nil {
+		return Upload{}, false
+	}
+	if err := json.Unmarshal(data, &u); err != nil || u.Name != name {
+		return Upload{}, false
+	}
+	u.Path = c.blobPath(u.SHA256)
+	if _, err := os.Stat(u.Path); err != nil {
+		return Upload{}, false
+	}
+	return u, true
+}
+
+// Delete removes the uploaded image name. Its file stays while it is
+// pinned.
+func (c *Cache) Delete(name string) error {
+	u, ok := c.Lookup(name)
+	if !ok {
+		return ErrNotFound
+	}
+	if err := os.Remove(c.uploadPath(name)); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.pinnedLocked(u.Path) {
+		if err := os.Remove(u.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// store streams r into the cache and returns the SHA-256 and length of
+// its content. size is the expected length, or -1 if unknown.
+func (c *Cache) store(r io.Reader, size int64, progress func(int)) (string, int64, error) {
+	tmp, err := os.CreateTemp(filepath.Join(c.dir, tmpDir), "download-")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	h := sha256.New()
+	if c.maxSize > 0 {
+		// One byte more than allowed tells an oversized image apart.
+		r = io.LimitReader(r, c.maxSize+1)
+	}
+	pw := &progressWriter{total: size, report: progress, last: -1}
+	n, err := io.Copy(io.MultiWriter(tmp, h, pw), r)
+	if err != nil {
+		return "", 0, err
+	}
+	if c.maxSize > 0 && n > c.maxSize {
+		return "", 0, ErrTooLarge
+	}
+	if size >= 0 && n != size {
+		return "", 0, fmt.Errorf("short transfer: %d of %d bytes", n, size)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+	// QEMU may run as another user.
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", 0, err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if err := os.Rename(tmp.Name(), c.blobPath(sum)); err != nil {
+		return "", 0, err
+	}
+	return sum, n, nil
+}
+
+// lookup returns the entry key of url if its image is in the cache.
+func (c *Cache) lookup(key, url string) (entry, bool) {
+	var e entry
+	data, err := os.ReadFile(c.urlPath(key))
+	if err != nil {
+		return entry{}, false
+	}
+	if err := json.Unmarshal(data, &e); err != nil || e.URL != url {
+		return entry{}, false
+	}
+	if _, err := os.Stat(c.blobPath(e.SHA256)); err != nil {
+		return entry{}, false
+	}
+	return e, true
+}
+
+// use marks the image of e as recently used and returns its path.
+func (c *Cache) use(e entry) string {
+	path := c.blobPath(e.SHA256)
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		log.Printf("Touching cached image %s: %v", path, err)
+	}
+	return path
+}
+
+// evict removes the least recently used images until the cache fits in
+// maxSize. keep and the pinned images are never removed.
+func (c *Cache) evict(keep string) {
+	if c.maxSize <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(filepath.Join(c.dir, blobDir))
+	if err != nil {
+		log.Printf("Reading media cache: %v", err)
+		return
+	}
+	type blob struct {
+		path  string
+		size  int64
+		mtime time.Time
+	}
+	var blobs []blob
+	var total int64
+	for _, de := range entries {
+		info, err := de.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		blobs = append(blobs, blob{filepath.Join(c.dir, blobDir, de.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	slices.SortFunc(blobs, func(a, b blob) int { return a.mtime.Compare(b.mtime) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, b := range blobs {
+		if total <= c.maxSize {
+			return
+		}
+		if b.path == keep || c.pinnedLocked(b.path) {
+			continue
+		}
+		if err := os.Remove(b.path); err != nil {
+			log.Printf("Evicting %s from the media cache: %v", b.path, err)
+			continue
+		}
+		total -= b.size
+	}
+}
+
+func (c *Cache) pinnedLocked(path string) bool {
+	for _, p := range c.pinned {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// removeStaleDownloads removes downloads left behind by a BMC that
+// stopped. Recent ones may belong to another BMC sharing the directory.
+func (c *Cache) removeStaleDownloads() {
+	entries, err := os.ReadDir(filepath.Join(c.dir, tmpDir))
+	if err != nil {
+		return
+	}
+	for _, de := range entries {
+		if info, err := de.Info(); err == nil && time.Since(info.ModTime()) > tmpMaxAge {
+			os.Remove(filepath.Join(c.dir, tmpDir, de.Name()))
+		}
+	}
+}
+
+// lock waits for other fetches of the entry key and returns the function
+// releasing it. The lock is dropped when no fetch holds or waits for it.
+func (c *Cache) lock(key string) func() {
+	c.mu.Lock()
+	d, ok := c.locks[key]
+	if !ok {
+		d = &download{}
+		c.locks[key] = d
+	}
+	d.waiters++
+	c.mu.Unlock()
+
+	d.mu.Lock()
+	return func() {
+		d.mu.Unlock()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if d.waiters--; d.waiters == 0 {
+			delete(c.locks, key)
+		}
+	}
+}
+
+// entryKey identifies the entry of url downloaded with the credentials.
+// Anonymous downloads are keyed by the URL alone.
+func entryKey(url, user, password string) string {
+	if user == "" && password == "" {
+		return url
+	}
+	return url + "\x00" + user + "\x00" + password
+}
+
+func (c *Cache) blobPath(sum string) string {
+	return filepath.Join(c.dir, blobDir, sum)
+}
+
+func (c *Cache) uploadPath(name string) string {
+	return filepath.Join(c.dir, uploadDir, name+".json")
+}
+
+func (c *Cache) urlPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, urlDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// writeFile replaces the file at path atomically.
+func writeFile(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// progressWriter reports the share of total bytes written so far
+type progressWriter struct {
+	total   int64
+	written int64
+	report  func(int)
+	last    int
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if p.report != nil && p.total > 0 {
+		if percent := int(p.written * 100 / p.total); percent != p.last {
+			p.last = percent
+			p.report(percent)
+		}
+	}
+	return len(b), nil
+}
@@ -0,0 +1,331 @@
+package imagecache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newImageServer serves images by path and counts the full downloads.
+func newImageServer(t *testing.T, images map[string]string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := images[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		downloads.Add(1)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &downloads
+}
+
+func TestFetch_DownloadsOnce(t *testing.T) {
+	srv, downloads := newImageServer(t, map[string]string{
+		"/boot.iso": "boot image",
+		"/copy.iso": "boot image",
+	})
+	c, err := New(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	path, err := c.Fetch(srv.URL+"/boot.iso", "", "", nil)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "boot image", string(data))
+	assert.True(t, c.Contains(path))
+
+	again, err := c.Fetch(srv.URL+"/boot.iso", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, path, again)
+	assert.Equal(t, int32(1), downloads.Load(), "served from the cache")
+
+	// The same content from another URL is stored once
+	copyPath, err := c.Fetch(srv.URL+"/copy.iso", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, path, copyPath)
+}
+
+func TestFetch_SharedDirectory(t *testing.T) {
+	srv, downloads := newImageServer(t, map[string]string{"/boot.iso": "boot image"})
+	dir := t.TempDir()
+	a, err := New(dir, 0)
+	require.NoError(t, err)
+	b, err := New(dir, 0)
+	require.NoError(t, err)
+
+	_, err = a.Fetch(srv.URL+"/boot.iso", "", "", nil)
+	require.NoError(t, err)
+	_, err = b.Fetch(srv.URL+"/boot.iso", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), downloads.Load())
+}
+
+func TestFetch_Revalidates(t *testing.T) {
+	etag := `"v1"`
+	body := "version 1"
+	var downloads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads.Add(1)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+	c, err := New(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	first, err := c.Fetch(srv.URL+"/latest.iso", "", "", nil)
+	require.NoError(t, err)
+	_, err = c.Fetch(srv.URL+"/latest.iso", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), downloads.Load(), "not modified")
+
+	etag, body = `"v2"`, "version 2"
+	second, err := c.Fetch(srv.URL+"/latest.iso", "", "", nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	data, err := os.ReadFile(second)
+	require.NoError(t, err)
+	assert.Equal(t, "version 2", string(data))
+
+	// The cached copy is used while the server is down
+	srv.Close()
+	offline, err := c.Fetch(srv.URL+"/latest.iso", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, second, offline)
+}
+
+func TestFetch_Credentials(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "deploy" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("private image"))
+	}))
+	defer srv.Close()
+	c, err := New(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	_, err = c.Fetch(srv.URL+"/private.iso", "", "", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+
+	path, err := c.Fetch(srv.URL+"/private.iso", "deploy", "secret", nil)
+	require.NoError(t, err)
+	again, err := c.Fetch(srv.URL+"/private.iso", "deploy", "secret", nil)
+	require.NoError(t, err)
+	assert.Equal(t, path, again)
+
+	// The cached copy is not served without the credentials
+	_, err = c.Fetch(srv.URL+"/private.iso", "", "", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+	_, err = c.Fetch(srv.URL+"/private.iso", "deploy", "guess", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestFetch_ReleasesLocks(t *testing.T) {
+	srv, _ := newImageServer(t, map[string]string{"/boot.iso": "boot image"})
+	c, err := New(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	done := make(chan error)
+	for range 4 {
+		go func() {
+			_, err := c.Fetch(srv.URL+"/boot.iso", "", "", nil)
+			done <- err
+		}()
+	}
+	for range 4 {
+		require.NoError(t, <-done)
+	}
+	_, err = c.Fetch(srv.URL+"/missing.iso", "", "", nil)
+	require.Error(t, err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	assert.Empty(t, c.locks)
+}
+
+func TestFetch_Progress(t *testing.T) {
+	srv, _ := newImageServer(t, map[string]string{"/big.iso": strings.Repeat("x", 1<<20)})
+	c, err := New(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	var reported []int
+	_, err = c.Fetch(srv.URL+"/big.iso", "", "", func(p int) { reported = append(reported, p) })
+	require.NoError(t, err)
+	require.NotEmpty(t, reported)
+	assert.Equal(t, 100, reported[len(reported)-1])
+	assert.IsIncreasing(t, reported)
+}
+
+func TestFetch_TooLarge(t *testing.T) {
+	srv, _ := newImageServer(t, map[string]string{"/big.iso": strings.Repeat("x", 100)})
+	chunked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 50)))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(strings.Repeat("x", 50)))
+	}))
+	defer chunked.Close()
+	dir := t.TempDir()
+	c, err := New(dir, 64)
+	require.NoError(t, err)
+
+	_, err = c.Fetch(srv.URL+"/big.iso", "", "", nil)
+	assert.ErrorIs(t, err, ErrTooLarge)
+	_, err = c.Fetch(chunked.URL+"/big.iso", "", "", nil)
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	blobs, err := os.ReadDir(filepath.Join(dir, blobDir))
+	require.NoError(t, err)
+	assert.Empty(t, blobs)
+	tmp, err := os.ReadDir(filepath.Join(dir, tmpDir))
+	require.NoError(t, err)
+	assert.Empty(t, tmp)
+}
+
+func TestFetch_EvictsLeastRecentlyUsed(t *testing.T) {
+	srv, _ := newImageServer(t, map[string]string{
+		"/a.iso": strings.Repeat("a", 40),
+		"/b.iso": strings.Repeat("b", 40),
+		"/c.iso": strings.Repeat("c", 40),
+		"/d.iso": strings.Repeat("d", 40),
+	})
+	c, err := New(t.TempDir(), 100)
+	require.NoError(t, err)
+	fetch := func(name string) string {
+		t.Helper()
+		path, err := c.Fetch(srv.URL+"/"+name, "", "", nil)
+		require.NoError(t, err)
+		return path
+	}
+	age := func(path string, d time.Duration) {
+		t.Helper()
+		old := time.Now().Add(-d)
+		require.NoError(t, os.Chtimes(path, old, old))
+	}
+
+	a := fetch("a.iso")
+	age(a, 2*time.Hour)
+	b := fetch("b.iso")
+	age(b, time.Hour)
+	c.Pin("CD1", a)
+
+	// c.iso exceeds the limit: b is evicted, a is pinned
+	cPath := fetch("c.iso")
+	assert.FileExists(t, a)
+	assert.NoFileExists(t, b)
+	assert.FileExists(t, cPath)
+
+	c.Pin("CD1", "")
+	age(cPath, time.Hour)
+	d := fetch("d.iso")
+	assert.NoFileExists(t, a)
+	assert.FileExists(t, cPath)
+	assert.FileExists(t, d)
+	assert.False(t, c.Contains(a))
+}
+
+func TestFetch_NotFound(t *testing.T) {
+	srv, _ := newImageServer(t, nil)
+	c, err := New(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	_, err = c.Fetch(srv.URL+"/missing.iso", "", "", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
+
+func TestCacheable(t *testing.T) {
+	assert.True(t, Cacheable("http://example.com/boot.iso"))
+	assert.True(t, Cacheable("https://example.com/boot.iso"))
+	assert.False(t, Cacheable("/images/boot.iso"))
+	assert.False(t, Cacheable("nfs://server/boot.iso"))
+}
+
+func TestStore(t *testing.T) {
+	c, err := New(t.TempDir(), 0)
+	require.NoError(t, err)
+
+	u, err := c.Store("rescue.iso", strings.NewReader("rescue image"), -1)
+	require.NoError(t, err)
+	assert.Equal(t, "rescue.iso", u.Name)
+	assert.Equal(t, int64(12), u.Size)
+	data, err := os.ReadFile(u.Path)
+	require.NoError(t, err)
+	assert.Equal(t, "rescue image", string(data))
+
+	_, err = c.Store("drivers.img", strings.NewReader("drivers"), 7)
+	require.NoError(t, err)
+	uploads, err := c.Uploads()
+	require.NoError(t, err)
+	require.Len(t, uploads, 2)
+	assert.Equal(t, "drivers.img", uploads[0].Name)
+	assert.Equal(t, "rescue.iso", uploads[1].Name)
+
+	found, ok := c.Lookup("rescue.iso")
+	require.True(t, ok)
+	assert.Equal(t, u.Path, found.Path)
+	assert.Equal(t, u.SHA256, found.SHA256)
+	_, ok = c.Lookup("missing.iso")
+	assert.False(t, ok)
+}
+
+func TestStore_Errors(t *testing.T) {
+	c, err := New(t.TempDir(), 16)
+	require.NoError(t, err)
+
+	for _, name := range []string{"", "../etc/passwd", ".hidden", "a/b.iso", "with space.iso", strings.Repeat("a", MaxNameLength+1)} {
+		_, err := c.Store(name, strings.NewReader("x"), 1)
+		assert.ErrorIs(t, err, ErrInvalidName, name)
+	}
+	_, err = c.Store("big.iso", strings.NewReader(strings.Repeat("x", 17)), 17)
+	assert.ErrorIs(t, err, ErrTooLarge)
+	_, err = c.Store("big.iso", strings.NewReader(strings.Repeat("x", 17)), -1)
+	assert.ErrorIs(t, err, ErrTooLarge)
+	_, err = c.Store("short.iso", strings.NewReader("abc"), 10)
+	assert.Error(t, err)
+
+	uploads, err := c.Uploads()
+	require.NoError(t, err)
+	assert.Empty(t, uploads)
+}
+
+func TestDelete(t *testing.T) {
+	c, err := New(t.TempDir(), 0)
+	require.NoError(t, err)
+	a, err := c.Store("a.iso", strings.NewReader("image a"), -1)
+	require.NoError(t, err)
+	b, err := c.Store("b.iso", strings.NewReader("image b"), -1)
+	require.NoError(t, err)
+	c.Pin("CD1", b.Path)
+
+	require.NoError(t, c.Delete("a.iso"))
+	assert.NoFileExists(t, a.Path)
+	require.NoError(t, c.Delete("b.iso"))
+	assert.FileExists(t, b.Path, "still in a drive")
+	_, ok := c.Lookup("b.iso")
+	assert.False(t, ok)
+
+	assert.ErrorIs(t, c.Delete("a.iso"), ErrNotFound)
+}
@@ -0,0 +1,143 @@
+package imagecache
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrVerification is returned for images that do not match the expected
+// checksum or signature
+var ErrVerification = errors.New("image verification failed")
+
+// Verification is the result of verifying an image
+type Verification struct {
+	SHA256            string `json:"SHA256"` // of the verified file
+	ChecksumVerified  bool   `json:"ChecksumVerified"`
+	SignatureVerified bool   `json:"SignatureVerified"`
+	Signer            string `json:"Signer,omitempty"` // SHA-256 fingerprint of the signing key
+}
+
+// Verifier checks images against checksums and detached signatures made
+// with trusted keys
+type Verifier struct {
+	keys []crypto.PublicKey
+}
+
+// NewVerifier returns a Verifier trusting keys, which are RSA or ECDSA
+// public keys. Without keys only checksums can be verified.
+func NewVerifier(keys ...crypto.PublicKey) *Verifier {
+	return &Verifier{keys: keys}
+}
+
+// LoadVerifier reads the trusted keys from a PEM file of PUBLIC KEY
+// blocks, as written by "openssl pkey -pubout".
+func LoadVerifier(path string) (*Verifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading trusted keys: %w", err)
+	}
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing trusted key in %s: %w", path, err)
+		}
+		switch key.(type) {
+		case *rsa.PublicKey, *ecdsa.PublicKey:
+			keys = append(keys, key)
+		default:
+			return nil, fmt.Errorf("trusted key in %s: unsupported key type %T", path, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no public keys in %s", path)
+	}
+	return NewVerifier(keys...), nil
+}
+
+// Verify computes the SHA-256 of the file at path and checks it against
+// sha256Hex and signature, each optional. The signature is an RSA
+// (PKCS #1 v1.5) or ECDSA (ASN.1) signature of the SHA-256 digest, as
+// made by "openssl dgst -sha256 -sign".
+func (v *Verifier) Verify(path, sha256Hex string, signature []byte) (Verification, error) {
+	digest, err := fileSHA256(path)
+	if err != nil {
+		return Verification{}, fmt.Errorf("hashing %s: %w", path, err)
+	}
+	res := Verification{SHA256: hex.EncodeToString(digest)}
+	if sha256Hex != "" {
+		if !strings.EqualFold(sha256Hex, res.SHA256) {
+			return res, fmt.Errorf("%w: SHA-256 is %s, expected %s", ErrVerification, res.SHA256, strings.ToLower(sha256Hex))
+		}
+		res.ChecksumVerified = true
+	}
+	if len(signature) > 0 {
+		if v == nil || len(v.keys) == 0 {
+			return res, fmt.Errorf("%w: no trusted keys to check the signature", ErrVerification)
+		}
+		key, ok := v.signer(digest, signature)
+		if !ok {
+			return res, fmt.Errorf("%w: signature does not match a trusted key", ErrVerification)
+		}
+		res.SignatureVerified = true
+		res.Signer = fingerprint(key)
+	}
+	return res, nil
+}
+
+// signer returns the trusted key signature was made with.
+func (v *Verifier) signer(digest, signature []byte) (crypto.PublicKey, bool) {
+	for _, key := range v.keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest, signature) == nil {
+				return key, true
+			}
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, digest, signature) {
+				return key, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func fingerprint(key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+func fileSHA256(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,112 @@
+package imagecache
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeImage(t *testing.T, content string) (string, []byte) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "boot.iso")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	sum := sha256.Sum256([]byte(content))
+	return path, sum[:]
+}
+
+func writePublicKeys(t *testing.T, keys ...crypto.PublicKey) string {
+	t.Helper()
+	var data []byte
+	for _, key := range keys {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		require.NoError(t, err)
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)
+	}
+	path := filepath.Join(t.TempDir(), "trusted.pem")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	return path
+}
+
+func TestVerify_Checksum(t *testing.T) {
+	path, digest := writeImage(t, "boot image")
+	sum := hex.EncodeToString(digest)
+	var v *Verifier
+
+	res, err := v.Verify(path, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, Verification{SHA256: sum}, res, "nothing to verify")
+
+	_, err = v.Verify(path, "  ", nil)
+	assert.ErrorIs(t, err, ErrVerification)
+
+	res, err = v.Verify(path, sum, nil)
+	require.NoError(t, err)
+	assert.True(t, res.ChecksumVerified)
+	assert.False(t, res.SignatureVerified)
+
+	_, err = v.Verify(path, hex.EncodeToString(make([]byte, 32)), nil)
+	assert.ErrorIs(t, err, ErrVerification)
+
+	_, err = v.Verify(filepath.Join(t.TempDir(), "missing.iso"), sum, nil)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrVerification)
+}
+
+func TestVerify_Signature(t *testing.T) {
+	path, digest := writeImage(t, "boot image")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	v, err := LoadVerifier(writePublicKeys(t, &rsaKey.PublicKey, &ecKey.PublicKey))
+	require.NoError(t, err)
+
+	rsaSig, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest)
+	require.NoError(t, err)
+	res, err := v.Verify(path, "", rsaSig)
+	require.NoError(t, err)
+	assert.True(t, res.SignatureVerified)
+	assert.Equal(t, fingerprint(&rsaKey.PublicKey), res.Signer)
+
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ecKey, digest)
+	require.NoError(t, err)
+	res, err = v.Verify(path, hex.EncodeToString(digest), ecSig)
+	require.NoError(t, err)
+	assert.True(t, res.ChecksumVerified)
+	assert.True(t, res.SignatureVerified)
+	assert.Equal(t, fingerprint(&ecKey.PublicKey), res.Signer)
+
+	otherSig, err := ecdsa.SignASN1(rand.Reader, otherKey, digest)
+	require.NoError(t, err)
+	_, err = v.Verify(path, "", otherSig)
+	assert.ErrorIs(t, err, ErrVerification)
+
+	// Without trusted keys a signature cannot be checked
+	_, err = NewVerifier().Verify(path, "", ecSig)
+	assert.ErrorIs(t, err, ErrVerification)
+}
+
+func TestLoadVerifier_Errors(t *testing.T) {
+	_, err := LoadVerifier(filepath.Join(t.TempDir(), "missing.pem"))
+	assert.Error(t, err)
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("not a key"), 0o644))
+	_, err = LoadVerifier(empty)
+	assert.Error(t, err)
+}
@@ -5,18 +5,22 @@ import (
 	"encoding/binary"
 
 	"github.com/tjst-t/qemu-bmc/internal/bmc"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
 )
 
 // ipmi15Session tracks IPMI 1.5 session state
 var ipmi15TempSessionID uint32
 var ipmi15Challenge [16]byte
 var ipmi15ActiveSessionID uint32
+var ipmi15Privilege uint8 // maximum privilege requested at Activate Session
 
 // handleAppCommand handles Application network function commands
 func handleAppCommand(msg *IPMIMessage, machine MachineInterface, state *bmc.State) (CompletionCode, []byte) {
 	switch msg.Command {
 	case CmdGetDeviceID:
 		return handleGetDeviceID()
+	case CmdGetACPIPowerState:
+		return handleGetACPIPowerState(machine)
 	case CmdGetChannelAuthCapabilities:
 		return handleGetChannelAuthCapabilities(msg.Data)
 	case CmdGetSessionChallenge:
@@ -43,11 +47,50 @@ func handleAppCommand(msg *IPMIMessage, machine MachineInterface, state *bmc.Sta
 		return handleSetChannelAccess(msg.Data, state)
 	case CmdGetChannelInfo:
 		return handleGetChannelInfo(msg.Data, state)
+	case CmdSetBMCGlobalEnables:
+		return handleSetBMCGlobalEnables(msg.Data, state)
+	case CmdGetBMCGlobalEnables:
+		return handleGetBMCGlobalEnables(state)
+	case CmdClearMessageFlags:
+		return handleClearMessageFlags(msg, state)
+	case CmdGetMessageFlags:
+		return handleGetMessageFlags(msg, state)
+	case CmdGetMessage:
+		return handleGetMessage(msg, state)
+	case CmdSendMessage:
+		return handleSendMessage(msg, state)
+	case CmdReadEventMessageBuffer:
+		return handleReadEventMessageBuffer(msg, state)
+	case CmdSetSystemInfoParameters:
+		return handleSetSystemInfoParams(msg, state)
+	case CmdGetSystemInfoParameters:
+		return handleGetSystemInfoParams(msg.Data, state)
 	default:
 		return CompletionCodeInvalidCommand, nil
 	}
 }
 
+// handleGetACPIPowerState reports the system and device ACPI power states
+// of the VM's power state. A transitioning VM is still working (S0); a
+// paused one is reported as S1, which also stops the CPUs, and a suspended
+// guest as S3.
+func handleGetACPIPowerState(m MachineInterface) (CompletionCode, []byte) {
+	state, err := m.GetPowerState()
+	if err != nil {
+		return CompletionCodeUnspecified, nil
+	}
+	switch state {
+	case machine.PowerOff:
+		return CompletionCodeOK, []byte{ACPISystemS5, ACPIDeviceD3}
+	case machine.PowerPaused:
+		return CompletionCodeOK, []byte{ACPISystemS1, ACPIDeviceD1}
+	case machine.PowerSuspended:
+		return CompletionCodeOK, []byte{ACPISystemS3, ACPIDeviceD3}
+	default:
+		return CompletionCodeOK, []byte{ACPISystemS0, ACPIDeviceD0}
+	}
+}
+
 func handleGetDeviceID() (CompletionCode, []byte) {
 	// Static response for a virtual BMC
 	data := []byte{
@@ -113,6 +156,8 @@ func handleActivateSession(reqData []byte) (CompletionCode, []byte) {
 		ipmi15ActiveSessionID = 1
 	}
 
+	ipmi15Privilege = maxPriv & 0x0F
+
 	resp := make([]byte, 10)
 	resp[0] = authType
 	binary.LittleEndian.PutUint32(resp[1:5], ipmi15ActiveSessionID)
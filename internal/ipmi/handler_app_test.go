@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
 )
 
 func TestHandleGetDeviceID(t *testing.T) {
@@ -14,6 +15,27 @@ func TestHandleGetDeviceID(t *testing.T) {
 	assert.Equal(t, byte(0x02), data[4]) // IPMI 2.0
 }
 
+func TestHandleGetACPIPowerState(t *testing.T) {
+	tests := []struct {
+		state  machine.PowerState
+		system byte
+		device byte
+	}{
+		{machine.PowerOn, ACPISystemS0, ACPIDeviceD0},
+		{machine.PowerPoweringOn, ACPISystemS0, ACPIDeviceD0},
+		{machine.PowerPoweringOff, ACPISystemS0, ACPIDeviceD0},
+		{machine.PowerPaused, ACPISystemS1, ACPIDeviceD1},
+		{machine.PowerSuspended, ACPISystemS3, ACPIDeviceD3},
+		{machine.PowerOff, ACPISystemS5, ACPIDeviceD3},
+	}
+	for _, tt := range tests {
+		msg := &IPMIMessage{Command: CmdGetACPIPowerState}
+		code, data := handleAppCommand(msg, newIPMIMockMachine(tt.state), nil)
+		assert.Equal(t, CompletionCodeOK, code)
+		assert.Equal(t, []byte{tt.system, tt.device}, data, string(tt.state))
+	}
+}
+
 func TestHandleGetChannelAuthCapabilities(t *testing.T) {
 	code, data := handleGetChannelAuthCapabilities([]byte{0x0e, 0x04})
 	assert.Equal(t, CompletionCodeOK, code)
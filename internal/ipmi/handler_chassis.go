@@ -30,8 +30,9 @@ func handleGetChassisStatus(m MachineInterface) (CompletionCode, []byte) {
 		return CompletionCodeUnspecified, nil
 	}
 
+	// Paused and transitioning VMs are powered
 	var powerByte byte
-	if state == machine.PowerOn {
+	if state != machine.PowerOff {
 		powerByte = 0x01 // bit 0 = power on
 	}
 
@@ -58,15 +59,21 @@ func handleChassisControl(reqData []byte, m MachineInterface) (CompletionCode, [
 	case ChassisControlPowerUp:
 		err = m.Reset("On")
 	case ChassisControlPowerCycle:
-		err = m.Reset("ForceOff")
-		if err == nil {
-			err = m.Reset("On")
-		}
+		err = m.Reset("PowerCycle")
 	case ChassisControlHardReset:
 		err = m.Reset("ForceRestart")
-	case ChassisControlPulse, ChassisControlSoftOff:
-		// No-op for virtual BMC
-		log.Printf("IPMI: chassis control 0x%02x (no-op)", control)
+	case ChassisControlPulse:
+		// Diagnostic interrupt
+		err = m.Reset("Nmi")
+	case ChassisControlSoftOff:
+		// ACPI power button press; a powered-off VM stays off
+		var state machine.PowerState
+		state, err = m.GetPowerState()
+		if err == nil && state == machine.PowerOn {
+			err = m.Reset("PushPowerButton")
+		} else if err == nil {
+			log.Printf("IPMI: soft off while powered off (no-op)")
+		}
 	default:
 		return CompletionCodeInvalidField, nil
 	}
@@ -24,6 +24,15 @@ func TestGetChassisStatus_PowerOff(t *testing.T) {
 	assert.Equal(t, byte(0x00), data[0]&0x01) // power off
 }
 
+func TestGetChassisStatus_Transitions(t *testing.T) {
+	for _, ps := range []machine.PowerState{machine.PowerPoweringOn, machine.PowerPoweringOff, machine.PowerPaused} {
+		mock := newIPMIMockMachine(ps)
+		code, data := handleChassisCommand(&IPMIMessage{Command: CmdGetChassisStatus}, mock)
+		assert.Equal(t, CompletionCodeOK, code)
+		assert.Equal(t, byte(0x01), data[0]&0x01, string(ps))
+	}
+}
+
 func TestChassisControl(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -33,8 +42,11 @@ func TestChassisControl(t *testing.T) {
 	}{
 		{"PowerOff", ChassisControlPowerDown, machine.PowerOn, []string{"ForceOff"}},
 		{"PowerOn", ChassisControlPowerUp, machine.PowerOff, []string{"On"}},
-		{"PowerCycle", ChassisControlPowerCycle, machine.PowerOn, []string{"ForceOff", "On"}},
+		{"PowerCycle", ChassisControlPowerCycle, machine.PowerOn, []string{"PowerCycle"}},
 		{"HardReset", ChassisControlHardReset, machine.PowerOn, []string{"ForceRestart"}},
+		{"DiagnosticInterrupt", ChassisControlPulse, machine.PowerOn, []string{"Nmi"}},
+		{"SoftOff", ChassisControlSoftOff, machine.PowerOn, []string{"PushPowerButton"}},
+		{"SoftOffWhileOff", ChassisControlSoftOff, machine.PowerOff, nil},
 	}
 
 	for _, tt := range tests {
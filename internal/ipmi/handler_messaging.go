@@ -0,0 +1,113 @@
+package ipmi
+
+import (
+	"errors"
+
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+)
+
+// IPMI channel numbers used for message bridging
+const (
+	ChannelLAN             = 0x01
+	ChannelSystemInterface = 0x0F
+)
+
+// handleGetBMCGlobalEnables handles Get BMC Global Enables (App cmd 0x2F).
+// Response: [enables]
+func handleGetBMCGlobalEnables(state *bmc.State) (CompletionCode, []byte) {
+	return CompletionCodeOK, []byte{state.GetGlobalEnables()}
+}
+
+// handleSetBMCGlobalEnables handles Set BMC Global Enables (App cmd 0x2E).
+// Request: [enables]
+func handleSetBMCGlobalEnables(reqData []byte, state *bmc.State) (CompletionCode, []byte) {
+	if len(reqData) < 1 {
+		return CompletionCodeInvalidField, nil
+	}
+	state.SetGlobalEnables(reqData[0])
+	return CompletionCodeOK, nil
+}
+
+// The Receive Message Queue and the Event Message Buffer belong to the system
+// software: the commands reading or clearing them are only accepted on the
+// system interface.
+
+// handleClearMessageFlags handles Clear Message Flags (App cmd 0x30).
+// Request: [flags to clear]
+func handleClearMessageFlags(msg *IPMIMessage, state *bmc.State) (CompletionCode, []byte) {
+	if msg.Channel != ChannelSystemInterface {
+		return CompletionCodeInvalidCommand, nil
+	}
+	if len(msg.Data) < 1 {
+		return CompletionCodeInvalidField, nil
+	}
+	state.ClearMessageFlags(msg.Data[0])
+	return CompletionCodeOK, nil
+}
+
+// handleGetMessageFlags handles Get Message Flags (App cmd 0x31).
+// Response: [flags] — bit 0 = Receive Message Available, bit 1 = Event Message Buffer Full
+func handleGetMessageFlags(msg *IPMIMessage, state *bmc.State) (CompletionCode, []byte) {
+	if msg.Channel != ChannelSystemInterface {
+		return CompletionCodeInvalidCommand, nil
+	}
+	return CompletionCodeOK, []byte{state.GetMessageFlags()}
+}
+
+// handleGetMessage handles Get Message (App cmd 0x33).
+// Response: [privilege(bits 7:4) | channel(bits 3:0)] [message data...]
+func handleGetMessage(req *IPMIMessage, state *bmc.State) (CompletionCode, []byte) {
+	if req.Channel != ChannelSystemInterface {
+		return CompletionCodeInvalidCommand, nil
+	}
+	msg, ok := state.DequeueReceiveMessage()
+	if !ok {
+		return CompletionCodeDataNotAvailable, nil
+	}
+	resp := make([]byte, 0, 1+len(msg.Data))
+	resp = append(resp, (msg.Privilege<<4)|(msg.Channel&0x0F))
+	resp = append(resp, msg.Data...)
+	return CompletionCodeOK, resp
+}
+
+// handleSendMessage handles Send Message (App cmd 0x34).
+// Request: [tracking(bits 7:6) | channel(bits 3:0)] [message data...]
+//
+// Only the system interface is supported as a destination: the message data
+// is placed in the Receive Message Queue for system software to fetch with
+// Get Message.
+func handleSendMessage(msg *IPMIMessage, state *bmc.State) (CompletionCode, []byte) {
+	if len(msg.Data) < 2 {
+		return CompletionCodeRequestDataLengthInvalid, nil
+	}
+	channel := msg.Data[0] & 0x0F
+	if channel != ChannelSystemInterface || msg.Channel == ChannelSystemInterface {
+		return CompletionCodeInvalidField, nil
+	}
+
+	err := state.EnqueueReceiveMessage(bmc.ReceivedMessage{
+		Channel:   msg.Channel,
+		Privilege: msg.Privilege,
+		Data:      msg.Data[1:],
+	})
+	if errors.Is(err, bmc.ErrMessageQueueFull) {
+		return CompletionCodeNodeBusy, nil
+	}
+	if err != nil {
+		return CompletionCodeUnspecified, nil
+	}
+	return CompletionCodeOK, nil
+}
+
+// handleReadEventMessageBuffer handles Read Event Message Buffer (App cmd 0x35).
+// Response: 16 bytes of event data in SEL record format
+func handleReadEventMessageBuffer(msg *IPMIMessage, state *bmc.State) (CompletionCode, []byte) {
+	if msg.Channel != ChannelSystemInterface {
+		return CompletionCodeInvalidCommand, nil
+	}
+	ev, ok := state.ReadEventMessageBuffer()
+	if !ok {
+		return CompletionCodeDataNotAvailable, nil
+	}
+	return CompletionCodeOK, ev[:]
+}
@@ -0,0 +1,92 @@
+package ipmi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+)
+
+func TestSendMessage_ToSystemInterface(t *testing.T) {
+	state := newTestBMCState()
+
+	// LAN client sends [channel=0x0F] [payload...]
+	send := &IPMIMessage{Command: CmdSendMessage, Data: []byte{0x0F, 0x20, 0x18, 0xC8, 0x81, 0x04, 0x01, 0x7A}, Channel: ChannelLAN, Privilege: 0x04}
+	code, _ := handleAppCommand(send, nil, state)
+	require.Equal(t, CompletionCodeOK, code)
+
+	code, data := handleAppCommand(&IPMIMessage{Command: CmdGetMessageFlags, Channel: ChannelSystemInterface}, nil, state)
+	require.Equal(t, CompletionCodeOK, code)
+	assert.Equal(t, []byte{bmc.MessageFlagReceiveMessageAvailable}, data)
+
+	code, data = handleAppCommand(&IPMIMessage{Command: CmdGetMessage, Channel: ChannelSystemInterface}, nil, state)
+	require.Equal(t, CompletionCodeOK, code)
+	assert.Equal(t, byte(ChannelLAN), data[0]&0x0F, "originating channel")
+	assert.Equal(t, byte(0x04), data[0]>>4, "privilege of the sending session")
+	assert.Equal(t, []byte{0x20, 0x18, 0xC8, 0x81, 0x04, 0x01, 0x7A}, data[1:])
+
+	code, _ = handleAppCommand(&IPMIMessage{Command: CmdGetMessage, Channel: ChannelSystemInterface}, nil, state)
+	assert.Equal(t, CompletionCodeDataNotAvailable, code, "queue is empty")
+}
+
+func TestMessaging_SystemInterfaceOnly(t *testing.T) {
+	state := newTestBMCState()
+	send := &IPMIMessage{Command: CmdSendMessage, Data: []byte{0x0F, 0x20}, Channel: ChannelLAN}
+	code, _ := handleAppCommand(send, nil, state)
+	require.Equal(t, CompletionCodeOK, code)
+
+	// A LAN session can neither read nor flush the guest's queue
+	for _, cmd := range []uint8{CmdGetMessageFlags, CmdGetMessage, CmdReadEventMessageBuffer} {
+		code, _ = handleAppCommand(&IPMIMessage{Command: cmd, Channel: ChannelLAN}, nil, state)
+		assert.Equal(t, CompletionCodeInvalidCommand, code, "command 0x%02x", cmd)
+	}
+	code, _ = handleAppCommand(&IPMIMessage{Command: CmdClearMessageFlags, Data: []byte{0x03}, Channel: ChannelLAN}, nil, state)
+	assert.Equal(t, CompletionCodeInvalidCommand, code)
+	assert.Equal(t, bmc.MessageFlagReceiveMessageAvailable, state.GetMessageFlags(), "message still queued")
+}
+
+func TestSendMessage_Errors(t *testing.T) {
+	state := newTestBMCState()
+
+	code, _ := handleSendMessage(&IPMIMessage{Data: []byte{0x0F}, Channel: ChannelLAN}, state)
+	assert.Equal(t, CompletionCodeRequestDataLengthInvalid, code)
+
+	code, _ = handleSendMessage(&IPMIMessage{Data: []byte{0x00, 0x20}, Channel: ChannelLAN}, state)
+	assert.Equal(t, CompletionCodeInvalidField, code, "IPMB is not bridged")
+
+	code, _ = handleSendMessage(&IPMIMessage{Data: []byte{0x0F, 0x20}, Channel: ChannelSystemInterface}, state)
+	assert.Equal(t, CompletionCodeInvalidField, code, "system interface cannot send to itself")
+
+	for i := 0; i < 16; i++ {
+		code, _ = handleSendMessage(&IPMIMessage{Data: []byte{0x0F, byte(i)}, Channel: ChannelLAN}, state)
+		require.Equal(t, CompletionCodeOK, code)
+	}
+	code, _ = handleSendMessage(&IPMIMessage{Data: []byte{0x0F, 0x20}, Channel: ChannelLAN}, state)
+	assert.Equal(t, CompletionCodeNodeBusy, code, "queue full")
+}
+
+func TestReadEventMessageBuffer(t *testing.T) {
+	state := newTestBMCState()
+
+	code, _ := handleReadEventMessageBuffer(&IPMIMessage{Channel: ChannelSystemInterface}, state)
+	assert.Equal(t, CompletionCodeDataNotAvailable, code)
+
+	code, _ = handleSetBMCGlobalEnables([]byte{0x0C}, state)
+	require.Equal(t, CompletionCodeOK, code)
+	code, data := handleGetBMCGlobalEnables(state)
+	require.Equal(t, CompletionCodeOK, code)
+	assert.Equal(t, []byte{0x0C}, data)
+
+	state.AddSELEntry(bmc.SELEntry{SensorType: bmc.SensorTypeSessionAudit})
+	code, data = handleReadEventMessageBuffer(&IPMIMessage{Channel: ChannelSystemInterface}, state)
+	require.Equal(t, CompletionCodeOK, code)
+	assert.Len(t, data, 16)
+
+	// Clear Message Flags flushes a buffered event
+	state.AddSELEntry(bmc.SELEntry{SensorType: bmc.SensorTypeSessionAudit})
+	code, _ = handleClearMessageFlags(&IPMIMessage{Data: []byte{0x02}, Channel: ChannelSystemInterface}, state)
+	require.Equal(t, CompletionCodeOK, code)
+	code, _ = handleReadEventMessageBuffer(&IPMIMessage{Channel: ChannelSystemInterface}, state)
+	assert.Equal(t, CompletionCodeDataNotAvailable, code)
+}
@@ -0,0 +1,85 @@
+package ipmi
+
+import (
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+)
+
+// sysInfoRevision is the parameter revision byte returned in Get System Info
+// Parameters responses. 0x11 = revision 1.1 per IPMI spec.
+const sysInfoRevision = 0x11
+
+// handleGetSystemInfoParams handles Get System Info Parameters (App cmd 0x59).
+// Request (4 bytes): [get_rev_only(bit 7)] [param_selector] [set_selector] [block_selector]
+// Response: [revision (0x11)] [param_data...]
+//
+// String parameters (1-6) return [set_selector] [16-byte block]. Block 0 holds
+// [encoding] [string length] [first 14 bytes]; later blocks hold 16 bytes each.
+func handleGetSystemInfoParams(reqData []byte, state *bmc.State) (CompletionCode, []byte) {
+	if len(reqData) < 4 {
+		return CompletionCodeInvalidField, nil
+	}
+
+	revisionOnly := reqData[0]&0x80 != 0
+	param := reqData[1]
+	setSelector := reqData[2]
+
+	if param != bmc.SysInfoSetInProgress && !bmc.IsSystemInfoStringParam(param) {
+		return CompletionCodeParameterNotSupported, nil
+	}
+	if revisionOnly {
+		return CompletionCodeOK, []byte{sysInfoRevision}
+	}
+
+	if param == bmc.SysInfoSetInProgress {
+		return CompletionCodeOK, []byte{sysInfoRevision, state.GetSystemInfoSetInProgress()}
+	}
+
+	block, err := state.GetSystemInfoBlock(param, setSelector)
+	if err != nil {
+		return CompletionCodeParameterOutOfRange, nil
+	}
+
+	resp := make([]byte, 0, 2+len(block))
+	resp = append(resp, sysInfoRevision, setSelector)
+	resp = append(resp, block...)
+	return CompletionCodeOK, resp
+}
+
+// handleSetSystemInfoParams handles Set System Info Parameters (App cmd 0x58).
+// Request: [param_selector] [data...]
+// For string parameters (1-6) the data is [set_selector] [up to 16 bytes of block data].
+// The values are reported by the system software, so they are only accepted
+// on the system interface.
+func handleSetSystemInfoParams(msg *IPMIMessage, state *bmc.State) (CompletionCode, []byte) {
+	if msg.Channel != ChannelSystemInterface {
+		return CompletionCodeInvalidCommand, nil
+	}
+	reqData := msg.Data
+	if len(reqData) < 2 {
+		return CompletionCodeInvalidField, nil
+	}
+
+	param := reqData[0]
+
+	if param == bmc.SysInfoSetInProgress {
+		if reqData[1]&0x03 == 0x03 {
+			return CompletionCodeInvalidField, nil // reserved value
+		}
+		state.SetSystemInfoSetInProgress(reqData[1])
+		return CompletionCodeOK, nil
+	}
+
+	if !bmc.IsSystemInfoStringParam(param) {
+		return CompletionCodeParameterNotSupported, nil
+	}
+
+	setSelector := reqData[1]
+	data := reqData[2:]
+	if len(data) > 16 {
+		return CompletionCodeRequestDataLengthInvalid, nil
+	}
+	if err := state.SetSystemInfoBlock(param, setSelector, data); err != nil {
+		return CompletionCodeParameterOutOfRange, nil
+	}
+	return CompletionCodeOK, nil
+}
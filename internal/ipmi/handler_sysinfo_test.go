@@ -0,0 +1,85 @@
+package ipmi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+)
+
+// setSystemInfo sends Set System Info Parameters on the system interface.
+func setSystemInfo(state *bmc.State, data []byte) (CompletionCode, []byte) {
+	return handleSetSystemInfoParams(&IPMIMessage{Command: CmdSetSystemInfoParameters, Data: data, Channel: ChannelSystemInterface}, state)
+}
+
+func TestSetGetSystemInfoParams_SystemName(t *testing.T) {
+	state := newTestBMCState()
+
+	// Set block 0: [param=2] [set_selector=0] [encoding=ASCII] [len=6] "node01"
+	setReq := append([]byte{0x02, 0x00, 0x00, 0x06}, "node01"...)
+	code, _ := handleAppCommand(&IPMIMessage{Command: CmdSetSystemInfoParameters, Data: setReq, Channel: ChannelSystemInterface}, nil, state)
+	require.Equal(t, CompletionCodeOK, code)
+	assert.Equal(t, "node01", state.GetSystemInfoString(bmc.SysInfoSystemName))
+
+	// Get: [get_rev_only=0] [param=2] [set_selector=0] [block_selector=0]
+	code, data := handleAppCommand(&IPMIMessage{Command: CmdGetSystemInfoParameters, Data: []byte{0x00, 0x02, 0x00, 0x00}}, nil, state)
+	require.Equal(t, CompletionCodeOK, code)
+	require.Len(t, data, 18)
+	assert.Equal(t, byte(0x11), data[0], "revision should be 1.1")
+	assert.Equal(t, byte(0x00), data[1], "set selector echoed")
+	assert.Equal(t, byte(0x06), data[3], "string length")
+	assert.Equal(t, "node01", string(data[4:10]))
+}
+
+func TestGetSystemInfoParams_RevisionOnly(t *testing.T) {
+	state := newTestBMCState()
+	code, data := handleGetSystemInfoParams([]byte{0x80, 0x04, 0x00, 0x00}, state)
+	assert.Equal(t, CompletionCodeOK, code)
+	assert.Equal(t, []byte{0x11}, data)
+}
+
+func TestSystemInfoParams_SetInProgress(t *testing.T) {
+	state := newTestBMCState()
+
+	code, _ := setSystemInfo(state, []byte{0x00, 0x01})
+	require.Equal(t, CompletionCodeOK, code)
+
+	code, data := handleGetSystemInfoParams([]byte{0x00, 0x00, 0x00, 0x00}, state)
+	assert.Equal(t, CompletionCodeOK, code)
+	assert.Equal(t, []byte{0x11, 0x01}, data)
+
+	code, _ = setSystemInfo(state, []byte{0x00, 0x03})
+	assert.Equal(t, CompletionCodeInvalidField, code, "value 3 is reserved")
+}
+
+func TestSystemInfoParams_Errors(t *testing.T) {
+	state := newTestBMCState()
+
+	code, _ := handleGetSystemInfoParams([]byte{0x00, 0xC0, 0x00, 0x00}, state)
+	assert.Equal(t, CompletionCodeParameterNotSupported, code)
+
+	code, _ = setSystemInfo(state, []byte{0x07, 0x00, 0x00})
+	assert.Equal(t, CompletionCodeParameterNotSupported, code)
+
+	code, _ = setSystemInfo(state, []byte{0x02, 0x40, 0x00})
+	assert.Equal(t, CompletionCodeParameterOutOfRange, code)
+
+	code, _ = setSystemInfo(state, append([]byte{0x02, 0x01}, make([]byte, 17)...))
+	assert.Equal(t, CompletionCodeRequestDataLengthInvalid, code)
+
+	code, _ = handleGetSystemInfoParams([]byte{0x00, 0x02}, state)
+	assert.Equal(t, CompletionCodeInvalidField, code)
+}
+
+func TestSetSystemInfoParams_SystemInterfaceOnly(t *testing.T) {
+	state := newTestBMCState()
+	setReq := append([]byte{0x02, 0x00, 0x00, 0x06}, "node01"...)
+	code, _ := handleAppCommand(&IPMIMessage{Command: CmdSetSystemInfoParameters, Data: setReq, Channel: ChannelLAN, Privilege: 0x04}, nil, state)
+	assert.Equal(t, CompletionCodeInvalidCommand, code)
+	assert.Empty(t, state.GetSystemInfoString(bmc.SysInfoSystemName))
+
+	// Get System Info Parameters stays available over LAN
+	code, _ = handleAppCommand(&IPMIMessage{Command: CmdGetSystemInfoParameters, Data: []byte{0x00, 0x02, 0x00, 0x00}, Channel: ChannelLAN}, nil, state)
+	assert.Equal(t, CompletionCodeOK, code)
+}
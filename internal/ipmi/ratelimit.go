@@ -0,0 +1,79 @@
+package ipmi
+
+import (
+	"sync"
+	"time"
+)
+
+// maxRateLimitEntries bounds the number of tracked source addresses.
+const maxRateLimitEntries = 4096
+
+// tokenBucket is the per-source rate limiter state.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter enforces a per-source packet rate using token buckets.
+// A zero rate disables limiting.
+type rateLimiter struct {
+	rate    float64 // tokens added per second
+	burst   float64 // bucket capacity
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+	mu      sync.Mutex
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow reports whether a packet from source may be processed now.
+func (rl *rateLimiter) Allow(source string) bool {
+	if rl == nil || rl.rate <= 0 {
+		return true
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	b, ok := rl.buckets[source]
+	if !ok {
+		if len(rl.buckets) >= maxRateLimitEntries {
+			rl.pruneLocked(now)
+		}
+		b = &tokenBucket{tokens: rl.burst, last: now}
+		rl.buckets[source] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * rl.rate
+	if b.tokens > rl.burst {
+		b.tokens = rl.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// pruneLocked drops buckets that have refilled completely, since they carry
+// no state beyond a fresh bucket. Caller must hold rl.mu.
+func (rl *rateLimiter) pruneLocked(now time.Time) {
+	for source, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rl.rate >= rl.burst {
+			delete(rl.buckets, source)
+		}
+	}
+}
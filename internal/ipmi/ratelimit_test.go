@@ -0,0 +1,38 @@
+package ipmi
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_BurstThenRefill(t *testing.T) {
+	rl := newRateLimiter(2, 3)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rl.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.True(t, rl.Allow("10.0.0.1"), "packet %d within burst", i)
+	}
+	assert.False(t, rl.Allow("10.0.0.1"), "burst exhausted")
+	assert.True(t, rl.Allow("10.0.0.2"), "other sources are independent")
+
+	now = now.Add(500 * time.Millisecond) // refills one token at 2/s
+	assert.True(t, rl.Allow("10.0.0.1"))
+	assert.False(t, rl.Allow("10.0.0.1"))
+}
+
+func TestRateLimiter_DisabledAllowsAll(t *testing.T) {
+	var rl *rateLimiter
+	for i := 0; i < 100; i++ {
+		assert.True(t, rl.Allow("10.0.0.1"))
+	}
+}
+
+func TestSourceHost(t *testing.T) {
+	assert.Equal(t, "", sourceHost(nil))
+	assert.Equal(t, "192.0.2.1", sourceHost(&net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 623}))
+	assert.Equal(t, "2001:db8::1", sourceHost(&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 40000}))
+}
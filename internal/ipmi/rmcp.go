@@ -31,6 +31,12 @@ type IPMIMessage struct {
 	SourceLun     uint8 // Sequence (upper 6 bits) + LUN (lower 2 bits)
 	Command       uint8
 	Data          []byte
+
+	// Channel is the IPMI channel the message arrived on, and Privilege
+	// the privilege level of its session (0 outside a session). They are
+	// not part of the wire format.
+	Channel   uint8
+	Privilege uint8
 }
 
 // GetNetFn returns the network function from the message
@@ -138,6 +144,7 @@ func ParseIPMIMessageBytes(data []byte) (*IPMIMessage, error) {
 		SourceAddress: data[3],
 		SourceLun:     data[4],
 		Command:       data[5],
+		Channel:       ChannelLAN,
 	}
 
 	if len(data) > 7 {
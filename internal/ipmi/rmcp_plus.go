@@ -9,6 +9,8 @@ import (
 	"crypto/sha1"
 	"encoding/binary"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/tjst-t/qemu-bmc/internal/bmc"
 )
@@ -68,6 +70,12 @@ type RAKPMessage3 struct {
 
 // HandleRMCPPlusMessage processes an RMCP+ message and returns a response
 func HandleRMCPPlusMessage(data []byte, sessionMgr *SessionManager, user, pass string, machine MachineInterface, state *bmc.State) ([]byte, error) {
+	return handleRMCPPlusMessageFrom(data, "", sessionMgr, user, pass, machine, state)
+}
+
+// handleRMCPPlusMessageFrom is HandleRMCPPlusMessage with the remote source
+// address, which is used for authentication failure tracking.
+func handleRMCPPlusMessageFrom(data []byte, source string, sessionMgr *SessionManager, user, pass string, machine MachineInterface, state *bmc.State) ([]byte, error) {
 	if len(data) < 12 {
 		return nil, fmt.Errorf("RMCP+ message too short")
 	}
@@ -84,9 +92,9 @@ func HandleRMCPPlusMessage(data []byte, sessionMgr *SessionManager, user, pass s
 	case PayloadTypeOpenSessionRequest:
 		return handleOpenSession(buf.Bytes(), header, sessionMgr)
 	case PayloadTypeRAKPMessage1:
-		return handleRAKPMessage1(buf.Bytes(), header, sessionMgr, user, pass, state)
+		return handleRAKPMessage1(buf.Bytes(), source, sessionMgr, user, pass, state)
 	case PayloadTypeRAKPMessage3:
-		return handleRAKPMessage3(buf.Bytes(), header, sessionMgr, pass, state)
+		return handleRAKPMessage3(buf.Bytes(), source, sessionMgr, pass, state)
 	case PayloadTypeIPMI:
 		if header.SessionID == 0 {
 			return handlePreSessionIPMI(data, header, machine, state)
@@ -175,7 +183,7 @@ func buildOpenSessionError(messageTag uint8, remoteConsoleSessionID uint32, stat
 	return wrapRMCPPlusResponse(PayloadTypeOpenSessionResponse, 0, 0, resp.Bytes())
 }
 
-func handleRAKPMessage1(payload []byte, header *RMCPPlusSessionHeader, sessionMgr *SessionManager, user, pass string, state *bmc.State) ([]byte, error) {
+func handleRAKPMessage1(payload []byte, source string, sessionMgr *SessionManager, user, pass string, state *bmc.State) ([]byte, error) {
 	// Parse manually to handle variable-length UserName
 	if len(payload) < 28 {
 		return nil, fmt.Errorf("RAKP message 1 too short: %d bytes", len(payload))
@@ -206,6 +214,16 @@ func handleRAKPMessage1(payload []byte, header *RMCPPlusSessionHeader, sessionMg
 	session.UserName = make([]byte, req.UserNameLength)
 	copy(session.UserName, req.UserName)
 
+	// Refuse to start authentication while the user or source is locked out.
+	// Answering RAKP 2 would otherwise hand out an HMAC keyed with the password.
+	if state != nil {
+		if remaining, locked := state.AuthLockedOut(string(session.UserName), source); locked {
+			log.Printf("IPMI: rejecting RAKP from %q for user %q: locked out for %s", source, session.UserName, remaining.Round(time.Second))
+			sessionMgr.RemoveSession(session.ManagedSystemSessionID)
+			return buildRAKPError(PayloadTypeRAKPMessage2, req.MessageTag, 0x0D, session.RemoteConsoleSessionID), nil // unauthorized name
+		}
+	}
+
 	// Try BMC state first, fall back to hardcoded user
 	var authPass string
 	if state != nil {
@@ -220,13 +238,8 @@ func handleRAKPMessage1(payload []byte, header *RMCPPlusSessionHeader, sessionMg
 	if authPass == "" {
 		// Fall back to hardcoded credentials
 		if string(session.UserName) != user {
-			// Return error status
-			resp := new(bytes.Buffer)
-			binary.Write(resp, binary.LittleEndian, req.MessageTag)
-			binary.Write(resp, binary.LittleEndian, uint8(0x0D)) // invalid username
-			binary.Write(resp, binary.LittleEndian, [2]byte{})
-			binary.Write(resp, binary.LittleEndian, session.RemoteConsoleSessionID)
-			return wrapRMCPPlusResponse(PayloadTypeRAKPMessage2, 0, 0, resp.Bytes()), nil
+			recordAuthFailure(state, "", source)
+			return buildRAKPError(PayloadTypeRAKPMessage2, req.MessageTag, 0x0D, session.RemoteConsoleSessionID), nil // invalid username
 		}
 		authPass = pass
 	}
@@ -259,7 +272,7 @@ func handleRAKPMessage1(payload []byte, header *RMCPPlusSessionHeader, sessionMg
 	return wrapRMCPPlusResponse(PayloadTypeRAKPMessage2, 0, 0, resp.Bytes()), nil
 }
 
-func handleRAKPMessage3(payload []byte, header *RMCPPlusSessionHeader, sessionMgr *SessionManager, pass string, state *bmc.State) ([]byte, error) {
+func handleRAKPMessage3(payload []byte, source string, sessionMgr *SessionManager, pass string, state *bmc.State) ([]byte, error) {
 	var req RAKPMessage3
 	buf := bytes.NewBuffer(payload)
 	if err := binary.Read(buf, binary.LittleEndian, &req); err != nil {
@@ -295,12 +308,12 @@ func handleRAKPMessage3(payload []byte, header *RMCPPlusSessionHeader, sessionMg
 	expectedAuthCode := mac.Sum(nil)
 
 	if !hmac.Equal(req.KeyExchangeAuthCode[:], expectedAuthCode) {
-		resp := new(bytes.Buffer)
-		binary.Write(resp, binary.LittleEndian, req.MessageTag)
-		binary.Write(resp, binary.LittleEndian, uint8(0x0F)) // invalid integrity check
-		binary.Write(resp, binary.LittleEndian, [2]byte{})
-		binary.Write(resp, binary.LittleEndian, session.RemoteConsoleSessionID)
-		return wrapRMCPPlusResponse(PayloadTypeRAKPMessage4, 0, 0, resp.Bytes()), nil
+		recordAuthFailure(state, string(session.UserName), source)
+		sessionMgr.RemoveSession(session.ManagedSystemSessionID)
+		return buildRAKPError(PayloadTypeRAKPMessage4, req.MessageTag, 0x0F, session.RemoteConsoleSessionID), nil // invalid integrity check
+	}
+	if state != nil {
+		state.RecordAuthSuccess(string(session.UserName), source)
 	}
 
 	// Derive Session Integrity Key (SIK)
@@ -355,6 +368,26 @@ func handleRAKPMessage3(payload []byte, header *RMCPPlusSessionHeader, sessionMg
 	return wrapRMCPPlusResponse(PayloadTypeRAKPMessage4, 0, 0, resp.Bytes()), nil
 }
 
+// buildRAKPError builds a RAKP message 2 or 4 carrying an error status code.
+func buildRAKPError(payloadType, messageTag, statusCode uint8, remoteConsoleSessionID uint32) []byte {
+	resp := new(bytes.Buffer)
+	binary.Write(resp, binary.LittleEndian, messageTag)
+	binary.Write(resp, binary.LittleEndian, statusCode)
+	binary.Write(resp, binary.LittleEndian, [2]byte{})
+	binary.Write(resp, binary.LittleEndian, remoteConsoleSessionID)
+	return wrapRMCPPlusResponse(payloadType, 0, 0, resp.Bytes())
+}
+
+// recordAuthFailure counts a failed RAKP exchange and logs any lockout it triggers.
+func recordAuthFailure(state *bmc.State, user, source string) {
+	if state == nil {
+		return
+	}
+	for _, ev := range state.RecordAuthFailure(user, source) {
+		log.Printf("IPMI: too many authentication failures, %s locked out for %s", ev.Key, ev.Duration)
+	}
+}
+
 func handlePreSessionIPMI(data []byte, header *RMCPPlusSessionHeader, machine MachineInterface, state *bmc.State) ([]byte, error) {
 	// Pre-session IPMI messages (e.g., Get Channel Auth Capabilities) sent via RMCP+
 	// with session ID 0, no encryption, no authentication
@@ -429,6 +462,7 @@ func handleEncryptedIPMI(data []byte, header *RMCPPlusSessionHeader, sessionMgr
 	}
 
 	// Route to handler
+	msg.Privilege = sessionPrivilege(session, state)
 	responseCode, responseData := handleIPMICommand(msg, machine, state)
 
 	// Build response IPMI message (echo request's sequence number)
@@ -567,6 +601,27 @@ func unpadPayload(data []byte) ([]byte, error) {
 	return data[:len(data)-padLen-1], nil // strip CPad (padLen bytes) + CPL (1 byte)
 }
 
+// sessionPrivilege is the privilege level an RMCP+ session runs at: the
+// requested role, or the user's privilege limit if the highest level the
+// user has was requested.
+func sessionPrivilege(session *Session, state *bmc.State) uint8 {
+	if priv := session.RequestedPrivilegeLevel & 0x0F; priv != 0 {
+		return priv
+	}
+	if state == nil {
+		return 0
+	}
+	userID, ok := state.LookupUserByName(string(session.UserName))
+	if !ok {
+		return 0
+	}
+	access, err := state.GetUserAccess(ChannelLAN, userID)
+	if err != nil {
+		return 0
+	}
+	return access.PrivilegeLimit
+}
+
 // handleIPMICommand routes an IPMI message to the appropriate handler
 func handleIPMICommand(msg *IPMIMessage, machine MachineInterface, state *bmc.State) (CompletionCode, []byte) {
 	netFn := msg.GetNetFn()
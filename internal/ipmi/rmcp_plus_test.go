@@ -7,6 +7,7 @@ import (
 	"crypto/sha1"
 	"encoding/binary"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -712,3 +713,83 @@ func (m *ipmiMockMachine) SetBootOverride(override machine.BootOverride) error {
 	m.bootOverride = override
 	return nil
 }
+
+// rakpWithPassword runs Open Session, RAKP 1 and RAKP 3 from source, computing the
+// RAKP 3 auth code with password. It returns the RAKP 2 and RAKP 4 status codes
+// (RAKP 4 status is 0xFF if RAKP 2 failed).
+func rakpWithPassword(t *testing.T, sm *SessionManager, state *bmc.State, source, user, password string) (uint8, uint8) {
+	t.Helper()
+	openData := wrapRMCPPlusPayload(PayloadTypeOpenSessionRequest, 0, 0, buildOpenSessionRequest(0x01, 0x12345678))
+	openResp, err := handleRMCPPlusMessageFrom(openData, source, sm, "admin", "password", nil, state)
+	require.NoError(t, err)
+	managedSessionID := binary.LittleEndian.Uint32(openResp[20:24])
+
+	rakp1Data := wrapRMCPPlusPayload(PayloadTypeRAKPMessage1, 0, 0, buildRAKPMessage1(0x02, managedSessionID, user))
+	rakp2Resp, err := handleRMCPPlusMessageFrom(rakp1Data, source, sm, "admin", "password", nil, state)
+	require.NoError(t, err)
+	if rakp2Resp[13] != 0x00 {
+		return rakp2Resp[13], 0xFF
+	}
+
+	session, ok := sm.GetSession(managedSessionID)
+	require.True(t, ok)
+	mac := hmac.New(sha1.New, []byte(password))
+	mac.Write(buildRAKP3AuthBuf(session.ManagedSystemRandomNumber[:], session.RemoteConsoleSessionID, session.RequestedPrivilegeLevel, session.UserNameLength, session.UserName))
+
+	rakp3Data := wrapRMCPPlusPayload(PayloadTypeRAKPMessage3, 0, 0, buildRAKPMessage3(0x03, managedSessionID, mac.Sum(nil)))
+	rakp4Resp, err := handleRMCPPlusMessageFrom(rakp3Data, source, sm, "admin", "password", nil, state)
+	require.NoError(t, err)
+	return rakp2Resp[13], rakp4Resp[13]
+}
+
+func TestRAKP_LockoutAfterRepeatedFailures(t *testing.T) {
+	sm := NewSessionManager()
+	state := bmc.NewState("admin", "password")
+	state.SetLockoutPolicy(bmc.LockoutPolicy{Threshold: 3, Duration: time.Minute, MaxDuration: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		rakp2, rakp4 := rakpWithPassword(t, sm, state, "192.0.2.10", "admin", "wrong")
+		assert.Equal(t, uint8(0x00), rakp2)
+		assert.Equal(t, uint8(0x0F), rakp4)
+	}
+
+	// Even the correct password is refused while locked out, from any source.
+	rakp2, _ := rakpWithPassword(t, sm, state, "192.0.2.11", "admin", "password")
+	assert.Equal(t, uint8(0x0D), rakp2, "locked-out user must not get RAKP 2")
+
+	entries := state.SELEntries()
+	require.NotEmpty(t, entries)
+	assert.Equal(t, bmc.SessionAuditLockout, entries[0].EventData[0])
+}
+
+func TestRAKP_SuccessResetsFailureCount(t *testing.T) {
+	sm := NewSessionManager()
+	state := bmc.NewState("admin", "password")
+	state.SetLockoutPolicy(bmc.LockoutPolicy{Threshold: 2, Duration: time.Minute, MaxDuration: time.Hour})
+
+	_, rakp4 := rakpWithPassword(t, sm, state, "192.0.2.10", "admin", "wrong")
+	assert.Equal(t, uint8(0x0F), rakp4)
+	_, rakp4 = rakpWithPassword(t, sm, state, "192.0.2.10", "admin", "password")
+	assert.Equal(t, uint8(0x00), rakp4)
+	_, rakp4 = rakpWithPassword(t, sm, state, "192.0.2.10", "admin", "wrong")
+	assert.Equal(t, uint8(0x0F), rakp4)
+
+	_, locked := state.AuthLockedOut("admin", "192.0.2.10")
+	assert.False(t, locked)
+}
+
+func TestSessionPrivilege(t *testing.T) {
+	state := bmc.NewState("admin", "password")
+
+	session := &Session{RequestedPrivilegeLevel: 0x13, UserName: []byte("admin")}
+	assert.Equal(t, uint8(0x03), sessionPrivilege(session, state), "name-only lookup bit is ignored")
+
+	// Highest level: the user's privilege limit
+	session.RequestedPrivilegeLevel = 0x00
+	access, err := state.GetUserAccess(ChannelLAN, 2)
+	require.NoError(t, err)
+	assert.Equal(t, access.PrivilegeLimit, sessionPrivilege(session, state))
+
+	session.UserName = []byte("nobody")
+	assert.Equal(t, uint8(0), sessionPrivilege(session, state))
+}
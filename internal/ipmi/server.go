@@ -17,6 +17,7 @@ type Server struct {
 	user       string
 	pass       string
 	conn       net.PacketConn
+	limiter    *rateLimiter
 }
 
 // NewServer creates a new IPMI server
@@ -30,6 +31,17 @@ func NewServer(m MachineInterface, state *bmc.State, user, pass string) *Server
 	}
 }
 
+// SetRateLimit limits the number of packets accepted from each source
+// address to rate per second, with bursts of up to burst packets.
+// Packets over the limit are dropped silently. A rate of 0 disables limiting.
+func (s *Server) SetRateLimit(rate float64, burst int) {
+	if rate <= 0 {
+		s.limiter = nil
+		return
+	}
+	s.limiter = newRateLimiter(rate, burst)
+}
+
 // ListenAndServe starts the IPMI UDP server
 func (s *Server) ListenAndServe(addr string) error {
 	conn, err := net.ListenPacket("udp", addr)
@@ -55,11 +67,15 @@ func (s *Server) serve() error {
 			return err
 		}
 
+		if !s.limiter.Allow(sourceHost(addr)) {
+			continue
+		}
+
 		// Make a copy of the data
 		data := make([]byte, n)
 		copy(data, buf[:n])
 
-		resp, err := s.HandleMessage(data)
+		resp, err := s.HandleMessageFrom(data, addr)
 		if err != nil {
 			log.Printf("IPMI error: %v", err)
 			continue
@@ -75,6 +91,12 @@ func (s *Server) serve() error {
 
 // HandleMessage processes a single IPMI/RMCP message and returns a response
 func (s *Server) HandleMessage(data []byte) ([]byte, error) {
+	return s.HandleMessageFrom(data, nil)
+}
+
+// HandleMessageFrom processes a single IPMI/RMCP message received from addr.
+// The source address is used for authentication failure tracking; it may be nil.
+func (s *Server) HandleMessageFrom(data []byte, addr net.Addr) ([]byte, error) {
 	header, payload, err := ParseRMCPMessage(data)
 	if err != nil {
 		return nil, err
@@ -90,7 +112,7 @@ func (s *Server) HandleMessage(data []byte) ([]byte, error) {
 
 	// Check if this is RMCP+ (auth type 0x06 at first byte of payload)
 	if len(payload) > 0 && payload[0] == AuthTypeRMCPPlus {
-		resp, err := HandleRMCPPlusMessage(payload, s.sessionMgr, s.user, s.pass, s.machine, s.bmcState)
+		resp, err := handleRMCPPlusMessageFrom(payload, sourceHost(addr), s.sessionMgr, s.user, s.pass, s.machine, s.bmcState)
 		if err != nil {
 			return nil, err
 		}
@@ -107,12 +129,28 @@ func (s *Server) HandleMessage(data []byte) ([]byte, error) {
 		return nil, fmt.Errorf("no IPMI message parsed")
 	}
 
+	if session.SessionID != 0 {
+		msg.Privilege = ipmi15Privilege
+	}
 	code, respData := handleIPMICommand(msg, s.machine, s.bmcState)
 
 	respPayload := SerializeIPMIResponse(session, msg.GetNetFn()|0x01, msg.Command, code, respData, msg.SourceLun, s.pass)
 	return SerializeRMCPMessage(RMCPClassIPMI, respPayload), nil
 }
 
+// sourceHost returns the host part of a UDP source address, so that
+// per-source accounting is not defeated by changing the source port.
+func sourceHost(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
 // handleASFPing responds to ASF Presence Ping with a Pong
 func handleASFPing(payload []byte) ([]byte, error) {
 	// ASF message header: 4-byte IANA + 1-byte type + 1-byte tag + 1-byte reserved + 1-byte length
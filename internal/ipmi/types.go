@@ -48,11 +48,21 @@ const (
 // IPMI App Commands
 const (
 	CmdGetDeviceID                = 0x01
+	CmdGetACPIPowerState          = 0x07
+	CmdSetBMCGlobalEnables        = 0x2E
+	CmdGetBMCGlobalEnables        = 0x2F
+	CmdClearMessageFlags          = 0x30
+	CmdGetMessageFlags            = 0x31
+	CmdGetMessage                 = 0x33
+	CmdSendMessage                = 0x34
+	CmdReadEventMessageBuffer     = 0x35
 	CmdGetChannelAuthCapabilities = 0x38
 	CmdGetSessionChallenge        = 0x39
 	CmdActivateSession            = 0x3A
 	CmdSetSessionPrivilege        = 0x3B
 	CmdCloseSession               = 0x3C
+	CmdSetSystemInfoParameters    = 0x58
+	CmdGetSystemInfoParameters    = 0x59
 )
 
 // IPMI App Commands - User Management
@@ -86,6 +96,17 @@ const (
 	ChassisControlSoftOff    = 0x05
 )
 
+// ACPI power states reported by Get ACPI Power State
+const (
+	ACPISystemS0 = 0x00 // working
+	ACPISystemS1 = 0x01 // sleeping, CPUs stopped
+	ACPISystemS3 = 0x03 // suspended to RAM
+	ACPISystemS5 = 0x05 // soft off
+	ACPIDeviceD0 = 0x00
+	ACPIDeviceD1 = 0x01
+	ACPIDeviceD3 = 0x03
+)
+
 // RMCP+ Payload Types
 const (
 	PayloadTypeIPMI                = 0x00
@@ -142,15 +163,18 @@ const (
 type CompletionCode uint8
 
 const (
-	CompletionCodeOK                  CompletionCode = 0x00
-	CompletionCodeNodeBusy            CompletionCode = 0xC0
-	CompletionCodeInvalidCommand      CompletionCode = 0xC1
-	CompletionCodeInvalidForLUN       CompletionCode = 0xC2
-	CompletionCodeTimeout             CompletionCode = 0xC3
-	CompletionCodeOutOfSpace          CompletionCode = 0xC4
-	CompletionCodeInvalidField        CompletionCode = 0xCC
-	CompletionCodeParameterOutOfRange CompletionCode = 0xC9
-	CompletionCodeUnspecified         CompletionCode = 0xFF
+	CompletionCodeOK                       CompletionCode = 0x00
+	CompletionCodeParameterNotSupported    CompletionCode = 0x80
+	CompletionCodeDataNotAvailable         CompletionCode = 0x80 // Get Message / Read Event Message Buffer
+	CompletionCodeNodeBusy                 CompletionCode = 0xC0
+	CompletionCodeInvalidCommand           CompletionCode = 0xC1
+	CompletionCodeInvalidForLUN            CompletionCode = 0xC2
+	CompletionCodeTimeout                  CompletionCode = 0xC3
+	CompletionCodeOutOfSpace               CompletionCode = 0xC4
+	CompletionCodeRequestDataLengthInvalid CompletionCode = 0xC7
+	CompletionCodeInvalidField             CompletionCode = 0xCC
+	CompletionCodeParameterOutOfRange      CompletionCode = 0xC9
+	CompletionCodeUnspecified              CompletionCode = 0xFF
 )
 
 // Boot device mapping for IPMI boot option parameter 5
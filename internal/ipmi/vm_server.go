@@ -69,6 +69,9 @@ func (vs *VMServer) ListenAndServe(addr string) error {
 func (vs *VMServer) HandleConnection(conn net.Conn) error {
 	defer conn.Close()
 
+	vc := &vmConn{conn: conn, done: make(chan struct{})}
+	defer close(vc.done)
+
 	reader := &vmReader{conn: conn}
 	for {
 		terminator, data, err := reader.ReadMessage()
@@ -82,15 +85,35 @@ func (vs *VMServer) HandleConnection(conn net.Conn) error {
 
 		switch terminator {
 		case VMCmdChar:
-			vs.handleControlCommand(data, conn)
+			vs.handleControlCommand(data, vc)
 		case VMMsgChar:
-			vs.handleIPMIMsg(data, conn)
+			vs.handleIPMIMsg(data, vc)
 		}
 	}
 }
 
+// vmConn is the per-connection state of a VM protocol peer. Writes are
+// serialized because attention changes are sent from a separate goroutine.
+type vmConn struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+	done    chan struct{}
+
+	attnMu      sync.Mutex
+	attnStarted bool
+	attnSent    uint8 // last attention command sent (VMCmdNoAttn, VMCmdAttn or VMCmdAttnIRQ)
+}
+
+// write sends a framed message to the peer.
+func (vc *vmConn) write(frame []byte) error {
+	vc.writeMu.Lock()
+	defer vc.writeMu.Unlock()
+	_, err := vc.conn.Write(frame)
+	return err
+}
+
 // handleControlCommand processes a VM control command.
-func (vs *VMServer) handleControlCommand(data []byte, conn net.Conn) {
+func (vs *VMServer) handleControlCommand(data []byte, vc *vmConn) {
 	cmd, rest, err := vmParseControlCommand(data)
 	if err != nil {
 		log.Printf("VM server: invalid control command: %v", err)
@@ -115,16 +138,76 @@ func (vs *VMServer) handleControlCommand(data []byte, conn net.Conn) {
 		vs.mu.Unlock()
 		log.Printf("VM server: peer capabilities 0x%02x", caps)
 
-		// Send NOATTN response
-		vs.sendControlCommand(conn, VMCmdNoAttn)
+		// Track Message Flags changes, then report the current attention
+		// state (NOATTN unless messages are already pending).
+		vc.attnMu.Lock()
+		start := !vc.attnStarted
+		vc.attnStarted = true
+		vc.attnMu.Unlock()
+		if start {
+			notify, cancel := vs.bmcState.SubscribeMessageFlags()
+			go vs.watchAttention(vc, notify, cancel)
+		}
+		vs.updateAttention(vc, true)
 
 	default:
 		log.Printf("VM server: unknown control command 0x%02x", cmd)
 	}
 }
 
+// watchAttention raises or drops the attention signal whenever the system
+// interface Message Flags change, until the connection is closed.
+func (vs *VMServer) watchAttention(vc *vmConn, notify <-chan struct{}, cancel func()) {
+	defer cancel()
+	for {
+		select {
+		case <-vc.done:
+			return
+		case <-notify:
+			vs.updateAttention(vc, false)
+		}
+	}
+}
+
+// updateAttention sends ATTN (or ATTN with IRQ) while messages or events are
+// pending and NOATTN once they are drained. Unchanged states are not resent
+// unless force is set.
+func (vs *VMServer) updateAttention(vc *vmConn, force bool) {
+	vs.mu.Lock()
+	caps := vs.vmCaps
+	vs.mu.Unlock()
+
+	cmd := uint8(VMCmdNoAttn)
+	if caps&VMCapAttn != 0 {
+		flags := vs.bmcState.GetMessageFlags()
+		enables := vs.bmcState.GetGlobalEnables()
+		var irqEnables uint8
+		if flags&bmc.MessageFlagReceiveMessageAvailable != 0 {
+			cmd = VMCmdAttn
+			irqEnables |= bmc.GlobalEnableReceiveMessageInterrupt
+		}
+		if flags&bmc.MessageFlagEventBufferFull != 0 {
+			cmd = VMCmdAttn
+			irqEnables |= bmc.GlobalEnableEventBufferInterrupt
+		}
+		if cmd == VMCmdAttn && caps&VMCapIRQ != 0 && enables&irqEnables != 0 {
+			cmd = VMCmdAttnIRQ
+		}
+	}
+
+	vc.attnMu.Lock()
+	if !force && cmd == vc.attnSent {
+		vc.attnMu.Unlock()
+		return
+	}
+	vc.attnSent = cmd
+	vc.attnMu.Unlock()
+
+	vs.sendControlCommand(vc, cmd)
+}
+
 // handleIPMIMsg processes an IPMI message received over the VM protocol.
-func (vs *VMServer) handleIPMIMsg(data []byte, conn net.Conn) {
+func (vs *VMServer) handleIPMIMsg(data []byte, vc *vmConn) {
 	req, err := vmParseIPMIRequest(data)
 	if err != nil {
 		log.Printf("VM server: invalid IPMI request: %v", err)
@@ -136,6 +219,7 @@ func (vs *VMServer) handleIPMIMsg(data []byte, conn net.Conn) {
 		TargetLun: (req.NetFn << 2) | (req.LUN & 0x03),
 		Command:   req.Cmd,
 		Data:      req.Data,
+		Channel:   ChannelSystemInterface,
 	}
 
 	// Route to the shared IPMI command handler
@@ -149,18 +233,18 @@ func (vs *VMServer) handleIPMIMsg(data []byte, conn net.Conn) {
 	escaped := vmEscapeBytes(response)
 	escaped = append(escaped, VMMsgChar)
 
-	if _, err := conn.Write(escaped); err != nil {
+	if err := vc.write(escaped); err != nil {
 		log.Printf("VM server: write IPMI response error: %v", err)
 	}
 }
 
 // sendControlCommand sends a control command to the peer.
-func (vs *VMServer) sendControlCommand(conn net.Conn, cmd uint8, data ...byte) {
+func (vs *VMServer) sendControlCommand(vc *vmConn, cmd uint8, data ...byte) {
 	raw := vmBuildControlCommand(cmd, data...)
 	escaped := vmEscapeBytes(raw)
 	escaped = append(escaped, VMCmdChar)
 
-	if _, err := conn.Write(escaped); err != nil {
+	if err := vc.write(escaped); err != nil {
 		log.Printf("VM server: write control command error: %v", err)
 	}
 }
@@ -334,3 +334,65 @@ func TestVMReader_ReadMessage_EOF(t *testing.T) {
 	_, _, err := reader.ReadMessage()
 	assert.ErrorIs(t, err, io.EOF)
 }
+
+func TestVMServer_AttentionOnBridgedMessage(t *testing.T) {
+	clientConn, vs, waitFn := vmTestHelper(t, machine.PowerOn)
+	vmDoHandshake(t, clientConn)
+
+	// A LAN client bridges a message to the system interface
+	code, _ := handleSendMessage(&IPMIMessage{Data: []byte{0x0F, 0x20, 0x18}, Channel: ChannelLAN}, vs.bmcState)
+	require.Equal(t, CompletionCodeOK, code)
+
+	terminator, data := vmReadResponse(t, clientConn)
+	require.Equal(t, uint8(VMCmdChar), terminator)
+	assert.Equal(t, []byte{VMCmdAttn}, data)
+
+	// The guest fetches it with Get Message (NetFn App)
+	_, err := clientConn.Write(vmBuildIPMIRequestFrame(0x01, NetFnApp, 0, CmdGetMessage, nil))
+	require.NoError(t, err)
+
+	// Expect the response and NOATTN, in either order
+	var gotResp, gotNoAttn bool
+	for i := 0; i < 2; i++ {
+		terminator, data = vmReadResponse(t, clientConn)
+		switch terminator {
+		case VMMsgChar:
+			require.GreaterOrEqual(t, len(data), 5)
+			assert.Equal(t, uint8(CompletionCodeOK), data[3])
+			assert.Equal(t, []byte{0x20, 0x18}, data[5:len(data)-1], "payload precedes the checksum")
+			gotResp = true
+		case VMCmdChar:
+			assert.Equal(t, []byte{VMCmdNoAttn}, data)
+			gotNoAttn = true
+		}
+	}
+	assert.True(t, gotResp)
+	assert.True(t, gotNoAttn)
+
+	clientConn.Close()
+	require.NoError(t, waitFn())
+}
+
+func TestVMServer_AttentionOnEveryConnection(t *testing.T) {
+	clientConn, vs, waitFn := vmTestHelper(t, machine.PowerOn)
+	vmDoHandshake(t, clientConn)
+
+	otherClient, otherServer := net.Pipe()
+	done := make(chan error, 1)
+	go func() { done <- vs.HandleConnection(otherServer) }()
+	vmDoHandshake(t, otherClient)
+
+	code, _ := handleSendMessage(&IPMIMessage{Data: []byte{0x0F, 0x20, 0x18}, Channel: ChannelLAN}, vs.bmcState)
+	require.Equal(t, CompletionCodeOK, code)
+
+	for _, conn := range []net.Conn{clientConn, otherClient} {
+		terminator, data := vmReadResponse(t, conn)
+		require.Equal(t, uint8(VMCmdChar), terminator)
+		assert.Equal(t, []byte{VMCmdAttn}, data)
+	}
+
+	otherClient.Close()
+	require.NoError(t, <-done)
+	clientConn.Close()
+	require.NoError(t, waitFn())
+}
@@ -0,0 +1,368 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/tjst-t/qemu-bmc/internal/qemu"
+)
+
+// BIOS errors
+var (
+	ErrInvalidBiosSetting = errors.New("invalid BIOS setting")
+)
+
+// BIOS boot modes
+const (
+	BiosBootModeLegacy = "Bios" // SeaBIOS
+	BiosBootModeUEFI   = "Uefi" // OVMF
+)
+
+// Limits of the BIOS settings
+const (
+	MaxBootMenuTimeout = 60 // seconds
+	MaxNumaNodes       = 8
+	MaxSMBIOSLength    = 64
+)
+
+// BiosSettings are the firmware settings of the VM. In process mode they
+// are read from, and applied to, the QEMU command line.
+type BiosSettings struct {
+	BootMode        string // BiosBootModeLegacy or BiosBootModeUEFI
+	BootMenuTimeout int    // seconds, 0 = no boot menu
+	SecureBoot      bool   // UEFI only
+	NumaNodes       int
+
+	// SMBIOS type 1 strings, empty for QEMU's defaults
+	SystemManufacturer string
+	SystemProductName  string
+	SystemVersion      string
+	SystemSerialNumber string
+	SystemSKU          string
+	SystemFamily       string
+}
+
+// SetFirmwareImages configures the OVMF images used when the BIOS settings
+// select UEFI. A variable store the QEMU command line already boots is
+// taken to match its Secure Boot setting.
+func (m *Machine) SetFirmwareImages(ovmf qemu.OVMF) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ovmf = ovmf
+	m.varsTemplate = ""
+	if m.processManager == nil {
+		return
+	}
+	args := m.processManager.Args()
+	if fw := qemu.ParseFirmware(args); fw.UEFI && usesFile(args, ovmf.Vars) {
+		m.varsTemplate = varsTemplate(ovmf, fw.SecureBoot)
+	}
+}
+
+// GetBios returns the BIOS settings QEMU runs, or will next start, with.
+func (m *Machine) GetBios() (BiosSettings, error) {
+	if m.processManager == nil {
+		return BiosSettings{}, ErrNotSupported
+	}
+	return biosFromFirmware(qemu.ParseFirmware(m.processManager.Args())), nil
+}
+
+// GetPendingBios returns the BIOS settings applied at the next power on:
+// the pending settings if there are any, the current ones otherwise.
+func (m *Machine) GetPendingBios() (BiosSettings, error) {
+	current, err := m.GetBios()
+	if err != nil {
+		return BiosSettings{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.biosPending != nil {
+		return *m.biosPending, nil
+	}
+	return current, nil
+}
+
+// SetPendingBios sets the BIOS settings applied at the next power on.
+func (m *Machine) SetPendingBios(s BiosSettings) error {
+	current, err := m.GetBios()
+	if err != nil {
+		return err
+	}
+	if err := validateBios(s); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.biosPending = &s
+	if s == current {
+		m.biosPending = nil
+	}
+	return nil
+}
+
+// ResetBios restores the BIOS settings QEMU was configured with at startup
+// from the next power on and reinitializes the UEFI variable store.
+func (m *Machine) ResetBios() error {
+	current, err := m.GetBios()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defaults := m.biosDefaults
+	m.biosPending = &defaults
+	if defaults == current {
+		m.biosPending = nil
+	}
+	m.biosReset = true
+	return nil
+}
+
+// applyPendingBios rewrites the QEMU arguments for the pending BIOS
+// settings before QEMU is started. Pending settings that cannot be applied
+// are kept and fail the power on.
+func (m *Machine) applyPendingBios() error {
+	m.mu.RLock()
+	pending, reset, ovmf := m.biosPending, m.biosReset, m.ovmf
+	m.mu.RUnlock()
+	if pending == nil && !reset {
+		return nil
+	}
+
+	args := m.processManager.Args()
+	current := biosFromFirmware(qemu.ParseFirmware(args))
+	target := current
+	if pending != nil {
+		target = *pending
+	}
+	fw := target.firmware()
+
+	if fw.UEFI {
+		reinit := reset || (current.BootMode == BiosBootModeUEFI && current.SecureBoot != target.SecureBoot)
+		if err := m.prepareVars(ovmf, fw.SecureBoot, reinit); err != nil {
+			return err
+		}
+	}
+	if pending != nil {
+		newArgs, err := qemu.ApplyFirmware(args, fw, ovmf)
+		if err != nil {
+			return err
+		}
+		m.processManager.SetArgs(newArgs)
+		log.Printf("Applied pending BIOS settings: %+v", target)
+	}
+
+	m.mu.Lock()
+	m.biosPending = nil
+	m.biosReset = false
+	m.mu.Unlock()
+	return nil
+}
+
+// bootConfig selects the firmware for the next start of QEMU: the boot
+// override's mode while an override is enabled, the firmware on the QEMU
+// command line (the BIOS BootMode) otherwise. Switching firmware needs the
+// OVMF images from SetFirmwareImages. The VM's UEFI variable store is
+// created from its template when UEFI boots without one.
+func (m *Machine) bootConfig() (qemu.Boot, error) {
+	m.mu.RLock()
+	override, ovmf := m.bootOverride, m.ovmf
+	m.mu.RUnlock()
+	if override.Enabled == "Disabled" {
+		override.Target = "None"
+	}
+
+	boot := qemu.Boot{Target: override.Target, OVMF: ovmf}
+	switch override.Target {
+	case "UefiTarget":
+		dev, err := m.resolveUefiTarget(override.UefiTarget)
+		if err != nil {
+			return qemu.Boot{}, err
+		}
+		boot.Device = dev
+	case "UefiShell", "UefiHttp":
+		dir, err := m.prepareBootDisk(override)
+		if err != nil {
+			return qemu.Boot{}, err
+		}
+		boot.BootDisk = dir
+	}
+
+	args := m.processManager.Args()
+	fw := qemu.ParseFirmware(args)
+	if override.Enabled != "Disabled" && ovmf.Code != "" {
+		if uefi := override.Mode == "UEFI"; uefi != fw.UEFI {
+			fw.UEFI = uefi
+			fw.SecureBoot = false
+			boot.Firmware = &fw
+		}
+	}
+
+	if fw.UEFI && (boot.Firmware != nil || usesFile(args, ovmf.Vars)) {
+		if err := m.prepareVars(ovmf, fw.SecureBoot, false); err != nil {
+			return qemu.Boot{}, err
+		}
+	}
+	return boot, nil
+}
+
+// nextBootArgs returns the QEMU arguments with the firmware override o
+// boots, as bootConfig selects it.
+func (m *Machine) nextBootArgs(o BootOverride) []string {
+	m.mu.RLock()
+	ovmf := m.ovmf
+	m.mu.RUnlock()
+
+	args := m.processManager.Args()
+	fw := qemu.ParseFirmware(args)
+	uefi := o.Mode == "UEFI"
+	if o.Enabled == "Disabled" || ovmf.Code == "" || uefi == fw.UEFI {
+		return args
+	}
+	fw.UEFI, fw.SecureBoot = uefi, false
+	if switched, err := qemu.ApplyFirmware(args, fw, ovmf); err == nil {
+		return switched
+	}
+	return args
+}
+
+// overrideMode names the firmware of fw as a boot override mode.
+func overrideMode(fw qemu.Firmware) string {
+	if fw.UEFI {
+		return "UEFI"
+	}
+	return "Legacy"
+}
+
+// usesFile reports whether an argument opens path as file=path.
+func usesFile(args []string, path string) bool {
+	if path == "" {
+		return false
+	}
+	for _, arg := range args {
+		if strings.Contains(arg+",", "file="+path+",") {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareVars creates the VM's UEFI variable store from the template
+// matching secureBoot if it is missing or was created from the other
+// template, or unconditionally if reinit is set. A store of unknown origin
+// is kept.
+func (m *Machine) prepareVars(ovmf qemu.OVMF, secureBoot, reinit bool) error {
+	if ovmf.Vars == "" {
+		return errors.New("no UEFI variable store configured")
+	}
+	template := varsTemplate(ovmf, secureBoot)
+	m.mu.RLock()
+	created := m.varsTemplate
+	m.mu.RUnlock()
+	if _, err := os.Stat(ovmf.Vars); err == nil && !reinit && (created == "" || created == template) {
+		return nil
+	}
+	if err := copyFile(template, ovmf.Vars); err != nil {
+		return fmt.Errorf("creating UEFI variable store: %w", err)
+	}
+	m.mu.Lock()
+	m.varsTemplate = template
+	m.mu.Unlock()
+	return nil
+}
+
+// varsTemplate returns the variable store template matching secureBoot.
+func varsTemplate(ovmf qemu.OVMF, secureBoot bool) string {
+	if secureBoot {
+		return ovmf.SecureVarsTemplate
+	}
+	return ovmf.VarsTemplate
+}
+
+// copyFile copies src to dst, creating the directory of dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func validateBios(s BiosSettings) error {
+	switch {
+	case s.BootMode != BiosBootModeLegacy && s.BootMode != BiosBootModeUEFI:
+		return fmt.Errorf("%w: unknown boot mode %q", ErrInvalidBiosSetting, s.BootMode)
+	case s.BootMenuTimeout < 0 || s.BootMenuTimeout > MaxBootMenuTimeout:
+		return fmt.Errorf("%w: boot menu timeout must be 0-%d seconds", ErrInvalidBiosSetting, MaxBootMenuTimeout)
+	case s.SecureBoot && s.BootMode != BiosBootModeUEFI:
+		return fmt.Errorf("%w: secure boot requires UEFI boot mode", ErrInvalidBiosSetting)
+	case s.NumaNodes < 1 || s.NumaNodes > MaxNumaNodes:
+		return fmt.Errorf("%w: NUMA nodes must be 1-%d", ErrInvalidBiosSetting, MaxNumaNodes)
+	}
+	for _, v := range []string{s.SystemManufacturer, s.SystemProductName, s.SystemVersion,
+		s.SystemSerialNumber, s.SystemSKU, s.SystemFamily} {
+		if len(v) > MaxSMBIOSLength {
+			return fmt.Errorf("%w: SMBIOS strings are limited to %d characters", ErrInvalidBiosSetting, MaxSMBIOSLength)
+		}
+		for _, r := range v {
+			if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+				return fmt.Errorf("%w: SMBIOS strings must be printable ASCII", ErrInvalidBiosSetting)
+			}
+		}
+	}
+	return nil
+}
+
+func biosFromFirmware(fw qemu.Firmware) BiosSettings {
+	s := BiosSettings{
+		BootMode:           BiosBootModeLegacy,
+		BootMenuTimeout:    fw.BootMenuTimeout,
+		SecureBoot:         fw.SecureBoot,
+		NumaNodes:          max(fw.NUMANodes, 1),
+		SystemManufacturer: fw.SMBIOS.Manufacturer,
+		SystemProductName:  fw.SMBIOS.Product,
+		SystemVersion:      fw.SMBIOS.Version,
+		SystemSerialNumber: fw.SMBIOS.Serial,
+		SystemSKU:          fw.SMBIOS.SKU,
+		SystemFamily:       fw.SMBIOS.Family,
+	}
+	if fw.UEFI {
+		s.BootMode = BiosBootModeUEFI
+	}
+	return s
+}
+
+func (s BiosSettings) firmware() qemu.Firmware {
+	return qemu.Firmware{
+		UEFI:            s.BootMode == BiosBootModeUEFI,
+		SecureBoot:      s.SecureBoot,
+		BootMenuTimeout: s.BootMenuTimeout,
+		NUMANodes:       s.NumaNodes,
+		SMBIOS: qemu.SMBIOS{
+			Manufacturer: s.SystemManufacturer,
+			Product:      s.SystemProductName,
+			Version:      s.SystemVersion,
+			Serial:       s.SystemSerialNumber,
+			SKU:          s.SystemSKU,
+			Family:       s.SystemFamily,
+		},
+	}
+}
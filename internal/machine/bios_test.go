@@ -0,0 +1,311 @@
+package machine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/qemu"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+// newBiosMachine returns a powered-off process-mode machine whose OVMF
+// images live in a temporary directory.
+func newBiosMachine(t *testing.T, args ...string) (*Machine, *mockProcessManager, qemu.OVMF) {
+	t.Helper()
+	dir := t.TempDir()
+	ovmf := qemu.OVMF{
+		Code:               filepath.Join(dir, "OVMF_CODE.fd"),
+		SecureCode:         filepath.Join(dir, "OVMF_CODE.secboot.fd"),
+		VarsTemplate:       filepath.Join(dir, "OVMF_VARS.fd"),
+		SecureVarsTemplate: filepath.Join(dir, "OVMF_VARS.ms.fd"),
+		Vars:               filepath.Join(dir, "vm", "OVMF_VARS.fd"),
+	}
+	require.NoError(t, os.WriteFile(ovmf.VarsTemplate, []byte("vars"), 0o644))
+	require.NoError(t, os.WriteFile(ovmf.SecureVarsTemplate, []byte("ms-vars"), 0o644))
+
+	pm := newMockProcessManager(false)
+	pm.args = append([]string{"-m", "2048", "-smp", "2"}, args...)
+	m := NewWithProcess(newMockQMPClient(qmp.StatusRunning), pm)
+	m.SetFirmwareImages(ovmf)
+	return m, pm, ovmf
+}
+
+func TestGetBios_FromArgs(t *testing.T) {
+	m, _, _ := newBiosMachine(t,
+		"-drive", "if=pflash,format=raw,readonly=on,file=/usr/share/OVMF/OVMF_CODE.fd",
+		"-boot", "menu=on,splash-time=5000",
+		"-smbios", "type=1,serial=SN1",
+	)
+
+	s, err := m.GetBios()
+	require.NoError(t, err)
+	assert.Equal(t, BiosSettings{
+		BootMode:           BiosBootModeUEFI,
+		BootMenuTimeout:    5,
+		NumaNodes:          1,
+		SystemSerialNumber: "SN1",
+	}, s)
+}
+
+func TestGetBios_LegacyMode(t *testing.T) {
+	m := New(newMockQMPClient(qmp.StatusRunning))
+	_, err := m.GetBios()
+	assert.ErrorIs(t, err, ErrNotSupported)
+	assert.ErrorIs(t, m.SetPendingBios(BiosSettings{BootMode: BiosBootModeLegacy, NumaNodes: 1}), ErrNotSupported)
+}
+
+func TestSetPendingBios_AppliedAtPowerOn(t *testing.T) {
+	m, pm, ovmf := newBiosMachine(t)
+	s, err := m.GetBios()
+	require.NoError(t, err)
+
+	s.BootMode = BiosBootModeUEFI
+	s.SystemManufacturer = "ACME"
+	require.NoError(t, m.SetPendingBios(s))
+
+	// Current settings are unchanged until the next power on
+	current, _ := m.GetBios()
+	assert.Equal(t, BiosBootModeLegacy, current.BootMode)
+	pending, _ := m.GetPendingBios()
+	assert.Equal(t, s, pending)
+
+	require.NoError(t, m.Reset("On"))
+	current, _ = m.GetBios()
+	assert.Equal(t, s, current)
+	assert.Contains(t, pm.args, "type=1,manufacturer=ACME")
+
+	vars, err := os.ReadFile(ovmf.Vars)
+	require.NoError(t, err)
+	assert.Equal(t, "vars", string(vars))
+}
+
+func TestSetPendingBios_SameAsCurrentClearsPending(t *testing.T) {
+	m, pm, _ := newBiosMachine(t)
+	s, _ := m.GetBios()
+	changed := s
+	changed.NumaNodes = 2
+	require.NoError(t, m.SetPendingBios(changed))
+	require.NoError(t, m.SetPendingBios(s))
+
+	args := pm.Args()
+	require.NoError(t, m.Reset("On"))
+	assert.Equal(t, args, pm.args)
+}
+
+func TestSetPendingBios_Invalid(t *testing.T) {
+	m, _, _ := newBiosMachine(t)
+	base, _ := m.GetBios()
+
+	tests := map[string]func(s *BiosSettings){
+		"boot mode":        func(s *BiosSettings) { s.BootMode = "Efi" },
+		"timeout":          func(s *BiosSettings) { s.BootMenuTimeout = MaxBootMenuTimeout + 1 },
+		"secure boot bios": func(s *BiosSettings) { s.SecureBoot = true },
+		"numa":             func(s *BiosSettings) { s.NumaNodes = 0 },
+		"smbios":           func(s *BiosSettings) { s.SystemSerialNumber = "bad\nserial" },
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := base
+			mutate(&s)
+			assert.ErrorIs(t, m.SetPendingBios(s), ErrInvalidBiosSetting)
+		})
+	}
+}
+
+func TestSetPendingBios_SecureBootUsesEnrolledVars(t *testing.T) {
+	m, pm, ovmf := newBiosMachine(t)
+	s, _ := m.GetBios()
+	s.BootMode = BiosBootModeUEFI
+	s.SecureBoot = true
+	require.NoError(t, m.SetPendingBios(s))
+	require.NoError(t, m.Reset("On"))
+
+	assert.Contains(t, pm.args, "if=pflash,format=raw,unit=0,readonly=on,file="+ovmf.SecureCode)
+	vars, err := os.ReadFile(ovmf.Vars)
+	require.NoError(t, err)
+	assert.Equal(t, "ms-vars", string(vars))
+}
+
+func TestApplyPendingBios_FailureKeepsPending(t *testing.T) {
+	m, pm, _ := newBiosMachine(t)
+	m.SetFirmwareImages(qemu.OVMF{})
+	s, _ := m.GetBios()
+	s.BootMode = BiosBootModeUEFI
+	require.NoError(t, m.SetPendingBios(s))
+
+	err := m.Reset("On")
+	assert.Error(t, err)
+	assert.False(t, pm.running)
+	pending, _ := m.GetPendingBios()
+	assert.Equal(t, BiosBootModeUEFI, pending.BootMode)
+}
+
+func TestResetBios(t *testing.T) {
+	m, pm, ovmf := newBiosMachine(t,
+		"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=code.fd",
+		"-drive", "if=pflash,format=raw,unit=1,file=vars.fd",
+	)
+	defaults, _ := m.GetBios()
+
+	changed := defaults
+	changed.BootMode = BiosBootModeLegacy
+	require.NoError(t, m.SetPendingBios(changed))
+	require.NoError(t, m.Reset("On"))
+	pm.Stop(0)
+	require.NoError(t, os.MkdirAll(filepath.Dir(ovmf.Vars), 0o755))
+	require.NoError(t, os.WriteFile(ovmf.Vars, []byte("modified"), 0o644))
+
+	require.NoError(t, m.ResetBios())
+	pending, _ := m.GetPendingBios()
+	assert.Equal(t, defaults, pending)
+
+	require.NoError(t, m.Reset("On"))
+	current, _ := m.GetBios()
+	assert.Equal(t, defaults, current)
+	assert.Contains(t, pm.args, "if=pflash,format=raw,unit=1,file="+ovmf.Vars)
+	vars, _ := os.ReadFile(ovmf.Vars)
+	assert.Equal(t, "vars", string(vars))
+}
+
+func TestBootOverride_ModeSwitchesFirmwareForOneBoot(t *testing.T) {
+	m, pm, ovmf := newBiosMachine(t)
+	assert.Equal(t, "Legacy", m.GetBootOverride().Mode)
+
+	require.NoError(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Cd", Mode: "UEFI"}))
+	require.NoError(t, m.Reset("On"))
+
+	require.Len(t, pm.boots, 1)
+	require.NotNil(t, pm.boots[0].Firmware)
+	assert.True(t, pm.boots[0].Firmware.UEFI)
+	assert.Equal(t, ovmf, pm.boots[0].OVMF)
+	vars, err := os.ReadFile(ovmf.Vars)
+	require.NoError(t, err)
+	assert.Equal(t, "vars", string(vars))
+
+	// The persistent firmware is unchanged
+	s, _ := m.GetBios()
+	assert.Equal(t, BiosBootModeLegacy, s.BootMode)
+	m.ConsumeBootOnce()
+	assert.Equal(t, "Legacy", m.GetBootOverride().Mode)
+}
+
+func TestBootOverride_ReinitializesSecureBootVars(t *testing.T) {
+	m, pm, ovmf := newBiosMachine(t)
+	s, _ := m.GetBios()
+	s.BootMode = BiosBootModeUEFI
+	s.SecureBoot = true
+	require.NoError(t, m.SetPendingBios(s))
+	require.NoError(t, m.Reset("On"))
+	pm.Stop(0)
+
+	s.BootMode = BiosBootModeLegacy
+	s.SecureBoot = false
+	require.NoError(t, m.SetPendingBios(s))
+	require.NoError(t, m.Reset("On"))
+	pm.Stop(0)
+	vars, _ := os.ReadFile(ovmf.Vars)
+	assert.Equal(t, "ms-vars", string(vars))
+
+	// The override boots OVMF without Secure Boot, so the enrolled store
+	// is replaced
+	require.NoError(t, m.SetBootOverride(BootOverride{Enabled: "Continuous", Target: "Cd", Mode: "UEFI"}))
+	require.NoError(t, m.Reset("On"))
+	pm.Stop(0)
+	vars, _ = os.ReadFile(ovmf.Vars)
+	assert.Equal(t, "vars", string(vars))
+
+	// and kept on the next override boot
+	require.NoError(t, os.WriteFile(ovmf.Vars, []byte("modified"), 0o644))
+	require.NoError(t, m.Reset("On"))
+	vars, _ = os.ReadFile(ovmf.Vars)
+	assert.Equal(t, "modified", string(vars))
+}
+
+func TestBootOverride_LegacyOverUEFI(t *testing.T) {
+	m, pm, _ := newBiosMachine(t,
+		"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=code.fd",
+	)
+	assert.Equal(t, "UEFI", m.GetBootOverride().Mode)
+
+	require.NoError(t, m.SetBootOverride(BootOverride{Enabled: "Continuous", Target: "Hdd", Mode: "Legacy"}))
+	require.NoError(t, m.Reset("On"))
+
+	require.Len(t, pm.boots, 1)
+	require.NotNil(t, pm.boots[0].Firmware)
+	assert.False(t, pm.boots[0].Firmware.UEFI)
+}
+
+func TestBootOverride_DisabledUsesPersistentFirmware(t *testing.T) {
+	m, pm, ovmf := newBiosMachine(t)
+	require.NoError(t, m.SetBootOverride(BootOverride{Enabled: "Disabled", Target: "None", Mode: "UEFI"}))
+	require.NoError(t, m.Reset("On"))
+
+	require.Len(t, pm.boots, 1)
+	assert.Nil(t, pm.boots[0].Firmware)
+	_, err := os.Stat(ovmf.Vars)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBootOverride_TargetsUnderOVMF(t *testing.T) {
+	m, _, _ := newBiosMachine(t,
+		"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=code.fd",
+		"-fda", "floppy.img",
+	)
+	m.SetFirmwareImages(qemu.OVMF{})
+
+	// Without OVMF images the override cannot switch to SeaBIOS
+	err := m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Floppy", Mode: "Legacy"})
+	assert.ErrorIs(t, err, qemu.ErrBootTargetNotSupported)
+	err = m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Pxe", Mode: "UEFI"})
+	assert.ErrorIs(t, err, qemu.ErrBootTargetNotSupported)
+	assert.NoError(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "BiosSetup", Mode: "UEFI"}))
+
+	// A disabled override keeps its target without booting it
+	assert.NoError(t, m.SetBootOverride(BootOverride{Enabled: "Disabled", Target: "Pxe", Mode: "UEFI"}))
+}
+
+func TestBootOverride_TargetCheckedWithSwitchedFirmware(t *testing.T) {
+	m, _, _ := newBiosMachine(t, "-fda", "floppy.img")
+
+	err := m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Pxe", Mode: "UEFI"})
+	assert.ErrorIs(t, err, qemu.ErrBootTargetNotSupported)
+	assert.NoError(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Pxe", Mode: "Legacy"}))
+	assert.NoError(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Floppy", Mode: "Legacy"}))
+}
+
+func TestBootOverride_NoFirmwareImages(t *testing.T) {
+	m, pm, _ := newBiosMachine(t)
+	m.SetFirmwareImages(qemu.OVMF{})
+	require.NoError(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Pxe", Mode: "UEFI"}))
+	require.NoError(t, m.Reset("On"))
+
+	require.Len(t, pm.boots, 1)
+	assert.Nil(t, pm.boots[0].Firmware)
+	assert.Equal(t, "Pxe", pm.boots[0].Target)
+}
+
+func TestBootConfig_CreatesVarsForPersistentUEFI(t *testing.T) {
+	dir := t.TempDir()
+	vars := filepath.Join(dir, "vm", "OVMF_VARS.fd")
+	m, pm, ovmf := newBiosMachine(t,
+		"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=code.fd",
+		"-drive", "if=pflash,format=raw,unit=1,file="+vars,
+	)
+	ovmf.Vars = vars
+	m.SetFirmwareImages(ovmf)
+
+	require.NoError(t, m.Reset("On"))
+	require.Len(t, pm.boots, 1)
+	assert.Nil(t, pm.boots[0].Firmware)
+	data, err := os.ReadFile(vars)
+	require.NoError(t, err)
+	assert.Equal(t, "vars", string(data))
+}
+
+func TestSetBootOverride_InvalidMode(t *testing.T) {
+	m, _, _ := newBiosMachine(t)
+	assert.Error(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Pxe", Mode: "Efi"}))
+}
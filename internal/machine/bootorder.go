@@ -0,0 +1,355 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tjst-t/qemu-bmc/internal/qemu"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+// Boot order errors
+var (
+	ErrBootOptionNotFound = errors.New("boot option not found")
+	ErrInvalidBootOrder   = errors.New("invalid boot order")
+)
+
+// bootClassNames names the boot device classes in BootOption.DisplayName.
+var bootClassNames = map[string]string{
+	qemu.BootClassPxe: "Network",
+	qemu.BootClassHdd: "Hard disk",
+	qemu.BootClassCd:  "CD-ROM",
+	qemu.BootClassUsb: "USB",
+}
+
+// BootOption is a device the VM can boot from
+type BootOption struct {
+	Reference      string // BootOptionReference, "Boot0000" style
+	Alias          string // qemu.BootClassPxe, BootClassHdd, BootClassCd or BootClassUsb
+	Driver         string
+	DeviceID       string // empty for devices QEMU creates without an id
+	DisplayName    string // BIOS style name
+	UefiDevicePath string // empty when the PCI address is unknown
+}
+
+// BootImages are the EFI applications the UefiShell and UefiHttp boot
+// targets start from a generated FAT boot disk.
+type BootImages struct {
+	UEFIShell string // UEFI shell, booted for UefiShell
+	IPXE      string // iPXE EFI build, booted for UefiHttp
+	Dir       string // directory the boot disk is generated in
+}
+
+// SetBootImages configures the images of the UefiShell and UefiHttp boot
+// targets.
+func (m *Machine) SetBootImages(images BootImages) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bootImages = images
+}
+
+// GetBootOptions returns the bootable devices on the QEMU command line.
+// Their references follow the command line order.
+func (m *Machine) GetBootOptions() ([]BootOption, error) {
+	if m.processManager == nil {
+		return nil, ErrNotSupported
+	}
+	args := m.processManager.Args()
+	devs := qemu.ParseBootDevices(args)
+	paths := m.pciDevicePaths(args)
+
+	opts := make([]BootOption, len(devs))
+	for i, dev := range devs {
+		name := dev.ID
+		if name == "" {
+			name = dev.Driver
+		}
+		opts[i] = BootOption{
+			Reference:   bootReference(i),
+			Alias:       dev.Class,
+			Driver:      dev.Driver,
+			DeviceID:    dev.ID,
+			DisplayName: fmt.Sprintf("%s (%s)", bootClassNames[dev.Class], name),
+		}
+		if path, ok := paths[dev.ID]; ok && dev.ID != "" && !dev.Implicit {
+			opts[i].UefiDevicePath = path
+			if dev.Class == qemu.BootClassPxe {
+				opts[i].UefiDevicePath += nicDevicePath(args, dev.ID)
+			}
+		}
+	}
+	return opts, nil
+}
+
+// GetBootOrder returns the references of the boot options in the order the
+// firmware tries them at the next power on.
+func (m *Machine) GetBootOrder() ([]string, error) {
+	if m.processManager == nil {
+		return nil, ErrNotSupported
+	}
+	devs := qemu.ParseBootDevices(m.processManager.Args())
+	var order []string
+	for _, i := range qemu.SortBootDevices(devs) {
+		order = append(order, bootReference(i))
+	}
+	return order, nil
+}
+
+// SetBootOrder sets the persistent boot order from the next power on by
+// rewriting the bootindex of the devices. Options not listed boot after the
+// listed ones.
+func (m *Machine) SetBootOrder(refs []string) error {
+	args, err := m.bootOrderArgs(refs)
+	if err != nil {
+		return err
+	}
+	m.processManager.SetArgs(args)
+	return nil
+}
+
+// ValidateBootOrder checks a boot order without applying it.
+func (m *Machine) ValidateBootOrder(refs []string) error {
+	_, err := m.bootOrderArgs(refs)
+	return err
+}
+
+// bootOrderArgs returns the QEMU arguments that boot in the order of refs.
+func (m *Machine) bootOrderArgs(refs []string) ([]string, error) {
+	if m.processManager == nil {
+		return nil, ErrNotSupported
+	}
+	args := m.processManager.Args()
+	n := len(qemu.ParseBootDevices(args))
+	order := make([]int, len(refs))
+	for i, ref := range refs {
+		idx, ok := parseBootReference(ref)
+		if !ok || idx >= n {
+			return nil, fmt.Errorf("%w: %s", ErrBootOptionNotFound, ref)
+		}
+		order[i] = idx
+	}
+
+	newArgs, err := qemu.ApplyBootOrder(args, order)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBootOrder, err)
+	}
+	return newArgs, nil
+}
+
+func bootReference(i int) string {
+	return fmt.Sprintf("Boot%04X", i)
+}
+
+func parseBootReference(ref string) (int, bool) {
+	hex, ok := strings.CutPrefix(ref, "Boot")
+	if !ok || len(hex) != 4 {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(hex, 16, 16)
+	return int(n), err == nil
+}
+
+// pciDevicePaths returns the UEFI device paths of the PCI devices with an
+// id: from query-pci while QEMU runs, from their addr property otherwise.
+func (m *Machine) pciDevicePaths(args []string) map[string]string {
+	paths := make(map[string]string)
+	if !m.processManager.IsRunning() {
+		for i := 0; i+1 < len(args); i++ {
+			if args[i] != "-device" {
+				continue
+			}
+			id, slot, fn, ok := rootPortAddress(args[i+1])
+			if ok {
+				paths[id] = pciNode("PciRoot(0x0)", slot, fn)
+			}
+		}
+		return paths
+	}
+
+	buses, err := m.qmpClient.QueryPCI()
+	if err != nil {
+		return paths
+	}
+	var walk func(prefix string, devices []qmp.PCIDevice)
+	walk = func(prefix string, devices []qmp.PCIDevice) {
+		for _, d := range devices {
+			path := pciNode(prefix, d.Slot, d.Function)
+			if d.QdevID != "" {
+				paths[d.QdevID] = path
+			}
+			if d.PCIBridge != nil {
+				walk(path, d.PCIBridge.Devices)
+			}
+		}
+	}
+	for _, b := range buses {
+		if b.Bus == 0 {
+			walk("PciRoot(0x0)", b.Devices)
+		}
+	}
+	return paths
+}
+
+func pciNode(prefix string, slot, fn int) string {
+	return fmt.Sprintf("%s/Pci(0x%X,0x%X)", prefix, slot, fn)
+}
+
+// rootPortAddress returns the id and PCI address of a -device on the root
+// bus with an explicit addr ("0x3", "03.1").
+func rootPortAddress(val string) (id string, slot, fn int, ok bool) {
+	for _, part := range strings.Split(val, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "id":
+			id = v
+		case "bus":
+			if v != "pcie.0" && v != "pci.0" {
+				return "", 0, 0, false
+			}
+		case "addr":
+			s, f, _ := strings.Cut(v, ".")
+			sn, err1 := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 8)
+			fnn, err2 := strconv.ParseUint(strings.TrimPrefix(f, "0x"), 16, 8)
+			if err1 != nil || (f != "" && err2 != nil) {
+				return "", 0, 0, false
+			}
+			slot, fn, ok = int(sn), int(fnn), true
+		}
+	}
+	return id, slot, fn, ok && id != ""
+}
+
+// nicDevicePath returns the MAC node of a NIC's UEFI device path, or "" when
+// QEMU assigns its MAC address.
+func nicDevicePath(args []string, id string) string {
+	for _, nic := range qemu.ParseNICs(args) {
+		if nic.ID == id && nic.MAC != "" {
+			return fmt.Sprintf("/MAC(%s,0x1)", strings.ToUpper(strings.ReplaceAll(nic.MAC, ":", "")))
+		}
+	}
+	return ""
+}
+
+// validateBootTarget checks the settings a boot override target depends on.
+func (m *Machine) validateBootTarget(o BootOverride) error {
+	switch o.Target {
+	case "Floppy":
+		if o.Mode != "Legacy" {
+			return fmt.Errorf("boot target Floppy requires Legacy boot mode")
+		}
+	case "UefiShell", "UefiHttp", "UefiTarget":
+		if o.Mode != "UEFI" {
+			return fmt.Errorf("boot target %s requires UEFI boot mode", o.Target)
+		}
+	}
+	if o.HttpBootURI != "" {
+		u, err := url.Parse(o.HttpBootURI)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			strings.ContainsAny(o.HttpBootURI, " \t") {
+			return fmt.Errorf("invalid HTTP boot URI: %s", o.HttpBootURI)
+		}
+	}
+
+	if m.processManager == nil {
+		return nil
+	}
+	switch o.Target {
+	case "UefiTarget":
+		_, err := m.resolveUefiTarget(o.UefiTarget)
+		return err
+	case "UefiShell", "UefiHttp":
+		_, err := m.bootDiskImage(o.Target)
+		return err
+	case "None":
+		return nil
+	}
+	if o.Enabled == "Disabled" {
+		return nil
+	}
+	_, err := qemu.ApplyBootTarget(m.nextBootArgs(o), qemu.Boot{Target: o.Target})
+	return err
+}
+
+// resolveUefiTarget returns the position of the boot option a UEFI device
+// path points into, or the other way round. The longest match wins.
+func (m *Machine) resolveUefiTarget(path string) (int, error) {
+	if path == "" {
+		return 0, fmt.Errorf("boot target UefiTarget requires a UEFI device path")
+	}
+	opts, err := m.GetBootOptions()
+	if err != nil {
+		return 0, err
+	}
+	best := -1
+	for i, o := range opts {
+		p := o.UefiDevicePath
+		if p == "" || (best >= 0 && len(p) <= len(opts[best].UefiDevicePath)) {
+			continue
+		}
+		if p == path || strings.HasPrefix(path, p+"/") || strings.HasPrefix(p, path+"/") {
+			best = i
+		}
+	}
+	if best < 0 {
+		return 0, fmt.Errorf("%w: no boot option for UEFI device path %s", ErrBootOptionNotFound, path)
+	}
+	return best, nil
+}
+
+// bootDiskImage returns the EFI application the boot disk of target
+// starts.
+func (m *Machine) bootDiskImage(target string) (string, error) {
+	m.mu.RLock()
+	images := m.bootImages
+	m.mu.RUnlock()
+
+	image := images.UEFIShell
+	if target == "UefiHttp" {
+		image = images.IPXE
+	}
+	if image == "" || images.Dir == "" {
+		return "", fmt.Errorf("boot target %s is not configured", target)
+	}
+	if _, err := os.Stat(image); err != nil {
+		return "", fmt.Errorf("boot target %s: %w", target, err)
+	}
+	return image, nil
+}
+
+// prepareBootDisk fills the boot disk directory for a UefiShell or UefiHttp
+// override: the EFI application as the removable media boot file and, for
+// iPXE, the script it runs.
+func (m *Machine) prepareBootDisk(o BootOverride) (string, error) {
+	image, err := m.bootDiskImage(o.Target)
+	if err != nil {
+		return "", err
+	}
+	m.mu.RLock()
+	dir := m.bootImages.Dir
+	m.mu.RUnlock()
+
+	if err := copyFile(image, filepath.Join(dir, "EFI", "BOOT", "BOOTX64.EFI")); err != nil {
+		return "", fmt.Errorf("creating boot disk: %w", err)
+	}
+	script := filepath.Join(dir, "autoexec.ipxe")
+	if o.Target != "UefiHttp" {
+		if err := os.Remove(script); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("creating boot disk: %w", err)
+		}
+		return dir, nil
+	}
+
+	cmd := "autoboot"
+	if o.HttpBootURI != "" {
+		cmd = "dhcp\nchain " + o.HttpBootURI
+	}
+	if err := os.WriteFile(script, []byte("#!ipxe\n"+cmd+"\n"), 0o644); err != nil {
+		return "", fmt.Errorf("creating boot disk: %w", err)
+	}
+	return dir, nil
+}
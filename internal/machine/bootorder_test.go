@@ -0,0 +1,161 @@
+package machine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/qemu"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func newBootOrderMachine(running bool) (*Machine, *mockProcessManager, *mockQMPClient) {
+	client := newMockQMPClient(qmp.StatusRunning)
+	pm := newMockProcessManager(running)
+	pm.args = []string{
+		"-drive", "file=disk.qcow2,format=qcow2,if=virtio",
+		"-device", "virtio-net-pci,netdev=net0,id=nic0,mac=52:54:00:12:34:56,addr=0x3",
+		"-device", "scsi-hd,drive=data0,id=data0,bootindex=0",
+		"-nodefaults",
+	}
+	return NewWithProcess(client, pm), pm, client
+}
+
+func TestGetBootOptions(t *testing.T) {
+	m, _, _ := newBootOrderMachine(false)
+
+	opts, err := m.GetBootOptions()
+	require.NoError(t, err)
+	require.Len(t, opts, 3)
+	assert.Equal(t, BootOption{
+		Reference:   "Boot0000",
+		Alias:       "Hdd",
+		Driver:      "virtio-blk-pci",
+		DisplayName: "Hard disk (virtio-blk-pci)",
+	}, opts[0])
+	assert.Equal(t, BootOption{
+		Reference:      "Boot0001",
+		Alias:          "Pxe",
+		Driver:         "virtio-net-pci",
+		DeviceID:       "nic0",
+		DisplayName:    "Network (nic0)",
+		UefiDevicePath: "PciRoot(0x0)/Pci(0x3,0x0)/MAC(525400123456,0x1)",
+	}, opts[1])
+	assert.Equal(t, "Boot0002", opts[2].Reference)
+	assert.Empty(t, opts[2].UefiDevicePath)
+}
+
+func TestGetBootOptions_RunningUsesPCIAddresses(t *testing.T) {
+	m, _, client := newBootOrderMachine(true)
+	var nic, bridge, disk qmp.PCIDevice
+	nic.Slot, nic.QdevID = 3, "nic0"
+	disk.Slot, disk.Function, disk.QdevID = 0, 0, "data0"
+	bridge.Slot, bridge.QdevID = 28, "rp0"
+	bridge.PCIBridge = &qmp.PCIBridgeInfo{Devices: []qmp.PCIDevice{disk}}
+	client.pci = []qmp.PCIBus{{Devices: []qmp.PCIDevice{nic, bridge}}}
+
+	opts, err := m.GetBootOptions()
+	require.NoError(t, err)
+	assert.Equal(t, "PciRoot(0x0)/Pci(0x3,0x0)/MAC(525400123456,0x1)", opts[1].UefiDevicePath)
+	assert.Equal(t, "PciRoot(0x0)/Pci(0x1C,0x0)/Pci(0x0,0x0)", opts[2].UefiDevicePath)
+}
+
+func TestSetBootOrder(t *testing.T) {
+	m, pm, _ := newBootOrderMachine(false)
+
+	order, err := m.GetBootOrder()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Boot0002", "Boot0000", "Boot0001"}, order)
+
+	require.NoError(t, m.SetBootOrder([]string{"Boot0001", "Boot0000"}))
+	assert.Contains(t, pm.args, "virtio-net-pci,netdev=net0,id=nic0,mac=52:54:00:12:34:56,addr=0x3,bootindex=0")
+	assert.Contains(t, pm.args, "virtio-blk-pci.bootindex=1")
+	assert.Contains(t, pm.args, "scsi-hd,drive=data0,id=data0")
+
+	order, _ = m.GetBootOrder()
+	assert.Equal(t, []string{"Boot0001", "Boot0000", "Boot0002"}, order)
+}
+
+func TestSetBootOrder_Invalid(t *testing.T) {
+	m, _, _ := newBootOrderMachine(false)
+	assert.ErrorIs(t, m.SetBootOrder([]string{"Boot0009"}), ErrBootOptionNotFound)
+	assert.ErrorIs(t, m.SetBootOrder([]string{"Disk0"}), ErrBootOptionNotFound)
+	assert.ErrorIs(t, m.SetBootOrder([]string{"Boot0000", "Boot0000"}), ErrInvalidBootOrder)
+}
+
+func TestValidateBootOrder(t *testing.T) {
+	m, pm, _ := newBootOrderMachine(false)
+	args := append([]string(nil), pm.args...)
+
+	require.NoError(t, m.ValidateBootOrder([]string{"Boot0001", "Boot0000"}))
+	assert.ErrorIs(t, m.ValidateBootOrder([]string{"Boot0009"}), ErrBootOptionNotFound)
+	assert.ErrorIs(t, m.ValidateBootOrder([]string{"Boot0000", "Boot0000"}), ErrInvalidBootOrder)
+	assert.Equal(t, args, pm.args, "validation does not change the command line")
+}
+
+func TestBootOrder_LegacyMode(t *testing.T) {
+	m := New(newMockQMPClient(qmp.StatusRunning))
+	_, err := m.GetBootOptions()
+	assert.ErrorIs(t, err, ErrNotSupported)
+	assert.ErrorIs(t, m.SetBootOrder(nil), ErrNotSupported)
+}
+
+func TestSetBootOverride_TargetModes(t *testing.T) {
+	m, _, _ := newBootOrderMachine(false)
+	assert.Error(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Floppy", Mode: "UEFI"}))
+	assert.NoError(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Floppy", Mode: "Legacy"}))
+	assert.Error(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "UefiShell", Mode: "Legacy"}))
+	// The machine has no USB storage device to boot first
+	assert.ErrorIs(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Usb", Mode: "Legacy"}), qemu.ErrBootTargetNotSupported)
+	assert.Error(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Pxe", Mode: "UEFI", HttpBootURI: "ftp://example.com/boot.efi"}))
+}
+
+func TestBootOverride_UefiTarget(t *testing.T) {
+	m, pm, _ := newBootOrderMachine(false)
+
+	err := m.SetBootOverride(BootOverride{Enabled: "Once", Target: "UefiTarget", Mode: "UEFI", UefiTarget: "PciRoot(0x0)/Pci(0x9,0x0)"})
+	assert.ErrorIs(t, err, ErrBootOptionNotFound)
+
+	require.NoError(t, m.SetBootOverride(BootOverride{
+		Enabled: "Once", Target: "UefiTarget", Mode: "UEFI",
+		UefiTarget: "PciRoot(0x0)/Pci(0x3,0x0)/MAC(525400123456,0x1)/IPv4(0.0.0.0)",
+	}))
+	require.NoError(t, m.Reset("On"))
+	require.Len(t, pm.boots, 1)
+	assert.Equal(t, "UefiTarget", pm.boots[0].Target)
+	assert.Equal(t, 1, pm.boots[0].Device)
+}
+
+func TestBootOverride_BootDisk(t *testing.T) {
+	m, pm, _ := newBootOrderMachine(false)
+	override := BootOverride{Enabled: "Once", Target: "UefiHttp", Mode: "UEFI", HttpBootURI: "http://192.0.2.1/boot.ipxe"}
+	assert.Error(t, m.SetBootOverride(override), "iPXE is not configured")
+
+	dir := t.TempDir()
+	images := BootImages{
+		UEFIShell: filepath.Join(dir, "shell.efi"),
+		IPXE:      filepath.Join(dir, "ipxe.efi"),
+		Dir:       filepath.Join(dir, "bootdisk"),
+	}
+	require.NoError(t, os.WriteFile(images.UEFIShell, []byte("shell"), 0o644))
+	require.NoError(t, os.WriteFile(images.IPXE, []byte("ipxe"), 0o644))
+	m.SetBootImages(images)
+
+	require.NoError(t, m.SetBootOverride(override))
+	require.NoError(t, m.Reset("On"))
+	require.Len(t, pm.boots, 1)
+	assert.Equal(t, images.Dir, pm.boots[0].BootDisk)
+	data, _ := os.ReadFile(filepath.Join(images.Dir, "EFI", "BOOT", "BOOTX64.EFI"))
+	assert.Equal(t, "ipxe", string(data))
+	data, _ = os.ReadFile(filepath.Join(images.Dir, "autoexec.ipxe"))
+	assert.Equal(t, "#!ipxe\ndhcp\nchain http://192.0.2.1/boot.ipxe\n", string(data))
+	pm.Stop(0)
+
+	require.NoError(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "UefiShell", Mode: "UEFI"}))
+	require.NoError(t, m.Reset("On"))
+	data, _ = os.ReadFile(filepath.Join(images.Dir, "EFI", "BOOT", "BOOTX64.EFI"))
+	assert.Equal(t, "shell", string(data))
+	assert.NoFileExists(t, filepath.Join(images.Dir, "autoexec.ipxe"))
+}
@@ -0,0 +1,96 @@
+package machine
+
+import (
+	"log"
+	"time"
+)
+
+// EventType identifies a machine state change
+type EventType string
+
+const (
+	EventPowerStateChanged    EventType = "PowerStateChanged"
+	EventBootOverrideConsumed EventType = "BootOverrideConsumed"
+	EventMediaInserted        EventType = "MediaInserted"
+	EventMediaEjected         EventType = "MediaEjected"
+)
+
+// eventBufferSize is the per-subscriber event buffer. Events are dropped for
+// subscribers that fall this far behind.
+const eventBufferSize = 64
+
+// Event describes a change of machine state, whichever interface caused it
+type Event struct {
+	Type         EventType
+	Time         time.Time
+	PowerState   PowerState   // EventPowerStateChanged: the new state
+	BootOverride BootOverride // EventBootOverrideConsumed: the override that was used
+	Media        string       // EventMediaInserted, EventMediaEjected: the virtual media slot
+	Image        string       // EventMediaInserted: the inserted image
+}
+
+// Subscribe registers for machine events. The returned function cancels the
+// subscription and closes the channel.
+func (m *Machine) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, eventBufferSize)
+	m.eventMu.Lock()
+	if m.subscribers == nil {
+		m.subscribers = make(map[chan Event]struct{})
+	}
+	m.subscribers[ch] = struct{}{}
+	m.eventMu.Unlock()
+
+	cancel := func() {
+		m.eventMu.Lock()
+		defer m.eventMu.Unlock()
+		if _, ok := m.subscribers[ch]; ok {
+			delete(m.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// emit delivers e to all subscribers without blocking.
+func (m *Machine) emit(e Event) {
+	e.Time = time.Now()
+	m.eventMu.Lock()
+	defer m.eventMu.Unlock()
+	for ch := range m.subscribers {
+		select {
+		case ch <- e:
+		default:
+			log.Printf("machine: event subscriber is full, dropping %s event", e.Type)
+		}
+	}
+}
+
+// observePowerState records the latest observed power state and emits
+// EventPowerStateChanged when it differs from the previous observation.
+func (m *Machine) observePowerState(ps PowerState) {
+	m.eventMu.Lock()
+	prev := m.lastPowerState
+	m.lastPowerState = ps
+	m.eventMu.Unlock()
+
+	if prev != "" && prev != ps {
+		m.emit(Event{Type: EventPowerStateChanged, PowerState: ps})
+	}
+}
+
+// WatchPowerState polls the power state every interval until stop is closed,
+// so that changes made by the guest (e.g. an OS shutdown) produce events too.
+func (m *Machine) WatchPowerState(stop <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.GetPowerState(); err != nil {
+			log.Printf("machine: power state poll failed: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package machine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func nextEvent(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestEvents_PowerStateChanged(t *testing.T) {
+	client := newMockQMPClient(qmp.StatusRunning)
+	m := New(client)
+	events, cancel := m.Subscribe()
+	defer cancel()
+
+	// The first observation establishes the baseline
+	_, err := m.GetPowerState()
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// A change made behind the BMC's back (e.g. by the guest) is detected
+	client.status = qmp.StatusShutdown
+	_, err = m.GetPowerState()
+	require.NoError(t, err)
+
+	e := nextEvent(t, events)
+	assert.Equal(t, EventPowerStateChanged, e.Type)
+	assert.Equal(t, PowerOff, e.PowerState)
+	assert.False(t, e.Time.IsZero())
+
+	// Unchanged state produces no event
+	m.GetPowerState()
+	assert.Empty(t, events)
+}
+
+func TestEvents_BootOverrideConsumed(t *testing.T) {
+	m := New(newMockQMPClient(qmp.StatusRunning))
+	events, cancel := m.Subscribe()
+	defer cancel()
+
+	require.NoError(t, m.SetBootOverride(BootOverride{Enabled: "Once", Target: "Pxe", Mode: "UEFI"}))
+	m.ConsumeBootOnce()
+
+	e := nextEvent(t, events)
+	assert.Equal(t, EventBootOverrideConsumed, e.Type)
+	assert.Equal(t, "Pxe", e.BootOverride.Target)
+
+	// Nothing left to consume
+	m.ConsumeBootOnce()
+	assert.Empty(t, events)
+}
+
+func TestEvents_Media(t *testing.T) {
+	m := New(newMediaMock())
+	events, cancel := m.Subscribe()
+
+	require.NoError(t, m.InsertMedia("CD1", "/images/boot.iso", InsertOptions{WriteProtected: true}))
+	e := nextEvent(t, events)
+	assert.Equal(t, EventMediaInserted, e.Type)
+	assert.Equal(t, "CD1", e.Media)
+	assert.Equal(t, "/images/boot.iso", e.Image)
+
+	require.NoError(t, m.EjectMedia("CD1"))
+	e = nextEvent(t, events)
+	assert.Equal(t, EventMediaEjected, e.Type)
+	assert.Equal(t, "CD1", e.Media)
+
+	cancel()
+	_, open := <-events
+	assert.False(t, open, "cancel closes the channel")
+}
+
+func TestWatchPowerState(t *testing.T) {
+	client := newMockQMPClient(qmp.StatusRunning)
+	pm := newMockProcessManager(true)
+	m := NewWithProcess(client, pm)
+	events, cancel := m.Subscribe()
+	defer cancel()
+
+	m.GetPowerState()
+	pm.running = false
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go m.WatchPowerState(stop, 10*time.Millisecond)
+
+	e := nextEvent(t, events)
+	assert.Equal(t, PowerOff, e.PowerState)
+}
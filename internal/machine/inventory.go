@@ -0,0 +1,161 @@
+package machine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInventoryUnavailable is returned when the VM hardware can neither be
+// queried from QEMU nor derived from its command line.
+var ErrInventoryUnavailable = errors.New("VM inventory not available")
+
+// CPU is one logical processor of the VM
+type CPU struct {
+	Index  int
+	Socket int
+	Die    int
+	Core   int
+	Thread int
+}
+
+// Inventory describes the hardware of the VM
+type Inventory struct {
+	MachineType string // e.g. "pc-q35-8.2"
+	Arch        string // QEMU target, e.g. "x86_64"
+	CPUModel    string // e.g. "qemu64"
+	CPUs        []CPU
+	MemoryBytes uint64
+}
+
+// TopologyCPUs lists count logical processors laid out over sockets of
+// cores x threads, in QEMU's CPU index order.
+func TopologyCPUs(count, cores, threads int) []CPU {
+	if cores < 1 {
+		cores = 1
+	}
+	if threads < 1 {
+		threads = 1
+	}
+	cpus := make([]CPU, count)
+	for i := range cpus {
+		cpus[i] = CPU{
+			Index:  i,
+			Socket: i / (cores * threads),
+			Core:   (i / threads) % cores,
+			Thread: i % threads,
+		}
+	}
+	return cpus
+}
+
+// SetConfiguredInventory sets the inventory derived from the QEMU command
+// line. It is reported while QEMU is not running and fills in anything QMP
+// does not tell.
+func (m *Machine) SetConfiguredInventory(inv Inventory) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.configured = inv
+}
+
+// GetInventory returns the VM hardware, queried over QMP when QEMU is
+// running.
+func (m *Machine) GetInventory() (Inventory, error) {
+	m.mu.RLock()
+	configured := m.configured
+	m.mu.RUnlock()
+
+	if m.processManager == nil || m.processManager.IsRunning() {
+		inv, err := m.queryInventory()
+		if err == nil {
+			if inv.MachineType == "" {
+				inv.MachineType = configured.MachineType
+			}
+			if inv.Arch == "" {
+				inv.Arch = configured.Arch
+			}
+			if inv.CPUModel == "" {
+				inv.CPUModel = configured.CPUModel
+			}
+			return inv, nil
+		}
+		if len(configured.CPUs) == 0 {
+			return Inventory{}, err
+		}
+	}
+
+	if len(configured.CPUs) == 0 {
+		return Inventory{}, ErrInventoryUnavailable
+	}
+	return configured, nil
+}
+
+// queryInventory collects the inventory from the running VM. CPUs and memory
+// are required; the machine and CPU model names are best-effort.
+func (m *Machine) queryInventory() (Inventory, error) {
+	cpus, err := m.qmpClient.QueryCPUsFast()
+	if err != nil {
+		return Inventory{}, fmt.Errorf("querying CPUs: %w", err)
+	}
+	mem, err := m.qmpClient.QueryMemorySizeSummary()
+	if err != nil {
+		return Inventory{}, fmt.Errorf("querying memory size: %w", err)
+	}
+
+	inv := Inventory{MemoryBytes: mem.BaseMemory + mem.PluggedMemory}
+	for _, c := range cpus {
+		inv.CPUs = append(inv.CPUs, CPU{
+			Index:  c.CPUIndex,
+			Socket: c.Props.SocketID,
+			Die:    c.Props.DieID,
+			Core:   c.Props.CoreID,
+			Thread: c.Props.ThreadID,
+		})
+		inv.Arch = c.Target
+	}
+
+	inv.MachineType = strings.TrimSuffix(m.qomString("/machine", "type"), "-machine")
+
+	// The CPU model is the QOM type of a CPU, or else the machine's
+	// default, translated to the name used with -cpu.
+	cpuType := ""
+	if len(cpus) > 0 {
+		cpuType = m.qomString(cpus[0].QOMPath, "type")
+	}
+	if cpuType == "" && inv.MachineType != "" {
+		if machines, err := m.qmpClient.QueryMachines(); err == nil {
+			for _, mi := range machines {
+				if mi.Name == inv.MachineType {
+					cpuType = mi.DefaultCPUType
+					break
+				}
+			}
+		}
+	}
+	if cpuType != "" {
+		inv.CPUModel = cpuType
+		if defs, err := m.qmpClient.QueryCPUDefinitions(); err == nil {
+			for _, d := range defs {
+				if d.TypeName == cpuType {
+					inv.CPUModel = d.Name
+					break
+				}
+			}
+		}
+	}
+	return inv, nil
+}
+
+// qomString reads a string QOM property, returning "" on any error.
+func (m *Machine) qomString(path, property string) string {
+	raw, err := m.qmpClient.QOMGet(path, property)
+	if err != nil {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func newInventoryQMPClient() *mockQMPClient {
+	client := newMockQMPClient(qmp.StatusRunning)
+	for i := 0; i < 4; i++ {
+		var c qmp.CPUInfoFast
+		c.CPUIndex = i
+		c.QOMPath = fmt.Sprintf("/machine/unattached/device[%d]", i)
+		c.Target = "x86_64"
+		c.Props.SocketID = i / 2
+		c.Props.CoreID = i % 2
+		client.cpus = append(client.cpus, c)
+	}
+	client.memory = qmp.MemorySizeSummary{BaseMemory: 4 << 30, PluggedMemory: 1 << 30}
+	client.machines = []qmp.MachineInfo{{Name: "pc-q35-8.2", Alias: "q35", DefaultCPUType: "qemu64-x86_64-cpu"}}
+	client.cpuDefs = []qmp.CPUDefinition{
+		{Name: "qemu64", TypeName: "qemu64-x86_64-cpu"},
+		{Name: "Skylake-Client", TypeName: "Skylake-Client-x86_64-cpu"},
+	}
+	client.qom = map[string]string{
+		"/machine.type":                      `"pc-q35-8.2-machine"`,
+		"/machine/unattached/device[0].type": `"Skylake-Client-x86_64-cpu"`,
+	}
+	return client
+}
+
+func TestGetInventory_FromQMP(t *testing.T) {
+	m := New(newInventoryQMPClient())
+
+	inv, err := m.GetInventory()
+	require.NoError(t, err)
+	assert.Equal(t, "pc-q35-8.2", inv.MachineType)
+	assert.Equal(t, "x86_64", inv.Arch)
+	assert.Equal(t, "Skylake-Client", inv.CPUModel)
+	require.Len(t, inv.CPUs, 4)
+	assert.Equal(t, CPU{Index: 3, Socket: 1, Core: 1}, inv.CPUs[3])
+	assert.Equal(t, uint64(5<<30), inv.MemoryBytes)
+}
+
+func TestGetInventory_MachineDefaultCPUModel(t *testing.T) {
+	client := newInventoryQMPClient()
+	delete(client.qom, "/machine/unattached/device[0].type")
+	m := New(client)
+
+	inv, err := m.GetInventory()
+	require.NoError(t, err)
+	assert.Equal(t, "qemu64", inv.CPUModel)
+}
+
+func TestGetInventory_ProcessOffUsesConfigured(t *testing.T) {
+	client := newInventoryQMPClient()
+	m := NewWithProcess(client, newMockProcessManager(false))
+	m.SetConfiguredInventory(Inventory{
+		MachineType: "q35",
+		Arch:        "x86_64",
+		CPUs:        TopologyCPUs(4, 2, 1),
+		MemoryBytes: 2 << 30,
+	})
+
+	inv, err := m.GetInventory()
+	require.NoError(t, err)
+	assert.Equal(t, "q35", inv.MachineType)
+	require.Len(t, inv.CPUs, 4)
+	assert.Equal(t, 1, inv.CPUs[2].Socket)
+	assert.Equal(t, uint64(2<<30), inv.MemoryBytes)
+	assert.NotContains(t, client.Calls(), "QueryCPUsFast")
+}
+
+func TestGetInventory_QMPErrorFallsBack(t *testing.T) {
+	client := newInventoryQMPClient()
+	client.queryErr = errors.New("not connected")
+	m := NewWithProcess(client, newMockProcessManager(true))
+
+	_, err := m.GetInventory()
+	assert.Error(t, err)
+
+	m.SetConfiguredInventory(Inventory{CPUs: TopologyCPUs(2, 1, 1), MemoryBytes: 1 << 30})
+	inv, err := m.GetInventory()
+	require.NoError(t, err)
+	assert.Len(t, inv.CPUs, 2)
+}
+
+func TestGetInventory_Unavailable(t *testing.T) {
+	m := NewWithProcess(newMockQMPClient(qmp.StatusShutdown), newMockProcessManager(false))
+
+	_, err := m.GetInventory()
+	assert.ErrorIs(t, err, ErrInventoryUnavailable)
+}
+
+func TestTopologyCPUs(t *testing.T) {
+	cpus := TopologyCPUs(8, 2, 2)
+	require.Len(t, cpus, 8)
+	assert.Equal(t, CPU{Index: 5, Socket: 1, Core: 0, Thread: 1}, cpus[5])
+	assert.Equal(t, CPU{Index: 7, Socket: 1, Core: 1, Thread: 1}, cpus[7])
+}
@@ -3,9 +3,12 @@ package machine
 import (
 	"fmt"
 	"log"
+	"slices"
 	"sync"
 	"time"
 
+	"github.com/tjst-t/qemu-bmc/internal/imagecache"
+	"github.com/tjst-t/qemu-bmc/internal/qemu"
 	"github.com/tjst-t/qemu-bmc/internal/qmp"
 )
 
@@ -13,24 +16,33 @@ import (
 type PowerState string
 
 const (
-	PowerOn  PowerState = "On"
-	PowerOff PowerState = "Off"
+	PowerOn          PowerState = "On"
+	PowerOff         PowerState = "Off"
+	PowerPoweringOn  PowerState = "PoweringOn"  // QEMU is starting
+	PowerPoweringOff PowerState = "PoweringOff" // waiting for QEMU to exit
+	PowerPaused      PowerState = "Paused"      // guest CPUs stopped
+	PowerSuspended   PowerState = "Suspended"   // guest in ACPI S3
 )
 
 // BootOverride represents boot source override settings
 type BootOverride struct {
 	Enabled string // "Disabled", "Once", "Continuous"
-	Target  string // "None", "Pxe", "Hdd", "Cd", "BiosSetup"
+	Target  string // "None", "Pxe", "Hdd", "Cd", "Usb", "Floppy", "BiosSetup", "UefiShell", "UefiHttp", "UefiTarget"
 	Mode    string // "UEFI", "Legacy"
+
+	HttpBootURI string // UefiHttp: URI to boot, "" for the one DHCP offers
+	UefiTarget  string // UefiTarget: UEFI device path of the boot option
 }
 
 // ProcessManager controls the QEMU process lifecycle.
 type ProcessManager interface {
-	Start(bootTarget string) error
+	Start(boot qemu.Boot) error
 	Stop(timeout time.Duration) error
 	Kill() error
 	IsRunning() bool
 	WaitForExit(timeout time.Duration) error
+	Args() []string
+	SetArgs(args []string)
 }
 
 // Machine manages the state of a QEMU VM
@@ -38,7 +50,31 @@ type Machine struct {
 	qmpClient      qmp.Client
 	processManager ProcessManager // nil = legacy mode
 	bootOverride   BootOverride
+	configured     Inventory // from the QEMU command line
+	volumeDir      string
+	volumeBus      string
+	guestAgent     qmp.GuestAgent
+	ovmf           qemu.OVMF
+	biosDefaults   BiosSettings  // from the QEMU command line at startup
+	biosPending    *BiosSettings // applied at the next power on
+	biosReset      bool          // reinitialize the UEFI variable store
+	varsTemplate   string        // template the UEFI variable store was created from, "" if unknown
+	bootImages     BootImages
+	mediaSlots     []MediaSlot
+	mediaStore     string                // file the inserted images are persisted in
+	media          map[string]mediaEntry // images inserted by InsertMedia
+	imageCache     *imagecache.Cache     // local copies of HTTP(S) images, nil to pass URLs to QEMU
+	verifier       *imagecache.Verifier  // trusted keys for image signatures
+	transition     PowerState            // PoweringOn or PoweringOff while a reset waits
+	paused         bool                  // legacy mode: stopped by Pause, not ForceOff
+	nicLinks       map[string]bool       // NIC links set by SetNICLink since QEMU started
+	inletCelsius   float64               // simulated inlet air temperature
+	load           *loadSampler          // vCPU load between GetEnvironment calls
 	mu             sync.RWMutex
+
+	eventMu        sync.Mutex
+	subscribers    map[chan Event]struct{}
+	lastPowerState PowerState // last observed, for change events
 }
 
 // New creates a new Machine with the given QMP client (legacy mode)
@@ -50,48 +86,90 @@ func New(client qmp.Client) *Machine {
 			Target:  "None",
 			Mode:    "UEFI",
 		},
+		mediaSlots:   defaultMediaSlots,
+		inletCelsius: DefaultInletCelsius,
+		load:         newLoadSampler(),
 	}
 }
 
-// NewWithProcess creates a Machine in process management mode
+// NewWithProcess creates a Machine in process management mode. The boot
+// override mode starts out as the firmware on the QEMU command line.
 func NewWithProcess(client qmp.Client, pm ProcessManager) *Machine {
+	fw := qemu.ParseFirmware(pm.Args())
 	return &Machine{
 		qmpClient:      client,
 		processManager: pm,
 		bootOverride: BootOverride{
 			Enabled: "Disabled",
 			Target:  "None",
-			Mode:    "UEFI",
+			Mode:    overrideMode(fw),
 		},
+		biosDefaults: biosFromFirmware(fw),
+		mediaSlots:   defaultMediaSlots,
+		inletCelsius: DefaultInletCelsius,
+		load:         newLoadSampler(),
 	}
 }
 
 // GetPowerState returns the current power state of the VM
 func (m *Machine) GetPowerState() (PowerState, error) {
+	var ps PowerState
+	var err error
 	if m.processManager != nil {
-		return m.getPowerStateProcess()
+		ps, err = m.getPowerStateProcess()
+	} else {
+		ps, err = m.getPowerStateLegacy()
+	}
+	if err == nil {
+		m.observePowerState(ps)
 	}
-	return m.getPowerStateLegacy()
+	return ps, err
 }
 
+// getPowerStateLegacy maps the QMP status. Legacy mode powers off by
+// stopping the VM, so a stopped VM is only Paused after a Pause reset.
 func (m *Machine) getPowerStateLegacy() (PowerState, error) {
 	status, err := m.qmpClient.QueryStatus()
 	if err != nil {
 		return "", fmt.Errorf("querying VM status: %w", err)
 	}
 
+	m.mu.RLock()
+	paused := m.paused
+	m.mu.RUnlock()
+
 	switch status {
 	case qmp.StatusRunning:
 		return PowerOn, nil
+	case qmp.StatusSuspended:
+		return PowerSuspended, nil
+	case qmp.StatusPaused:
+		if paused {
+			return PowerPaused, nil
+		}
+		return PowerOff, nil
 	default:
 		return PowerOff, nil
 	}
 }
 
+// getPowerStateProcess derives the power state from the QEMU process and
+// its QMP status. A reset waiting for QEMU to start or exit reports the
+// transition.
 func (m *Machine) getPowerStateProcess() (PowerState, error) {
+	m.mu.RLock()
+	transition := m.transition
+	m.mu.RUnlock()
+
 	if !m.processManager.IsRunning() {
+		if transition == PowerPoweringOn {
+			return PowerPoweringOn, nil
+		}
 		return PowerOff, nil
 	}
+	if transition != "" {
+		return transition, nil
+	}
 
 	status, err := m.qmpClient.QueryStatus()
 	if err != nil {
@@ -100,14 +178,33 @@ func (m *Machine) getPowerStateProcess() (PowerState, error) {
 	}
 
 	switch status {
-	case qmp.StatusRunning, qmp.StatusPaused:
+	case qmp.StatusRunning:
 		return PowerOn, nil
+	case qmp.StatusSuspended:
+		return PowerSuspended, nil
 	case qmp.StatusShutdown:
 		// Guest has shut down — stop the process
 		m.processManager.Stop(30 * time.Second)
 		return PowerOff, nil
+	case qmp.StatusPrelaunch, qmp.StatusInMigrate, qmp.StatusRestoreVM:
+		return PowerPoweringOn, nil
 	default:
-		return PowerOn, nil
+		// paused, or stopped by an error (io-error, guest-panicked, ...)
+		return PowerPaused, nil
+	}
+}
+
+// beginTransition reports ps as the power state until the returned function
+// is called.
+func (m *Machine) beginTransition(ps PowerState) func() {
+	m.mu.Lock()
+	m.transition = ps
+	m.mu.Unlock()
+	m.observePowerState(ps)
+	return func() {
+		m.mu.Lock()
+		m.transition = ""
+		m.mu.Unlock()
 	}
 }
 
@@ -134,6 +231,9 @@ func (m *Machine) getQMPStatusProcess() (qmp.Status, error) {
 
 // Reset performs a reset action on the VM
 func (m *Machine) Reset(resetType string) error {
+	if ok, err := m.resetExtended(resetType); ok {
+		return err
+	}
 	if m.processManager != nil {
 		return m.resetProcessMode(resetType)
 	}
@@ -141,13 +241,17 @@ func (m *Machine) Reset(resetType string) error {
 }
 
 func (m *Machine) resetLegacy(resetType string) error {
+	m.mu.Lock()
+	m.paused = false
+	m.mu.Unlock()
+
 	switch resetType {
 	case "On":
 		state, err := m.GetPowerState()
 		if err != nil {
 			return err
 		}
-		if state == PowerOn {
+		if state == PowerOn || state == PowerSuspended {
 			return nil // already on, no-op
 		}
 		return m.qmpClient.Cont()
@@ -162,6 +266,15 @@ func (m *Machine) resetLegacy(resetType string) error {
 		return m.qmpClient.SystemReset()
 	case "GracefulRestart":
 		return m.qmpClient.SystemReset()
+	case "PowerCycle":
+		// Reset while stopped so the guest restarts from firmware
+		if err := m.qmpClient.Stop(); err != nil {
+			return err
+		}
+		if err := m.qmpClient.SystemReset(); err != nil {
+			return err
+		}
+		return m.qmpClient.Cont()
 	default:
 		return fmt.Errorf("unsupported reset type: %s", resetType)
 	}
@@ -173,22 +286,41 @@ func (m *Machine) resetProcessMode(resetType string) error {
 		if m.processManager.IsRunning() {
 			return nil // already running
 		}
-		m.mu.RLock()
-		target := m.bootOverride.Target
-		m.mu.RUnlock()
+		defer m.beginTransition(PowerPoweringOn)()
+		if err := m.applyPendingBios(); err != nil {
+			return fmt.Errorf("applying BIOS settings: %w", err)
+		}
 
-		if err := m.processManager.Start(target); err != nil {
+		boot, err := m.bootConfig()
+		if err != nil {
+			return fmt.Errorf("preparing firmware: %w", err)
+		}
+		// Hold the CPUs until the virtual media is back in the drive,
+		// unless the command line already starts QEMU stopped.
+		boot.Paused = m.mediaInsertPending() && !slices.Contains(m.processManager.Args(), "-S")
+		m.mu.Lock()
+		m.nicLinks = nil // a new QEMU starts with every link up
+		m.mu.Unlock()
+		if err := m.processManager.Start(boot); err != nil {
 			return fmt.Errorf("starting QEMU: %w", err)
 		}
 
 		if err := m.waitForQMP(30 * time.Second); err != nil {
 			return fmt.Errorf("waiting for QMP: %w", err)
 		}
+		m.reattachVolumes()
+		m.reinsertMedia()
+		if boot.Paused {
+			if err := m.qmpClient.Cont(); err != nil {
+				return fmt.Errorf("continuing QEMU: %w", err)
+			}
+		}
 
 		m.ConsumeBootOnce()
 		return nil
 
 	case "ForceOff":
+		defer m.beginTransition(PowerPoweringOff)()
 		if err := m.qmpClient.Quit(); err != nil {
 			// QMP may not be connected; force-kill the process
 			log.Printf("QMP quit failed (%v), killing process", err)
@@ -203,6 +335,7 @@ func (m *Machine) resetProcessMode(resetType string) error {
 		if err := m.qmpClient.SystemPowerdown(); err != nil {
 			return err
 		}
+		defer m.beginTransition(PowerPoweringOff)()
 		if err := m.processManager.WaitForExit(120 * time.Second); err != nil {
 			log.Printf("Graceful shutdown timed out: %v", err)
 		}
@@ -212,11 +345,21 @@ func (m *Machine) resetProcessMode(resetType string) error {
 		if err := m.qmpClient.SystemPowerdown(); err != nil {
 			return err
 		}
+		end := m.beginTransition(PowerPoweringOff)
 		if err := m.processManager.WaitForExit(120 * time.Second); err != nil {
 			log.Printf("Graceful shutdown timed out, killing: %v", err)
 			m.processManager.Kill()
 			m.processManager.WaitForExit(5 * time.Second)
 		}
+		end()
+		return m.resetProcessMode("On")
+
+	case "PowerCycle":
+		if m.processManager.IsRunning() {
+			if err := m.resetProcessMode("ForceOff"); err != nil {
+				return err
+			}
+		}
 		return m.resetProcessMode("On")
 
 	default:
@@ -247,9 +390,21 @@ func (m *Machine) GetBootOverride() BootOverride {
 
 // SetBootOverride sets the boot override settings
 func (m *Machine) SetBootOverride(override BootOverride) error {
+	if err := m.ValidateBootOverride(override); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bootOverride = override
+	return nil
+}
+
+// ValidateBootOverride checks boot override settings without applying them.
+func (m *Machine) ValidateBootOverride(override BootOverride) error {
 	// Validate target
 	validTargets := map[string]bool{
-		"None": true, "Pxe": true, "Hdd": true, "Cd": true, "BiosSetup": true,
+		"None": true, "Pxe": true, "Hdd": true, "Cd": true, "Usb": true, "Floppy": true,
+		"BiosSetup": true, "UefiShell": true, "UefiHttp": true, "UefiTarget": true,
 	}
 	if !validTargets[override.Target] {
 		return fmt.Errorf("invalid boot target: %s", override.Target)
@@ -263,28 +418,29 @@ func (m *Machine) SetBootOverride(override BootOverride) error {
 		return fmt.Errorf("invalid boot enabled: %s", override.Enabled)
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	m.bootOverride = override
-	return nil
+	// Validate mode
+	if override.Mode != "UEFI" && override.Mode != "Legacy" {
+		return fmt.Errorf("invalid boot mode: %s", override.Mode)
+	}
+	return m.validateBootTarget(override)
 }
 
-// ConsumeBootOnce consumes a "Once" boot override (resets to Disabled after use)
+// ConsumeBootOnce consumes a "Once" boot override (resets to Disabled after use).
+// In process mode the mode returns to the persistent firmware.
 func (m *Machine) ConsumeBootOnce() {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	if m.bootOverride.Enabled == "Once" {
+	used := m.bootOverride
+	consumed := used.Enabled == "Once"
+	if consumed {
 		m.bootOverride.Enabled = "Disabled"
 		m.bootOverride.Target = "None"
+		if m.processManager != nil {
+			m.bootOverride.Mode = overrideMode(qemu.ParseFirmware(m.processManager.Args()))
+		}
 	}
-}
+	m.mu.Unlock()
 
-// InsertMedia inserts virtual media into the VM
-func (m *Machine) InsertMedia(image string) error {
-	return m.qmpClient.BlockdevChangeMedium("ide0-cd0", image)
-}
-
-// EjectMedia ejects virtual media from the VM
-func (m *Machine) EjectMedia() error {
-	return m.qmpClient.BlockdevRemoveMedium("ide0-cd0")
+	if consumed {
+		m.emit(Event{Type: EventBootOverrideConsumed, BootOverride: used})
+	}
 }
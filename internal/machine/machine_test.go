@@ -1,12 +1,16 @@
 package machine
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/qemu"
 	"github.com/tjst-t/qemu-bmc/internal/qmp"
 )
 
@@ -16,6 +20,22 @@ type mockQMPClient struct {
 	calls      []string
 	connectErr error
 	queryErr   error
+	cpus       []qmp.CPUInfoFast
+	memory     qmp.MemorySizeSummary
+	machines   []qmp.MachineInfo
+	cpuDefs    []qmp.CPUDefinition
+	qom        map[string]string // "path.property" -> JSON value
+	blocks     []qmp.BlockInfo
+	blockStats []qmp.BlockStats
+	jobs       []qmp.JobInfo
+	jobErr     string // error reported by blockdev-create jobs
+	deviceErr  error
+	mediumErr  error         // blockdev-change-medium/blockdev-remove-medium
+	added      []interface{} // blockdev-add options
+	pci        []qmp.PCIBus
+	stats      []qmp.StatsResult // query-stats reply
+	statsErr   error
+	onQuit     func() // e.g. stops the mock QEMU process
 }
 
 func newMockQMPClient(status qmp.Status) *mockQMPClient {
@@ -63,19 +83,203 @@ func (m *mockQMPClient) Cont() error {
 func (m *mockQMPClient) Quit() error {
 	m.calls = append(m.calls, "Quit")
 	m.status = qmp.StatusShutdown
+	if m.onQuit != nil {
+		m.onQuit()
+	}
+	return nil
+}
+
+func (m *mockQMPClient) InjectNMI() error {
+	m.calls = append(m.calls, "InjectNMI")
 	return nil
 }
 
-func (m *mockQMPClient) BlockdevChangeMedium(device, filename string) error {
+func (m *mockQMPClient) SystemWakeup() error {
+	m.calls = append(m.calls, "SystemWakeup")
+	m.status = qmp.StatusRunning
+	return nil
+}
+
+func (m *mockQMPClient) BlockdevChangeMedium(id, filename string, readOnly bool) error {
 	m.calls = append(m.calls, "BlockdevChangeMedium")
+	if m.mediumErr != nil {
+		return m.mediumErr
+	}
+	closed := false
+	for i := range m.blocks {
+		if m.blocks[i].QDev == id {
+			m.blocks[i].Inserted = &qmp.BlockInserted{File: filename, RO: readOnly}
+			m.blocks[i].TrayOpen = &closed
+		}
+	}
 	return nil
 }
 
-func (m *mockQMPClient) BlockdevRemoveMedium(device string) error {
+func (m *mockQMPClient) BlockdevRemoveMedium(id string) error {
 	m.calls = append(m.calls, "BlockdevRemoveMedium")
+	if m.mediumErr != nil {
+		return m.mediumErr
+	}
+	open := true
+	for i := range m.blocks {
+		if m.blocks[i].QDev == id {
+			m.blocks[i].Inserted = nil
+			m.blocks[i].TrayOpen = &open
+		}
+	}
+	return nil
+}
+
+func (m *mockQMPClient) QueryCPUsFast() ([]qmp.CPUInfoFast, error) {
+	m.calls = append(m.calls, "QueryCPUsFast")
+	if m.queryErr != nil {
+		return nil, m.queryErr
+	}
+	return m.cpus, nil
+}
+
+func (m *mockQMPClient) QueryMemorySizeSummary() (qmp.MemorySizeSummary, error) {
+	m.calls = append(m.calls, "QueryMemorySizeSummary")
+	if m.queryErr != nil {
+		return qmp.MemorySizeSummary{}, m.queryErr
+	}
+	return m.memory, nil
+}
+
+func (m *mockQMPClient) QueryMachines() ([]qmp.MachineInfo, error) {
+	m.calls = append(m.calls, "QueryMachines")
+	return m.machines, nil
+}
+
+func (m *mockQMPClient) QueryCPUDefinitions() ([]qmp.CPUDefinition, error) {
+	m.calls = append(m.calls, "QueryCPUDefinitions")
+	return m.cpuDefs, nil
+}
+
+func (m *mockQMPClient) QOMGet(path, property string) (json.RawMessage, error) {
+	m.calls = append(m.calls, "QOMGet")
+	v, ok := m.qom[path+"."+property]
+	if !ok {
+		return nil, errors.New("QMP error: GenericError: property not found")
+	}
+	return json.RawMessage(v), nil
+}
+
+func (m *mockQMPClient) QueryBlock() ([]qmp.BlockInfo, error) {
+	m.calls = append(m.calls, "QueryBlock")
+	if m.queryErr != nil {
+		return nil, m.queryErr
+	}
+	return m.blocks, nil
+}
+
+func (m *mockQMPClient) QueryBlockstats() ([]qmp.BlockStats, error) {
+	m.calls = append(m.calls, "QueryBlockstats")
+	return m.blockStats, nil
+}
+
+func (m *mockQMPClient) BlockdevCreate(jobID string, options interface{}) error {
+	m.calls = append(m.calls, "BlockdevCreate")
+	m.jobs = append(m.jobs, qmp.JobInfo{ID: jobID, Type: "create", Status: "concluded", Error: m.jobErr})
+	return nil
+}
+
+func (m *mockQMPClient) BlockdevAdd(options interface{}) error {
+	m.calls = append(m.calls, "BlockdevAdd")
+	m.added = append(m.added, options)
+	return nil
+}
+
+func (m *mockQMPClient) BlockdevDel(nodeName string) error {
+	m.calls = append(m.calls, "BlockdevDel:"+nodeName)
 	return nil
 }
 
+func (m *mockQMPClient) QueryJobs() ([]qmp.JobInfo, error) {
+	m.calls = append(m.calls, "QueryJobs")
+	return m.jobs, nil
+}
+
+func (m *mockQMPClient) JobDismiss(id string) error {
+	m.calls = append(m.calls, "JobDismiss")
+	for i, j := range m.jobs {
+		if j.ID == id {
+			m.jobs = append(m.jobs[:i], m.jobs[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *mockQMPClient) DeviceAdd(driver, id string, props map[string]interface{}) error {
+	m.calls = append(m.calls, "DeviceAdd:"+id)
+	if m.deviceErr != nil {
+		return m.deviceErr
+	}
+	if m.qom == nil {
+		m.qom = map[string]string{}
+	}
+	m.qom["/machine/peripheral/"+id+".type"] = `"` + driver + `"`
+	switch driver {
+	case "virtio-blk-pci":
+		m.blocks = append(m.blocks, qmp.BlockInfo{
+			QDev:     "/machine/peripheral/" + id + "/virtio-backend",
+			Inserted: &qmp.BlockInserted{NodeName: id, Drv: "qcow2"},
+		})
+	case "scsi-cd":
+		closed := false
+		m.blocks = append(m.blocks, qmp.BlockInfo{QDev: id, Removable: true, TrayOpen: &closed})
+	case "usb-storage":
+		inserted := &qmp.BlockInserted{NodeName: id}
+		for _, opts := range m.added {
+			o := opts.(map[string]interface{})
+			if o["node-name"] == id {
+				inserted.RO = o["read-only"].(bool)
+				file := o["file"].(map[string]interface{})
+				inserted.File, _ = file["filename"].(string)
+				if url, ok := file["url"].(string); ok {
+					inserted.File = url
+				}
+			}
+		}
+		m.blocks = append(m.blocks, qmp.BlockInfo{QDev: "/machine/peripheral/" + id + "/scsi-disk", Inserted: inserted})
+	}
+	return nil
+}
+
+func (m *mockQMPClient) DeviceDel(id string) error {
+	m.calls = append(m.calls, "DeviceDel:"+id)
+	delete(m.qom, "/machine/peripheral/"+id+".type")
+	for i, b := range m.blocks {
+		if strings.HasPrefix(b.QDev, "/machine/peripheral/"+id+"/") {
+			m.blocks = append(m.blocks[:i], m.blocks[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *mockQMPClient) QueryPCI() ([]qmp.PCIBus, error) {
+	m.calls = append(m.calls, "QueryPCI")
+	if m.queryErr != nil {
+		return nil, m.queryErr
+	}
+	return m.pci, nil
+}
+
+func (m *mockQMPClient) SetLink(name string, up bool) error {
+	m.calls = append(m.calls, fmt.Sprintf("SetLink:%s:%t", name, up))
+	return m.queryErr
+}
+
+func (m *mockQMPClient) QueryStats(target, provider string, names []string) ([]qmp.StatsResult, error) {
+	m.calls = append(m.calls, "QueryStats")
+	if m.statsErr != nil {
+		return nil, m.statsErr
+	}
+	return m.stats, nil
+}
+
 func (m *mockQMPClient) Close() error {
 	return nil
 }
@@ -88,8 +292,10 @@ func (m *mockQMPClient) Calls() []string {
 type mockProcessManager struct {
 	running    bool
 	startCalls []string // boot targets passed to Start
+	boots      []qemu.Boot
 	calls      []string
 	exitCh     chan struct{}
+	args       []string
 }
 
 func newMockProcessManager(running bool) *mockProcessManager {
@@ -103,9 +309,10 @@ func newMockProcessManager(running bool) *mockProcessManager {
 	}
 }
 
-func (m *mockProcessManager) Start(bootTarget string) error {
+func (m *mockProcessManager) Start(boot qemu.Boot) error {
 	m.calls = append(m.calls, "Start")
-	m.startCalls = append(m.startCalls, bootTarget)
+	m.startCalls = append(m.startCalls, boot.Target)
+	m.boots = append(m.boots, boot)
 	m.running = true
 	m.exitCh = make(chan struct{})
 	return nil
@@ -133,6 +340,14 @@ func (m *mockProcessManager) Kill() error {
 	return nil
 }
 
+func (m *mockProcessManager) Args() []string {
+	return append([]string(nil), m.args...)
+}
+
+func (m *mockProcessManager) SetArgs(args []string) {
+	m.args = append([]string(nil), args...)
+}
+
 func (m *mockProcessManager) IsRunning() bool {
 	return m.running
 }
@@ -243,24 +458,6 @@ func TestBootOverride_InvalidTarget(t *testing.T) {
 	assert.Error(t, err)
 }
 
-func TestInsertMedia(t *testing.T) {
-	mock := newMockQMPClient(qmp.StatusRunning)
-	m := New(mock)
-
-	err := m.InsertMedia("http://example.com/boot.iso")
-	require.NoError(t, err)
-	assert.Contains(t, mock.Calls(), "BlockdevChangeMedium")
-}
-
-func TestEjectMedia(t *testing.T) {
-	mock := newMockQMPClient(qmp.StatusRunning)
-	m := New(mock)
-
-	err := m.EjectMedia()
-	require.NoError(t, err)
-	assert.Contains(t, mock.Calls(), "BlockdevRemoveMedium")
-}
-
 // --- Process mode tests ---
 
 func TestProcessMode_GetPowerState_ProcessNotRunning(t *testing.T) {
@@ -0,0 +1,643 @@
+package machine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tjst-t/qemu-bmc/internal/imagecache"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+// Virtual media errors
+var (
+	ErrNoMediaDevice = errors.New("VM has no drive for this virtual media")
+	ErrMediaNotFound = errors.New("virtual media not found")
+	ErrInvalidMedia  = errors.New("invalid virtual media configuration")
+)
+
+// MediaType is the kind of device a virtual media slot emulates
+type MediaType string
+
+const (
+	MediaCD       MediaType = "CD"       // CD/DVD drive, always write protected
+	MediaUSBStick MediaType = "USBStick" // USB mass storage, plugged while inserted
+	MediaFloppy   MediaType = "Floppy"   // floppy drive A: or B:
+)
+
+// mediaIDPrefix prefixes the IDs of devices and nodes hot-plugged for
+// virtual media.
+const mediaIDPrefix = "vmedia-"
+
+const (
+	mediaSCSIController = mediaIDPrefix + "scsi" // virtio-scsi-pci for CD drives
+	mediaUSBController  = mediaIDPrefix + "xhci" // qemu-xhci for USB sticks
+	maxFloppies         = 2
+)
+
+// MediaSlot is a virtual media device of the VM
+type MediaSlot struct {
+	ID   string // "CD1", "USB1", "Floppy1", ...
+	Type MediaType
+}
+
+// defaultMediaSlots is the single CD drive of a Machine without
+// SetMediaSlots.
+var defaultMediaSlots = []MediaSlot{{ID: "CD1", Type: MediaCD}}
+
+// ParseMediaSlots parses a comma-separated list of slot types ("CD",
+// "USB", "Floppy", case-insensitive) and numbers the slots per type.
+func ParseMediaSlots(spec string) ([]MediaSlot, error) {
+	var slots []MediaSlot
+	count := map[MediaType]int{}
+	for _, field := range strings.Split(spec, ",") {
+		var t MediaType
+		var prefix string
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "":
+			continue
+		case "cd", "dvd":
+			t, prefix = MediaCD, "CD"
+		case "usb", "usbstick":
+			t, prefix = MediaUSBStick, "USB"
+		case "floppy":
+			t, prefix = MediaFloppy, "Floppy"
+		default:
+			return nil, fmt.Errorf("%w: unknown media type %q", ErrInvalidMedia, field)
+		}
+		count[t]++
+		slots = append(slots, MediaSlot{ID: fmt.Sprintf("%s%d", prefix, count[t]), Type: t})
+	}
+	if count[MediaFloppy] > maxFloppies {
+		return nil, fmt.Errorf("%w: at most %d floppy drives", ErrInvalidMedia, maxFloppies)
+	}
+	return slots, nil
+}
+
+// FloppyCount returns the number of floppy slots, which need floppy drives
+// on the QEMU command line since QEMU cannot hot-plug them.
+func FloppyCount(slots []MediaSlot) int {
+	n := 0
+	for _, s := range slots {
+		if s.Type == MediaFloppy {
+			n++
+		}
+	}
+	return n
+}
+
+// Media is the state of a virtual media slot
+type Media struct {
+	ID             string
+	Type           MediaType
+	Device         string // qdev ID or QOM path, "" without a drive
+	Image          string // image in the drive, or to insert at the next power on
+	Inserted       bool   // a medium is in the drive and the tray is closed
+	WriteProtected bool
+	TrayOpen       bool
+	Verification   *imagecache.Verification // nil if the image was not verified
+}
+
+// InsertOptions are the optional parameters of InsertMedia
+type InsertOptions struct {
+	WriteProtected bool
+	UserName       string            // credentials for downloading the image
+	Password       string            // (not persisted)
+	Progress       func(percent int) // download progress, may be nil
+	File           string            // local copy of the image to insert instead, e.g. an upload
+	SHA256         string            // expected SHA-256 of the image, hex
+	Signature      []byte            // detached signature checked against the trusted keys
+}
+
+// mediaEntry is an inserted image as SetMediaStore persists it.
+type mediaEntry struct {
+	Image          string                   `json:"Image"`
+	File           string                   `json:"File,omitempty"` // cached copy of Image in the drive
+	WriteProtected bool                     `json:"WriteProtected"`
+	Verification   *imagecache.Verification `json:"Verification,omitempty"`
+}
+
+// SetMediaSlots configures the virtual media slots. CD slots use the CD
+// drives on the QEMU command line first and hot-plug SCSI CD drives for
+// the rest; USB sticks are hot-plugged on insert and unplugged on eject;
+// floppy slots use the floppy drives of the command line.
+func (m *Machine) SetMediaSlots(slots []MediaSlot) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mediaSlots = append([]MediaSlot(nil), slots...)
+}
+
+// MediaSlots returns the configured virtual media slots.
+func (m *Machine) MediaSlots() []MediaSlot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]MediaSlot(nil), m.mediaSlots...)
+}
+
+// SetMediaStore configures the file the images inserted by InsertMedia are
+// kept in, and loads it. In process mode the images are inserted again
+// whenever QEMU starts. Nothing is persisted while path is empty.
+func (m *Machine) SetMediaStore(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mediaStore = path
+	m.media = map[string]mediaEntry{}
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading media state: %w", err)
+	}
+	if err := json.Unmarshal(data, &m.media); err != nil {
+		return fmt.Errorf("parsing media state %s: %w", path, err)
+	}
+	m.pinMediaLocked()
+	return nil
+}
+
+// SetImageCache makes InsertMedia download HTTP(S) images into cache and
+// insert the local copy. Without a cache QEMU opens the URL itself, which
+// needs its curl block driver.
+func (m *Machine) SetImageCache(cache *imagecache.Cache) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.imageCache = cache
+	m.pinMediaLocked()
+}
+
+// SetImageVerifier sets the trusted keys detached signatures of images are
+// checked against. Checksums are verified without it.
+func (m *Machine) SetImageVerifier(v *imagecache.Verifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.verifier = v
+}
+
+// GetMedia returns the state of the virtual media slots as QEMU reports
+// it. While QEMU is not running the images are the ones inserted at the
+// next power on.
+func (m *Machine) GetMedia() ([]Media, error) {
+	slots := m.MediaSlots()
+	media := make([]Media, len(slots))
+	for i, s := range slots {
+		media[i] = Media{ID: s.ID, Type: s.Type}
+	}
+	if err := m.checkRunning(); err != nil {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		for i := range media {
+			e := m.media[media[i].ID]
+			media[i].Image, media[i].WriteProtected = e.Image, e.WriteProtected
+			media[i].Verification = e.Verification
+		}
+		return media, nil
+	}
+
+	drives, err := m.mediaDrives(slots)
+	if err != nil {
+		return nil, err
+	}
+	for i := range media {
+		b, ok := drives[media[i].ID]
+		if !ok {
+			continue
+		}
+		media[i].Device = b.QDev
+		media[i].TrayOpen = b.TrayOpen != nil && *b.TrayOpen
+		if b.Inserted != nil {
+			media[i].Image = b.Inserted.File
+			if e, ok := m.insertedEntry(media[i].ID, b.Inserted.File); ok {
+				media[i].Image, media[i].Verification = e.Image, e.Verification
+			}
+			media[i].WriteProtected = b.Inserted.RO
+			media[i].Inserted = !media[i].TrayOpen
+		}
+	}
+	return media, nil
+}
+
+// InsertMedia inserts image into virtual media slot id and remembers it
+// for the next start of QEMU. CDs are always write protected. HTTP(S)
+// images are downloaded first when an image cache is set. In process mode
+// a powered-off VM only remembers the image.
+func (m *Machine) InsertMedia(id, image string, opts InsertOptions) error {
+	slot, ok := m.mediaSlot(id)
+	if !ok {
+		return ErrMediaNotFound
+	}
+	e := mediaEntry{Image: image, File: opts.File, WriteProtected: opts.WriteProtected || slot.Type == MediaCD}
+	if cache := m.cache(); e.File == "" && cache != nil && imagecache.Cacheable(image) {
+		file, err := cache.Fetch(image, opts.UserName, opts.Password, opts.Progress)
+		if err != nil {
+			return err
+		}
+		e.File = file
+	}
+	if opts.SHA256 != "" || len(opts.Signature) > 0 {
+		v, err := m.verifyMedia(e, opts.SHA256, opts.Signature)
+		if err != nil {
+			return err
+		}
+		e.Verification = &v
+	}
+	if err := m.checkRunning(); err == nil {
+		if err := m.insertMedia(slot, e.file(), e.WriteProtected); err != nil {
+			return err
+		}
+	}
+	m.setMedia(id, e)
+	m.emit(Event{Type: EventMediaInserted, Media: id, Image: image})
+	return nil
+}
+
+// EjectMedia removes the medium from virtual media slot id and forgets
+// it. A USB stick is unplugged.
+func (m *Machine) EjectMedia(id string) error {
+	slot, ok := m.mediaSlot(id)
+	if !ok {
+		return ErrMediaNotFound
+	}
+	if err := m.checkRunning(); err == nil {
+		drives, err := m.mediaDrives(m.MediaSlots())
+		if err != nil {
+			return err
+		}
+		if b, ok := drives[id]; ok {
+			switch {
+			case slot.Type == MediaUSBStick:
+				err = m.unplugUSBStick(mediaDeviceID(slot))
+			case b.Inserted != nil:
+				err = m.qmpClient.BlockdevRemoveMedium(b.QDev)
+			}
+			if err != nil {
+				return fmt.Errorf("ejecting %s: %w", id, err)
+			}
+		}
+	}
+	m.setMedia(id, mediaEntry{})
+	m.emit(Event{Type: EventMediaEjected, Media: id})
+	return nil
+}
+
+// insertMedia puts image into the drive of slot, hot-plugging the drive
+// if needed.
+func (m *Machine) insertMedia(slot MediaSlot, image string, readOnly bool) error {
+	drives, err := m.mediaDrives(m.MediaSlots())
+	if err != nil {
+		return err
+	}
+	b, ok := drives[slot.ID]
+	devID := mediaDeviceID(slot)
+
+	switch slot.Type {
+	case MediaUSBStick:
+		if ok {
+			if err := m.unplugUSBStick(devID); err != nil {
+				return fmt.Errorf("replacing %s: %w", slot.ID, err)
+			}
+		}
+		if err := m.plugUSBStick(devID, image, readOnly); err != nil {
+			return fmt.Errorf("inserting %s: %w", image, err)
+		}
+		return nil
+	case MediaCD:
+		if !ok {
+			if err := m.plugCDDrive(devID); err != nil {
+				return err
+			}
+			b.QDev = devID
+		}
+	case MediaFloppy:
+		if !ok {
+			return ErrNoMediaDevice
+		}
+	}
+	if err := m.qmpClient.BlockdevChangeMedium(b.QDev, image, readOnly); err != nil {
+		return fmt.Errorf("inserting %s: %w", image, err)
+	}
+	return nil
+}
+
+// mediaDrives maps slot IDs to their drives in the running VM. The n-th
+// CD slot is the n-th CD drive of the command line, or else the SCSI CD
+// drive hot-plugged for it; the n-th floppy slot is the n-th floppy drive;
+// a USB slot has a drive while a stick is plugged.
+func (m *Machine) mediaDrives(slots []MediaSlot) (map[string]qmp.BlockInfo, error) {
+	blocks, err := m.qmpClient.QueryBlock()
+	if err != nil {
+		return nil, fmt.Errorf("querying block devices: %w", err)
+	}
+	var cds, floppies []qmp.BlockInfo
+	plugged := map[string]qmp.BlockInfo{} // by device or node ID
+	for _, b := range blocks {
+		if b.Inserted != nil && strings.HasPrefix(b.Inserted.NodeName, mediaIDPrefix) {
+			plugged[b.Inserted.NodeName] = b
+			continue
+		}
+		if strings.HasPrefix(b.QDev, mediaIDPrefix) {
+			plugged[b.QDev] = b
+			continue
+		}
+		if !b.Removable || b.QDev == "" {
+			continue
+		}
+		switch m.deviceType(b.QDev) {
+		case "ide-cd", "scsi-cd":
+			cds = append(cds, b)
+		case "floppy":
+			floppies = append(floppies, b)
+		}
+	}
+
+	drives := map[string]qmp.BlockInfo{}
+	count := map[MediaType]int{}
+	for _, s := range slots {
+		n := count[s.Type]
+		count[s.Type]++
+		switch {
+		case s.Type == MediaCD && n < len(cds):
+			drives[s.ID] = cds[n]
+		case s.Type == MediaFloppy && n < len(floppies):
+			drives[s.ID] = floppies[n]
+		default:
+			if b, ok := plugged[mediaDeviceID(s)]; ok {
+				drives[s.ID] = b
+			}
+		}
+	}
+	return drives, nil
+}
+
+// deviceType returns the QOM type of the device with qdev ID or QOM path
+// qdev, or "" if there is none.
+func (m *Machine) deviceType(qdev string) string {
+	path := qdev
+	if !strings.HasPrefix(path, "/") {
+		path = "/machine/peripheral/" + qdev
+	}
+	raw, err := m.qmpClient.QOMGet(path, "type")
+	if err != nil {
+		return ""
+	}
+	var t string
+	json.Unmarshal(raw, &t)
+	return t
+}
+
+// plugController hot-plugs the controller id unless the VM already has it.
+func (m *Machine) plugController(driver, id string) error {
+	if m.deviceType(id) != "" {
+		return nil
+	}
+	props := map[string]interface{}{}
+	if _, bus := m.volumeStore(); bus != "" {
+		props["bus"] = bus
+	}
+	if err := m.qmpClient.DeviceAdd(driver, id, props); err != nil {
+		return fmt.Errorf("hot-plugging %s: %w", driver, err)
+	}
+	return nil
+}
+
+// plugCDDrive hot-plugs an empty SCSI CD drive.
+func (m *Machine) plugCDDrive(id string) error {
+	if err := m.plugController("virtio-scsi-pci", mediaSCSIController); err != nil {
+		return err
+	}
+	if err := m.qmpClient.DeviceAdd("scsi-cd", id, map[string]interface{}{
+		"bus": mediaSCSIController + ".0",
+	}); err != nil {
+		return fmt.Errorf("hot-plugging %s: %w", id, err)
+	}
+	return nil
+}
+
+// plugUSBStick opens image and hot-plugs a USB mass storage device for it.
+func (m *Machine) plugUSBStick(id, image string, readOnly bool) error {
+	if err := m.plugController("qemu-xhci", mediaUSBController); err != nil {
+		return err
+	}
+	if err := m.qmpClient.BlockdevAdd(map[string]interface{}{
+		"driver":    "raw",
+		"node-name": id,
+		"read-only": readOnly,
+		"file":      imageProtocol(image),
+	}); err != nil {
+		return err
+	}
+	if err := m.qmpClient.DeviceAdd("usb-storage", id, map[string]interface{}{
+		"drive":     id,
+		"bus":       mediaUSBController + ".0",
+		"removable": true,
+	}); err != nil {
+		m.qmpClient.BlockdevDel(id)
+		return fmt.Errorf("hot-plugging %s: %w", id, err)
+	}
+	return nil
+}
+
+// unplugUSBStick unplugs a USB stick and closes its image.
+func (m *Machine) unplugUSBStick(id string) error {
+	if err := m.qmpClient.DeviceDel(id); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(deviceDelTimeout)
+	for {
+		blocks, err := m.qmpClient.QueryBlock()
+		if err == nil && !hasMediaNode(blocks, id) {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s was not unplugged within %s", id, deviceDelTimeout)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return m.qmpClient.BlockdevDel(id)
+}
+
+func hasMediaNode(blocks []qmp.BlockInfo, id string) bool {
+	for _, b := range blocks {
+		if b.Inserted != nil && b.Inserted.NodeName == id {
+			return true
+		}
+	}
+	return false
+}
+
+// imageProtocol returns the protocol node options for an image path or
+// HTTP(S) URL.
+func imageProtocol(image string) map[string]interface{} {
+	for _, scheme := range []string{"http", "https"} {
+		if strings.HasPrefix(image, scheme+"://") {
+			return map[string]interface{}{"driver": scheme, "url": image}
+		}
+	}
+	return map[string]interface{}{"driver": "file", "filename": image}
+}
+
+func mediaDeviceID(slot MediaSlot) string {
+	return mediaIDPrefix + strings.ToLower(slot.ID)
+}
+
+func (m *Machine) mediaSlot(id string) (MediaSlot, bool) {
+	for _, s := range m.MediaSlots() {
+		if s.ID == id {
+			return s, true
+		}
+	}
+	return MediaSlot{}, false
+}
+
+// mediaInsertPending reports whether QEMU has to be started paused so
+// reinsertMedia can run before the guest boots.
+func (m *Machine) mediaInsertPending() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.media) > 0
+}
+
+// reinsertMedia inserts the remembered images after QEMU started. Failures
+// are logged: the VM still powers on, with the drive empty.
+func (m *Machine) reinsertMedia() {
+	for _, slot := range m.MediaSlots() {
+		m.mu.RLock()
+		e, ok := m.media[slot.ID]
+		m.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		if e.File != "" {
+			if _, err := os.Stat(e.File); err != nil {
+				// Evicted from the cache: download it again.
+				if e, err = m.refetchMedia(slot.ID, e); err != nil {
+					log.Printf("Re-inserting virtual media %s into %s: %v", e.Image, slot.ID, err)
+					continue
+				}
+			}
+		}
+		if e.Verification != nil {
+			// The file must still be the image verified at insert.
+			if _, err := m.verifyMedia(e, e.Verification.SHA256, nil); err != nil {
+				log.Printf("Re-inserting virtual media %s into %s: %v", e.Image, slot.ID, err)
+				continue
+			}
+		}
+		if err := m.insertMedia(slot, e.file(), e.WriteProtected); err != nil {
+			log.Printf("Re-inserting virtual media %s into %s: %v", e.Image, slot.ID, err)
+		}
+	}
+}
+
+// setMedia remembers the image of slot id (none for a zero entry) and
+// persists the images.
+func (m *Machine) setMedia(id string, e mediaEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.media == nil {
+		m.media = map[string]mediaEntry{}
+	}
+	if e.Image == "" {
+		delete(m.media, id)
+	} else {
+		m.media[id] = e
+	}
+	if m.imageCache != nil {
+		m.imageCache.Pin(id, e.File)
+	}
+	if m.mediaStore == "" {
+		return
+	}
+	if err := writeMediaState(m.mediaStore, m.media); err != nil {
+		log.Printf("Saving virtual media state: %v", err)
+	}
+}
+
+// refetchMedia downloads the image of e again, without credentials, and
+// remembers the new copy.
+func (m *Machine) refetchMedia(id string, e mediaEntry) (mediaEntry, error) {
+	cache := m.cache()
+	if cache == nil {
+		return e, fmt.Errorf("cached copy %s: %w", e.File, os.ErrNotExist)
+	}
+	file, err := cache.Fetch(e.Image, "", "", nil)
+	if err != nil {
+		return e, err
+	}
+	e.File = file
+	m.setMedia(id, e)
+	return e, nil
+}
+
+// insertedEntry returns the remembered image of slot id if file, in its
+// drive, is that image.
+func (m *Machine) insertedEntry(id, file string) (mediaEntry, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	e, ok := m.media[id]
+	return e, ok && e.file() == file
+}
+
+// verifyMedia checks the file of e against an expected checksum and
+// signature before QEMU gets to see it.
+func (m *Machine) verifyMedia(e mediaEntry, sha256Hex string, signature []byte) (imagecache.Verification, error) {
+	file := e.file()
+	if imagecache.Cacheable(file) {
+		return imagecache.Verification{}, fmt.Errorf("%w: %s is read by QEMU from the network", imagecache.ErrVerification, e.Image)
+	}
+	m.mu.RLock()
+	v := m.verifier
+	m.mu.RUnlock()
+	return v.Verify(file, sha256Hex, signature)
+}
+
+func (m *Machine) cache() *imagecache.Cache {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.imageCache
+}
+
+// pinMediaLocked keeps the cached copies of the remembered images in the
+// cache.
+func (m *Machine) pinMediaLocked() {
+	if m.imageCache == nil {
+		return
+	}
+	for id, e := range m.media {
+		if e.File != "" {
+			m.imageCache.Pin(id, e.File)
+		}
+	}
+}
+
+// file returns what to put into the drive: the cached copy if any.
+func (e mediaEntry) file() string {
+	if e.File != "" {
+		return e.File
+	}
+	return e.Image
+}
+
+// writeMediaState replaces the file at path atomically.
+func writeMediaState(path string, media map[string]mediaEntry) error {
+	data, err := json.Marshal(media)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,402 @@
+package machine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/imagecache"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+// newMediaMock returns a running VM with an empty -cdrom drive.
+func newMediaMock() *mockQMPClient {
+	mock := newMockQMPClient(qmp.StatusRunning)
+	closed := false
+	mock.blocks = []qmp.BlockInfo{
+		{QDev: "/machine/peripheral/disk0/virtio-backend"},
+		{Device: "ide1-cd0", QDev: "/machine/unattached/device[23]", Removable: true, TrayOpen: &closed},
+	}
+	mock.qom = map[string]string{"/machine/unattached/device[23].type": `"ide-cd"`}
+	return mock
+}
+
+// addFloppy adds an empty floppy drive as -drive if=floppy creates it.
+func addFloppy(mock *mockQMPClient) {
+	mock.blocks = append(mock.blocks, qmp.BlockInfo{Device: "floppy0", QDev: "/machine/unattached/device[14]", Removable: true})
+	mock.qom["/machine/unattached/device[14].type"] = `"floppy"`
+}
+
+func mediaByID(t *testing.T, m *Machine, id string) Media {
+	t.Helper()
+	media, err := m.GetMedia()
+	require.NoError(t, err)
+	for _, md := range media {
+		if md.ID == id {
+			return md
+		}
+	}
+	t.Fatalf("no media %s", id)
+	return Media{}
+}
+
+func TestParseMediaSlots(t *testing.T) {
+	slots, err := ParseMediaSlots("CD, usb,cd,Floppy")
+	require.NoError(t, err)
+	assert.Equal(t, []MediaSlot{
+		{ID: "CD1", Type: MediaCD},
+		{ID: "USB1", Type: MediaUSBStick},
+		{ID: "CD2", Type: MediaCD},
+		{ID: "Floppy1", Type: MediaFloppy},
+	}, slots)
+	assert.Equal(t, 1, FloppyCount(slots))
+
+	_, err = ParseMediaSlots("CD,Tape")
+	assert.ErrorIs(t, err, ErrInvalidMedia)
+	_, err = ParseMediaSlots("Floppy,Floppy,Floppy")
+	assert.ErrorIs(t, err, ErrInvalidMedia)
+}
+
+func TestInsertMedia(t *testing.T) {
+	mock := newMediaMock()
+	m := New(mock)
+
+	require.NoError(t, m.InsertMedia("CD1", "http://example.com/boot.iso", InsertOptions{}))
+	assert.Contains(t, mock.Calls(), "BlockdevChangeMedium")
+
+	assert.Equal(t, Media{
+		ID:             "CD1",
+		Type:           MediaCD,
+		Device:         "/machine/unattached/device[23]",
+		Image:          "http://example.com/boot.iso",
+		Inserted:       true,
+		WriteProtected: true,
+	}, mediaByID(t, m, "CD1"), "CDs are always write protected")
+}
+
+func TestInsertMedia_Errors(t *testing.T) {
+	mock := newMediaMock()
+	m := New(mock)
+	assert.ErrorIs(t, m.InsertMedia("CD9", "/images/boot.iso", InsertOptions{WriteProtected: true}), ErrMediaNotFound)
+
+	mock.mediumErr = errors.New("QMP error: GenericError: Could not open '/images/boot.iso'")
+	err := m.InsertMedia("CD1", "/images/boot.iso", InsertOptions{WriteProtected: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not open")
+
+	media := mediaByID(t, m, "CD1")
+	assert.False(t, media.Inserted)
+	assert.Empty(t, media.Image)
+}
+
+func TestInsertMedia_HotPlugsCDDrive(t *testing.T) {
+	mock := newMediaMock()
+	m := New(mock)
+	m.SetVolumeStore("", "hotplug0")
+	m.SetMediaSlots([]MediaSlot{{ID: "CD1", Type: MediaCD}, {ID: "CD2", Type: MediaCD}})
+
+	require.NoError(t, m.InsertMedia("CD2", "/images/drivers.iso", InsertOptions{WriteProtected: true}))
+	assert.Contains(t, mock.Calls(), "DeviceAdd:vmedia-scsi")
+	assert.Contains(t, mock.Calls(), "DeviceAdd:vmedia-cd2")
+
+	media := mediaByID(t, m, "CD2")
+	assert.Equal(t, "vmedia-cd2", media.Device)
+	assert.True(t, media.Inserted)
+	assert.Equal(t, "/images/drivers.iso", media.Image)
+	assert.False(t, mediaByID(t, m, "CD1").Inserted)
+
+	// The controller and drive are only added once
+	require.NoError(t, m.InsertMedia("CD2", "/images/other.iso", InsertOptions{WriteProtected: true}))
+	assert.Equal(t, 1, countCalls(mock.Calls(), "DeviceAdd:vmedia-cd2"))
+	assert.Equal(t, 1, countCalls(mock.Calls(), "DeviceAdd:vmedia-scsi"))
+}
+
+func TestUSBStick(t *testing.T) {
+	mock := newMediaMock()
+	m := New(mock)
+	m.SetMediaSlots([]MediaSlot{{ID: "USB1", Type: MediaUSBStick}})
+
+	media := mediaByID(t, m, "USB1")
+	assert.Empty(t, media.Device, "no stick is plugged")
+
+	require.NoError(t, m.InsertMedia("USB1", "/images/ks.img", InsertOptions{}))
+	assert.Contains(t, mock.Calls(), "DeviceAdd:vmedia-xhci")
+	assert.Contains(t, mock.Calls(), "DeviceAdd:vmedia-usb1")
+	media = mediaByID(t, m, "USB1")
+	assert.True(t, media.Inserted)
+	assert.False(t, media.WriteProtected)
+	assert.Equal(t, "/images/ks.img", media.Image)
+
+	// Inserting another image replaces the stick
+	require.NoError(t, m.InsertMedia("USB1", "https://example.com/drivers.img", InsertOptions{WriteProtected: true}))
+	media = mediaByID(t, m, "USB1")
+	assert.True(t, media.WriteProtected)
+	assert.Equal(t, "https://example.com/drivers.img", media.Image)
+
+	require.NoError(t, m.EjectMedia("USB1"))
+	calls := mock.Calls()
+	assert.Equal(t, 2, countCalls(calls, "DeviceDel:vmedia-usb1"))
+	assert.Equal(t, 2, countCalls(calls, "BlockdevDel:vmedia-usb1"))
+	assert.False(t, mediaByID(t, m, "USB1").Inserted)
+}
+
+func TestFloppy(t *testing.T) {
+	mock := newMediaMock()
+	m := New(mock)
+	m.SetMediaSlots([]MediaSlot{{ID: "Floppy1", Type: MediaFloppy}})
+	assert.ErrorIs(t, m.InsertMedia("Floppy1", "/images/dos.img", InsertOptions{}), ErrNoMediaDevice)
+
+	addFloppy(mock)
+	require.NoError(t, m.InsertMedia("Floppy1", "/images/dos.img", InsertOptions{}))
+	media := mediaByID(t, m, "Floppy1")
+	assert.Equal(t, "/machine/unattached/device[14]", media.Device)
+	assert.True(t, media.Inserted)
+	assert.False(t, media.WriteProtected)
+}
+
+func TestEjectMedia(t *testing.T) {
+	mock := newMediaMock()
+	m := New(mock)
+	require.NoError(t, m.InsertMedia("CD1", "/images/boot.iso", InsertOptions{WriteProtected: true}))
+
+	require.NoError(t, m.EjectMedia("CD1"))
+	assert.Contains(t, mock.Calls(), "BlockdevRemoveMedium")
+
+	media := mediaByID(t, m, "CD1")
+	assert.False(t, media.Inserted)
+	assert.True(t, media.TrayOpen)
+	assert.Empty(t, media.Image)
+	assert.ErrorIs(t, m.EjectMedia("USB1"), ErrMediaNotFound)
+}
+
+func TestGetMedia_TrayOpen(t *testing.T) {
+	mock := newMediaMock()
+	open := true
+	mock.blocks[1].TrayOpen = &open
+	mock.blocks[1].Inserted = &qmp.BlockInserted{File: "/images/boot.iso", RO: true}
+	m := New(mock)
+
+	media := mediaByID(t, m, "CD1")
+	assert.Equal(t, "/images/boot.iso", media.Image)
+	assert.False(t, media.Inserted, "the guest opened the tray")
+	assert.True(t, media.TrayOpen)
+}
+
+func TestMedia_ProcessOff(t *testing.T) {
+	mock := newMockQMPClient(qmp.StatusShutdown)
+	m := NewWithProcess(mock, newMockProcessManager(false))
+	m.SetMediaSlots([]MediaSlot{{ID: "CD1", Type: MediaCD}, {ID: "USB1", Type: MediaUSBStick}})
+
+	require.NoError(t, m.InsertMedia("USB1", "/images/ks.img", InsertOptions{}))
+	assert.NotContains(t, mock.Calls(), "BlockdevAdd")
+
+	media, err := m.GetMedia()
+	require.NoError(t, err)
+	assert.Equal(t, []Media{
+		{ID: "CD1", Type: MediaCD},
+		{ID: "USB1", Type: MediaUSBStick, Image: "/images/ks.img"},
+	}, media)
+
+	require.NoError(t, m.EjectMedia("USB1"))
+	assert.Empty(t, mediaByID(t, m, "USB1").Image)
+}
+
+func TestMedia_ReinsertedAtPowerOn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "media.json")
+	mock := newMediaMock()
+	mock.status = qmp.StatusShutdown
+	pm := newMockProcessManager(false)
+	slots := []MediaSlot{{ID: "CD1", Type: MediaCD}, {ID: "USB1", Type: MediaUSBStick}}
+	m := NewWithProcess(mock, pm)
+	m.SetMediaSlots(slots)
+	require.NoError(t, m.SetMediaStore(path))
+	require.NoError(t, m.InsertMedia("CD1", "/images/boot.iso", InsertOptions{WriteProtected: true}))
+	require.NoError(t, m.InsertMedia("USB1", "/images/ks.img", InsertOptions{}))
+
+	// A new BMC process picks the images up from the store.
+	m = NewWithProcess(mock, pm)
+	m.SetMediaSlots(slots)
+	require.NoError(t, m.SetMediaStore(path))
+	require.NoError(t, m.Reset("On"))
+
+	require.Len(t, pm.boots, 1)
+	assert.True(t, pm.boots[0].Paused, "QEMU holds the CPUs until the media is inserted")
+	calls := mock.Calls()
+	require.Contains(t, calls, "BlockdevChangeMedium")
+	require.Contains(t, calls, "DeviceAdd:vmedia-usb1")
+	require.Contains(t, calls, "Cont")
+	assert.Less(t, slices.Index(calls, "BlockdevChangeMedium"), slices.Index(calls, "Cont"))
+	assert.Less(t, slices.Index(calls, "DeviceAdd:vmedia-usb1"), slices.Index(calls, "Cont"))
+
+	cd := mediaByID(t, m, "CD1")
+	assert.True(t, cd.Inserted)
+	assert.Equal(t, "/images/boot.iso", cd.Image)
+	usb := mediaByID(t, m, "USB1")
+	assert.True(t, usb.Inserted)
+	assert.False(t, usb.WriteProtected)
+}
+
+func TestMedia_PowerOnWithoutMedia(t *testing.T) {
+	mock := newMockQMPClient(qmp.StatusShutdown)
+	pm := newMockProcessManager(false)
+	m := NewWithProcess(mock, pm)
+
+	require.NoError(t, m.Reset("On"))
+	require.Len(t, pm.boots, 1)
+	assert.False(t, pm.boots[0].Paused)
+	assert.NotContains(t, mock.Calls(), "Cont")
+}
+
+// newImageCache returns a cache and a server with the image /boot.iso,
+// which requires the credentials admin/secret.
+func newImageCache(t *testing.T) (*imagecache.Cache, *httptest.Server) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "admin" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Length", "10")
+		w.Write([]byte("boot image"))
+	}))
+	t.Cleanup(srv.Close)
+	cache, err := imagecache.New(t.TempDir(), 0)
+	require.NoError(t, err)
+	return cache, srv
+}
+
+func TestInsertMedia_ImageCache(t *testing.T) {
+	cache, srv := newImageCache(t)
+	mock := newMediaMock()
+	m := New(mock)
+	m.SetImageCache(cache)
+	url := srv.URL + "/boot.iso"
+
+	err := m.InsertMedia("CD1", url, InsertOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+	assert.NotContains(t, mock.Calls(), "BlockdevChangeMedium")
+
+	var progress []int
+	require.NoError(t, m.InsertMedia("CD1", url, InsertOptions{
+		UserName: "admin",
+		Password: "secret",
+		Progress: func(p int) { progress = append(progress, p) },
+	}))
+	assert.Contains(t, progress, 100)
+	file := mock.blocks[1].Inserted.File
+	assert.True(t, cache.Contains(file), "QEMU opens the cached copy")
+	assert.Equal(t, url, mediaByID(t, m, "CD1").Image)
+}
+
+func TestInsertMedia_File(t *testing.T) {
+	mock := newMediaMock()
+	m := New(mock)
+
+	image := "/redfish/v1/Managers/1/Oem/QemuBmc/MediaImages/rescue.iso"
+	require.NoError(t, m.InsertMedia("CD1", image, InsertOptions{File: "/cache/blobs/0123"}))
+	assert.Equal(t, "/cache/blobs/0123", mock.blocks[1].Inserted.File)
+	assert.Equal(t, image, mediaByID(t, m, "CD1").Image)
+}
+
+func TestInsertMedia_Verification(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "boot.iso")
+	require.NoError(t, os.WriteFile(image, []byte("boot image"), 0o644))
+	sum := sha256.Sum256([]byte("boot image"))
+	mock := newMediaMock()
+	m := New(mock)
+
+	err := m.InsertMedia("CD1", image, InsertOptions{SHA256: strings.Repeat("0", 64)})
+	assert.ErrorIs(t, err, imagecache.ErrVerification)
+	assert.NotContains(t, mock.Calls(), "BlockdevChangeMedium", "refused before QEMU sees it")
+
+	err = m.InsertMedia("CD1", "https://example.com/boot.iso", InsertOptions{SHA256: hex.EncodeToString(sum[:])})
+	assert.ErrorIs(t, err, imagecache.ErrVerification, "QEMU would read it from the network")
+
+	err = m.InsertMedia("CD1", image, InsertOptions{Signature: []byte("sig")})
+	assert.ErrorIs(t, err, imagecache.ErrVerification, "no trusted keys")
+
+	require.NoError(t, m.InsertMedia("CD1", image, InsertOptions{SHA256: hex.EncodeToString(sum[:])}))
+	media := mediaByID(t, m, "CD1")
+	require.NotNil(t, media.Verification)
+	assert.True(t, media.Verification.ChecksumVerified)
+	assert.Equal(t, hex.EncodeToString(sum[:]), media.Verification.SHA256)
+
+	require.NoError(t, m.InsertMedia("CD1", image, InsertOptions{}))
+	assert.Nil(t, mediaByID(t, m, "CD1").Verification)
+}
+
+func TestMedia_ReverifiedAtPowerOn(t *testing.T) {
+	image := filepath.Join(t.TempDir(), "boot.iso")
+	require.NoError(t, os.WriteFile(image, []byte("boot image"), 0o644))
+	sum := sha256.Sum256([]byte("boot image"))
+	mock := newMediaMock()
+	mock.status = qmp.StatusShutdown
+	m := NewWithProcess(mock, newMockProcessManager(false))
+	require.NoError(t, m.InsertMedia("CD1", image, InsertOptions{SHA256: hex.EncodeToString(sum[:])}))
+
+	// The image changed while the VM was off
+	require.NoError(t, os.WriteFile(image, []byte("tampered"), 0o644))
+	require.NoError(t, m.Reset("On"))
+	assert.NotContains(t, mock.Calls(), "BlockdevChangeMedium")
+	assert.Nil(t, mock.blocks[1].Inserted)
+}
+
+func TestMedia_RefetchedAtPowerOn(t *testing.T) {
+	cache, srv := newImageCache(t)
+	path := filepath.Join(t.TempDir(), "media.json")
+	mock := newMediaMock()
+	mock.status = qmp.StatusShutdown
+	pm := newMockProcessManager(false)
+	m := NewWithProcess(mock, pm)
+	m.SetImageCache(cache)
+	require.NoError(t, m.SetMediaStore(path))
+	url := srv.URL + "/boot.iso"
+	require.NoError(t, m.InsertMedia("CD1", url, InsertOptions{UserName: "admin", Password: "secret"}))
+	file := m.media["CD1"].File
+	require.True(t, cache.Contains(file))
+
+	// The cached copy is still there at power on
+	require.NoError(t, m.Reset("On"))
+	assert.Equal(t, file, mock.blocks[1].Inserted.File)
+
+	// Once evicted, it is downloaded again, which fails without the
+	// credentials: the drive stays empty.
+	require.NoError(t, os.Remove(file))
+	mock.status = qmp.StatusShutdown
+	mock.blocks[1].Inserted = nil
+	m = NewWithProcess(mock, newMockProcessManager(false))
+	m.SetImageCache(cache)
+	require.NoError(t, m.SetMediaStore(path))
+	require.NoError(t, m.Reset("On"))
+	assert.Nil(t, mock.blocks[1].Inserted)
+	assert.Equal(t, url, m.media["CD1"].Image, "retried at the next power on")
+}
+
+func TestSetMediaStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "media.json")
+	m := New(newMockQMPClient(qmp.StatusRunning))
+	require.NoError(t, m.SetMediaStore(path), "a missing file is no media")
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	assert.Error(t, m.SetMediaStore(path))
+}
+
+func countCalls(calls []string, call string) int {
+	n := 0
+	for _, c := range calls {
+		if c == call {
+			n++
+		}
+	}
+	return n
+}
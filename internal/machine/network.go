@@ -0,0 +1,250 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/tjst-t/qemu-bmc/internal/qemu"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+// NIC errors
+var (
+	ErrNICNotFound   = errors.New("network interface not found")
+	ErrInvalidMAC    = errors.New("invalid unicast MAC address")
+	ErrNotSupported  = errors.New("not supported in legacy mode")
+	ErrNoNICArgument = errors.New("network interface is not a -device with an id on the QEMU command line")
+)
+
+// nicModels names NICs that have no QOM path to read their type from, by
+// PCI vendor and device ID.
+var nicModels = map[[2]int]string{
+	{0x1af4, 0x1000}: "virtio-net-pci",
+	{0x1af4, 0x1041}: "virtio-net-pci",
+	{0x8086, 0x100e}: "e1000",
+	{0x8086, 0x10d3}: "e1000e",
+	{0x8086, 0x10c9}: "igb",
+	{0x10ec, 0x8139}: "rtl8139",
+	{0x15ad, 0x07b0}: "vmxnet3",
+}
+
+// LinkState is the link state of a NIC
+type LinkState string
+
+const (
+	LinkUnknown LinkState = ""
+	LinkUp      LinkState = "Up"
+	LinkDown    LinkState = "Down"
+)
+
+// NIC is a network interface of the VM
+type NIC struct {
+	ID           string
+	Model        string
+	MAC          string
+	PendingMAC   string // MAC address applied at the next power on
+	Link         LinkState
+	LinkDisabled bool        // link set down with SetNICLink
+	Addresses    []IPAddress // reported by the guest agent
+}
+
+// IPAddress is a guest-visible address of a NIC
+type IPAddress struct {
+	Address string
+	Prefix  int
+	IPv6    bool
+}
+
+// SetGuestAgent configures the qemu-guest-agent used to report guest IP
+// addresses and to suspend the guest. nil disables it.
+func (m *Machine) SetGuestAgent(ga qmp.GuestAgent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.guestAgent = ga
+}
+
+// GetNICs returns the network interfaces of the VM: queried over QMP while
+// QEMU runs, from the QEMU command line otherwise.
+func (m *Machine) GetNICs() ([]NIC, error) {
+	var configured []qemu.NIC
+	if m.processManager != nil {
+		configured = qemu.ParseNICs(m.processManager.Args())
+		if !m.processManager.IsRunning() {
+			nics := make([]NIC, len(configured))
+			for i, c := range configured {
+				nics[i] = NIC{ID: c.ID, Model: c.Driver, MAC: c.MAC, Link: LinkDown}
+			}
+			return nics, nil
+		}
+	}
+
+	buses, err := m.qmpClient.QueryPCI()
+	if err != nil {
+		return nil, fmt.Errorf("querying PCI devices: %w", err)
+	}
+	var nics []NIC
+	var walk func(devices []qmp.PCIDevice)
+	walk = func(devices []qmp.PCIDevice) {
+		for _, d := range devices {
+			if d.PCIBridge != nil {
+				walk(d.PCIBridge.Devices)
+			}
+			if d.ClassInfo.Class == qmp.PCIClassEthernet {
+				nics = append(nics, m.pciNIC(d))
+			}
+		}
+	}
+	for _, b := range buses {
+		walk(b.Devices)
+	}
+
+	for i := range nics {
+		for _, c := range configured {
+			if c.ID == nics[i].ID && c.MAC != "" && !strings.EqualFold(c.MAC, nics[i].MAC) {
+				nics[i].PendingMAC = c.MAC
+			}
+		}
+	}
+	m.addLinkStates(nics)
+	m.addGuestAddresses(nics)
+	return nics, nil
+}
+
+// addLinkStates fills in the link state of each NIC. QEMU cannot report it,
+// so it is the state set with SetNICLink, or up in a QEMU the BMC started.
+// A stopped VM has no link.
+func (m *Machine) addLinkStates(nics []NIC) {
+	ps, err := m.GetPowerState()
+	if err != nil {
+		return
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := range nics {
+		up, set := m.nicLinks[nics[i].ID]
+		nics[i].LinkDisabled = set && !up
+		switch {
+		case ps == PowerOff || ps == PowerPaused:
+			nics[i].Link = LinkDown
+		case set && up:
+			nics[i].Link = LinkUp
+		case set:
+			nics[i].Link = LinkDown
+		case m.processManager != nil:
+			nics[i].Link = LinkUp
+		}
+	}
+}
+
+// SetNICLink connects or disconnects the link of a NIC, like plugging or
+// pulling its cable. It lasts until QEMU is restarted.
+func (m *Machine) SetNICLink(id string, up bool) error {
+	if m.processManager != nil && !m.processManager.IsRunning() {
+		return ErrNotRunning
+	}
+	nics, err := m.GetNICs()
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, n := range nics {
+		found = found || n.ID == id
+	}
+	if !found {
+		return ErrNICNotFound
+	}
+	// Only NICs with a qdev ID can be named in set_link
+	if m.qomString("/machine/peripheral/"+id, "type") == "" {
+		return ErrNoNICArgument
+	}
+
+	if err := m.qmpClient.SetLink(id, up); err != nil {
+		return fmt.Errorf("setting link of %s: %w", id, err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.nicLinks == nil {
+		m.nicLinks = make(map[string]bool)
+	}
+	m.nicLinks[id] = up
+	return nil
+}
+
+// pciNIC describes a NIC found on the PCI bus. Devices with an ID are read
+// through QOM; anonymous ones are named by their PCI address.
+func (m *Machine) pciNIC(d qmp.PCIDevice) NIC {
+	nic := NIC{
+		ID:    d.QdevID,
+		Model: nicModels[[2]int{d.ID.Vendor, d.ID.Device}],
+	}
+	if nic.ID == "" {
+		nic.ID = fmt.Sprintf("pci-%02x-%02x-%x", d.Bus, d.Slot, d.Function)
+		return nic
+	}
+	path := "/machine/peripheral/" + d.QdevID
+	nic.MAC = m.qomString(path, "mac")
+	if model := m.qomString(path, "type"); model != "" {
+		nic.Model = model
+	}
+	return nic
+}
+
+// addGuestAddresses fills in the addresses the guest agent reports for each
+// NIC, matched by MAC address. A missing agent is not an error.
+func (m *Machine) addGuestAddresses(nics []NIC) {
+	m.mu.RLock()
+	ga := m.guestAgent
+	m.mu.RUnlock()
+	if ga == nil {
+		return
+	}
+	ifaces, err := ga.NetworkInterfaces()
+	if err != nil {
+		return
+	}
+	for i := range nics {
+		for _, iface := range ifaces {
+			if nics[i].MAC == "" || !strings.EqualFold(iface.HardwareAddress, nics[i].MAC) {
+				continue
+			}
+			for _, a := range iface.IPAddresses {
+				nics[i].Addresses = append(nics[i].Addresses, IPAddress{
+					Address: a.Address,
+					Prefix:  a.Prefix,
+					IPv6:    a.Type == "ipv6",
+				})
+			}
+		}
+	}
+}
+
+// SetNICMAC changes the MAC address of a NIC from the next power on by
+// rewriting its -device argument. Only NICs given as -device with an id can
+// be changed, and only in process mode.
+func (m *Machine) SetNICMAC(id, mac string) error {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 || hw[0]&0x01 != 0 {
+		return ErrInvalidMAC
+	}
+	if m.processManager == nil {
+		return ErrNotSupported
+	}
+
+	args, err := qemu.SetNICMAC(m.processManager.Args(), id, hw.String())
+	if errors.Is(err, qemu.ErrNICNotFound) {
+		nics, _ := m.GetNICs()
+		for _, n := range nics {
+			if n.ID == id {
+				return ErrNoNICArgument
+			}
+		}
+		return ErrNICNotFound
+	}
+	if err != nil {
+		return err
+	}
+	m.processManager.SetArgs(args)
+	return nil
+}
@@ -0,0 +1,179 @@
+package machine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+type mockGuestAgent struct {
+	ifaces  []qmp.GuestNetworkInterface
+	err     error
+	suspend func() // run by SuspendRAM
+}
+
+func (g *mockGuestAgent) NetworkInterfaces() ([]qmp.GuestNetworkInterface, error) {
+	return g.ifaces, g.err
+}
+
+func (g *mockGuestAgent) SuspendRAM() error {
+	if g.err != nil {
+		return g.err
+	}
+	if g.suspend != nil {
+		g.suspend()
+	}
+	return nil
+}
+
+func newNICQMPClient() *mockQMPClient {
+	client := newMockQMPClient(qmp.StatusRunning)
+	var nic0, anon, bridge qmp.PCIDevice
+	nic0.Slot = 2
+	nic0.QdevID = "nic0"
+	nic0.ClassInfo.Class = qmp.PCIClassEthernet
+	nic0.ID.Vendor, nic0.ID.Device = 0x1af4, 0x1000
+
+	anon.Bus = 1
+	anon.ClassInfo.Class = qmp.PCIClassEthernet
+	anon.ID.Vendor, anon.ID.Device = 0x8086, 0x10d3
+
+	bridge.Slot = 28
+	bridge.QdevID = "rp0"
+	bridge.ClassInfo.Class = 0x0604
+	bridge.PCIBridge = &qmp.PCIBridgeInfo{Devices: []qmp.PCIDevice{anon}}
+
+	client.pci = []qmp.PCIBus{{Devices: []qmp.PCIDevice{nic0, bridge}}}
+	client.qom = map[string]string{
+		"/machine/peripheral/nic0.mac":  `"52:54:00:12:34:56"`,
+		"/machine/peripheral/nic0.type": `"virtio-net-pci"`,
+	}
+	return client
+}
+
+func TestGetNICs_FromQMP(t *testing.T) {
+	m := New(newNICQMPClient())
+	m.SetGuestAgent(&mockGuestAgent{ifaces: []qmp.GuestNetworkInterface{{
+		Name:            "eth0",
+		HardwareAddress: "52:54:00:12:34:56",
+		IPAddresses: []qmp.GuestIPAddress{
+			{Type: "ipv4", Address: "192.0.2.10", Prefix: 24},
+			{Type: "ipv6", Address: "2001:db8::10", Prefix: 64},
+		},
+	}}})
+
+	nics, err := m.GetNICs()
+	require.NoError(t, err)
+	require.Len(t, nics, 2)
+
+	assert.Equal(t, "nic0", nics[0].ID)
+	assert.Equal(t, "virtio-net-pci", nics[0].Model)
+	assert.Equal(t, "52:54:00:12:34:56", nics[0].MAC)
+	assert.Equal(t, LinkUnknown, nics[0].Link, "the link may have been set before the BMC connected")
+	require.Len(t, nics[0].Addresses, 2)
+	assert.Equal(t, IPAddress{Address: "2001:db8::10", Prefix: 64, IPv6: true}, nics[0].Addresses[1])
+
+	assert.Equal(t, "pci-01-00-0", nics[1].ID)
+	assert.Equal(t, "e1000e", nics[1].Model)
+	assert.Empty(t, nics[1].MAC)
+}
+
+func TestGetNICs_GuestAgentUnavailable(t *testing.T) {
+	m := New(newNICQMPClient())
+	m.SetGuestAgent(&mockGuestAgent{err: errors.New("connection refused")})
+
+	nics, err := m.GetNICs()
+	require.NoError(t, err)
+	assert.Empty(t, nics[0].Addresses)
+}
+
+func TestGetNICs_ProcessOffUsesArgs(t *testing.T) {
+	pm := newMockProcessManager(false)
+	pm.args = []string{"-netdev", "user,id=net0", "-device", "e1000,netdev=net0,id=nic0,mac=52:54:00:aa:bb:cc"}
+	m := NewWithProcess(newNICQMPClient(), pm)
+
+	nics, err := m.GetNICs()
+	require.NoError(t, err)
+	require.Len(t, nics, 1)
+	assert.Equal(t, NIC{ID: "nic0", Model: "e1000", MAC: "52:54:00:aa:bb:cc", Link: LinkDown}, nics[0])
+}
+
+func TestSetNICMAC(t *testing.T) {
+	pm := newMockProcessManager(true)
+	pm.args = []string{"-netdev", "user,id=net0", "-device", "virtio-net-pci,netdev=net0,id=nic0,mac=52:54:00:12:34:56"}
+	m := NewWithProcess(newNICQMPClient(), pm)
+
+	require.NoError(t, m.SetNICMAC("nic0", "52:54:00:AA:BB:CC"))
+	assert.Equal(t, "virtio-net-pci,netdev=net0,id=nic0,mac=52:54:00:aa:bb:cc", pm.args[3])
+
+	// The running VM keeps its MAC until the next power on
+	nics, err := m.GetNICs()
+	require.NoError(t, err)
+	assert.Equal(t, "52:54:00:12:34:56", nics[0].MAC)
+	assert.Equal(t, "52:54:00:aa:bb:cc", nics[0].PendingMAC)
+
+	assert.ErrorIs(t, m.SetNICMAC("nic0", "01:00:5e:00:00:01"), ErrInvalidMAC)
+	assert.ErrorIs(t, m.SetNICMAC("nic0", "bogus"), ErrInvalidMAC)
+	assert.ErrorIs(t, m.SetNICMAC("pci-01-00-0", "52:54:00:aa:bb:cd"), ErrNoNICArgument)
+	assert.ErrorIs(t, m.SetNICMAC("nic9", "52:54:00:aa:bb:cd"), ErrNICNotFound)
+}
+
+func TestSetNICLink(t *testing.T) {
+	client := newNICQMPClient()
+	m := NewWithProcess(client, newMockProcessManager(true))
+
+	// A QEMU the BMC started has every link up
+	nics, err := m.GetNICs()
+	require.NoError(t, err)
+	assert.Equal(t, LinkUp, nics[0].Link)
+	assert.Equal(t, LinkUp, nics[1].Link)
+
+	require.NoError(t, m.SetNICLink("nic0", false))
+	assert.Contains(t, client.calls, "SetLink:nic0:false")
+	nics, _ = m.GetNICs()
+	assert.Equal(t, LinkDown, nics[0].Link)
+	assert.True(t, nics[0].LinkDisabled)
+	assert.Equal(t, LinkUp, nics[1].Link)
+
+	require.NoError(t, m.SetNICLink("nic0", true))
+	nics, _ = m.GetNICs()
+	assert.Equal(t, LinkUp, nics[0].Link)
+	assert.False(t, nics[0].LinkDisabled)
+
+	// A paused VM has no link
+	client.status = qmp.StatusPaused
+	nics, _ = m.GetNICs()
+	assert.Equal(t, LinkDown, nics[0].Link)
+
+	assert.ErrorIs(t, m.SetNICLink("pci-01-00-0", false), ErrNoNICArgument)
+	assert.ErrorIs(t, m.SetNICLink("nic9", false), ErrNICNotFound)
+}
+
+func TestSetNICLink_Legacy(t *testing.T) {
+	client := newNICQMPClient()
+	m := New(client)
+
+	require.NoError(t, m.SetNICLink("nic0", false))
+	nics, err := m.GetNICs()
+	require.NoError(t, err)
+	assert.Equal(t, LinkDown, nics[0].Link)
+	assert.Equal(t, LinkUnknown, nics[1].Link)
+
+	// Powered off in legacy mode is a stopped VM
+	client.status = qmp.StatusPaused
+	nics, _ = m.GetNICs()
+	assert.Equal(t, LinkDown, nics[1].Link)
+}
+
+func TestSetNICLink_PoweredOff(t *testing.T) {
+	m := NewWithProcess(newNICQMPClient(), newMockProcessManager(false))
+	assert.ErrorIs(t, m.SetNICLink("nic0", false), ErrNotRunning)
+}
+
+func TestSetNICMAC_LegacyMode(t *testing.T) {
+	m := New(newNICQMPClient())
+	assert.ErrorIs(t, m.SetNICMAC("nic0", "52:54:00:aa:bb:cc"), ErrNotSupported)
+}
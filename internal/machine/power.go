@@ -0,0 +1,161 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+// ErrGuestAgentUnavailable is returned for operations that need the
+// qemu-guest-agent when none is configured.
+var ErrGuestAgentUnavailable = errors.New("qemu-guest-agent is not configured")
+
+// suspendTimeout bounds the wait for the guest to reach ACPI S3.
+var suspendTimeout = 30 * time.Second
+
+// resetExtended performs the reset types that behave the same in legacy and
+// process mode. ok is false for the other types.
+func (m *Machine) resetExtended(resetType string) (ok bool, err error) {
+	switch resetType {
+	case "Nmi":
+		return true, m.injectNMI()
+	case "PushPowerButton":
+		return true, m.pushPowerButton()
+	case "Pause":
+		return true, m.pause()
+	case "Resume":
+		return true, m.resume()
+	case "Suspend":
+		return true, m.suspend()
+	}
+	return false, nil
+}
+
+// checkPoweredOn fails unless the VM is powered on.
+func (m *Machine) checkPoweredOn() error {
+	state, err := m.GetPowerState()
+	if err != nil {
+		return err
+	}
+	if state != PowerOn {
+		return ErrNotRunning
+	}
+	return nil
+}
+
+// injectNMI raises a non-maskable interrupt in the guest, which e.g.
+// triggers a kdump crash dump.
+func (m *Machine) injectNMI() error {
+	if err := m.checkPoweredOn(); err != nil {
+		return err
+	}
+	return m.qmpClient.InjectNMI()
+}
+
+// pushPowerButton presses the power button: a suspended guest wakes up, a
+// powered-on guest gets an ACPI power button event and a powered-off VM is
+// turned on.
+func (m *Machine) pushPowerButton() error {
+	status, err := m.GetQMPStatus()
+	if err != nil {
+		return err
+	}
+	if status == qmp.StatusSuspended {
+		return m.qmpClient.SystemWakeup()
+	}
+	state, err := m.GetPowerState()
+	if err != nil {
+		return err
+	}
+	if state == PowerOff {
+		return m.Reset("On")
+	}
+	return m.qmpClient.SystemPowerdown()
+}
+
+// pause stops the guest CPUs.
+func (m *Machine) pause() error {
+	state, err := m.GetPowerState()
+	if err != nil {
+		return err
+	}
+	switch state {
+	case PowerPaused:
+		return nil
+	case PowerOn:
+	default:
+		return ErrNotRunning
+	}
+	if err := m.qmpClient.Stop(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.paused = true
+	m.mu.Unlock()
+	return nil
+}
+
+// resume continues a paused guest or wakes a suspended one.
+func (m *Machine) resume() error {
+	state, err := m.GetPowerState()
+	if err != nil {
+		return err
+	}
+	if state != PowerOn && state != PowerPaused && state != PowerSuspended {
+		return ErrNotRunning
+	}
+	m.mu.Lock()
+	m.paused = false
+	m.mu.Unlock()
+
+	status, err := m.GetQMPStatus()
+	if err != nil {
+		return err
+	}
+	switch status {
+	case qmp.StatusRunning:
+		return nil
+	case qmp.StatusSuspended:
+		return m.qmpClient.SystemWakeup()
+	}
+	return m.qmpClient.Cont()
+}
+
+// suspend asks the guest, through the guest agent, to enter ACPI S3 and
+// waits until QEMU reports it suspended.
+func (m *Machine) suspend() error {
+	m.mu.RLock()
+	ga := m.guestAgent
+	m.mu.RUnlock()
+	if ga == nil {
+		return ErrGuestAgentUnavailable
+	}
+
+	status, err := m.GetQMPStatus()
+	if err != nil {
+		return err
+	}
+	switch status {
+	case qmp.StatusSuspended:
+		return nil
+	case qmp.StatusRunning:
+	default:
+		return ErrNotRunning
+	}
+
+	if err := ga.SuspendRAM(); err != nil {
+		return fmt.Errorf("suspending guest: %w", err)
+	}
+	deadline := time.Now().Add(suspendTimeout)
+	for {
+		if status, err := m.qmpClient.QueryStatus(); err == nil && status == qmp.StatusSuspended {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("guest did not suspend within %s", suspendTimeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
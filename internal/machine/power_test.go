@@ -0,0 +1,174 @@
+package machine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func TestReset_Nmi(t *testing.T) {
+	qmpMock := newMockQMPClient(qmp.StatusRunning)
+	m := NewWithProcess(qmpMock, newMockProcessManager(true))
+
+	require.NoError(t, m.Reset("Nmi"))
+	assert.Contains(t, qmpMock.Calls(), "InjectNMI")
+
+	m = NewWithProcess(qmpMock, newMockProcessManager(false))
+	assert.ErrorIs(t, m.Reset("Nmi"), ErrNotRunning)
+}
+
+func TestReset_PowerCycle(t *testing.T) {
+	mock := newMockQMPClient(qmp.StatusRunning)
+	m := New(mock)
+	require.NoError(t, m.Reset("PowerCycle"))
+	assert.Equal(t, []string{"Stop", "SystemReset", "Cont"}, mock.Calls())
+
+	qmpMock := newMockQMPClient(qmp.StatusRunning)
+	pm := newMockProcessManager(true)
+	qmpMock.onQuit = func() { pm.Stop(0) }
+	m = NewWithProcess(qmpMock, pm)
+	require.NoError(t, m.Reset("PowerCycle"))
+	assert.Contains(t, qmpMock.Calls(), "Quit")
+	assert.Equal(t, []string{"Stop", "WaitForExit", "Start"}, pm.calls)
+
+	// A powered-off VM is just turned on
+	pm = newMockProcessManager(false)
+	m = NewWithProcess(newMockQMPClient(qmp.StatusRunning), pm)
+	require.NoError(t, m.Reset("PowerCycle"))
+	assert.Equal(t, []string{"Start"}, pm.calls)
+}
+
+func TestReset_PushPowerButton(t *testing.T) {
+	tests := []struct {
+		name    string
+		running bool
+		status  qmp.Status
+		call    string
+	}{
+		{"on sends ACPI power button", true, qmp.StatusRunning, "SystemPowerdown"},
+		{"suspended wakes up", true, qmp.StatusSuspended, "SystemWakeup"},
+		{"off powers on", false, qmp.StatusRunning, "Connect"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qmpMock := newMockQMPClient(tt.status)
+			pm := newMockProcessManager(tt.running)
+			m := NewWithProcess(qmpMock, pm)
+
+			require.NoError(t, m.Reset("PushPowerButton"))
+			assert.Contains(t, qmpMock.Calls(), tt.call)
+			assert.Equal(t, !tt.running, len(pm.startCalls) == 1)
+		})
+	}
+
+	// Legacy mode: a stopped VM is continued
+	mock := newMockQMPClient(qmp.StatusPaused)
+	require.NoError(t, New(mock).Reset("PushPowerButton"))
+	assert.Contains(t, mock.Calls(), "Cont")
+}
+
+func TestReset_PauseResume(t *testing.T) {
+	qmpMock := newMockQMPClient(qmp.StatusRunning)
+	m := NewWithProcess(qmpMock, newMockProcessManager(true))
+
+	require.NoError(t, m.Reset("Pause"))
+	assert.Equal(t, qmp.StatusPaused, qmpMock.status)
+	require.NoError(t, m.Reset("Resume"))
+	assert.Equal(t, qmp.StatusRunning, qmpMock.status)
+	assert.Contains(t, qmpMock.Calls(), "Cont")
+
+	qmpMock.status = qmp.StatusSuspended
+	require.NoError(t, m.Reset("Resume"))
+	assert.Contains(t, qmpMock.Calls(), "SystemWakeup")
+
+	m = NewWithProcess(qmpMock, newMockProcessManager(false))
+	assert.ErrorIs(t, m.Reset("Pause"), ErrNotRunning)
+	assert.ErrorIs(t, m.Reset("Resume"), ErrNotRunning)
+}
+
+func TestReset_Suspend(t *testing.T) {
+	qmpMock := newMockQMPClient(qmp.StatusRunning)
+	m := NewWithProcess(qmpMock, newMockProcessManager(true))
+	assert.ErrorIs(t, m.Reset("Suspend"), ErrGuestAgentUnavailable)
+
+	m.SetGuestAgent(&mockGuestAgent{suspend: func() { qmpMock.status = qmp.StatusSuspended }})
+	require.NoError(t, m.Reset("Suspend"))
+	assert.Equal(t, qmp.StatusSuspended, qmpMock.status)
+
+	// Already suspended
+	require.NoError(t, m.Reset("Suspend"))
+}
+
+func TestReset_Suspend_Timeout(t *testing.T) {
+	old := suspendTimeout
+	suspendTimeout = 200 * time.Millisecond
+	defer func() { suspendTimeout = old }()
+
+	m := NewWithProcess(newMockQMPClient(qmp.StatusRunning), newMockProcessManager(true))
+	m.SetGuestAgent(&mockGuestAgent{})
+	assert.Error(t, m.Reset("Suspend"))
+}
+
+func TestProcessMode_GetPowerState_States(t *testing.T) {
+	tests := []struct {
+		status qmp.Status
+		want   PowerState
+	}{
+		{qmp.StatusRunning, PowerOn},
+		{qmp.StatusSuspended, PowerSuspended},
+		{qmp.StatusPaused, PowerPaused},
+		{"io-error", PowerPaused},
+		{qmp.StatusPrelaunch, PowerPoweringOn},
+		{qmp.StatusInMigrate, PowerPoweringOn},
+	}
+	for _, tt := range tests {
+		m := NewWithProcess(newMockQMPClient(tt.status), newMockProcessManager(true))
+		state, err := m.GetPowerState()
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, state, string(tt.status))
+	}
+}
+
+func TestProcessMode_GetPowerState_Transitions(t *testing.T) {
+	qmpMock := newMockQMPClient(qmp.StatusRunning)
+	pm := newMockProcessManager(false)
+	m := NewWithProcess(qmpMock, pm)
+
+	end := m.beginTransition(PowerPoweringOn)
+	state, _ := m.GetPowerState()
+	assert.Equal(t, PowerPoweringOn, state, "before QEMU runs")
+	pm.running = true
+	state, _ = m.GetPowerState()
+	assert.Equal(t, PowerPoweringOn, state, "before QMP is ready")
+	end()
+	state, _ = m.GetPowerState()
+	assert.Equal(t, PowerOn, state)
+
+	end = m.beginTransition(PowerPoweringOff)
+	state, _ = m.GetPowerState()
+	assert.Equal(t, PowerPoweringOff, state)
+	pm.running = false
+	state, _ = m.GetPowerState()
+	assert.Equal(t, PowerOff, state, "once QEMU exited")
+	end()
+}
+
+func TestLegacyMode_GetPowerState_Paused(t *testing.T) {
+	mock := newMockQMPClient(qmp.StatusRunning)
+	m := New(mock)
+
+	require.NoError(t, m.Reset("Pause"))
+	state, err := m.GetPowerState()
+	require.NoError(t, err)
+	assert.Equal(t, PowerPaused, state)
+
+	// Legacy ForceOff stops the VM too, but reports Off
+	require.NoError(t, m.Reset("ForceOff"))
+	state, err = m.GetPowerState()
+	require.NoError(t, err)
+	assert.Equal(t, PowerOff, state)
+	assert.ErrorIs(t, m.Reset("Resume"), ErrNotRunning)
+}
@@ -0,0 +1,343 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+// Volume errors
+var (
+	ErrVolumesDisabled = errors.New("volume management is not configured")
+	ErrVolumeExists    = errors.New("volume already exists")
+	ErrVolumeNotFound  = errors.New("volume not found")
+	ErrInvalidVolume   = errors.New("invalid volume name or size")
+	ErrNotRunning      = errors.New("VM is not running")
+)
+
+// VolumePrefix prefixes the node and device names of volumes created by
+// CreateVolume, so they can be told apart from disks on the command line.
+const VolumePrefix = "rfvol-"
+
+const (
+	volumeFormat     = "qcow2"
+	volumeDevice     = "virtio-blk-pci"
+	blockJobTimeout  = 60 * time.Second
+	deviceDelTimeout = 30 * time.Second
+)
+
+var volumeNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Drive is a block device of the VM
+type Drive struct {
+	ID            string // device name, qdev ID or node name
+	NodeName      string
+	File          string
+	Format        string
+	BackingFile   string
+	CapacityBytes int64
+	ReadOnly      bool
+	Removable     bool
+	Inserted      bool
+	Managed       bool // created by CreateVolume
+
+	ReadBytes       int64
+	WriteBytes      int64
+	ReadOperations  int64
+	WriteOperations int64
+}
+
+// SetVolumeStore configures the directory volume images are created in and
+// the bus their devices are hot-plugged on ("" for QEMU's choice). Volume
+// management is disabled while dir is empty.
+func (m *Machine) SetVolumeStore(dir, bus string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.volumeDir = dir
+	m.volumeBus = bus
+}
+
+// GetDrives returns the block devices of the running VM with their I/O
+// statistics.
+func (m *Machine) GetDrives() ([]Drive, error) {
+	if err := m.checkRunning(); err != nil {
+		return nil, err
+	}
+	blocks, err := m.qmpClient.QueryBlock()
+	if err != nil {
+		return nil, fmt.Errorf("querying block devices: %w", err)
+	}
+	stats, err := m.qmpClient.QueryBlockstats()
+	if err != nil {
+		return nil, fmt.Errorf("querying block statistics: %w", err)
+	}
+
+	drives := make([]Drive, 0, len(blocks))
+	for _, b := range blocks {
+		d := Drive{
+			ID:        blockID(b),
+			Removable: b.Removable,
+		}
+		if b.Inserted != nil {
+			d.Inserted = true
+			d.NodeName = b.Inserted.NodeName
+			d.File = b.Inserted.File
+			d.Format = b.Inserted.Image.Format
+			if d.Format == "" {
+				d.Format = b.Inserted.Drv
+			}
+			d.BackingFile = b.Inserted.BackingFile
+			if d.BackingFile == "" {
+				d.BackingFile = b.Inserted.Image.BackingFilename
+			}
+			d.CapacityBytes = b.Inserted.Image.VirtualSize
+			d.ReadOnly = b.Inserted.RO
+		}
+		d.Managed = strings.HasPrefix(d.ID, VolumePrefix)
+
+		for _, st := range stats {
+			if (b.Device != "" && st.Device == b.Device) || (b.QDev != "" && st.QDev == b.QDev) {
+				d.ReadBytes = st.Stats.RdBytes
+				d.WriteBytes = st.Stats.WrBytes
+				d.ReadOperations = st.Stats.RdOperations
+				d.WriteOperations = st.Stats.WrOperations
+				break
+			}
+		}
+		drives = append(drives, d)
+	}
+	return drives, nil
+}
+
+// blockID names a block device by its legacy drive name, or else by the ID
+// of the device it is attached to.
+func blockID(b qmp.BlockInfo) string {
+	if b.Device != "" {
+		return b.Device
+	}
+	id := strings.TrimSuffix(b.QDev, "/virtio-backend")
+	id = strings.TrimPrefix(id, "/machine/peripheral/")
+	if id == "" && b.Inserted != nil {
+		id = b.Inserted.NodeName
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+// CreateVolume creates a qcow2 image of sizeBytes in the volume directory
+// and hot-plugs it into the running VM. It returns the new drive's ID.
+func (m *Machine) CreateVolume(name string, sizeBytes int64) (string, error) {
+	dir, bus := m.volumeStore()
+	if dir == "" {
+		return "", ErrVolumesDisabled
+	}
+	if !volumeNameRe.MatchString(name) || sizeBytes <= 0 {
+		return "", ErrInvalidVolume
+	}
+	if err := m.checkRunning(); err != nil {
+		return "", err
+	}
+
+	id := VolumePrefix + name
+	path := filepath.Join(dir, name+"."+volumeFormat)
+	if _, err := os.Stat(path); err == nil {
+		return "", ErrVolumeExists
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("creating volume directory: %w", err)
+	}
+
+	if err := m.formatVolume(id, path, sizeBytes); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	if err := m.attachVolume(id, path, bus); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return id, nil
+}
+
+// formatVolume creates the image file and formats it with blockdev-create.
+func (m *Machine) formatVolume(id, path string, sizeBytes int64) error {
+	if err := m.runBlockJob(id+"-create-file", map[string]interface{}{
+		"driver":   "file",
+		"filename": path,
+		"size":     0,
+	}); err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+
+	// The format driver writes through a temporary protocol node
+	fileNode := id + "-fmt"
+	if err := m.qmpClient.BlockdevAdd(map[string]interface{}{
+		"driver":    "file",
+		"node-name": fileNode,
+		"filename":  path,
+	}); err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer m.qmpClient.BlockdevDel(fileNode)
+
+	if err := m.runBlockJob(id+"-create-fmt", map[string]interface{}{
+		"driver": volumeFormat,
+		"file":   fileNode,
+		"size":   sizeBytes,
+	}); err != nil {
+		return fmt.Errorf("formatting %s: %w", path, err)
+	}
+	return nil
+}
+
+// attachVolume opens a volume image and hot-plugs a disk device for it.
+func (m *Machine) attachVolume(id, path, bus string) error {
+	if err := m.qmpClient.BlockdevAdd(map[string]interface{}{
+		"driver":    volumeFormat,
+		"node-name": id,
+		"file": map[string]interface{}{
+			"driver":   "file",
+			"filename": path,
+		},
+	}); err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	props := map[string]interface{}{"drive": id}
+	if bus != "" {
+		props["bus"] = bus
+	}
+	if err := m.qmpClient.DeviceAdd(volumeDevice, id, props); err != nil {
+		m.qmpClient.BlockdevDel(id)
+		return fmt.Errorf("hot-plugging %s: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteVolume unplugs a volume created by CreateVolume and removes its
+// image. The guest must release the device.
+func (m *Machine) DeleteVolume(id string) error {
+	dir, _ := m.volumeStore()
+	if dir == "" {
+		return ErrVolumesDisabled
+	}
+	name := strings.TrimPrefix(id, VolumePrefix)
+	if !strings.HasPrefix(id, VolumePrefix) || !volumeNameRe.MatchString(name) {
+		return ErrVolumeNotFound
+	}
+	if err := m.checkRunning(); err != nil {
+		return err
+	}
+
+	drives, err := m.GetDrives()
+	if err != nil {
+		return err
+	}
+	if !hasDrive(drives, id) {
+		return ErrVolumeNotFound
+	}
+
+	if err := m.qmpClient.DeviceDel(id); err != nil {
+		return fmt.Errorf("unplugging %s: %w", id, err)
+	}
+	deadline := time.Now().Add(deviceDelTimeout)
+	for {
+		drives, err := m.GetDrives()
+		if err == nil && !hasDrive(drives, id) {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("unplugging %s: guest did not release the device within %s", id, deviceDelTimeout)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+
+	if err := m.qmpClient.BlockdevDel(id); err != nil {
+		log.Printf("Volume %s: blockdev-del failed: %v", id, err)
+	}
+	if err := os.Remove(filepath.Join(dir, name+"."+volumeFormat)); err != nil {
+		return fmt.Errorf("removing volume image: %w", err)
+	}
+	return nil
+}
+
+// reattachVolumes hot-plugs the volumes in the volume directory after QEMU
+// has been started, so they persist across power cycles like real disks.
+func (m *Machine) reattachVolumes() {
+	dir, bus := m.volumeStore()
+	if dir == "" {
+		return
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, "*."+volumeFormat))
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), "."+volumeFormat)
+		if !volumeNameRe.MatchString(name) {
+			continue
+		}
+		if err := m.attachVolume(VolumePrefix+name, path, bus); err != nil {
+			log.Printf("Volume %s: re-attach failed: %v", name, err)
+		}
+	}
+}
+
+// runBlockJob runs blockdev-create and waits for the job to conclude.
+func (m *Machine) runBlockJob(jobID string, options interface{}) error {
+	if err := m.qmpClient.BlockdevCreate(jobID, options); err != nil {
+		return err
+	}
+	defer m.qmpClient.JobDismiss(jobID)
+
+	deadline := time.Now().Add(blockJobTimeout)
+	for {
+		jobs, err := m.qmpClient.QueryJobs()
+		if err != nil {
+			return err
+		}
+		for _, j := range jobs {
+			if j.ID != jobID || j.Status != "concluded" {
+				continue
+			}
+			if j.Error != "" {
+				return errors.New(j.Error)
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("job %s timed out after %s", jobID, blockJobTimeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func (m *Machine) volumeStore() (dir, bus string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.volumeDir, m.volumeBus
+}
+
+// checkRunning fails in process mode while QEMU is not running.
+func (m *Machine) checkRunning() error {
+	if m.processManager != nil && !m.processManager.IsRunning() {
+		return ErrNotRunning
+	}
+	return nil
+}
+
+func hasDrive(drives []Drive, id string) bool {
+	for _, d := range drives {
+		if d.ID == id {
+			return true
+		}
+	}
+	return false
+}
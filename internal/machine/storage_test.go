@@ -0,0 +1,130 @@
+package machine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func TestGetDrives(t *testing.T) {
+	client := newMockQMPClient(qmp.StatusRunning)
+	closed := false
+	client.blocks = []qmp.BlockInfo{
+		{
+			QDev: "/machine/peripheral/disk0/virtio-backend",
+			Inserted: &qmp.BlockInserted{
+				File: "/vm/disk.qcow2", NodeName: "disk0", RO: false, Drv: "qcow2",
+				BackingFile: "/vm/base.qcow2",
+				Image:       qmp.ImageInfo{Format: "qcow2", VirtualSize: 10 << 30},
+			},
+		},
+		{Device: "ide0-cd0", Removable: true, TrayOpen: &closed},
+	}
+	var st qmp.BlockStats
+	st.QDev = "/machine/peripheral/disk0/virtio-backend"
+	st.Stats.RdBytes = 4096
+	client.blockStats = []qmp.BlockStats{st}
+	m := New(client)
+
+	drives, err := m.GetDrives()
+	require.NoError(t, err)
+	require.Len(t, drives, 2)
+
+	assert.Equal(t, "disk0", drives[0].ID)
+	assert.Equal(t, "qcow2", drives[0].Format)
+	assert.Equal(t, "/vm/base.qcow2", drives[0].BackingFile)
+	assert.Equal(t, int64(10<<30), drives[0].CapacityBytes)
+	assert.Equal(t, int64(4096), drives[0].ReadBytes)
+	assert.True(t, drives[0].Inserted)
+	assert.False(t, drives[0].Managed)
+
+	assert.Equal(t, "ide0-cd0", drives[1].ID)
+	assert.True(t, drives[1].Removable)
+	assert.False(t, drives[1].Inserted)
+}
+
+func TestGetDrives_ProcessOff(t *testing.T) {
+	m := NewWithProcess(newMockQMPClient(qmp.StatusShutdown), newMockProcessManager(false))
+
+	_, err := m.GetDrives()
+	assert.ErrorIs(t, err, ErrNotRunning)
+}
+
+func TestCreateDeleteVolume(t *testing.T) {
+	dir := t.TempDir()
+	client := newMockQMPClient(qmp.StatusRunning)
+	m := New(client)
+
+	_, err := m.CreateVolume("data", 1<<30)
+	assert.ErrorIs(t, err, ErrVolumesDisabled)
+
+	m.SetVolumeStore(dir, "hotplug0")
+	_, err = m.CreateVolume("bad/name", 1<<30)
+	assert.ErrorIs(t, err, ErrInvalidVolume)
+
+	id, err := m.CreateVolume("data", 1<<30)
+	require.NoError(t, err)
+	assert.Equal(t, "rfvol-data", id)
+	assert.Contains(t, client.Calls(), "BlockdevCreate")
+	assert.Contains(t, client.Calls(), "BlockdevDel:rfvol-data-fmt")
+	assert.Contains(t, client.Calls(), "DeviceAdd:rfvol-data")
+	assert.Empty(t, client.jobs, "jobs must be dismissed")
+
+	drives, err := m.GetDrives()
+	require.NoError(t, err)
+	require.Len(t, drives, 1)
+	assert.True(t, drives[0].Managed)
+
+	// The mock QEMU does not write the image file
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data.qcow2"), nil, 0o644))
+	_, err = m.CreateVolume("data", 1<<30)
+	assert.ErrorIs(t, err, ErrVolumeExists)
+
+	require.NoError(t, m.DeleteVolume("rfvol-data"))
+	assert.Contains(t, client.Calls(), "DeviceDel:rfvol-data")
+	assert.Contains(t, client.Calls(), "BlockdevDel:rfvol-data")
+	_, err = os.Stat(filepath.Join(dir, "data.qcow2"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.ErrorIs(t, m.DeleteVolume("rfvol-data"), ErrVolumeNotFound)
+	assert.ErrorIs(t, m.DeleteVolume("disk0"), ErrVolumeNotFound)
+}
+
+func TestCreateVolume_JobError(t *testing.T) {
+	client := newMockQMPClient(qmp.StatusRunning)
+	client.jobErr = "Could not create file: Permission denied"
+	m := New(client)
+	m.SetVolumeStore(t.TempDir(), "")
+
+	_, err := m.CreateVolume("data", 1<<30)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Permission denied")
+	assert.NotContains(t, client.Calls(), "DeviceAdd:rfvol-data")
+}
+
+func TestCreateVolume_HotplugError(t *testing.T) {
+	client := newMockQMPClient(qmp.StatusRunning)
+	client.deviceErr = errors.New("Bus 'pcie.0' does not support hotplugging")
+	m := New(client)
+	m.SetVolumeStore(t.TempDir(), "")
+
+	_, err := m.CreateVolume("data", 1<<30)
+	require.Error(t, err)
+	assert.Contains(t, client.Calls(), "BlockdevDel:rfvol-data")
+}
+
+func TestReattachVolumesOnPowerOn(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data.qcow2"), nil, 0o644))
+	client := newMockQMPClient(qmp.StatusRunning)
+	m := NewWithProcess(client, newMockProcessManager(false))
+	m.SetVolumeStore(dir, "")
+
+	require.NoError(t, m.Reset("On"))
+	assert.Contains(t, client.Calls(), "DeviceAdd:rfvol-data")
+}
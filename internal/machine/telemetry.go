@@ -0,0 +1,208 @@
+package machine
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// A VM has no sensors, so the chassis environment is simulated from the
+// power state and the vCPU load, the way a real server heats up under load.
+
+// DefaultInletCelsius is the simulated inlet air temperature unless set
+// with SetInletTemperature
+const DefaultInletCelsius = 22.0
+
+// ChassisFans is the number of simulated system fans
+const ChassisFans = 2
+
+// Simulation model parameters
+const (
+	fanMinRPM         = 2000
+	fanMaxRPM         = 12000
+	standbyWatts      = 10  // BMC and standby rail while off
+	baseWatts         = 50  // board, disks and NICs while on
+	wattsPerGiB       = 0.4 // memory
+	idleWattsPerVCPU  = 1.5
+	extraWattsPerVCPU = 8.5 // at full load
+)
+
+// loadSampleInterval is the shortest time the vCPU load is measured over.
+// The first measurement waits this long between its two samples.
+const loadSampleInterval = 200 * time.Millisecond
+
+// haltStats are the KVM vCPU statistics counting the nanoseconds a vCPU was
+// idle, halted or polling before halting.
+var haltStats = []string{"halt_wait_ns", "halt_poll_success_ns", "halt_poll_fail_ns"}
+
+// Environment is a snapshot of the simulated chassis sensors
+type Environment struct {
+	PowerState   PowerState
+	VCPUs        int
+	CPULoad      float64 // average vCPU utilization, 0 to 1
+	LoadMeasured bool    // CPULoad is from KVM statistics; otherwise it is assumed idle
+	InletCelsius float64
+	CPUCelsius   float64
+	BoardCelsius float64
+	FanRPMs      []int // one per chassis fan, 0 while off
+	PowerWatts   float64
+}
+
+// Powered reports whether the VM draws more than standby power. A guest
+// suspended to RAM does not.
+func (e Environment) Powered() bool {
+	return e.PowerState != PowerOff && e.PowerState != PowerSuspended
+}
+
+// loadSample is the idle time of each vCPU, by QOM path, at a point in time
+type loadSample struct {
+	at   time.Time
+	idle map[string]uint64
+}
+
+// loadSampler measures the vCPU load between two query-stats samples
+type loadSampler struct {
+	mu       sync.Mutex
+	last     *loadSample
+	load     float64
+	measured bool
+	now      func() time.Time
+	sleep    func(time.Duration)
+}
+
+func newLoadSampler() *loadSampler {
+	return &loadSampler{now: time.Now, sleep: time.Sleep}
+}
+
+// SetInletTemperature sets the simulated inlet air temperature in degrees
+// Celsius. Raising it makes the whole chassis run hot.
+func (m *Machine) SetInletTemperature(celsius float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inletCelsius = celsius
+}
+
+// GetEnvironment returns the simulated temperatures, fan speeds and power
+// draw of the chassis.
+func (m *Machine) GetEnvironment() (Environment, error) {
+	ps, err := m.GetPowerState()
+	if err != nil {
+		return Environment{}, err
+	}
+	env := Environment{PowerState: ps}
+	var memoryBytes uint64
+	if inv, err := m.GetInventory(); err == nil {
+		env.VCPUs = len(inv.CPUs)
+		memoryBytes = inv.MemoryBytes
+	}
+
+	// Halted vCPUs do not count idle time, so only a running VM is
+	// sampled and a pause starts the measurement over.
+	if ps == PowerOn {
+		env.CPULoad, env.LoadMeasured = m.cpuLoad()
+	} else {
+		m.load.reset()
+	}
+
+	m.mu.RLock()
+	env.InletCelsius = m.inletCelsius
+	m.mu.RUnlock()
+	simulateEnvironment(&env, memoryBytes)
+	return env, nil
+}
+
+// simulateEnvironment derives the sensor readings from the power state,
+// load and inlet temperature of env.
+func simulateEnvironment(env *Environment, memoryBytes uint64) {
+	env.FanRPMs = make([]int, ChassisFans)
+	if !env.Powered() {
+		env.CPUCelsius = env.InletCelsius
+		env.BoardCelsius = env.InletCelsius + 2
+		env.PowerWatts = standbyWatts
+		return
+	}
+
+	load := env.CPULoad
+	env.CPUCelsius = env.InletCelsius + 13 + 50*load
+	env.BoardCelsius = env.InletCelsius + 5 + 10*load
+	env.PowerWatts = baseWatts + wattsPerGiB*float64(memoryBytes)/(1<<30) +
+		float64(env.VCPUs)*(idleWattsPerVCPU+extraWattsPerVCPU*load)
+
+	// Fans speed up from their minimum as the CPU passes 40 degrees and
+	// run flat out from 80.
+	duty := min(max((env.CPUCelsius-40)/40, 0), 1)
+	for i := range env.FanRPMs {
+		env.FanRPMs[i] = fanMinRPM + int(duty*(fanMaxRPM-fanMinRPM))
+	}
+}
+
+// cpuLoad returns the average vCPU load since the previous call, and whether
+// it could be measured. Calls closer together than loadSampleInterval
+// return the previous result.
+func (m *Machine) cpuLoad() (float64, bool) {
+	s := m.load
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last != nil && s.now().Sub(s.last.at) < loadSampleInterval {
+		return s.load, s.measured
+	}
+	cur, ok := m.idleSample(s.now())
+	if !ok {
+		s.last, s.load, s.measured = nil, 0, false
+		return 0, false
+	}
+	if s.last == nil {
+		s.last = cur
+		s.sleep(loadSampleInterval)
+		if cur, ok = m.idleSample(s.now()); !ok {
+			s.last, s.load, s.measured = nil, 0, false
+			return 0, false
+		}
+	}
+
+	elapsed := cur.at.Sub(s.last.at).Nanoseconds()
+	total, n := 0.0, 0
+	for path, idle := range cur.idle {
+		prev, ok := s.last.idle[path]
+		if !ok || idle < prev || elapsed <= 0 {
+			continue // hot-plugged vCPU or a restarted QEMU
+		}
+		total += 1 - min(float64(idle-prev)/float64(elapsed), 1)
+		n++
+	}
+	s.last = cur
+	if n == 0 {
+		s.load, s.measured = 0, false
+		return 0, false
+	}
+	s.load, s.measured = total/float64(n), true
+	return s.load, true
+}
+
+// idleSample queries the idle time of each vCPU from KVM. It fails without
+// KVM or before QEMU 7.1.
+func (m *Machine) idleSample(at time.Time) (*loadSample, bool) {
+	results, err := m.qmpClient.QueryStats("vcpu", "kvm", haltStats)
+	if err != nil || len(results) == 0 {
+		return nil, false
+	}
+	sample := &loadSample{at: at, idle: make(map[string]uint64)}
+	for _, r := range results {
+		var idle uint64
+		for _, st := range r.Stats {
+			var v uint64
+			if err := json.Unmarshal(st.Value, &v); err == nil {
+				idle += v
+			}
+		}
+		sample.idle[r.QOMPath] = idle
+	}
+	return sample, true
+}
+
+func (s *loadSampler) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last, s.load, s.measured = nil, 0, false
+}
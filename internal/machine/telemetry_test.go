@@ -0,0 +1,172 @@
+package machine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+// setIdle makes query-stats report idleNs of halt_wait_ns for each vCPU.
+func setIdle(client *mockQMPClient, idleNs ...uint64) {
+	client.stats = nil
+	for i, ns := range idleNs {
+		client.stats = append(client.stats, qmp.StatsResult{
+			Provider: "kvm",
+			QOMPath:  fmt.Sprintf("/machine/unattached/device[%d]", i),
+			Stats:    []qmp.Stat{{Name: "halt_wait_ns", Value: json.RawMessage(strconv.FormatUint(ns, 10))}},
+		})
+	}
+}
+
+// fakeClock makes m measure the load against a clock advanced by sleeps.
+func fakeClock(m *Machine, onSleep func()) *time.Time {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	m.load.now = func() time.Time { return now }
+	m.load.sleep = func(d time.Duration) {
+		now = now.Add(d)
+		onSleep()
+	}
+	return &now
+}
+
+func TestGetEnvironment_MeasuredLoad(t *testing.T) {
+	client := newInventoryQMPClient()
+	setIdle(client, 0, 0, 0, 0)
+	m := New(client)
+	// 50ms idle of the 200ms sample interval
+	now := fakeClock(m, func() { setIdle(client, 50e6, 50e6, 50e6, 50e6) })
+
+	env, err := m.GetEnvironment()
+	require.NoError(t, err)
+	assert.Equal(t, PowerOn, env.PowerState)
+	assert.Equal(t, 4, env.VCPUs)
+	assert.True(t, env.LoadMeasured)
+	assert.InDelta(t, 0.75, env.CPULoad, 1e-9)
+	assert.InDelta(t, 72.5, env.CPUCelsius, 1e-9)
+	assert.InDelta(t, 34.5, env.BoardCelsius, 1e-9)
+	assert.InDelta(t, 50+0.4*5+4*(1.5+8.5*0.75), env.PowerWatts, 1e-9)
+	assert.Equal(t, []int{10125, 10125}, env.FanRPMs)
+
+	// Too soon for a new sample
+	setIdle(client, 250e6, 250e6, 250e6, 250e6)
+	env, err = m.GetEnvironment()
+	require.NoError(t, err)
+	assert.InDelta(t, 0.75, env.CPULoad, 1e-9)
+
+	// Idle for the whole second since the last sample
+	*now = now.Add(time.Second)
+	setIdle(client, 1050e6, 1050e6, 1050e6, 1050e6)
+	env, err = m.GetEnvironment()
+	require.NoError(t, err)
+	assert.True(t, env.LoadMeasured)
+	assert.InDelta(t, 0, env.CPULoad, 1e-9)
+	assert.InDelta(t, 35, env.CPUCelsius, 1e-9)
+	assert.Equal(t, []int{fanMinRPM, fanMinRPM}, env.FanRPMs)
+}
+
+func TestGetEnvironment_RestartedQEMU(t *testing.T) {
+	client := newInventoryQMPClient()
+	setIdle(client, 0)
+	m := New(client)
+	now := fakeClock(m, func() { setIdle(client, 100e6) })
+
+	env, err := m.GetEnvironment()
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, env.CPULoad, 1e-9)
+
+	// The counters of a new QEMU start over
+	*now = now.Add(time.Second)
+	setIdle(client, 10e6)
+	env, err = m.GetEnvironment()
+	require.NoError(t, err)
+	assert.False(t, env.LoadMeasured)
+}
+
+func TestGetEnvironment_WithoutStats(t *testing.T) {
+	client := newInventoryQMPClient()
+	client.statsErr = errors.New("QMP error: CommandNotFound: The command query-stats has not been found")
+	m := New(client)
+	fakeClock(m, func() {})
+
+	env, err := m.GetEnvironment()
+	require.NoError(t, err)
+	assert.False(t, env.LoadMeasured)
+	assert.Zero(t, env.CPULoad)
+	assert.InDelta(t, 35, env.CPUCelsius, 1e-9)
+	assert.InDelta(t, 50+0.4*5+4*1.5, env.PowerWatts, 1e-9)
+	assert.Equal(t, []int{fanMinRPM, fanMinRPM}, env.FanRPMs)
+}
+
+func TestGetEnvironment_Off(t *testing.T) {
+	client := newInventoryQMPClient()
+	client.status = qmp.StatusShutdown
+	m := New(client)
+
+	env, err := m.GetEnvironment()
+	require.NoError(t, err)
+	assert.Equal(t, PowerOff, env.PowerState)
+	assert.False(t, env.Powered())
+	assert.InDelta(t, standbyWatts, env.PowerWatts, 1e-9)
+	assert.Equal(t, []int{0, 0}, env.FanRPMs)
+	assert.InDelta(t, DefaultInletCelsius, env.CPUCelsius, 1e-9)
+	assert.NotContains(t, client.calls, "QueryStats")
+}
+
+func TestGetEnvironment_Paused(t *testing.T) {
+	client := newInventoryQMPClient()
+	client.status = qmp.StatusPaused
+	m := New(client)
+	m.paused = true
+
+	env, err := m.GetEnvironment()
+	require.NoError(t, err)
+	assert.Equal(t, PowerPaused, env.PowerState)
+	assert.True(t, env.Powered())
+	assert.Zero(t, env.CPULoad)
+	assert.Equal(t, []int{fanMinRPM, fanMinRPM}, env.FanRPMs)
+	assert.NotContains(t, client.calls, "QueryStats")
+}
+
+func TestGetEnvironment_Suspended(t *testing.T) {
+	client := newInventoryQMPClient()
+	client.status = qmp.StatusSuspended
+	m := New(client)
+
+	env, err := m.GetEnvironment()
+	require.NoError(t, err)
+	assert.Equal(t, PowerSuspended, env.PowerState)
+	assert.False(t, env.Powered(), "a guest suspended to RAM draws standby power")
+	assert.InDelta(t, standbyWatts, env.PowerWatts, 1e-9)
+	assert.Equal(t, []int{0, 0}, env.FanRPMs)
+	assert.NotContains(t, client.calls, "QueryStats")
+}
+
+func TestSetInletTemperature(t *testing.T) {
+	client := newInventoryQMPClient()
+	client.statsErr = errors.New("not supported")
+	m := New(client)
+	m.SetInletTemperature(40)
+
+	env, err := m.GetEnvironment()
+	require.NoError(t, err)
+	assert.InDelta(t, 40, env.InletCelsius, 1e-9)
+	assert.InDelta(t, 53, env.CPUCelsius, 1e-9)
+	assert.InDelta(t, 45, env.BoardCelsius, 1e-9)
+	assert.Equal(t, []int{5250, 5250}, env.FanRPMs)
+}
+
+func TestGetEnvironment_QueryError(t *testing.T) {
+	client := newInventoryQMPClient()
+	client.queryErr = errors.New("not connected")
+	m := New(client)
+
+	_, err := m.GetEnvironment()
+	assert.Error(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/websocket"
 )
@@ -13,6 +14,17 @@ import (
 //go:embed static
 var staticFiles embed.FS
 
+// overlayFiles are qemu-bmc's additions to the noVNC UI, served under
+// overlayPrefix. The noVNC files themselves are downloaded at build time.
+//
+//go:embed overlay
+var overlayFiles embed.FS
+
+const overlayPrefix = "qemu-bmc/"
+
+// overlayScripts are added to vnc.html.
+const overlayScripts = `<script src="` + overlayPrefix + `media.js"></script>`
+
 var upgrader = websocket.Upgrader{
 	// Basic Auth handles security; allow all origins
 	CheckOrigin:     func(r *http.Request) bool { return true },
@@ -39,7 +51,45 @@ func (h *Handler) ServeFiles() http.Handler {
 		// Should never happen with a correct embed path
 		panic("novnc: failed to create sub-filesystem: " + err.Error())
 	}
-	return http.FileServer(http.FS(sub))
+	overlay, err := fs.Sub(overlayFiles, "overlay")
+	if err != nil {
+		panic("novnc: failed to create sub-filesystem: " + err.Error())
+	}
+	return serveFiles(sub, overlay)
+}
+
+// serveFiles serves static, with vnc.html loading the overlay scripts, and
+// overlay under overlayPrefix.
+func serveFiles(static, overlay fs.FS) http.Handler {
+	files := http.FileServer(http.FS(static))
+	overlayFiles := http.StripPrefix(overlayPrefix, http.FileServer(http.FS(overlay)))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		switch {
+		case path == "vnc.html":
+			page, err := fs.ReadFile(static, path)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(injectScripts(page))
+		case strings.HasPrefix(path, overlayPrefix):
+			r.URL.Path = path
+			overlayFiles.ServeHTTP(w, r)
+		default:
+			files.ServeHTTP(w, r)
+		}
+	})
+}
+
+// injectScripts adds overlayScripts at the end of the body of page.
+func injectScripts(page []byte) []byte {
+	s := string(page)
+	if i := strings.LastIndex(s, "</body>"); i >= 0 {
+		return []byte(s[:i] + overlayScripts + "\n" + s[i:])
+	}
+	return []byte(s + overlayScripts + "\n")
 }
 
 // ServeWebSocket upgrades the HTTP connection to a WebSocket and proxies data
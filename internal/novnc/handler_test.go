@@ -0,0 +1,50 @@
+package novnc
+
+import (
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeFiles_InjectsOverlay(t *testing.T) {
+	static := fstest.MapFS{
+		"vnc.html":  {Data: []byte("<html><body><div id=\"noVNC\"></div></body></html>")},
+		"app/ui.js": {Data: []byte("// ui")},
+	}
+	overlay := fstest.MapFS{"media.js": {Data: []byte("// media")}}
+	h := serveFiles(static, overlay)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/", nil)
+		r.URL.Path = path // as left by http.StripPrefix("/novnc/")
+		h.ServeHTTP(w, r)
+		return w
+	}
+	w := get("vnc.html")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "<html><body><div id=\"noVNC\"></div>"+overlayScripts+"\n</body></html>", w.Body.String())
+
+	w = get("qemu-bmc/media.js")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "// media", w.Body.String())
+
+	w = get("app/ui.js")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "// ui", w.Body.String())
+}
+
+func TestInjectScripts_NoBody(t *testing.T) {
+	assert.Equal(t, "<html></html>"+overlayScripts+"\n", string(injectScripts([]byte("<html></html>"))))
+}
+
+func TestServeFiles_EmbeddedOverlay(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	r.URL.Path = "qemu-bmc/media.js"
+	NewHandler("").ServeFiles().ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "MediaImages")
+}
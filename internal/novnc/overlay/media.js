@@ -0,0 +1,115 @@
+// qemu-bmc virtual media control for the noVNC page: uploads an image
+// from the browser into the BMC and inserts it into a virtual media slot.
+(function () {
+  "use strict";
+
+  var vmPath = "/redfish/v1/Managers/1/VirtualMedia";
+  var imagesPath = "/redfish/v1/Managers/1/Oem/QemuBmc/MediaImages";
+
+  function el(tag, attrs, text) {
+    var e = document.createElement(tag);
+    Object.keys(attrs || {}).forEach(function (k) { e.setAttribute(k, attrs[k]); });
+    if (text) e.textContent = text;
+    return e;
+  }
+
+  function request(method, url, body, onProgress) {
+    return new Promise(function (resolve, reject) {
+      var xhr = new XMLHttpRequest();
+      xhr.open(method, url);
+      if (onProgress) {
+        xhr.upload.onprogress = function (ev) {
+          if (ev.lengthComputable) onProgress(Math.floor(ev.loaded * 100 / ev.total));
+        };
+      }
+      xhr.onload = function () {
+        if (xhr.status >= 400) {
+          var msg = xhr.statusText;
+          try { msg = JSON.parse(xhr.responseText).error.message; } catch (e) { /* not JSON */ }
+          reject(new Error(msg));
+        } else {
+          resolve(xhr);
+        }
+      };
+      xhr.onerror = function () { reject(new Error("network error")); };
+      if (body !== undefined && !(body instanceof Blob)) {
+        xhr.setRequestHeader("Content-Type", "application/json");
+        body = JSON.stringify(body);
+      }
+      xhr.send(body);
+    });
+  }
+
+  // waitTask follows the task monitor of an action that answered 202.
+  function waitTask(xhr) {
+    if (xhr.status !== 202) return Promise.resolve(xhr);
+    var monitor = xhr.getResponseHeader("Location");
+    return new Promise(function (r) { setTimeout(r, 1000); })
+      .then(function () { return request("GET", monitor); })
+      .then(waitTask);
+  }
+
+  // imageName mirrors the names the BMC accepts for uploads.
+  function imageName(file) {
+    return file.name.replace(/[^A-Za-z0-9._-]/g, "_").replace(/^[._-]+/, "").slice(0, 250) || "image.iso";
+  }
+
+  function init() {
+    var panel = el("div", { id: "qemu-bmc-media" });
+    panel.style.cssText = "position:fixed;right:8px;bottom:8px;z-index:1000;padding:6px 8px;" +
+      "background:rgba(40,40,40,0.85);color:#fff;font:12px sans-serif;border-radius:4px;";
+    var slot = el("select", { title: "Virtual media slot" });
+    var input = el("input", { type: "file", accept: ".iso,.img,.ima" });
+    input.style.display = "none";
+    var attach = el("button", { type: "button" }, "Attach ISO");
+    var eject = el("button", { type: "button" }, "Eject");
+    var status = el("span");
+    status.style.marginLeft = "6px";
+    [slot, attach, eject, input, status].forEach(function (c) { panel.appendChild(c); });
+    document.body.appendChild(panel);
+
+    function show(text) { status.textContent = text; }
+    function busy(b) { attach.disabled = eject.disabled = slot.disabled = b; }
+    function action(name) { return vmPath + "/" + slot.value + "/Actions/VirtualMedia." + name; }
+
+    request("GET", vmPath).then(function (xhr) {
+      JSON.parse(xhr.responseText).Members.forEach(function (m) {
+        var id = m["@odata.id"].split("/").pop();
+        slot.appendChild(el("option", { value: id }, id));
+      });
+    }).catch(function (err) { show("Virtual media unavailable: " + err.message); busy(true); });
+
+    attach.onclick = function () { input.click(); };
+    input.onchange = function () {
+      var file = input.files[0];
+      input.value = "";
+      if (!file) return;
+      var name = imageName(file);
+      busy(true);
+      request("PUT", imagesPath + "/" + encodeURIComponent(name), file, function (p) {
+        show("Uploading " + name + " " + p + "%");
+      }).then(function () {
+        show("Inserting " + name + "...");
+        return request("POST", action("InsertMedia"), { Image: imagesPath + "/" + name });
+      }).then(waitTask).then(function () {
+        show(name + " in " + slot.value);
+      }).catch(function (err) {
+        show("Failed: " + err.message);
+      }).then(function () { busy(false); });
+    };
+    eject.onclick = function () {
+      busy(true);
+      request("POST", action("EjectMedia"), {}).then(function () {
+        show(slot.value + " ejected");
+      }).catch(function (err) {
+        show("Failed: " + err.message);
+      }).then(function () { busy(false); });
+    };
+  }
+
+  if (document.readyState === "loading") {
+    document.addEventListener("DOMContentLoaded", init);
+  } else {
+    init();
+  }
+})();
@@ -74,12 +74,19 @@ func ApplyDefaults(args []string) []string {
 
 // BuildOptions configures auto-injected QEMU arguments.
 type BuildOptions struct {
-	QMPSocketPath string
-	SerialAddr    string
+	QMPSocketPath    string
+	SerialAddr       string
+	GuestAgentSocket string // qemu-guest-agent channel socket ("" = none)
+	FloppyDrives     int    // empty floppy drives for virtual media (QEMU cannot hot-plug them)
+
+	// Firmware replaces the firmware configuration of the user args when
+	// set. OVMF locates the images for UEFI.
+	Firmware *Firmware
+	OVMF     OVMF
 }
 
 // BuildCommandLine validates user args, applies defaults, and injects
-// qemu-bmc-managed arguments (QMP, serial, display).
+// qemu-bmc-managed arguments (QMP, serial, display, firmware).
 func BuildCommandLine(userArgs []string, opts BuildOptions) ([]string, error) {
 	if err := ValidateArgs(userArgs); err != nil {
 		return nil, err
@@ -87,6 +94,13 @@ func BuildCommandLine(userArgs []string, opts BuildOptions) ([]string, error) {
 
 	args := ApplyDefaults(userArgs)
 
+	if opts.Firmware != nil {
+		var err error
+		if args, err = ApplyFirmware(args, *opts.Firmware, opts.OVMF); err != nil {
+			return nil, err
+		}
+	}
+
 	// Inject QMP socket
 	args = append(args,
 		"-qmp", fmt.Sprintf("unix:%s,server,nowait", opts.QMPSocketPath),
@@ -107,6 +121,20 @@ func BuildCommandLine(userArgs []string, opts BuildOptions) ([]string, error) {
 		)
 	}
 
+	// Inject the qemu-guest-agent channel
+	if opts.GuestAgentSocket != "" {
+		args = append(args,
+			"-chardev", fmt.Sprintf("socket,id=qga0,path=%s,server=on,wait=off", opts.GuestAgentSocket),
+			"-device", "virtio-serial-pci,id=qga-serial0",
+			"-device", "virtserialport,bus=qga-serial0.0,chardev=qga0,name=org.qemu.guest_agent.0",
+		)
+	}
+
+	// Inject the floppy drives of virtual media
+	for i := 0; i < opts.FloppyDrives; i++ {
+		args = append(args, "-drive", fmt.Sprintf("if=floppy,index=%d,id=vmedia-floppy%d", i, i+1))
+	}
+
 	return args, nil
 }
 
@@ -115,6 +143,7 @@ var bootTargetToQEMU = map[string]string{
 	"Pxe":       "n",
 	"Hdd":       "c",
 	"Cd":        "d",
+	"Floppy":    "a",
 	"BiosSetup": "menu=on",
 }
 
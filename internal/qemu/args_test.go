@@ -174,6 +174,30 @@ func TestBuildCommandLine_InjectsSerial(t *testing.T) {
 	assert.Contains(t, result, "chardev:serial0")
 }
 
+func TestBuildCommandLine_InjectsGuestAgent(t *testing.T) {
+	result, err := BuildCommandLine([]string{"-m", "4096"}, BuildOptions{
+		QMPSocketPath:    "/tmp/qmp.sock",
+		GuestAgentSocket: "/tmp/qga.sock",
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result, "socket,id=qga0,path=/tmp/qga.sock,server=on,wait=off")
+	assert.Contains(t, result, "virtserialport,bus=qga-serial0.0,chardev=qga0,name=org.qemu.guest_agent.0")
+
+	result, err = BuildCommandLine([]string{"-m", "4096"}, BuildOptions{QMPSocketPath: "/tmp/qmp.sock"})
+	require.NoError(t, err)
+	assert.NotContains(t, result, "virtio-serial-pci,id=qga-serial0")
+}
+
+func TestBuildCommandLine_InjectsFloppyDrives(t *testing.T) {
+	result, err := BuildCommandLine([]string{"-m", "4096"}, BuildOptions{
+		QMPSocketPath: "/tmp/qmp.sock",
+		FloppyDrives:  2,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, result, "if=floppy,index=0,id=vmedia-floppy1")
+	assert.Contains(t, result, "if=floppy,index=1,id=vmedia-floppy2")
+}
+
 func TestBuildCommandLine_UserArgsPreserved(t *testing.T) {
 	result, err := BuildCommandLine([]string{"-m", "4096", "-smp", "8"}, BuildOptions{
 		QMPSocketPath: "/tmp/qmp.sock",
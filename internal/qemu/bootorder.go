@@ -0,0 +1,377 @@
+package qemu
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrSharedBootDriver is returned when a boot order needs a bootindex on an
+// implicit device whose driver is shared with other implicit devices.
+var ErrSharedBootDriver = errors.New("device cannot be ordered: its driver is shared by other implicit devices")
+
+// ErrBootTargetNotSupported is returned by ApplyBootTarget for a boot target
+// the firmware given in the arguments cannot boot.
+var ErrBootTargetNotSupported = errors.New("boot target not supported")
+
+// Boot device classes, named after the Redfish boot source override
+// targets that select them.
+const (
+	BootClassPxe = "Pxe"
+	BootClassHdd = "Hdd"
+	BootClassCd  = "Cd"
+	BootClassUsb = "Usb"
+)
+
+// bootDiskID is the drive and device id of the boot disk ApplyBootTarget
+// adds for UefiShell and UefiHttp.
+const bootDiskID = "bmc-bootdisk"
+
+// biosSetupWait is the boot menu wait, in seconds, for the BiosSetup target
+// under OVMF. OVMF has no boot option for its setup utility; it opens it
+// when Esc is pressed while the boot menu waits.
+const biosSetupWait = 30
+
+// bootDrivers maps the -device drivers of disks and CD-ROMs to their boot
+// class. NICs are taken from nicDrivers.
+var bootDrivers = map[string]string{
+	"virtio-blk-pci":    BootClassHdd,
+	"virtio-blk-device": BootClassHdd,
+	"scsi-hd":           BootClassHdd,
+	"ide-hd":            BootClassHdd,
+	"nvme":              BootClassHdd,
+	"ide-cd":            BootClassCd,
+	"scsi-cd":           BootClassCd,
+	"usb-storage":       BootClassUsb,
+}
+
+// BootDevice is a device the firmware can boot from.
+type BootDevice struct {
+	Class  string // BootClassPxe, BootClassHdd, BootClassCd or BootClassUsb
+	Driver string // QEMU device driver
+	ID     string // device or drive id, empty when not given
+	Index  int    // bootindex, -1 when not set
+
+	// Implicit devices are created by QEMU from shorthand options such as
+	// -drive if=virtio and -cdrom, or by default, and cannot carry a
+	// bootindex of their own.
+	Implicit bool
+
+	arg int // position of the -device value in args
+}
+
+// ParseBootDevices returns the bootable devices in args in command line
+// order, followed by QEMU's default CD-ROM unless the arguments have one or
+// disable defaults.
+func ParseBootDevices(args []string) []BootDevice {
+	var devs []BootDevice
+	noDefaults, cdrom := false, false
+	globals := make(map[string]int)
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-nodefaults" {
+			noDefaults = true
+			continue
+		}
+		if i+1 >= len(args) {
+			break
+		}
+		val := args[i+1]
+		dev := BootDevice{Index: -1, Implicit: true, arg: -1}
+		switch args[i] {
+		case "-device":
+			dev.Driver = deviceDriver(strings.Split(val, ","))
+			dev.ID = option(val, "id")
+			dev.Implicit = false
+			dev.arg = i + 1
+			if n, err := strconv.Atoi(option(val, "bootindex")); err == nil {
+				dev.Index = n
+			}
+		case "-drive":
+			dev.Driver = shorthandDriver(option(val, "if"), option(val, "media") == "cdrom")
+			dev.ID = option(val, "id")
+		case "-cdrom":
+			dev.Driver = "ide-cd"
+		case "-hda", "-hdb", "-hdc", "-hdd":
+			dev.Driver = "ide-hd"
+		case "-nic":
+			if !strings.HasPrefix(val, "none") {
+				dev.Driver = option(val, "model")
+			}
+		case "-global":
+			if driver, n, ok := parseBootIndexGlobal(val); ok {
+				globals[driver] = n
+			}
+			i++
+			continue
+		default:
+			continue
+		}
+		i++
+
+		dev.Class = bootDrivers[dev.Driver]
+		if nicDrivers[dev.Driver] {
+			dev.Class = BootClassPxe
+		}
+		if dev.Class == "" {
+			continue
+		}
+		cdrom = cdrom || (dev.Implicit && dev.Class == BootClassCd)
+		devs = append(devs, dev)
+	}
+
+	if !noDefaults && !cdrom {
+		devs = append(devs, BootDevice{Class: BootClassCd, Driver: "ide-cd", Index: -1, Implicit: true, arg: -1})
+	}
+
+	shared := implicitDrivers(devs)
+	for i := range devs {
+		if n, ok := globals[devs[i].Driver]; ok && devs[i].Implicit && shared[devs[i].Driver] == 1 {
+			devs[i].Index = n
+		}
+	}
+	return devs
+}
+
+// ApplyBootOrder returns a copy of args in which the devices at the given
+// positions of ParseBootDevices(args) boot in that order. Devices not in
+// order lose their bootindex and follow in the firmware's default order.
+func ApplyBootOrder(args []string, order []int) ([]string, error) {
+	devs := ParseBootDevices(args)
+	index := unordered(len(devs))
+	for n, d := range order {
+		if d < 0 || d >= len(devs) {
+			return nil, fmt.Errorf("boot device %d does not exist", d)
+		}
+		if index[d] >= 0 {
+			return nil, fmt.Errorf("boot device %d is listed twice", d)
+		}
+		index[d] = n
+	}
+	return setBootIndexes(args, devs, index)
+}
+
+// ApplyBootTarget returns a copy of args that boots the Redfish boot target
+// of boot first with the firmware given in args. SeaBIOS follows -boot for
+// the targets it has a drive letter for; OVMF ignores -boot, so the devices
+// of the target are moved to the front of the boot order through their
+// bootindex instead, which both firmwares follow. Targets the firmware
+// cannot boot, such as Floppy under OVMF or a class without a device that
+// can carry a bootindex, return ErrBootTargetNotSupported.
+func ApplyBootTarget(args []string, boot Boot) ([]string, error) {
+	uefi := ParseFirmware(args).UEFI
+	var first func(i int, dev BootDevice) bool
+	switch boot.Target {
+	case BootClassPxe, BootClassHdd, BootClassCd:
+		if !uefi {
+			return ApplyBootOverride(args, boot.Target), nil
+		}
+		first = byClass(boot.Target)
+	case BootClassUsb:
+		first = byClass(boot.Target)
+	case "Floppy":
+		if uefi {
+			return nil, fmt.Errorf("%w: OVMF cannot boot from a floppy", ErrBootTargetNotSupported)
+		}
+		return ApplyBootOverride(args, boot.Target), nil
+	case "BiosSetup":
+		if uefi {
+			return setBootMenuArgs(args, biosSetupWait), nil
+		}
+		return ApplyBootOverride(args, boot.Target), nil
+	case "UefiTarget":
+		first = func(i int, _ BootDevice) bool { return i == boot.Device }
+	case "UefiShell", "UefiHttp":
+		if boot.BootDisk == "" {
+			return args, nil
+		}
+		args = append(append([]string(nil), args...),
+			"-drive", "if=none,id="+bootDiskID+",format=raw,readonly=on,file=fat:"+escapeOption(boot.BootDisk),
+			"-device", "virtio-blk-pci,drive="+bootDiskID+",id="+bootDiskID,
+		)
+		first = func(_ int, dev BootDevice) bool { return dev.ID == bootDiskID }
+	default:
+		return ApplyBootOverride(args, boot.Target), nil
+	}
+
+	result, ok := bootFirst(args, first)
+	if !ok {
+		return nil, fmt.Errorf("%w: no %s device can be booted first", ErrBootTargetNotSupported, boot.Target)
+	}
+	return result, nil
+}
+
+// setBootMenuArgs returns a copy of args whose -boot shows the boot menu
+// for timeout seconds, keeping the boot order keys.
+func setBootMenuArgs(args []string, timeout int) []string {
+	result := make([]string, 0, len(args)+2)
+	seen := false
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-boot" && i+1 < len(args) {
+			result = append(result, "-boot", setBootMenu(args[i+1], timeout))
+			seen = true
+			i++
+			continue
+		}
+		result = append(result, args[i])
+	}
+	if !seen {
+		result = append(result, "-boot", setBootMenu("", timeout))
+	}
+	return result
+}
+
+func byClass(class string) func(int, BootDevice) bool {
+	return func(_ int, dev BootDevice) bool { return dev.Class == class }
+}
+
+// bootFirst gives the devices matching first the lowest bootindex values
+// and shifts the devices that had one behind them. Implicit devices get
+// theirs through -global, which only works while a driver has a single
+// implicit device; shared ones are left in the firmware's default order.
+// It reports false when no device matching first got a bootindex.
+func bootFirst(args []string, first func(i int, dev BootDevice) bool) ([]string, bool) {
+	devs := ParseBootDevices(args)
+	shared := implicitDrivers(devs)
+	index := unordered(len(devs))
+
+	next := 0
+	for _, implicit := range []bool{false, true} {
+		for i, dev := range devs {
+			if first(i, dev) && dev.Implicit == implicit && (!implicit || shared[dev.Driver] == 1) {
+				index[i] = next
+				next++
+			}
+		}
+	}
+	for i, dev := range devs {
+		if !first(i, dev) && dev.Index >= 0 {
+			index[i] = dev.Index + next
+		}
+	}
+
+	if next == 0 {
+		return nil, false
+	}
+	result, err := setBootIndexes(args, devs, index)
+	if err != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+// setBootIndexes returns a copy of args with the bootindex of each device
+// in devs set to index, or removed where it is negative. Explicit devices
+// carry it as a property, implicit ones through -global.
+func setBootIndexes(args []string, devs []BootDevice, index []int) ([]string, error) {
+	shared := implicitDrivers(devs)
+	result := append([]string(nil), args...)
+	var globals []string
+	for i, dev := range devs {
+		switch {
+		case !dev.Implicit && index[i] >= 0:
+			result[dev.arg] = setOption(result[dev.arg], "bootindex", strconv.Itoa(index[i]))
+		case !dev.Implicit:
+			result[dev.arg] = removeOption(result[dev.arg], "bootindex")
+		case index[i] >= 0 && shared[dev.Driver] > 1:
+			return nil, fmt.Errorf("%w: %s", ErrSharedBootDriver, dev.Driver)
+		case index[i] >= 0:
+			globals = append(globals, "-global", dev.Driver+".bootindex="+strconv.Itoa(index[i]))
+		}
+	}
+
+	// Drop the -global bootindex of boot drivers; the ones still needed
+	// were collected above
+	filtered := result[:0]
+	for i := 0; i < len(result); i++ {
+		if result[i] == "-global" && i+1 < len(result) {
+			if driver, _, ok := parseBootIndexGlobal(result[i+1]); ok && isBootDriver(driver) {
+				i++
+				continue
+			}
+		}
+		filtered = append(filtered, result[i])
+	}
+	return append(filtered, globals...), nil
+}
+
+// unordered returns n bootindex values that are all unset.
+func unordered(n int) []int {
+	index := make([]int, n)
+	for i := range index {
+		index[i] = -1
+	}
+	return index
+}
+
+// implicitDrivers counts the implicit devices of each driver.
+func implicitDrivers(devs []BootDevice) map[string]int {
+	n := make(map[string]int)
+	for _, dev := range devs {
+		if dev.Implicit {
+			n[dev.Driver]++
+		}
+	}
+	return n
+}
+
+// parseBootIndexGlobal parses a -global driver.bootindex=N value.
+func parseBootIndexGlobal(val string) (string, int, bool) {
+	prop, v, found := strings.Cut(val, "=")
+	driver, ok := strings.CutSuffix(prop, ".bootindex")
+	if !found || !ok {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return "", 0, false
+	}
+	return driver, n, true
+}
+
+func isBootDriver(driver string) bool {
+	return bootDrivers[driver] != "" || nicDrivers[driver]
+}
+
+// SortBootDevices returns the positions of devs in boot order: devices with
+// a bootindex by their index, the others after them in command line order.
+func SortBootDevices(devs []BootDevice) []int {
+	order := make([]int, len(devs))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		ia, ib := devs[a].Index, devs[b].Index
+		switch {
+		case ia < 0 && ib < 0:
+			return 0
+		case ia < 0:
+			return 1
+		case ib < 0:
+			return -1
+		}
+		return ia - ib
+	})
+	return order
+}
+
+// shorthandDriver returns the device driver QEMU creates for a -drive with
+// the given interface, or "" for drives that are not bootable devices.
+func shorthandDriver(iface string, cdrom bool) string {
+	switch iface {
+	case "", "ide":
+		if cdrom {
+			return "ide-cd"
+		}
+		return "ide-hd"
+	case "scsi":
+		if cdrom {
+			return "scsi-cd"
+		}
+		return "scsi-hd"
+	case "virtio":
+		return "virtio-blk-pci"
+	}
+	return ""
+}
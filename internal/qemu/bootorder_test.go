@@ -0,0 +1,207 @@
+package qemu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBootDevices(t *testing.T) {
+	args := []string{
+		"-drive", "file=disk.qcow2,format=qcow2,if=virtio",
+		"-drive", "if=none,id=data0,file=data.qcow2",
+		"-device", "scsi-hd,drive=data0,id=data0-dev,bootindex=2",
+		"-device", "virtio-net-pci,netdev=net0,id=nic0",
+		"-drive", "if=pflash,format=raw,file=code.fd",
+		"-cdrom", "install.iso",
+	}
+
+	devs := ParseBootDevices(args)
+	require.Len(t, devs, 4)
+	assert.Equal(t, BootDevice{Class: BootClassHdd, Driver: "virtio-blk-pci", Index: -1, Implicit: true, arg: -1}, devs[0])
+	assert.Equal(t, BootDevice{Class: BootClassHdd, Driver: "scsi-hd", ID: "data0-dev", Index: 2, arg: 5}, devs[1])
+	assert.Equal(t, BootDevice{Class: BootClassPxe, Driver: "virtio-net-pci", ID: "nic0", Index: -1, arg: 7}, devs[2])
+	assert.Equal(t, BootDevice{Class: BootClassCd, Driver: "ide-cd", Index: -1, Implicit: true, arg: -1}, devs[3])
+}
+
+func TestParseBootDevices_DefaultCdrom(t *testing.T) {
+	devs := ParseBootDevices([]string{"-m", "2048"})
+	require.Len(t, devs, 1)
+	assert.Equal(t, "ide-cd", devs[0].Driver)
+	assert.True(t, devs[0].Implicit)
+
+	assert.Empty(t, ParseBootDevices([]string{"-nodefaults"}))
+}
+
+func TestApplyBootTarget_LegacyUsesBootOption(t *testing.T) {
+	args := []string{"-device", "virtio-net-pci,netdev=net0,id=nic0", "-boot", "c"}
+	result, err := ApplyBootTarget(args, Boot{Target: "Pxe"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-device", "virtio-net-pci,netdev=net0,id=nic0", "-boot", "n"}, result)
+}
+
+func TestApplyBootTarget_UEFIPxe(t *testing.T) {
+	args := []string{
+		"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=code.fd",
+		"-device", "virtio-blk-pci,drive=disk0,id=disk0-dev,bootindex=0",
+		"-device", "virtio-net-pci,netdev=net0,id=nic0",
+		"-device", "e1000,netdev=net1,id=nic1,bootindex=5",
+	}
+
+	result, err := ApplyBootTarget(args, Boot{Target: "Pxe"})
+	require.NoError(t, err)
+	assert.Equal(t, "virtio-blk-pci,drive=disk0,id=disk0-dev,bootindex=2", result[3])
+	assert.Equal(t, "virtio-net-pci,netdev=net0,id=nic0,bootindex=0", result[5])
+	assert.Equal(t, "e1000,netdev=net1,id=nic1,bootindex=1", result[7])
+	assert.NotContains(t, result, "-boot")
+	assert.Equal(t, "virtio-net-pci,netdev=net0,id=nic0", args[5], "input must not be modified")
+}
+
+func TestApplyBootTarget_UEFIImplicitDevices(t *testing.T) {
+	args := []string{
+		"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=code.fd",
+		"-drive", "file=disk.qcow2,format=qcow2,if=virtio",
+		"-device", "virtio-net-pci,netdev=net0,id=nic0,bootindex=0",
+	}
+
+	result, err := ApplyBootTarget(args, Boot{Target: "Cd"})
+	require.NoError(t, err)
+	assert.Equal(t, "virtio-net-pci,netdev=net0,id=nic0,bootindex=1", result[5])
+	assert.Equal(t, []string{"-global", "ide-cd.bootindex=0"}, result[6:])
+
+	result, err = ApplyBootTarget(args, Boot{Target: "Hdd"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-global", "virtio-blk-pci.bootindex=0"}, result[6:])
+}
+
+func TestApplyBootTarget_UEFISharedImplicitDriver(t *testing.T) {
+	args := []string{
+		"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=code.fd",
+		"-drive", "file=a.qcow2,if=virtio",
+		"-drive", "file=b.qcow2,if=virtio",
+	}
+	_, err := ApplyBootTarget(args, Boot{Target: "Hdd"})
+	assert.ErrorIs(t, err, ErrBootTargetNotSupported)
+}
+
+func TestApplyBootTarget_UEFIPxeWithoutNIC(t *testing.T) {
+	// QEMU's default NIC is not on the command line and cannot be ordered
+	args := []string{
+		"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=code.fd",
+		"-device", "virtio-blk-pci,drive=disk0,id=disk0,bootindex=0",
+	}
+	_, err := ApplyBootTarget(args, Boot{Target: "Pxe"})
+	assert.ErrorIs(t, err, ErrBootTargetNotSupported)
+}
+
+func TestApplyBootTarget_UEFIBiosSetup(t *testing.T) {
+	args := []string{"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=code.fd", "-boot", "order=c"}
+	result, err := ApplyBootTarget(args, Boot{Target: "BiosSetup"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-boot", "order=c,menu=on,splash-time=30000"}, result[2:])
+
+	result, err = ApplyBootTarget(args[:2], Boot{Target: "BiosSetup"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-boot", "menu=on,splash-time=30000"}, result[2:])
+}
+
+func TestApplyBootTarget_LegacyBiosSetup(t *testing.T) {
+	result, err := ApplyBootTarget([]string{"-boot", "c"}, Boot{Target: "BiosSetup"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-boot", "menu=on"}, result)
+}
+
+func TestParseBootDevices_GlobalBootIndex(t *testing.T) {
+	devs := ParseBootDevices([]string{
+		"-drive", "file=disk.qcow2,if=virtio",
+		"-global", "virtio-blk-pci.bootindex=3",
+	})
+	require.Len(t, devs, 2)
+	assert.Equal(t, 3, devs[0].Index)
+	assert.Equal(t, -1, devs[1].Index)
+}
+
+func TestApplyBootOrder(t *testing.T) {
+	args := []string{
+		"-drive", "file=disk.qcow2,if=virtio",
+		"-device", "virtio-net-pci,netdev=net0,id=nic0,bootindex=0",
+		"-device", "e1000,netdev=net1,id=nic1",
+		"-global", "ide-cd.bootindex=1",
+	}
+
+	result, err := ApplyBootOrder(args, []int{0, 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"-drive", "file=disk.qcow2,if=virtio",
+		"-device", "virtio-net-pci,netdev=net0,id=nic0",
+		"-device", "e1000,netdev=net1,id=nic1,bootindex=1",
+		"-global", "virtio-blk-pci.bootindex=0",
+	}, result)
+
+	devs := ParseBootDevices(result)
+	assert.Equal(t, []int{0, 2, 1, 3}, SortBootDevices(devs))
+}
+
+func TestApplyBootOrder_Invalid(t *testing.T) {
+	args := []string{"-drive", "file=a.qcow2,if=virtio", "-drive", "file=b.qcow2,if=virtio"}
+
+	_, err := ApplyBootOrder(args, []int{0, 0})
+	assert.Error(t, err)
+	_, err = ApplyBootOrder(args, []int{5})
+	assert.Error(t, err)
+	_, err = ApplyBootOrder(args, []int{1})
+	assert.ErrorIs(t, err, ErrSharedBootDriver)
+}
+
+func TestApplyBootTarget_Usb(t *testing.T) {
+	args := []string{
+		"-device", "virtio-blk-pci,drive=disk0,id=disk0,bootindex=0",
+		"-device", "qemu-xhci,id=xhci",
+		"-device", "usb-storage,drive=stick,id=stick0",
+	}
+	result, err := ApplyBootTarget(args, Boot{Target: "Usb"})
+	require.NoError(t, err)
+	assert.Equal(t, "virtio-blk-pci,drive=disk0,id=disk0,bootindex=1", result[1])
+	assert.Equal(t, "usb-storage,drive=stick,id=stick0,bootindex=0", result[5])
+}
+
+func TestApplyBootTarget_Floppy(t *testing.T) {
+	result, err := ApplyBootTarget([]string{"-fda", "floppy.img"}, Boot{Target: "Floppy"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-fda", "floppy.img", "-boot", "a"}, result)
+
+	uefi := []string{"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=code.fd", "-fda", "floppy.img"}
+	_, err = ApplyBootTarget(uefi, Boot{Target: "Floppy"})
+	assert.ErrorIs(t, err, ErrBootTargetNotSupported)
+}
+
+func TestApplyBootTarget_UefiTarget(t *testing.T) {
+	args := []string{
+		"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=code.fd",
+		"-device", "virtio-net-pci,netdev=net0,id=nic0,bootindex=0",
+		"-device", "virtio-blk-pci,drive=disk0,id=disk0",
+	}
+	result, err := ApplyBootTarget(args, Boot{Target: "UefiTarget", Device: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "virtio-net-pci,netdev=net0,id=nic0,bootindex=1", result[3])
+	assert.Equal(t, "virtio-blk-pci,drive=disk0,id=disk0,bootindex=0", result[5])
+}
+
+func TestApplyBootTarget_BootDisk(t *testing.T) {
+	args := []string{
+		"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=code.fd",
+		"-device", "virtio-blk-pci,drive=disk0,id=disk0,bootindex=0",
+	}
+	result, err := ApplyBootTarget(args, Boot{Target: "UefiShell", BootDisk: "/var/lib/qemu-bmc/bootdisk"})
+	require.NoError(t, err)
+	assert.Equal(t, "virtio-blk-pci,drive=disk0,id=disk0,bootindex=1", result[3])
+	assert.Equal(t, []string{
+		"-drive", "if=none,id=bmc-bootdisk,format=raw,readonly=on,file=fat:/var/lib/qemu-bmc/bootdisk",
+		"-device", "virtio-blk-pci,drive=bmc-bootdisk,id=bmc-bootdisk,bootindex=0",
+	}, result[4:])
+
+	result, err = ApplyBootTarget(args, Boot{Target: "UefiHttp"})
+	require.NoError(t, err)
+	assert.Equal(t, args, result)
+}
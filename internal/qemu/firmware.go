@@ -0,0 +1,322 @@
+package qemu
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// numaMemPrefix prefixes the IDs of the memory backends ApplyFirmware
+// creates for NUMA nodes.
+const numaMemPrefix = "bmc-numa"
+
+// seabiosMenuWait is the boot menu wait, in seconds, when -boot menu=on is
+// given without splash-time (SeaBIOS waits 2.5s).
+const seabiosMenuWait = 3
+
+// smmMachine is the -machine value ApplyFirmware adds for Secure Boot. QEMU
+// merges repeated -machine options, so the operator's -machine is left as
+// given and only this argument is removed when Secure Boot is turned off.
+const smmMachine = "smm=on"
+
+// Firmware is the firmware configuration of a VM: the firmware image, its
+// boot menu and the platform tables it presents to the guest.
+type Firmware struct {
+	UEFI            bool // OVMF instead of SeaBIOS
+	SecureBoot      bool // UEFI only
+	BootMenuTimeout int  // seconds the boot menu waits, 0 = no menu
+	SMBIOS          SMBIOS
+	NUMANodes       int // 0 or 1 = no NUMA topology
+}
+
+// SMBIOS holds the SMBIOS type 1 (system information) strings. Empty
+// strings are left to QEMU.
+type SMBIOS struct {
+	Manufacturer string
+	Product      string
+	Version      string
+	Serial       string
+	SKU          string
+	Family       string
+}
+
+// OVMF locates the OVMF images used for UEFI boot.
+type OVMF struct {
+	Code               string // firmware code, mapped read-only
+	SecureCode         string // code built with Secure Boot and SMM
+	VarsTemplate       string // empty variable store
+	SecureVarsTemplate string // variable store with Secure Boot keys enrolled
+	Vars               string // the VM's writable variable store
+}
+
+// smbiosKeys maps -smbios type=1 keys to the SMBIOS fields.
+var smbiosKeys = []struct {
+	key   string
+	field func(s *SMBIOS) *string
+}{
+	{"manufacturer", func(s *SMBIOS) *string { return &s.Manufacturer }},
+	{"product", func(s *SMBIOS) *string { return &s.Product }},
+	{"version", func(s *SMBIOS) *string { return &s.Version }},
+	{"serial", func(s *SMBIOS) *string { return &s.Serial }},
+	{"sku", func(s *SMBIOS) *string { return &s.SKU }},
+	{"family", func(s *SMBIOS) *string { return &s.Family }},
+}
+
+// ParseFirmware reads the firmware configuration from QEMU arguments.
+func ParseFirmware(args []string) Firmware {
+	var fw Firmware
+	for i := 0; i+1 < len(args); i++ {
+		val := args[i+1]
+		switch args[i] {
+		case "-drive":
+			if option(val, "if") == "pflash" {
+				fw.UEFI = true
+			}
+		case "-bios":
+			if isOVMFImage(val) {
+				fw.UEFI = true
+			}
+		case "-global":
+			if isSecurePflash(val) {
+				fw.SecureBoot = true
+			}
+		case "-boot":
+			if option(val, "menu") != "on" {
+				break
+			}
+			fw.BootMenuTimeout = seabiosMenuWait
+			if ms, err := strconv.Atoi(option(val, "splash-time")); err == nil {
+				fw.BootMenuTimeout = (ms + 999) / 1000
+			}
+		case "-smbios":
+			if option(val, "type") != "1" {
+				break
+			}
+			for _, k := range smbiosKeys {
+				if v, ok := lookupOption(val, k.key); ok {
+					*k.field(&fw.SMBIOS) = v
+				}
+			}
+		case "-numa":
+			if strings.HasPrefix(val, "node") {
+				fw.NUMANodes++
+			}
+		default:
+			continue
+		}
+		i++
+	}
+	fw.SecureBoot = fw.SecureBoot && fw.UEFI
+	return fw
+}
+
+// ApplyFirmware returns a copy of args configured for fw. The firmware
+// image, boot menu, SMBIOS type 1 strings and NUMA nodes given in args are
+// replaced; other arguments are kept. A -bios image other than OVMF is kept
+// while fw selects SeaBIOS.
+func ApplyFirmware(args []string, fw Firmware, ovmf OVMF) ([]string, error) {
+	hw, err := ParseHardware("", args)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(args)+8)
+	bootSeen := false
+	for i := 0; i < len(args); i++ {
+		if i+1 >= len(args) {
+			result = append(result, args[i])
+			break
+		}
+		flag, val := args[i], args[i+1]
+		switch {
+		case flag == "-drive" && option(val, "if") == "pflash",
+			flag == "-bios" && (fw.UEFI || isOVMFImage(val)),
+			flag == "-global" && isSecurePflash(val),
+			flag == "-machine" && val == smmMachine,
+			flag == "-smbios" && option(val, "type") == "1",
+			flag == "-numa",
+			flag == "-object" && strings.Contains(val, "id="+numaMemPrefix):
+			// replaced below
+		case flag == "-boot":
+			bootSeen = true
+			if v := setBootMenu(val, fw.BootMenuTimeout); v != "" {
+				result = append(result, flag, v)
+			}
+		default:
+			result = append(result, flag)
+			continue
+		}
+		i++
+	}
+
+	if !bootSeen && fw.BootMenuTimeout > 0 {
+		result = append(result, "-boot", setBootMenu("", fw.BootMenuTimeout))
+	}
+
+	if fw.UEFI {
+		code, vars := ovmf.Code, ovmf.Vars
+		if fw.SecureBoot {
+			code = ovmf.SecureCode
+			result = append(result,
+				"-machine", smmMachine,
+				"-global", "driver=cfi.pflash01,property=secure,value=on",
+			)
+		}
+		if code == "" || vars == "" {
+			return nil, fmt.Errorf("UEFI boot needs the OVMF code and variable store images")
+		}
+		result = append(result,
+			"-drive", "if=pflash,format=raw,unit=0,readonly=on,file="+escapeOption(code),
+			"-drive", "if=pflash,format=raw,unit=1,file="+escapeOption(vars),
+		)
+	}
+
+	var smbios []string
+	for _, k := range smbiosKeys {
+		if v := *k.field(&fw.SMBIOS); v != "" {
+			smbios = append(smbios, k.key+"="+escapeOption(v))
+		}
+	}
+	if len(smbios) > 0 {
+		result = append(result, "-smbios", "type=1,"+strings.Join(smbios, ","))
+	}
+
+	if fw.NUMANodes > 1 {
+		numa, err := numaArgs(fw.NUMANodes, hw)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, numa...)
+	}
+	return result, nil
+}
+
+// numaArgs splits the memory and CPUs of hw evenly over nodes NUMA nodes.
+// The last node takes the remainder.
+func numaArgs(nodes int, hw Hardware) ([]string, error) {
+	memMiB := hw.MemoryBytes >> 20
+	if memMiB < uint64(nodes) {
+		return nil, fmt.Errorf("cannot split %d MiB of memory over %d NUMA nodes", memMiB, nodes)
+	}
+
+	var args []string
+	for n := 0; n < nodes; n++ {
+		size := memMiB / uint64(nodes)
+		if n == nodes-1 {
+			size = memMiB - size*uint64(nodes-1)
+		}
+		id := fmt.Sprintf("%s%d", numaMemPrefix, n)
+		node := fmt.Sprintf("node,nodeid=%d,memdev=%s", n, id)
+		first, last := n*hw.MaxCPUs/nodes, (n+1)*hw.MaxCPUs/nodes-1
+		if last >= first {
+			node += fmt.Sprintf(",cpus=%d-%d", first, last)
+		}
+		args = append(args,
+			"-object", fmt.Sprintf("memory-backend-ram,id=%s,size=%dM", id, size),
+			"-numa", node,
+		)
+	}
+	return args, nil
+}
+
+// setBootMenu sets the menu and splash-time keys of a -boot value, keeping
+// the boot order keys. It returns "" when nothing is left.
+func setBootMenu(val string, timeout int) string {
+	var parts []string
+	for _, part := range strings.Split(val, ",") {
+		k, _, _ := strings.Cut(part, "=")
+		if part == "" || k == "menu" || k == "splash-time" {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	if timeout > 0 {
+		parts = append(parts, "menu=on", fmt.Sprintf("splash-time=%d", timeout*1000))
+	}
+	return strings.Join(parts, ",")
+}
+
+// setOption sets key=value in a QEMU option string.
+func setOption(val, key, value string) string {
+	parts := strings.Split(val, ",")
+	for i, part := range parts {
+		if k, _, found := strings.Cut(part, "="); found && k == key {
+			parts[i] = key + "=" + value
+			return strings.Join(parts, ",")
+		}
+	}
+	return val + "," + key + "=" + value
+}
+
+// removeOption removes key from a QEMU option string.
+func removeOption(val, key string) string {
+	parts := strings.Split(val, ",")
+	kept := parts[:0]
+	for _, part := range parts {
+		if k, _, found := strings.Cut(part, "="); found && k == key {
+			continue
+		}
+		kept = append(kept, part)
+	}
+	return strings.Join(kept, ",")
+}
+
+// option returns the value of key in a QEMU option string, or "".
+func option(val, key string) string {
+	v, _ := lookupOption(val, key)
+	return v
+}
+
+// lookupOption returns the value of key in a QEMU option string, undoing
+// the doubling of commas within values.
+func lookupOption(val, key string) (string, bool) {
+	for _, part := range splitOptions(val) {
+		if k, v, found := strings.Cut(part, "="); found && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// splitOptions splits a QEMU option string at single commas; ",," is an
+// escaped comma.
+func splitOptions(val string) []string {
+	var parts []string
+	var cur strings.Builder
+	for i := 0; i < len(val); i++ {
+		if val[i] != ',' {
+			cur.WriteByte(val[i])
+			continue
+		}
+		if i+1 < len(val) && val[i+1] == ',' {
+			cur.WriteByte(',')
+			i++
+			continue
+		}
+		parts = append(parts, cur.String())
+		cur.Reset()
+	}
+	return append(parts, cur.String())
+}
+
+// escapeOption escapes commas in a QEMU option value.
+func escapeOption(v string) string {
+	return strings.ReplaceAll(v, ",", ",,")
+}
+
+// isOVMFImage reports whether a -bios image is OVMF.
+func isOVMFImage(path string) bool {
+	return strings.Contains(strings.ToUpper(filepath.Base(path)), "OVMF")
+}
+
+// isSecurePflash reports whether a -global value enables the secure
+// (SMM-only) flash interface Secure Boot needs.
+func isSecurePflash(val string) bool {
+	if strings.HasPrefix(val, "cfi.pflash01.secure=") {
+		return strings.HasSuffix(val, "=on")
+	}
+	return strings.Contains(val, "driver=cfi.pflash01") &&
+		strings.Contains(val, "property=secure") &&
+		strings.Contains(val, "value=on")
+}
@@ -0,0 +1,194 @@
+package qemu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testOVMF = OVMF{
+	Code:               "/usr/share/OVMF/OVMF_CODE.fd",
+	SecureCode:         "/usr/share/OVMF/OVMF_CODE_4M.secboot.fd",
+	VarsTemplate:       "/usr/share/OVMF/OVMF_VARS.fd",
+	SecureVarsTemplate: "/usr/share/OVMF/OVMF_VARS_4M.ms.fd",
+	Vars:               "/vm/OVMF_VARS.fd",
+}
+
+func TestParseFirmware_Defaults(t *testing.T) {
+	fw := ParseFirmware(ApplyDefaults(nil))
+	assert.Equal(t, Firmware{}, fw)
+}
+
+func TestParseFirmware_EntrypointUEFI(t *testing.T) {
+	args := []string{
+		"-machine", "q35,accel=kvm",
+		"-boot", "c,menu=on,splash-time=5000",
+		"-drive", "if=pflash,format=raw,readonly=on,file=/usr/share/OVMF/OVMF_CODE.fd",
+		"-drive", "if=pflash,format=raw,file=/vm/OVMF_VARS.fd",
+	}
+	fw := ParseFirmware(args)
+	assert.True(t, fw.UEFI)
+	assert.False(t, fw.SecureBoot)
+	assert.Equal(t, 5, fw.BootMenuTimeout)
+}
+
+func TestParseFirmware_MenuWithoutSplashTime(t *testing.T) {
+	fw := ParseFirmware([]string{"-boot", "menu=on"})
+	assert.Equal(t, seabiosMenuWait, fw.BootMenuTimeout)
+}
+
+func TestParseFirmware_SMBIOSAndNUMA(t *testing.T) {
+	args := []string{
+		"-smbios", "type=0,vendor=ignored",
+		"-smbios", "type=1,manufacturer=ACME,, Inc.,serial=SN1",
+		"-numa", "node,nodeid=0",
+		"-numa", "node,nodeid=1",
+		"-numa", "dist,src=0,dst=1,val=20",
+	}
+	fw := ParseFirmware(args)
+	assert.Equal(t, SMBIOS{Manufacturer: "ACME, Inc.", Serial: "SN1"}, fw.SMBIOS)
+	assert.Equal(t, 2, fw.NUMANodes)
+}
+
+func TestApplyFirmware_UEFI(t *testing.T) {
+	args := ApplyDefaults([]string{"-boot", "c"})
+	result, err := ApplyFirmware(args, Firmware{UEFI: true}, testOVMF)
+	require.NoError(t, err)
+
+	assert.Contains(t, result, "if=pflash,format=raw,unit=0,readonly=on,file=/usr/share/OVMF/OVMF_CODE.fd")
+	assert.Contains(t, result, "if=pflash,format=raw,unit=1,file=/vm/OVMF_VARS.fd")
+	assert.Contains(t, result, "c")
+	assert.True(t, ParseFirmware(result).UEFI)
+}
+
+func TestApplyFirmware_SecureBoot(t *testing.T) {
+	args := ApplyDefaults(nil)
+	result, err := ApplyFirmware(args, Firmware{UEFI: true, SecureBoot: true}, testOVMF)
+	require.NoError(t, err)
+
+	assert.Contains(t, result, "q35")
+	assert.Contains(t, result, "smm=on")
+	assert.Contains(t, result, "driver=cfi.pflash01,property=secure,value=on")
+	assert.Contains(t, result, "if=pflash,format=raw,unit=0,readonly=on,file=/usr/share/OVMF/OVMF_CODE_4M.secboot.fd")
+	assert.Equal(t, Firmware{UEFI: true, SecureBoot: true}, ParseFirmware(result))
+}
+
+func TestApplyFirmware_BackToSeaBIOS(t *testing.T) {
+	uefi, err := ApplyFirmware(ApplyDefaults(nil), Firmware{UEFI: true, SecureBoot: true}, testOVMF)
+	require.NoError(t, err)
+
+	result, err := ApplyFirmware(uefi, Firmware{}, testOVMF)
+	require.NoError(t, err)
+	assert.Equal(t, Firmware{}, ParseFirmware(result))
+	assert.Equal(t, ApplyDefaults(nil), result)
+}
+
+func TestApplyFirmware_KeepsOperatorSMM(t *testing.T) {
+	args := []string{"-machine", "q35,smm=on"}
+	uefi, err := ApplyFirmware(args, Firmware{UEFI: true, SecureBoot: true}, testOVMF)
+	require.NoError(t, err)
+
+	result, err := ApplyFirmware(uefi, Firmware{UEFI: true}, testOVMF)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"-machine", "q35,smm=on",
+		"-drive", "if=pflash,format=raw,unit=0,readonly=on,file=/usr/share/OVMF/OVMF_CODE.fd",
+		"-drive", "if=pflash,format=raw,unit=1,file=/vm/OVMF_VARS.fd",
+	}, result)
+}
+
+func TestApplyFirmware_Bios(t *testing.T) {
+	args := []string{"-bios", "/vm/seabios-custom.bin"}
+	result, err := ApplyFirmware(args, Firmware{BootMenuTimeout: 5}, testOVMF)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-bios", "/vm/seabios-custom.bin", "-boot", "menu=on,splash-time=5000"}, result)
+
+	result, err = ApplyFirmware(args, Firmware{UEFI: true}, testOVMF)
+	require.NoError(t, err)
+	assert.NotContains(t, result, "-bios")
+	assert.True(t, ParseFirmware(result).UEFI)
+
+	result, err = ApplyFirmware([]string{"-bios", "/usr/share/OVMF/OVMF.fd"}, Firmware{}, testOVMF)
+	require.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestApplyFirmware_UEFIWithoutImages(t *testing.T) {
+	_, err := ApplyFirmware(ApplyDefaults(nil), Firmware{UEFI: true}, OVMF{})
+	assert.Error(t, err)
+}
+
+func TestApplyFirmware_BootMenu(t *testing.T) {
+	result, err := ApplyFirmware([]string{"-boot", "order=c,menu=on"}, Firmware{BootMenuTimeout: 10}, testOVMF)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-boot", "order=c,menu=on,splash-time=10000"}, result)
+
+	result, err = ApplyFirmware(result, Firmware{}, testOVMF)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-boot", "order=c"}, result)
+
+	result, err = ApplyFirmware([]string{"-boot", "menu=on"}, Firmware{}, testOVMF)
+	require.NoError(t, err)
+	assert.Empty(t, result)
+
+	result, err = ApplyFirmware(nil, Firmware{BootMenuTimeout: 2}, testOVMF)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-boot", "menu=on,splash-time=2000"}, result)
+}
+
+func TestApplyFirmware_SMBIOS(t *testing.T) {
+	args := []string{"-smbios", "type=1,manufacturer=Old", "-smbios", "type=0,vendor=Keep"}
+	fw := Firmware{SMBIOS: SMBIOS{Manufacturer: "ACME, Inc.", Product: "VM-1"}}
+	result, err := ApplyFirmware(args, fw, testOVMF)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"-smbios", "type=0,vendor=Keep",
+		"-smbios", "type=1,manufacturer=ACME,, Inc.,product=VM-1",
+	}, result)
+	assert.Equal(t, fw.SMBIOS, ParseFirmware(result).SMBIOS)
+}
+
+func TestApplyFirmware_NUMA(t *testing.T) {
+	args := []string{"-m", "3G", "-smp", "4"}
+	result, err := ApplyFirmware(args, Firmware{NUMANodes: 2}, testOVMF)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"-m", "3G", "-smp", "4",
+		"-object", "memory-backend-ram,id=bmc-numa0,size=1536M",
+		"-numa", "node,nodeid=0,memdev=bmc-numa0,cpus=0-1",
+		"-object", "memory-backend-ram,id=bmc-numa1,size=1536M",
+		"-numa", "node,nodeid=1,memdev=bmc-numa1,cpus=2-3",
+	}, result)
+	assert.Equal(t, 2, ParseFirmware(result).NUMANodes)
+
+	result, err = ApplyFirmware(result, Firmware{NUMANodes: 1}, testOVMF)
+	require.NoError(t, err)
+	assert.Equal(t, args, result)
+}
+
+func TestApplyFirmware_NUMAMoreNodesThanCPUs(t *testing.T) {
+	result, err := ApplyFirmware([]string{"-m", "1024", "-smp", "1"}, Firmware{NUMANodes: 2}, testOVMF)
+	require.NoError(t, err)
+	assert.Contains(t, result, "node,nodeid=0,memdev=bmc-numa0")
+	assert.Contains(t, result, "node,nodeid=1,memdev=bmc-numa1,cpus=0-0")
+}
+
+func TestApplyFirmware_KeepsOtherArgs(t *testing.T) {
+	args := []string{"-enable-kvm", "-drive", "file=disk.qcow2,if=virtio", "-nographic"}
+	result, err := ApplyFirmware(args, Firmware{}, testOVMF)
+	require.NoError(t, err)
+	assert.Equal(t, args, result)
+}
+
+func TestBuildCommandLine_AppliesFirmware(t *testing.T) {
+	args, err := BuildCommandLine(nil, BuildOptions{
+		QMPSocketPath: "/tmp/qmp.sock",
+		Firmware:      &Firmware{UEFI: true},
+		OVMF:          testOVMF,
+	})
+	require.NoError(t, err)
+	assert.True(t, ParseFirmware(args).UEFI)
+}
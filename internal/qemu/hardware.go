@@ -0,0 +1,171 @@
+package qemu
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Hardware is the VM configuration described by a QEMU command line. It is
+// used to report inventory while QEMU is not running.
+type Hardware struct {
+	Machine     string // -machine type as given, e.g. "q35"
+	Arch        string // target from the binary name, e.g. "x86_64"
+	CPUModel    string // -cpu model, empty for the machine default
+	CPUs        int    // vCPUs present at start
+	MaxCPUs     int
+	Sockets     int
+	Cores       int // cores per socket (dies folded in)
+	Threads     int // threads per core
+	MemoryBytes uint64
+}
+
+// ParseHardware extracts the machine type, CPU topology and memory size
+// from QEMU arguments. Arguments not given take QEMU's defaults.
+func ParseHardware(binary string, args []string) (Hardware, error) {
+	hw := Hardware{
+		Arch:        strings.TrimPrefix(filepath.Base(binary), "qemu-system-"),
+		CPUs:        1,
+		MaxCPUs:     1,
+		Sockets:     1,
+		Cores:       1,
+		Threads:     1,
+		MemoryBytes: 128 << 20,
+	}
+
+	for i := 0; i+1 < len(args); i++ {
+		val := args[i+1]
+		switch args[i] {
+		case "-machine", "-M":
+			// QEMU merges repeated -machine options; one without a
+			// type keeps the type given before.
+			if m := optionValue(val, "type"); m != "" {
+				hw.Machine = m
+			}
+		case "-cpu":
+			hw.CPUModel, _, _ = strings.Cut(val, ",")
+		case "-smp":
+			if err := parseSMP(val, &hw); err != nil {
+				return Hardware{}, err
+			}
+		case "-m":
+			size, err := parseMemory(val)
+			if err != nil {
+				return Hardware{}, err
+			}
+			hw.MemoryBytes = size
+		default:
+			continue
+		}
+		i++
+	}
+	return hw, nil
+}
+
+// optionValue returns the implied first value of a QEMU option string such
+// as "q35,accel=kvm", or the value of key if given explicitly.
+func optionValue(val, key string) string {
+	for i, part := range strings.Split(val, ",") {
+		k, v, found := strings.Cut(part, "=")
+		if !found {
+			if i == 0 {
+				return part
+			}
+			continue
+		}
+		if k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// parseSMP parses an -smp value, computing missing topology values the way
+// QEMU does: sockets are preferred over cores and threads.
+func parseSMP(val string, hw *Hardware) error {
+	var cpus, maxCPUs, sockets, dies, cores, threads int
+	for i, part := range strings.Split(val, ",") {
+		k, v, found := strings.Cut(part, "=")
+		if !found {
+			if i != 0 {
+				return fmt.Errorf("invalid -smp value %q", val)
+			}
+			k, v = "cpus", part
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid -smp %s %q", k, v)
+		}
+		switch k {
+		case "cpus":
+			cpus = n
+		case "maxcpus":
+			maxCPUs = n
+		case "sockets":
+			sockets = n
+		case "dies":
+			dies = n
+		case "cores":
+			cores = n
+		case "threads":
+			threads = n
+		}
+	}
+
+	if dies == 0 {
+		dies = 1
+	}
+	if cores == 0 {
+		cores = 1
+	}
+	if threads == 0 {
+		threads = 1
+	}
+	if maxCPUs == 0 {
+		maxCPUs = cpus
+	}
+	if sockets == 0 {
+		total := maxCPUs
+		if total == 0 {
+			total = 1
+		}
+		sockets = (total + dies*cores*threads - 1) / (dies * cores * threads)
+	}
+	if maxCPUs == 0 {
+		maxCPUs = sockets * dies * cores * threads
+	}
+	if cpus == 0 {
+		cpus = maxCPUs
+	}
+	if cpus > maxCPUs {
+		return fmt.Errorf("invalid -smp %q: cpus exceeds maxcpus", val)
+	}
+
+	hw.CPUs = cpus
+	hw.MaxCPUs = maxCPUs
+	hw.Sockets = sockets
+	hw.Cores = dies * cores
+	hw.Threads = threads
+	return nil
+}
+
+// parseMemory parses an -m value. Plain numbers are MiB; B, K, M, G and T
+// suffixes are accepted.
+func parseMemory(val string) (uint64, error) {
+	size := strings.ToUpper(optionValue(val, "size"))
+	shifts := map[byte]int{'B': 0, 'K': 10, 'M': 20, 'G': 30, 'T': 40}
+
+	shift := 20
+	if size != "" {
+		if s, ok := shifts[size[len(size)-1]]; ok {
+			shift = s
+			size = size[:len(size)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(size, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid -m value %q", val)
+	}
+	return uint64(n * float64(uint64(1)<<shift)), nil
+}
@@ -0,0 +1,95 @@
+package qemu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHardware_Defaults(t *testing.T) {
+	args := ApplyDefaults([]string{"-enable-kvm"})
+	hw, err := ParseHardware("/usr/bin/qemu-system-x86_64", args)
+	require.NoError(t, err)
+
+	assert.Equal(t, "q35", hw.Machine)
+	assert.Equal(t, "x86_64", hw.Arch)
+	assert.Equal(t, "", hw.CPUModel)
+	assert.Equal(t, 2, hw.CPUs)
+	assert.Equal(t, 2, hw.Sockets)
+	assert.Equal(t, 1, hw.Cores)
+	assert.Equal(t, 1, hw.Threads)
+	assert.Equal(t, uint64(2048<<20), hw.MemoryBytes)
+}
+
+func TestParseHardware_Options(t *testing.T) {
+	args := []string{
+		"-machine", "type=pc,accel=kvm",
+		"-machine", "smm=on",
+		"-cpu", "host,+vmx",
+		"-smp", "cpus=6,sockets=2,cores=4,threads=1,maxcpus=8",
+		"-m", "size=4G,slots=2,maxmem=8G",
+	}
+	hw, err := ParseHardware("qemu-system-x86_64", args)
+	require.NoError(t, err)
+
+	assert.Equal(t, "pc", hw.Machine)
+	assert.Equal(t, "host", hw.CPUModel)
+	assert.Equal(t, 6, hw.CPUs)
+	assert.Equal(t, 8, hw.MaxCPUs)
+	assert.Equal(t, 2, hw.Sockets)
+	assert.Equal(t, 4, hw.Cores)
+	assert.Equal(t, uint64(4<<30), hw.MemoryBytes)
+}
+
+func TestParseSMP(t *testing.T) {
+	tests := []struct {
+		val                                    string
+		cpus, maxCPUs, sockets, cores, threads int
+	}{
+		{"4", 4, 4, 4, 1, 1},
+		{"8,cores=4", 8, 8, 2, 4, 1},
+		{"cores=2,threads=2", 4, 4, 1, 2, 2},
+		{"2,maxcpus=4,cores=2", 2, 4, 2, 2, 1},
+		{"4,sockets=1,dies=2,cores=2", 4, 4, 1, 4, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.val, func(t *testing.T) {
+			var hw Hardware
+			require.NoError(t, parseSMP(tt.val, &hw))
+			assert.Equal(t, tt.cpus, hw.CPUs)
+			assert.Equal(t, tt.maxCPUs, hw.MaxCPUs)
+			assert.Equal(t, tt.sockets, hw.Sockets)
+			assert.Equal(t, tt.cores, hw.Cores)
+			assert.Equal(t, tt.threads, hw.Threads)
+		})
+	}
+
+	var hw Hardware
+	assert.Error(t, parseSMP("x", &hw))
+	assert.Error(t, parseSMP("8,maxcpus=4", &hw))
+}
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		val  string
+		want uint64
+	}{
+		{"2048", 2048 << 20},
+		{"512M", 512 << 20},
+		{"4G", 4 << 30},
+		{"1.5G", 3 << 29},
+		{"size=1T", 1 << 40},
+		{"65536k", 64 << 20},
+	}
+	for _, tt := range tests {
+		got, err := parseMemory(tt.val)
+		require.NoError(t, err, tt.val)
+		assert.Equal(t, tt.want, got, tt.val)
+	}
+
+	_, err := parseMemory("lots")
+	assert.Error(t, err)
+	_, err = parseMemory("slots=2")
+	assert.Error(t, err)
+}
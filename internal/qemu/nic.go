@@ -0,0 +1,113 @@
+package qemu
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNICNotFound is returned when no -device NIC has the requested ID.
+var ErrNICNotFound = errors.New("NIC not found")
+
+// nicDrivers are the -device drivers treated as network interfaces.
+var nicDrivers = map[string]bool{
+	"virtio-net-pci":    true,
+	"virtio-net-device": true,
+	"e1000":             true,
+	"e1000e":            true,
+	"igb":               true,
+	"rtl8139":           true,
+	"vmxnet3":           true,
+	"ne2k_pci":          true,
+	"pcnet":             true,
+}
+
+// NIC is a network interface given as -device on the QEMU command line
+type NIC struct {
+	ID     string
+	Driver string
+	MAC    string // empty when QEMU assigns one
+	Netdev string
+}
+
+// ParseNICs returns the -device NICs in args. NICs without an id cannot be
+// addressed and are skipped.
+func ParseNICs(args []string) []NIC {
+	var nics []NIC
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] != "-device" {
+			continue
+		}
+		opts := strings.Split(args[i+1], ",")
+		if !nicDrivers[deviceDriver(opts)] {
+			continue
+		}
+		nic := NIC{Driver: deviceDriver(opts)}
+		for _, opt := range opts {
+			k, v, _ := strings.Cut(opt, "=")
+			switch k {
+			case "id":
+				nic.ID = v
+			case "mac":
+				nic.MAC = v
+			case "netdev":
+				nic.Netdev = v
+			}
+		}
+		if nic.ID != "" {
+			nics = append(nics, nic)
+		}
+	}
+	return nics
+}
+
+// SetNICMAC returns a copy of args with the mac property of the -device NIC
+// with the given id set to mac.
+func SetNICMAC(args []string, id, mac string) ([]string, error) {
+	result := append([]string(nil), args...)
+	for i := 0; i+1 < len(result); i++ {
+		if result[i] != "-device" {
+			continue
+		}
+		opts := strings.Split(result[i+1], ",")
+		if !nicDrivers[deviceDriver(opts)] || !hasOption(opts, "id", id) {
+			continue
+		}
+
+		replaced := false
+		for j, opt := range opts {
+			if strings.HasPrefix(opt, "mac=") {
+				opts[j] = "mac=" + mac
+				replaced = true
+			}
+		}
+		if !replaced {
+			opts = append(opts, "mac="+mac)
+		}
+		result[i+1] = strings.Join(opts, ",")
+		return result, nil
+	}
+	return nil, ErrNICNotFound
+}
+
+// deviceDriver returns the driver of a -device option list.
+func deviceDriver(opts []string) string {
+	for i, opt := range opts {
+		k, v, found := strings.Cut(opt, "=")
+		if !found && i == 0 {
+			return opt
+		}
+		if k == "driver" {
+			return v
+		}
+	}
+	return ""
+}
+
+func hasOption(opts []string, key, value string) bool {
+	for _, opt := range opts {
+		if opt == key+"="+value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package qemu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNICs(t *testing.T) {
+	args := []string{
+		"-netdev", "bridge,id=net0,br=br0",
+		"-device", "virtio-net-pci,netdev=net0,id=nic0,mac=52:54:00:12:34:56",
+		"-netdev", "user,id=net1",
+		"-device", "driver=e1000,netdev=net1,id=nic1",
+		"-device", "virtio-net-pci,netdev=net2",
+		"-device", "virtio-blk-pci,drive=disk0,id=disk0",
+	}
+
+	nics := ParseNICs(args)
+	require.Len(t, nics, 2)
+	assert.Equal(t, NIC{ID: "nic0", Driver: "virtio-net-pci", MAC: "52:54:00:12:34:56", Netdev: "net0"}, nics[0])
+	assert.Equal(t, NIC{ID: "nic1", Driver: "e1000", Netdev: "net1"}, nics[1])
+}
+
+func TestSetNICMAC(t *testing.T) {
+	args := []string{
+		"-device", "virtio-net-pci,netdev=net0,id=nic0,mac=52:54:00:12:34:56",
+		"-device", "e1000,netdev=net1,id=nic1",
+	}
+
+	got, err := SetNICMAC(args, "nic0", "52:54:00:aa:bb:cc")
+	require.NoError(t, err)
+	assert.Equal(t, "virtio-net-pci,netdev=net0,id=nic0,mac=52:54:00:aa:bb:cc", got[1])
+	assert.Equal(t, "virtio-net-pci,netdev=net0,id=nic0,mac=52:54:00:12:34:56", args[1], "input must not be modified")
+
+	got, err = SetNICMAC(got, "nic1", "52:54:00:dd:ee:ff")
+	require.NoError(t, err)
+	assert.Equal(t, "e1000,netdev=net1,id=nic1,mac=52:54:00:dd:ee:ff", got[3])
+
+	_, err = SetNICMAC(args, "nic9", "52:54:00:dd:ee:ff")
+	assert.ErrorIs(t, err, ErrNICNotFound)
+}
@@ -3,19 +3,43 @@ package qemu
 import (
 	"fmt"
 	"os/exec"
+	"slices"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/tjst-t/qemu-bmc/internal/eventlog"
 )
 
+// maxProcessLogEntries bounds the log of QEMU output and exits.
+const maxProcessLogEntries = 1000
+
+// Boot configures a single start of QEMU on top of its base arguments.
+type Boot struct {
+	Target   string    // Redfish boot source override target, "" or "None" for none
+	Firmware *Firmware // firmware for this start only, nil for the base arguments' firmware
+	OVMF     OVMF      // OVMF images, used with Firmware
+
+	Device   int    // UefiTarget: position in ParseBootDevices of the device booted first
+	BootDisk string // UefiShell, UefiHttp: directory served to the guest as a FAT boot disk
+
+	// Paused starts QEMU with its CPUs stopped (-S) so the caller can set
+	// the VM up over QMP before continuing it.
+	Paused bool
+}
+
 // ProcessManager controls the lifecycle of a QEMU process.
 type ProcessManager interface {
-	Start(bootTarget string) error
+	Start(boot Boot) error
 	Stop(timeout time.Duration) error
 	Kill() error
 	IsRunning() bool
 	WaitForExit(timeout time.Duration) error
 	ExitCh() <-chan struct{}
+	Args() []string
+	SetArgs(args []string)
+	Log() *eventlog.Log
 }
 
 // CommandFactory creates exec.Cmd instances. Allows test injection.
@@ -31,8 +55,11 @@ type processManager struct {
 	baseArgs   []string
 	cmdFactory CommandFactory
 	cmd        *exec.Cmd
+	output     []*lineWriter // stdout and stderr of cmd
 	running    bool
+	stopping   bool // Stop or Kill was called for cmd
 	exitCh     chan struct{}
+	log        *eventlog.Log
 	mu         sync.RWMutex
 }
 
@@ -43,10 +70,11 @@ func NewProcessManager(binary string, baseArgs []string, factory CommandFactory)
 		baseArgs:   baseArgs,
 		cmdFactory: factory,
 		exitCh:     make(chan struct{}),
+		log:        eventlog.New(maxProcessLogEntries),
 	}
 }
 
-func (p *processManager) Start(bootTarget string) error {
+func (p *processManager) Start(boot Boot) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -54,15 +82,38 @@ func (p *processManager) Start(bootTarget string) error {
 		return nil // already running, no-op
 	}
 
-	args := ApplyBootOverride(p.baseArgs, bootTarget)
+	args := p.baseArgs
+	var err error
+	if boot.Firmware != nil {
+		if args, err = ApplyFirmware(args, *boot.Firmware, boot.OVMF); err != nil {
+			return err
+		}
+	}
+	if args, err = ApplyBootTarget(args, boot); err != nil {
+		return err
+	}
+	if boot.Paused && !slices.Contains(args, "-S") {
+		args = append(args[:len(args):len(args)], "-S")
+	}
 	p.cmd = p.cmdFactory(p.binary, args)
 	p.cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	stdout := &lineWriter{log: p.log, severity: eventlog.SeverityOK}
+	stderr := &lineWriter{log: p.log, severity: eventlog.SeverityWarning}
+	p.cmd.Stdout = teeOutput(p.cmd.Stdout, stdout)
+	p.cmd.Stderr = teeOutput(p.cmd.Stderr, stderr)
+	p.output = []*lineWriter{stdout, stderr}
 
 	if err := p.cmd.Start(); err != nil {
-		return fmt.Errorf("starting QEMU process: %w", err)
+		err = fmt.Errorf("starting QEMU process: %w", err)
+		p.log.Add(eventlog.Entry{Severity: eventlog.SeverityCritical, Message: err.Error()})
+		return err
 	}
+	p.log.Add(eventlog.Entry{
+		Message: fmt.Sprintf("QEMU started (pid %d): %s", p.cmd.Process.Pid, strings.Join(p.cmd.Args, " ")),
+	})
 
 	p.running = true
+	p.stopping = false
 	p.exitCh = make(chan struct{})
 
 	go p.monitor()
@@ -70,11 +121,30 @@ func (p *processManager) Start(bootTarget string) error {
 }
 
 func (p *processManager) monitor() {
-	p.cmd.Wait()
+	p.mu.RLock()
+	cmd, output := p.cmd, p.output
+	p.mu.RUnlock()
+
+	cmd.Wait()
+	for _, w := range output {
+		w.Flush()
+	}
+
 	p.mu.Lock()
 	p.running = false
+	stopping := p.stopping
 	ch := p.exitCh
 	p.mu.Unlock()
+
+	exit := eventlog.Entry{Message: "QEMU exited: " + cmd.ProcessState.String()}
+	switch {
+	case stopping:
+		exit.Message = "QEMU stopped: " + cmd.ProcessState.String()
+	case !cmd.ProcessState.Success():
+		exit.Severity = eventlog.SeverityCritical
+		exit.Message = "QEMU exited unexpectedly: " + cmd.ProcessState.String()
+	}
+	p.log.Add(exit)
 	close(ch)
 }
 
@@ -90,6 +160,7 @@ func (p *processManager) Stop(timeout time.Duration) error {
 	if cmd.Process == nil {
 		return nil
 	}
+	p.markStopping()
 
 	// Send SIGTERM
 	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
@@ -116,6 +187,7 @@ func (p *processManager) Kill() error {
 	if cmd.Process == nil {
 		return nil
 	}
+	p.markStopping()
 
 	if err := cmd.Process.Signal(syscall.SIGKILL); err != nil {
 		return fmt.Errorf("sending SIGKILL: %w", err)
@@ -147,3 +219,29 @@ func (p *processManager) ExitCh() <-chan struct{} {
 	defer p.mu.RUnlock()
 	return p.exitCh
 }
+
+// Args returns the QEMU arguments used for the next start.
+func (p *processManager) Args() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]string(nil), p.baseArgs...)
+}
+
+// SetArgs replaces the QEMU arguments. A running process is not affected;
+// the new arguments take effect at the next Start.
+func (p *processManager) SetArgs(args []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.baseArgs = append([]string(nil), args...)
+}
+
+// Log returns the log of QEMU starts, output and exits.
+func (p *processManager) Log() *eventlog.Log {
+	return p.log
+}
+
+func (p *processManager) markStopping() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopping = true
+}
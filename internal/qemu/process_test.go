@@ -1,12 +1,14 @@
 package qemu
 
 import (
+	"bytes"
 	"os/exec"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/eventlog"
 )
 
 // sleepFactory returns a CommandFactory that runs "sleep" for testing.
@@ -27,7 +29,7 @@ func (f *trackingFactory) create(binary string, args []string) *exec.Cmd {
 
 func TestProcessManager_Start_IsRunning(t *testing.T) {
 	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, sleepFactory)
-	require.NoError(t, pm.Start(""))
+	require.NoError(t, pm.Start(Boot{}))
 	defer pm.Kill()
 
 	assert.True(t, pm.IsRunning())
@@ -35,17 +37,17 @@ func TestProcessManager_Start_IsRunning(t *testing.T) {
 
 func TestProcessManager_Start_AlreadyRunning_Noop(t *testing.T) {
 	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, sleepFactory)
-	require.NoError(t, pm.Start(""))
+	require.NoError(t, pm.Start(Boot{}))
 	defer pm.Kill()
 
 	// Second start is a no-op
-	require.NoError(t, pm.Start(""))
+	require.NoError(t, pm.Start(Boot{}))
 	assert.True(t, pm.IsRunning())
 }
 
 func TestProcessManager_Stop_TerminatesProcess(t *testing.T) {
 	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, sleepFactory)
-	require.NoError(t, pm.Start(""))
+	require.NoError(t, pm.Start(Boot{}))
 
 	err := pm.Stop(5 * time.Second)
 	require.NoError(t, err)
@@ -57,7 +59,7 @@ func TestProcessManager_Stop_TerminatesProcess(t *testing.T) {
 
 func TestProcessManager_Kill_TerminatesImmediately(t *testing.T) {
 	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, sleepFactory)
-	require.NoError(t, pm.Start(""))
+	require.NoError(t, pm.Start(Boot{}))
 
 	err := pm.Kill()
 	require.NoError(t, err)
@@ -73,7 +75,7 @@ func TestProcessManager_IsRunning_AfterExit(t *testing.T) {
 		return exec.Command("true")
 	}
 	pm := NewProcessManager("qemu-system-x86_64", []string{}, factory)
-	require.NoError(t, pm.Start(""))
+	require.NoError(t, pm.Start(Boot{}))
 
 	// Wait for the process to exit naturally
 	time.Sleep(200 * time.Millisecond)
@@ -82,7 +84,7 @@ func TestProcessManager_IsRunning_AfterExit(t *testing.T) {
 
 func TestProcessManager_WaitForExit_Timeout(t *testing.T) {
 	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, sleepFactory)
-	require.NoError(t, pm.Start(""))
+	require.NoError(t, pm.Start(Boot{}))
 	defer pm.Kill()
 
 	err := pm.WaitForExit(100 * time.Millisecond)
@@ -95,7 +97,7 @@ func TestProcessManager_ExitCh_ClosedOnExit(t *testing.T) {
 		return exec.Command("true")
 	}
 	pm := NewProcessManager("qemu-system-x86_64", []string{}, factory)
-	require.NoError(t, pm.Start(""))
+	require.NoError(t, pm.Start(Boot{}))
 
 	select {
 	case <-pm.ExitCh():
@@ -108,7 +110,7 @@ func TestProcessManager_ExitCh_ClosedOnExit(t *testing.T) {
 func TestProcessManager_Start_AppliesBootOverride(t *testing.T) {
 	f := &trackingFactory{}
 	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, f.create)
-	require.NoError(t, pm.Start("Pxe"))
+	require.NoError(t, pm.Start(Boot{Target: "Pxe"}))
 	defer pm.Kill()
 
 	assert.Contains(t, f.lastArgs, "-boot")
@@ -118,12 +120,22 @@ func TestProcessManager_Start_AppliesBootOverride(t *testing.T) {
 func TestProcessManager_Start_NoBootOverride(t *testing.T) {
 	f := &trackingFactory{}
 	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, f.create)
-	require.NoError(t, pm.Start(""))
+	require.NoError(t, pm.Start(Boot{}))
 	defer pm.Kill()
 
 	assert.NotContains(t, f.lastArgs, "-boot")
 }
 
+func TestProcessManager_Start_Paused(t *testing.T) {
+	f := &trackingFactory{}
+	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, f.create)
+	require.NoError(t, pm.Start(Boot{Paused: true}))
+	defer pm.Kill()
+
+	assert.Equal(t, []string{"-m", "2048", "-S"}, f.lastArgs)
+	assert.Equal(t, []string{"-m", "2048"}, pm.Args())
+}
+
 func TestProcessManager_Stop_NotRunning_Noop(t *testing.T) {
 	pm := NewProcessManager("qemu-system-x86_64", []string{}, sleepFactory)
 	err := pm.Stop(time.Second)
@@ -140,7 +152,7 @@ func TestProcessManager_Restart_AfterStop(t *testing.T) {
 	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, sleepFactory)
 
 	// Start
-	require.NoError(t, pm.Start(""))
+	require.NoError(t, pm.Start(Boot{}))
 	assert.True(t, pm.IsRunning())
 
 	// Stop
@@ -149,7 +161,108 @@ func TestProcessManager_Restart_AfterStop(t *testing.T) {
 	assert.False(t, pm.IsRunning())
 
 	// Re-start
-	require.NoError(t, pm.Start(""))
+	require.NoError(t, pm.Start(Boot{}))
 	defer pm.Kill()
 	assert.True(t, pm.IsRunning())
 }
+
+func TestProcessManager_SetArgs_NextStart(t *testing.T) {
+	f := &trackingFactory{}
+	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, f.create)
+	assert.Equal(t, []string{"-m", "2048"}, pm.Args())
+
+	pm.SetArgs([]string{"-m", "4096"})
+	require.NoError(t, pm.Start(Boot{}))
+	defer pm.Kill()
+
+	assert.Equal(t, []string{"-m", "4096"}, f.lastArgs)
+	assert.Equal(t, []string{"-m", "4096"}, pm.Args())
+}
+
+func TestProcessManager_Start_FirmwareForOneBoot(t *testing.T) {
+	f := &trackingFactory{}
+	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, f.create)
+	require.NoError(t, pm.Start(Boot{Firmware: &Firmware{UEFI: true}, OVMF: testOVMF}))
+	defer pm.Kill()
+
+	assert.True(t, ParseFirmware(f.lastArgs).UEFI)
+	assert.Equal(t, []string{"-m", "2048"}, pm.Args())
+}
+
+func TestProcessManager_Start_InvalidFirmware(t *testing.T) {
+	f := &trackingFactory{}
+	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, f.create)
+	assert.Error(t, pm.Start(Boot{Firmware: &Firmware{UEFI: true}}))
+	assert.False(t, pm.IsRunning())
+}
+
+func TestProcessManager_Start_UEFIBootTarget(t *testing.T) {
+	f := &trackingFactory{}
+	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, f.create)
+	require.NoError(t, pm.Start(Boot{Target: "Cd", Firmware: &Firmware{UEFI: true}, OVMF: testOVMF}))
+	defer pm.Kill()
+
+	assert.Contains(t, f.lastArgs, "ide-cd.bootindex=0")
+	assert.NotContains(t, f.lastArgs, "-boot")
+}
+
+func TestProcessManager_Log_OutputAndExit(t *testing.T) {
+	factory := func(binary string, args []string) *exec.Cmd {
+		return exec.Command("sh", "-c", "echo booting; echo 'could not open disk' >&2; printf partial; exit 3")
+	}
+	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, factory)
+	require.NoError(t, pm.Start(Boot{}))
+	require.NoError(t, pm.WaitForExit(2*time.Second))
+
+	entries := pm.Log().Entries()
+	require.Len(t, entries, 5)
+	assert.Contains(t, entries[0].Message, "QEMU started")
+	messages := map[string]string{}
+	for _, e := range entries[1:4] {
+		messages[e.Message] = e.Severity
+	}
+	assert.Equal(t, map[string]string{
+		"booting":             eventlog.SeverityOK,
+		"could not open disk": eventlog.SeverityWarning,
+		"partial":             eventlog.SeverityOK,
+	}, messages)
+	assert.Equal(t, "QEMU exited unexpectedly: exit status 3", entries[4].Message)
+	assert.Equal(t, eventlog.SeverityCritical, entries[4].Severity)
+}
+
+func TestProcessManager_Log_Stopped(t *testing.T) {
+	pm := NewProcessManager("qemu-system-x86_64", []string{"-m", "2048"}, sleepFactory)
+	require.NoError(t, pm.Start(Boot{}))
+	require.NoError(t, pm.Stop(5*time.Second))
+	require.NoError(t, pm.WaitForExit(2*time.Second))
+
+	entries := pm.Log().Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, "QEMU stopped: signal: terminated", entries[1].Message)
+	assert.Equal(t, eventlog.SeverityOK, entries[1].Severity)
+}
+
+func TestProcessManager_Log_StartFailure(t *testing.T) {
+	factory := func(binary string, args []string) *exec.Cmd {
+		return exec.Command("/nonexistent/qemu-system-x86_64")
+	}
+	pm := NewProcessManager("qemu-system-x86_64", nil, factory)
+	require.Error(t, pm.Start(Boot{}))
+
+	entries := pm.Log().Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, eventlog.SeverityCritical, entries[0].Severity)
+	assert.Contains(t, entries[0].Message, "starting QEMU process")
+}
+
+func TestLineWriter_SplitsLongLines(t *testing.T) {
+	log := eventlog.New(10)
+	w := &lineWriter{log: log, severity: eventlog.SeverityOK}
+	w.Write(bytes.Repeat([]byte("x"), maxLogLine+10))
+	w.Write([]byte("\r\n\n"))
+
+	entries := log.Entries()
+	require.Len(t, entries, 2)
+	assert.Len(t, entries[0].Message, maxLogLine)
+	assert.Equal(t, "xxxxxxxxxx", entries[1].Message)
+}
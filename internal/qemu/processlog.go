@@ -0,0 +1,64 @@
+package qemu
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/tjst-t/qemu-bmc/internal/eventlog"
+)
+
+// maxLogLine is the longest output line logged as one entry. Longer lines
+// are split.
+const maxLogLine = 4096
+
+// lineWriter adds each line of QEMU output written to it to a log.
+type lineWriter struct {
+	log      *eventlog.Log
+	severity string
+	mu       sync.Mutex
+	buf      []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.add(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	for len(w.buf) >= maxLogLine {
+		w.add(w.buf[:maxLogLine])
+		w.buf = w.buf[maxLogLine:]
+	}
+	return len(p), nil
+}
+
+// Flush logs a final line without a newline.
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.add(w.buf)
+	w.buf = nil
+}
+
+func (w *lineWriter) add(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) == 0 {
+		return
+	}
+	w.log.Add(eventlog.Entry{Severity: w.severity, Message: string(line)})
+}
+
+// teeOutput also writes to the output set up by the CommandFactory, if any.
+func teeOutput(out io.Writer, w *lineWriter) io.Writer {
+	if out == nil {
+		return w
+	}
+	return io.MultiWriter(out, w)
+}
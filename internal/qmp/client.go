@@ -226,19 +226,184 @@ func (c *qmpClient) Quit() error {
 	return c.execute("quit", nil)
 }
 
-func (c *qmpClient) BlockdevChangeMedium(device, filename string) error {
+func (c *qmpClient) InjectNMI() error {
+	return c.execute("inject-nmi", nil)
+}
+
+func (c *qmpClient) SystemWakeup() error {
+	return c.execute("system_wakeup", nil)
+}
+
+func (c *qmpClient) BlockdevChangeMedium(id, filename string, readOnly bool) error {
+	mode := "read-write"
+	if readOnly {
+		mode = "read-only"
+	}
 	return c.execute("blockdev-change-medium", blockdevChangeMediumArgs{
-		Device:   device,
-		Filename: filename,
+		ID:           id,
+		Filename:     filename,
+		ReadOnlyMode: mode,
 	})
 }
 
-func (c *qmpClient) BlockdevRemoveMedium(device string) error {
+// BlockdevRemoveMedium opens the tray, forcing it open if the guest locked
+// it, and removes the medium.
+func (c *qmpClient) BlockdevRemoveMedium(id string) error {
+	if err := c.execute("blockdev-open-tray", blockdevOpenTrayArgs{
+		ID:    id,
+		Force: true,
+	}); err != nil {
+		return err
+	}
 	return c.execute("blockdev-remove-medium", blockdevRemoveMediumArgs{
-		Device: device,
+		ID: id,
 	})
 }
 
+// query runs command and decodes its "return" member into out.
+func (c *qmpClient) query(command string, arguments, out interface{}) error {
+	raw, err := c.executeWithResponse(command, arguments)
+	if err != nil {
+		return err
+	}
+
+	var resp qmpReturn
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return fmt.Errorf("parsing %s response: %w", command, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("QMP error: %s: %s", resp.Error.Class, resp.Error.Desc)
+	}
+	if err := json.Unmarshal(resp.Return, out); err != nil {
+		return fmt.Errorf("parsing %s response: %w", command, err)
+	}
+	return nil
+}
+
+func (c *qmpClient) QueryCPUsFast() ([]CPUInfoFast, error) {
+	var cpus []CPUInfoFast
+	if err := c.query("query-cpus-fast", nil, &cpus); err != nil {
+		return nil, err
+	}
+	return cpus, nil
+}
+
+func (c *qmpClient) QueryMemorySizeSummary() (MemorySizeSummary, error) {
+	var summary MemorySizeSummary
+	err := c.query("query-memory-size-summary", nil, &summary)
+	return summary, err
+}
+
+func (c *qmpClient) QueryMachines() ([]MachineInfo, error) {
+	var machines []MachineInfo
+	if err := c.query("query-machines", nil, &machines); err != nil {
+		return nil, err
+	}
+	return machines, nil
+}
+
+func (c *qmpClient) QueryCPUDefinitions() ([]CPUDefinition, error) {
+	var defs []CPUDefinition
+	if err := c.query("query-cpu-definitions", nil, &defs); err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
+
+func (c *qmpClient) QOMGet(path, property string) (json.RawMessage, error) {
+	var value json.RawMessage
+	if err := c.query("qom-get", qomGetArgs{Path: path, Property: property}, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (c *qmpClient) QueryBlock() ([]BlockInfo, error) {
+	var blocks []BlockInfo
+	if err := c.query("query-block", nil, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+func (c *qmpClient) QueryBlockstats() ([]BlockStats, error) {
+	var stats []BlockStats
+	if err := c.query("query-blockstats", nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// BlockdevCreate starts a blockdev-create job. Poll QueryJobs for its
+// completion and dismiss it with JobDismiss.
+func (c *qmpClient) BlockdevCreate(jobID string, options interface{}) error {
+	return c.execute("blockdev-create", blockdevCreateArgs{
+		JobID:   jobID,
+		Options: options,
+	})
+}
+
+func (c *qmpClient) BlockdevAdd(options interface{}) error {
+	return c.execute("blockdev-add", options)
+}
+
+func (c *qmpClient) BlockdevDel(nodeName string) error {
+	return c.execute("blockdev-del", nodeNameArgs{NodeName: nodeName})
+}
+
+func (c *qmpClient) QueryJobs() ([]JobInfo, error) {
+	var jobs []JobInfo
+	if err := c.query("query-jobs", nil, &jobs); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (c *qmpClient) JobDismiss(id string) error {
+	return c.execute("job-dismiss", idArgs{ID: id})
+}
+
+func (c *qmpClient) DeviceAdd(driver, id string, props map[string]interface{}) error {
+	args := map[string]interface{}{"driver": driver, "id": id}
+	for k, v := range props {
+		args[k] = v
+	}
+	return c.execute("device_add", args)
+}
+
+func (c *qmpClient) DeviceDel(id string) error {
+	return c.execute("device_del", idArgs{ID: id})
+}
+
+func (c *qmpClient) QueryPCI() ([]PCIBus, error) {
+	var buses []PCIBus
+	if err := c.query("query-pci", nil, &buses); err != nil {
+		return nil, err
+	}
+	return buses, nil
+}
+
+// SetLink connects or disconnects the link of a NIC, named by its qdev ID
+// or netdev ID. QEMU has no command to read the link state back.
+func (c *qmpClient) SetLink(name string, up bool) error {
+	return c.execute("set_link", setLinkArgs{Name: name, Up: up})
+}
+
+// QueryStats returns the statistics of provider for target, e.g. "vcpu",
+// limited to names unless nil. It needs QEMU 7.1, and the provider, e.g.
+// "kvm", must be in use.
+func (c *qmpClient) QueryStats(target, provider string, names []string) ([]StatsResult, error) {
+	var results []StatsResult
+	args := queryStatsArgs{
+		Target:    target,
+		Providers: []statsProvider{{Provider: provider, Names: names}},
+	}
+	if err := c.query("query-stats", args, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
 func (c *qmpClient) Close() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -76,6 +76,23 @@ func TestClient_SystemReset(t *testing.T) {
 	assert.Equal(t, "system_reset", mockQMP.LastCommand())
 }
 
+func TestClient_InjectNMIAndWakeup(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "qmp.sock")
+	mockQMP := newMockQMPServer(t, socketPath)
+	defer mockQMP.Close()
+
+	time.Sleep(50 * time.Millisecond)
+
+	client, err := NewClient(socketPath)
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.NoError(t, client.InjectNMI())
+	assert.Equal(t, "inject-nmi", mockQMP.LastCommand())
+	require.NoError(t, client.SystemWakeup())
+	assert.Equal(t, "system_wakeup", mockQMP.LastCommand())
+}
+
 func TestClient_Quit(t *testing.T) {
 	socketPath := filepath.Join(t.TempDir(), "qmp.sock")
 	mockQMP := newMockQMPServer(t, socketPath)
@@ -103,7 +120,7 @@ func TestClient_BlockdevChangeMedium(t *testing.T) {
 	require.NoError(t, err)
 	defer client.Close()
 
-	err = client.BlockdevChangeMedium("ide0-cd0", "/path/to/image.iso")
+	err = client.BlockdevChangeMedium("cd0", "/path/to/image.iso", true)
 	require.NoError(t, err)
 	assert.Equal(t, "blockdev-change-medium", mockQMP.LastCommand())
 }
@@ -119,7 +136,7 @@ func TestClient_BlockdevRemoveMedium(t *testing.T) {
 	require.NoError(t, err)
 	defer client.Close()
 
-	err = client.BlockdevRemoveMedium("ide0-cd0")
+	err = client.BlockdevRemoveMedium("cd0")
 	require.NoError(t, err)
 	assert.Equal(t, "blockdev-remove-medium", mockQMP.LastCommand())
 }
@@ -188,3 +205,165 @@ func TestClient_Close_ThenConnect(t *testing.T) {
 
 	client.Close()
 }
+
+func TestClient_InventoryQueries(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "qmp.sock")
+	mockQMP := newMockQMPServer(t, socketPath)
+	defer mockQMP.Close()
+	mockQMP.SetResponse("query-cpus-fast", `[
+		{"cpu-index": 0, "qom-path": "/machine/unattached/device[0]", "thread-id": 101, "target": "x86_64",
+		 "props": {"socket-id": 0, "die-id": 0, "core-id": 0, "thread-id": 0}},
+		{"cpu-index": 1, "qom-path": "/machine/unattached/device[1]", "thread-id": 102, "target": "x86_64",
+		 "props": {"socket-id": 1, "die-id": 0, "core-id": 0, "thread-id": 0}}]`)
+	mockQMP.SetResponse("query-memory-size-summary", `{"base-memory": 4294967296, "plugged-memory": 0}`)
+	mockQMP.SetResponse("query-machines", `[{"name": "pc-q35-8.2", "alias": "q35", "cpu-max": 1024, "default-cpu-type": "qemu64-x86_64-cpu"}]`)
+	mockQMP.SetResponse("query-cpu-definitions", `[{"name": "qemu64", "typename": "qemu64-x86_64-cpu"}]`)
+	mockQMP.SetResponse("qom-get", `"pc-q35-8.2-machine"`)
+
+	time.Sleep(50 * time.Millisecond)
+
+	client, err := NewClient(socketPath)
+	require.NoError(t, err)
+	defer client.Close()
+
+	cpus, err := client.QueryCPUsFast()
+	require.NoError(t, err)
+	require.Len(t, cpus, 2)
+	assert.Equal(t, 1, cpus[1].CPUIndex)
+	assert.Equal(t, 1, cpus[1].Props.SocketID)
+	assert.Equal(t, "x86_64", cpus[1].Target)
+
+	mem, err := client.QueryMemorySizeSummary()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4<<30), mem.BaseMemory)
+
+	machines, err := client.QueryMachines()
+	require.NoError(t, err)
+	require.Len(t, machines, 1)
+	assert.Equal(t, "q35", machines[0].Alias)
+	assert.Equal(t, 1024, machines[0].CPUMax)
+
+	defs, err := client.QueryCPUDefinitions()
+	require.NoError(t, err)
+	require.Len(t, defs, 1)
+	assert.Equal(t, "qemu64-x86_64-cpu", defs[0].TypeName)
+
+	value, err := client.QOMGet("/machine", "type")
+	require.NoError(t, err)
+	assert.JSONEq(t, `"pc-q35-8.2-machine"`, string(value))
+	assert.Equal(t, "qom-get", mockQMP.LastCommand())
+}
+
+func TestNewDisconnectedClient_QueryNotConnected(t *testing.T) {
+	client := NewDisconnectedClient("/tmp/nonexistent.sock")
+	defer client.Close()
+
+	_, err := client.QueryCPUsFast()
+	assert.ErrorIs(t, err, ErrNotConnected)
+}
+
+func TestClient_BlockQueries(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "qmp.sock")
+	mockQMP := newMockQMPServer(t, socketPath)
+	defer mockQMP.Close()
+	mockQMP.SetResponse("query-block", `[
+		{"device": "", "qdev": "/machine/peripheral/disk0/virtio-backend", "removable": false, "locked": false,
+		 "inserted": {"file": "/vm/disk.qcow2", "node-name": "disk0", "ro": false, "drv": "qcow2",
+		  "backing_file": "/vm/base.qcow2",
+		  "image": {"filename": "/vm/disk.qcow2", "format": "qcow2", "virtual-size": 10737418240, "backing-filename": "/vm/base.qcow2"}}},
+		{"device": "ide0-cd0", "qdev": "/machine/unattached/device[23]", "removable": true, "locked": false, "tray_open": false}]`)
+	mockQMP.SetResponse("query-blockstats", `[{"qdev": "/machine/peripheral/disk0/virtio-backend", "stats": {"rd_bytes": 512, "wr_bytes": 1024, "rd_operations": 1, "wr_operations": 2}}]`)
+	mockQMP.SetResponse("query-jobs", `[{"id": "job0", "type": "create", "status": "concluded", "error": "boom"}]`)
+
+	time.Sleep(50 * time.Millisecond)
+
+	client, err := NewClient(socketPath)
+	require.NoError(t, err)
+	defer client.Close()
+
+	blocks, err := client.QueryBlock()
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	require.NotNil(t, blocks[0].Inserted)
+	assert.Equal(t, "qcow2", blocks[0].Inserted.Image.Format)
+	assert.Equal(t, int64(10<<30), blocks[0].Inserted.Image.VirtualSize)
+	assert.Equal(t, "/vm/base.qcow2", blocks[0].Inserted.BackingFile)
+	assert.Nil(t, blocks[1].Inserted)
+	require.NotNil(t, blocks[1].TrayOpen)
+
+	stats, err := client.QueryBlockstats()
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, int64(1024), stats[0].Stats.WrBytes)
+
+	jobs, err := client.QueryJobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "boom", jobs[0].Error)
+
+	require.NoError(t, client.BlockdevCreate("job0", map[string]interface{}{"driver": "file", "filename": "/tmp/x", "size": 0}))
+	assert.Equal(t, "blockdev-create", mockQMP.LastCommand())
+	require.NoError(t, client.JobDismiss("job0"))
+	assert.Equal(t, "job-dismiss", mockQMP.LastCommand())
+	require.NoError(t, client.BlockdevAdd(map[string]interface{}{"driver": "file", "node-name": "n0", "filename": "/tmp/x"}))
+	assert.Equal(t, "blockdev-add", mockQMP.LastCommand())
+	require.NoError(t, client.DeviceAdd("virtio-blk-pci", "dev0", map[string]interface{}{"drive": "n0"}))
+	assert.Equal(t, "device_add", mockQMP.LastCommand())
+	require.NoError(t, client.DeviceDel("dev0"))
+	assert.Equal(t, "device_del", mockQMP.LastCommand())
+	require.NoError(t, client.BlockdevDel("n0"))
+	assert.Equal(t, "blockdev-del", mockQMP.LastCommand())
+	require.NoError(t, client.SetLink("nic0", false))
+	assert.Equal(t, "set_link", mockQMP.LastCommand())
+}
+
+func TestClient_QueryPCI(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "qmp.sock")
+	mockQMP := newMockQMPServer(t, socketPath)
+	defer mockQMP.Close()
+	mockQMP.SetResponse("query-pci", `[{"bus": 0, "devices": [
+		{"bus": 0, "slot": 2, "function": 0, "qdev_id": "nic0", "class_info": {"class": 512, "desc": "Ethernet controller"},
+		 "id": {"vendor": 6900, "device": 4096}},
+		{"bus": 0, "slot": 28, "function": 0, "qdev_id": "rp0", "class_info": {"class": 1540},
+		 "id": {"vendor": 6966, "device": 12}, "pci_bridge": {"devices": [
+			{"bus": 1, "slot": 0, "function": 0, "qdev_id": "", "class_info": {"class": 512}, "id": {"vendor": 32902, "device": 4307}}]}}]}]`)
+
+	time.Sleep(50 * time.Millisecond)
+
+	client, err := NewClient(socketPath)
+	require.NoError(t, err)
+	defer client.Close()
+
+	buses, err := client.QueryPCI()
+	require.NoError(t, err)
+	require.Len(t, buses, 1)
+	require.Len(t, buses[0].Devices, 2)
+	assert.Equal(t, PCIClassEthernet, buses[0].Devices[0].ClassInfo.Class)
+	assert.Equal(t, "nic0", buses[0].Devices[0].QdevID)
+	require.NotNil(t, buses[0].Devices[1].PCIBridge)
+	assert.Equal(t, 0x8086, buses[0].Devices[1].PCIBridge.Devices[0].ID.Vendor)
+}
+
+func TestClient_QueryStats(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "qmp.sock")
+	mockQMP := newMockQMPServer(t, socketPath)
+	defer mockQMP.Close()
+	mockQMP.SetResponse("query-stats", `[
+		{"provider": "kvm", "qom-path": "/machine/unattached/device[0]", "stats": [{"name": "halt_wait_ns", "value": 1500}]},
+		{"provider": "kvm", "qom-path": "/machine/unattached/device[1]", "stats": [{"name": "halt_wait_ns", "value": 2500}]}]`)
+
+	time.Sleep(50 * time.Millisecond)
+
+	client, err := NewClient(socketPath)
+	require.NoError(t, err)
+	defer client.Close()
+
+	results, err := client.QueryStats("vcpu", "kvm", []string{"halt_wait_ns"})
+	require.NoError(t, err)
+	assert.Equal(t, "query-stats", mockQMP.LastCommand())
+	require.Len(t, results, 2)
+	assert.Equal(t, "/machine/unattached/device[1]", results[1].QOMPath)
+	require.Len(t, results[1].Stats, 1)
+	assert.Equal(t, "halt_wait_ns", results[1].Stats[0].Name)
+	assert.JSONEq(t, "2500", string(results[1].Stats[0].Value))
+}
@@ -0,0 +1,135 @@
+package qmp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// guestAgentTimeout bounds a whole exchange with the guest agent, which
+// does not answer while the guest is not running it.
+const guestAgentTimeout = 3 * time.Second
+
+// GuestAgent talks to qemu-guest-agent inside the VM
+type GuestAgent interface {
+	NetworkInterfaces() ([]GuestNetworkInterface, error)
+	SuspendRAM() error
+}
+
+// GuestNetworkInterface is an entry of the guest-network-get-interfaces reply
+type GuestNetworkInterface struct {
+	Name            string           `json:"name"`
+	HardwareAddress string           `json:"hardware-address"`
+	IPAddresses     []GuestIPAddress `json:"ip-addresses"`
+}
+
+// GuestIPAddress is an address of a guest network interface
+type GuestIPAddress struct {
+	Type    string `json:"ip-address-type"` // "ipv4" or "ipv6"
+	Address string `json:"ip-address"`
+	Prefix  int    `json:"prefix"`
+}
+
+// guestAgent connects to the agent's virtio-serial socket for each request.
+// The agent keeps no session state, and a stale connection would otherwise
+// have to be detected after every guest reboot.
+type guestAgent struct {
+	socketPath string
+}
+
+// NewGuestAgent creates a guest agent client for the given chardev socket
+func NewGuestAgent(socketPath string) GuestAgent {
+	return &guestAgent{socketPath: socketPath}
+}
+
+func (g *guestAgent) NetworkInterfaces() ([]GuestNetworkInterface, error) {
+	var ifaces []GuestNetworkInterface
+	if err := g.execute("guest-network-get-interfaces", &ifaces); err != nil {
+		return nil, err
+	}
+	return ifaces, nil
+}
+
+// SuspendRAM asks the guest to enter ACPI S3. The agent does not reply
+// once the guest suspends, so the command is sent without waiting for one;
+// QEMU reports the "suspended" status when the guest got there.
+func (g *guestAgent) SuspendRAM() error {
+	conn, _, err := g.open()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return writeCommand(conn, "guest-suspend-ram", nil)
+}
+
+// open connects and synchronizes with the agent.
+func (g *guestAgent) open() (net.Conn, *bufio.Scanner, error) {
+	conn, err := net.DialTimeout("unix", g.socketPath, guestAgentTimeout)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connecting to guest agent: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(guestAgentTimeout))
+
+	// Replies to requests of an earlier, abandoned connection may still be
+	// queued; skip them until the sync ID comes back.
+	syncID := rand.Int63n(1 << 31)
+	scanner := bufio.NewScanner(conn)
+	if err := writeCommand(conn, "guest-sync", map[string]int64{"id": syncID}); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	for {
+		if !scanner.Scan() {
+			conn.Close()
+			return nil, nil, fmt.Errorf("guest agent sync: connection closed")
+		}
+		var resp struct {
+			Return int64 `json:"return"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &resp) == nil && resp.Return == syncID {
+			return conn, scanner, nil
+		}
+	}
+}
+
+// execute synchronizes with the agent, runs command and decodes its return
+// value into out.
+func (g *guestAgent) execute(command string, out interface{}) error {
+	conn, scanner, err := g.open()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := writeCommand(conn, command, nil); err != nil {
+		return err
+	}
+	if !scanner.Scan() {
+		return fmt.Errorf("reading %s response: connection closed", command)
+	}
+	var resp qmpReturn
+	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+		return fmt.Errorf("parsing %s response: %w", command, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("guest agent error: %s: %s", resp.Error.Class, resp.Error.Desc)
+	}
+	if err := json.Unmarshal(resp.Return, out); err != nil {
+		return fmt.Errorf("parsing %s response: %w", command, err)
+	}
+	return nil
+}
+
+func writeCommand(conn net.Conn, command string, arguments interface{}) error {
+	data, err := json.Marshal(qmpCommand{Execute: command, Arguments: arguments})
+	if err != nil {
+		return fmt.Errorf("marshaling command: %w", err)
+	}
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("writing command: %w", err)
+	}
+	return nil
+}
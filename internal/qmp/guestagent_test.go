@@ -0,0 +1,90 @@
+package qmp
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serveGuestAgent answers guest-sync and guest-network-get-interfaces on a
+// UNIX socket, first replaying a stale reply like a real agent might. Other
+// commands are sent to received.
+func serveGuestAgent(t *testing.T, socketPath string, received chan<- string) {
+	t.Helper()
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				conn.Write([]byte(`{"return": 12345}` + "\n"))
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					var cmd struct {
+						Execute   string          `json:"execute"`
+						Arguments json.RawMessage `json:"arguments"`
+					}
+					json.Unmarshal(scanner.Bytes(), &cmd)
+					switch cmd.Execute {
+					case "guest-sync":
+						var args struct {
+							ID int64 `json:"id"`
+						}
+						json.Unmarshal(cmd.Arguments, &args)
+						data, _ := json.Marshal(map[string]int64{"return": args.ID})
+						conn.Write(append(data, '\n'))
+					case "guest-network-get-interfaces":
+						conn.Write([]byte(`{"return": [{"name": "eth0", "hardware-address": "52:54:00:12:34:56", "ip-addresses": [{"ip-address-type": "ipv4", "ip-address": "192.0.2.10", "prefix": 24}, {"ip-address-type": "ipv6", "ip-address": "2001:db8::10", "prefix": 64}]}]}` + "\n"))
+					case "guest-suspend-ram":
+						received <- cmd.Execute // no reply once the guest suspends
+					default:
+						conn.Write([]byte(`{"error": {"class": "CommandNotFound", "desc": "unknown"}}` + "\n"))
+					}
+				}
+			}(conn)
+		}
+	}()
+}
+
+func TestGuestAgent_NetworkInterfaces(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "qga.sock")
+	serveGuestAgent(t, socketPath, nil)
+
+	ifaces, err := NewGuestAgent(socketPath).NetworkInterfaces()
+	require.NoError(t, err)
+	require.Len(t, ifaces, 1)
+	assert.Equal(t, "52:54:00:12:34:56", ifaces[0].HardwareAddress)
+	require.Len(t, ifaces[0].IPAddresses, 2)
+	assert.Equal(t, GuestIPAddress{Type: "ipv4", Address: "192.0.2.10", Prefix: 24}, ifaces[0].IPAddresses[0])
+}
+
+func TestGuestAgent_SuspendRAM(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "qga.sock")
+	received := make(chan string, 1)
+	serveGuestAgent(t, socketPath, received)
+
+	require.NoError(t, NewGuestAgent(socketPath).SuspendRAM())
+	select {
+	case cmd := <-received:
+		assert.Equal(t, "guest-suspend-ram", cmd)
+	case <-time.After(time.Second):
+		t.Fatal("guest-suspend-ram not received")
+	}
+}
+
+func TestGuestAgent_Unavailable(t *testing.T) {
+	_, err := NewGuestAgent(filepath.Join(t.TempDir(), "missing.sock")).NetworkInterfaces()
+	assert.Error(t, err)
+}
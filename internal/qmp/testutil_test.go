@@ -2,6 +2,7 @@ package qmp
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"net"
 	"os"
@@ -14,6 +15,7 @@ type mockQMPServer struct {
 	listener    net.Listener
 	status      Status
 	lastCommand string
+	responses   map[string]string // command -> canned "return" JSON
 	mu          sync.Mutex
 	t           *testing.T
 	done        chan struct{}
@@ -73,8 +75,14 @@ func (m *mockQMPServer) handleConnection(conn net.Conn) {
 
 		m.mu.Lock()
 		m.lastCommand = cmd.Execute
+		canned, hasCanned := m.responses[cmd.Execute]
 		m.mu.Unlock()
 
+		if hasCanned {
+			conn.Write([]byte(`{"return": ` + canned + `}` + "\n"))
+			continue
+		}
+
 		var response string
 		switch cmd.Execute {
 		case "qmp_capabilities":
@@ -104,7 +112,7 @@ func (m *mockQMPServer) handleConnection(conn net.Conn) {
 			}
 			m.mu.Unlock()
 			response = `{"return": {}}` + "\n"
-		case "blockdev-change-medium", "blockdev-remove-medium":
+		case "blockdev-change-medium", "blockdev-open-tray", "blockdev-remove-medium":
 			response = `{"return": {}}` + "\n"
 		default:
 			response = `{"return": {}}` + "\n"
@@ -120,6 +128,20 @@ func (m *mockQMPServer) SetStatus(status Status) {
 	m.status = status
 }
 
+// SetResponse makes the server reply to command with the given "return" JSON.
+func (m *mockQMPServer) SetResponse(command, returnJSON string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.responses == nil {
+		m.responses = make(map[string]string)
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(returnJSON)); err != nil {
+		m.t.Fatalf("invalid canned response for %s: %v", command, err)
+	}
+	m.responses[command] = buf.String()
+}
+
 func (m *mockQMPServer) LastCommand() string {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -1,12 +1,18 @@
 package qmp
 
+import "encoding/json"
+
 // Status represents QEMU VM running status
 type Status string
 
 const (
-	StatusRunning  Status = "running"
-	StatusShutdown Status = "shutdown"
-	StatusPaused   Status = "paused"
+	StatusRunning   Status = "running"
+	StatusShutdown  Status = "shutdown"
+	StatusPaused    Status = "paused"
+	StatusSuspended Status = "suspended" // ACPI S3
+	StatusPrelaunch Status = "prelaunch" // -S, before the first cont
+	StatusInMigrate Status = "inmigrate"
+	StatusRestoreVM Status = "restore-vm"
 )
 
 // Client is the interface for QMP communication
@@ -18,11 +24,162 @@ type Client interface {
 	Stop() error
 	Cont() error
 	Quit() error
-	BlockdevChangeMedium(device, filename string) error
-	BlockdevRemoveMedium(device string) error
+	InjectNMI() error
+	SystemWakeup() error
+	BlockdevChangeMedium(id, filename string, readOnly bool) error // id: qdev ID or QOM path
+	BlockdevRemoveMedium(id string) error
+	QueryCPUsFast() ([]CPUInfoFast, error)
+	QueryMemorySizeSummary() (MemorySizeSummary, error)
+	QueryMachines() ([]MachineInfo, error)
+	QueryCPUDefinitions() ([]CPUDefinition, error)
+	QOMGet(path, property string) (json.RawMessage, error)
+	QueryBlock() ([]BlockInfo, error)
+	QueryBlockstats() ([]BlockStats, error)
+	BlockdevCreate(jobID string, options interface{}) error
+	BlockdevAdd(options interface{}) error
+	BlockdevDel(nodeName string) error
+	QueryJobs() ([]JobInfo, error)
+	JobDismiss(id string) error
+	DeviceAdd(driver, id string, props map[string]interface{}) error
+	DeviceDel(id string) error
+	QueryPCI() ([]PCIBus, error)
+	SetLink(name string, up bool) error
+	QueryStats(target, provider string, names []string) ([]StatsResult, error)
 	Close() error
 }
 
+// CPUInfoFast is an entry of the query-cpus-fast reply
+type CPUInfoFast struct {
+	CPUIndex int    `json:"cpu-index"`
+	QOMPath  string `json:"qom-path"`
+	ThreadID int    `json:"thread-id"`
+	Target   string `json:"target"`
+	Props    struct {
+		NodeID   *int `json:"node-id,omitempty"`
+		SocketID int  `json:"socket-id"`
+		DieID    int  `json:"die-id"`
+		CoreID   int  `json:"core-id"`
+		ThreadID int  `json:"thread-id"`
+	} `json:"props"`
+}
+
+// MemorySizeSummary is the query-memory-size-summary reply
+type MemorySizeSummary struct {
+	BaseMemory    uint64 `json:"base-memory"`
+	PluggedMemory uint64 `json:"plugged-memory"`
+}
+
+// MachineInfo is an entry of the query-machines reply
+type MachineInfo struct {
+	Name           string `json:"name"`
+	Alias          string `json:"alias,omitempty"`
+	IsDefault      bool   `json:"is-default,omitempty"`
+	CPUMax         int    `json:"cpu-max"`
+	DefaultCPUType string `json:"default-cpu-type,omitempty"`
+}
+
+// BlockInfo is an entry of the query-block reply
+type BlockInfo struct {
+	Device    string         `json:"device"`
+	QDev      string         `json:"qdev,omitempty"`
+	Removable bool           `json:"removable"`
+	Locked    bool           `json:"locked"`
+	TrayOpen  *bool          `json:"tray_open,omitempty"`
+	Inserted  *BlockInserted `json:"inserted,omitempty"`
+}
+
+// BlockInserted describes the medium of a block device
+type BlockInserted struct {
+	File        string    `json:"file"`
+	NodeName    string    `json:"node-name"`
+	RO          bool      `json:"ro"`
+	Drv         string    `json:"drv"`
+	BackingFile string    `json:"backing_file,omitempty"`
+	Image       ImageInfo `json:"image"`
+}
+
+// ImageInfo describes an image file
+type ImageInfo struct {
+	Filename        string `json:"filename"`
+	Format          string `json:"format"`
+	VirtualSize     int64  `json:"virtual-size"`
+	ActualSize      int64  `json:"actual-size,omitempty"`
+	BackingFilename string `json:"backing-filename,omitempty"`
+}
+
+// BlockStats is an entry of the query-blockstats reply
+type BlockStats struct {
+	Device   string `json:"device,omitempty"`
+	QDev     string `json:"qdev,omitempty"`
+	NodeName string `json:"node-name,omitempty"`
+	Stats    struct {
+		RdBytes      int64 `json:"rd_bytes"`
+		WrBytes      int64 `json:"wr_bytes"`
+		RdOperations int64 `json:"rd_operations"`
+		WrOperations int64 `json:"wr_operations"`
+	} `json:"stats"`
+}
+
+// JobInfo is an entry of the query-jobs reply
+type JobInfo struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// PCIBus is an entry of the query-pci reply
+type PCIBus struct {
+	Bus     int         `json:"bus"`
+	Devices []PCIDevice `json:"devices"`
+}
+
+// PCIDevice is a device on a PCI bus
+type PCIDevice struct {
+	Bus       int    `json:"bus"`
+	Slot      int    `json:"slot"`
+	Function  int    `json:"function"`
+	QdevID    string `json:"qdev_id"`
+	ClassInfo struct {
+		Class int    `json:"class"`
+		Desc  string `json:"desc,omitempty"`
+	} `json:"class_info"`
+	ID struct {
+		Vendor int `json:"vendor"`
+		Device int `json:"device"`
+	} `json:"id"`
+	PCIBridge *PCIBridgeInfo `json:"pci_bridge,omitempty"`
+}
+
+// PCIBridgeInfo lists the devices behind a PCI bridge
+type PCIBridgeInfo struct {
+	Devices []PCIDevice `json:"devices,omitempty"`
+}
+
+// PCIClassEthernet is the PCI class code of Ethernet controllers
+const PCIClassEthernet = 0x0200
+
+// CPUDefinition is an entry of the query-cpu-definitions reply
+type CPUDefinition struct {
+	Name     string `json:"name"`
+	TypeName string `json:"typename"`
+}
+
+// StatsResult is an entry of the query-stats reply, the statistics of one
+// provider for one object
+type StatsResult struct {
+	Provider string `json:"provider"`
+	QOMPath  string `json:"qom-path,omitempty"` // vcpu target: the vCPU
+	Stats    []Stat `json:"stats"`
+}
+
+// Stat is a named statistic. Its value is a number, a boolean or a list of
+// numbers (histograms).
+type Stat struct {
+	Name  string          `json:"name"`
+	Value json.RawMessage `json:"value"`
+}
+
 // QMP protocol message types
 type qmpGreeting struct {
 	QMP struct {
@@ -61,10 +218,55 @@ type qmpStatusResponse struct {
 }
 
 type blockdevChangeMediumArgs struct {
-	Device   string `json:"device"`
-	Filename string `json:"filename"`
+	ID           string `json:"id"`
+	Filename     string `json:"filename"`
+	ReadOnlyMode string `json:"read-only-mode"`
+}
+
+type blockdevOpenTrayArgs struct {
+	ID    string `json:"id"`
+	Force bool   `json:"force"`
 }
 
 type blockdevRemoveMediumArgs struct {
-	Device string `json:"device"`
+	ID string `json:"id"`
+}
+
+type qomGetArgs struct {
+	Path     string `json:"path"`
+	Property string `json:"property"`
+}
+
+type blockdevCreateArgs struct {
+	JobID   string      `json:"job-id"`
+	Options interface{} `json:"options"`
+}
+
+type nodeNameArgs struct {
+	NodeName string `json:"node-name"`
+}
+
+type idArgs struct {
+	ID string `json:"id"`
+}
+
+type setLinkArgs struct {
+	Name string `json:"name"`
+	Up   bool   `json:"up"`
+}
+
+type queryStatsArgs struct {
+	Target    string          `json:"target"`
+	Providers []statsProvider `json:"providers,omitempty"`
+}
+
+type statsProvider struct {
+	Provider string   `json:"provider"`
+	Names    []string `json:"names,omitempty"`
+}
+
+// qmpReturn is a reply whose "return" member is decoded by the caller
+type qmpReturn struct {
+	Return json.RawMessage `json:"return"`
+	Error  *qmpError       `json:"error,omitempty"`
 }
@@ -0,0 +1,61 @@
+package redfish
+
+import (
+	"net/http"
+	"slices"
+)
+
+// Redfish privileges (DMTF Redfish Privilege Registry)
+const (
+	privLogin               = "Login"
+	privConfigureManager    = "ConfigureManager"
+	privConfigureUsers      = "ConfigureUsers"
+	privConfigureComponents = "ConfigureComponents"
+	privConfigureSelf       = "ConfigureSelf"
+)
+
+// requirePrivilege wraps h so that it only runs for callers whose role
+// grants priv. Login is enforced for every request by authMiddleware.
+func (s *Server) requirePrivilege(priv string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.hasPrivilege(r, priv) {
+			writeInsufficientPrivilege(w)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// hasPrivilege reports whether the authenticated caller holds priv. When
+// authentication is disabled there is no caller and everything is allowed.
+func (s *Server) hasPrivilege(r *http.Request, priv string) bool {
+	user, ok := callerName(r)
+	if !ok {
+		return true
+	}
+	return slices.Contains(s.userRole(user).Privileges, priv)
+}
+
+// userRole returns the role derived from the IPMI privilege limit of user's
+// slot. Unknown users get NoAccess.
+func (s *Server) userRole(user string) roleDef {
+	userID, ok := s.bmcState.LookupUserByName(user)
+	if !ok {
+		return roles[len(roles)-1]
+	}
+	access, _ := s.bmcState.GetUserAccess(0, userID)
+	return roleForPrivilege(access.PrivilegeLimit)
+}
+
+// callerName returns the authenticated user name recorded by authMiddleware.
+func callerName(r *http.Request) (string, bool) {
+	user, ok := r.Context().Value(contextKeyUser).(string)
+	return user, ok
+}
+
+// writeInsufficientPrivilege writes the Base.1.0.InsufficientPrivilege error.
+func writeInsufficientPrivilege(w http.ResponseWriter) {
+	writeBaseError(w, http.StatusForbidden, "InsufficientPrivilege",
+		"There are insufficient privileges for the account or credentials associated with the current session to perform the requested operation.",
+		"Either abandon the operation or change the associated access rights and resubmit the request if the operation failed.")
+}
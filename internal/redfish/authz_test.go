@@ -0,0 +1,111 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+// newAuthzTestServer returns a server with admin (slot 2), operator (3),
+// monitor (4, ReadOnly) and locked (5, NoAccess) accounts.
+func newAuthzTestServer(t *testing.T) (*Server, *mockMachine) {
+	t.Helper()
+	state := bmc.NewState("admin", "password")
+	for _, u := range []struct {
+		name string
+		priv uint8
+	}{
+		{"operator", bmc.PrivilegeOperator},
+		{"monitor", bmc.PrivilegeUser},
+		{"locked", bmc.PrivilegeNoAccess},
+	} {
+		_, err := state.CreateUser(u.name, "secret", bmc.UserAccess{PrivilegeLimit: u.priv, Enabled: true})
+		require.NoError(t, err)
+	}
+	mock := newMockMachine(qmp.StatusRunning)
+	return NewServer(mock, state, "admin", "password", ""), mock
+}
+
+func TestAuthz_ReadOnlyCannotPowerOff(t *testing.T) {
+	srv, mock := newAuthzTestServer(t)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1", "", "monitor", "secret")
+	assert.Equal(t, http.StatusOK, w.Code, "ReadOnly may read")
+
+	w = doRequest(srv, "POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"ForceOff"}`, "monitor", "secret")
+	require.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, mock.Calls(), "reset must not reach the machine")
+
+	var errResp RedfishError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &errResp))
+	assert.Equal(t, "Base.1.0.InsufficientPrivilege", errResp.Error.Code)
+	require.Len(t, errResp.Error.ExtendedInfo, 1)
+	assert.Equal(t, "Base.1.0.InsufficientPrivilege", errResp.Error.ExtendedInfo[0].MessageID)
+
+	w = doRequest(srv, "PATCH", "/redfish/v1/Systems/1", `{"Boot":{"BootSourceOverrideTarget":"Pxe"}}`, "monitor", "secret")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = doRequest(srv, "POST", "/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.InsertMedia", `{"Image":"http://x/a.iso"}`, "monitor", "secret")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuthz_Operator(t *testing.T) {
+	srv, mock := newAuthzTestServer(t)
+
+	w := doRequest(srv, "POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"ForceOff"}`, "operator", "secret")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []string{"ForceOff"}, mock.Calls())
+
+	w = doRequest(srv, "POST", "/redfish/v1/AccountService/Accounts", `{"UserName":"eve","Password":"x","RoleId":"Administrator"}`, "operator", "secret")
+	assert.Equal(t, http.StatusForbidden, w.Code, "ConfigureUsers is required")
+	w = doRequest(srv, "PATCH", "/redfish/v1/SessionService", `{"SessionTimeout":600}`, "operator", "secret")
+	assert.Equal(t, http.StatusForbidden, w.Code, "ConfigureManager is required")
+}
+
+func TestAuthz_ConfigureSelf(t *testing.T) {
+	srv, _ := newAuthzTestServer(t)
+
+	// monitor (slot 4) may change its own password
+	w := doRequest(srv, "PATCH", "/redfish/v1/AccountService/Accounts/4", `{"Password":"newsecret"}`, "monitor", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// but not its role
+	w = doRequest(srv, "PATCH", "/redfish/v1/AccountService/Accounts/4", `{"RoleId":"Administrator"}`, "monitor", "newsecret")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// nor another user's password
+	w = doRequest(srv, "PATCH", "/redfish/v1/AccountService/Accounts/2", `{"Password":"pwned"}`, "monitor", "newsecret")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAuthz_NoAccess(t *testing.T) {
+	srv, _ := newAuthzTestServer(t)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1", "", "locked", "secret")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = doRequest(srv, "POST", "/redfish/v1/SessionService/Sessions", `{"UserName":"locked","Password":"secret"}`, "", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("X-Auth-Token"))
+}
+
+func TestAuthz_SessionLogout(t *testing.T) {
+	srv, _ := newAuthzTestServer(t)
+	_, adminSession := login(t, srv, "admin", "password")
+	monitorToken, monitorSession := login(t, srv, "monitor", "secret")
+
+	del := func(path string) int {
+		req := httptest.NewRequest("DELETE", path, nil)
+		req.Header.Set("X-Auth-Token", monitorToken)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w.Code
+	}
+	assert.Equal(t, http.StatusForbidden, del(adminSession), "cannot end other users' sessions")
+	assert.Equal(t, http.StatusNoContent, del(monitorSession), "can log out")
+}
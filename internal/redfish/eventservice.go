@@ -0,0 +1,292 @@
+package redfish
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+)
+
+// Event delivery defaults
+const (
+	defaultDeliveryRetryAttempts = 3
+	defaultDeliveryRetryInterval = 5 * time.Second
+	eventQueueSize               = 64
+)
+
+// subscription is an event destination with its delivery queue
+type subscription struct {
+	EventDestination
+	queue chan []byte
+	done  chan struct{}
+}
+
+// sseEvent is an event queued for a Server-Sent Events stream
+type sseEvent struct {
+	ID      string
+	Payload []byte
+}
+
+// eventService fans events out to push subscriptions and SSE streams
+type eventService struct {
+	mu            sync.Mutex
+	subs          map[string]*subscription
+	nextSubID     uint64
+	nextEventID   uint64
+	sse           map[chan sseEvent]struct{}
+	client        *http.Client
+	retryAttempts int
+	retryInterval time.Duration
+}
+
+func newEventService() *eventService {
+	return &eventService{
+		subs:          make(map[string]*subscription),
+		sse:           make(map[chan sseEvent]struct{}),
+		client:        &http.Client{Timeout: 10 * time.Second},
+		retryAttempts: defaultDeliveryRetryAttempts,
+		retryInterval: defaultDeliveryRetryInterval,
+	}
+}
+
+// Subscribe adds a push subscription and starts its delivery worker.
+func (es *eventService) Subscribe(dest EventDestination) EventDestination {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.nextSubID++
+	dest.ID = strconv.FormatUint(es.nextSubID, 10)
+	sub := &subscription{
+		EventDestination: dest,
+		queue:            make(chan []byte, eventQueueSize),
+		done:             make(chan struct{}),
+	}
+	es.subs[dest.ID] = sub
+	go es.deliver(sub)
+	return dest
+}
+
+// Unsubscribe removes a push subscription. It returns false if no such
+// subscription exists.
+func (es *eventService) Unsubscribe(id string) bool {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	sub, ok := es.subs[id]
+	if !ok {
+		return false
+	}
+	delete(es.subs, id)
+	close(sub.done)
+	return true
+}
+
+// Get returns the subscription with the given ID.
+func (es *eventService) Get(id string) (EventDestination, bool) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	sub, ok := es.subs[id]
+	if !ok {
+		return EventDestination{}, false
+	}
+	return sub.EventDestination, true
+}
+
+// List returns the IDs of all subscriptions in creation order.
+func (es *eventService) List() []string {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	ids := make([]uint64, 0, len(es.subs))
+	for id := range es.subs {
+		n, _ := strconv.ParseUint(id, 10, 64)
+		ids = append(ids, n)
+	}
+	slices.Sort(ids)
+	out := make([]string, len(ids))
+	for i, n := range ids {
+		out[i] = strconv.FormatUint(n, 10)
+	}
+	return out
+}
+
+// OpenStream registers a Server-Sent Events listener. The returned function
+// unregisters it.
+func (es *eventService) OpenStream() (<-chan sseEvent, func()) {
+	ch := make(chan sseEvent, eventQueueSize)
+	es.mu.Lock()
+	es.sse[ch] = struct{}{}
+	es.mu.Unlock()
+	return ch, func() {
+		es.mu.Lock()
+		delete(es.sse, ch)
+		es.mu.Unlock()
+	}
+}
+
+// Publish assigns an event ID and timestamp to rec and queues it for every
+// matching subscription and SSE stream. Test events bypass the filters.
+func (es *eventService) Publish(rec EventRecord, test bool) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	es.nextEventID++
+	id := strconv.FormatUint(es.nextEventID, 10)
+	if rec.EventID == "" {
+		rec.EventID = id
+	}
+	if rec.EventTimestamp == "" {
+		rec.EventTimestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	for _, sub := range es.subs {
+		if !test && !sub.matches(rec) {
+			continue
+		}
+		payload, err := json.Marshal(newEventPayload(id, sub.Context, rec))
+		if err != nil {
+			continue
+		}
+		select {
+		case sub.queue <- payload:
+		default:
+			log.Printf("EventService: queue for subscription %s is full, dropping event", sub.ID)
+		}
+	}
+
+	if len(es.sse) > 0 {
+		payload, err := json.Marshal(newEventPayload(id, "", rec))
+		if err != nil {
+			return
+		}
+		for ch := range es.sse {
+			select {
+			case ch <- sseEvent{ID: id, Payload: payload}:
+			default:
+			}
+		}
+	}
+}
+
+// matches applies the RegistryPrefixes and ResourceTypes filters.
+func (sub *subscription) matches(rec EventRecord) bool {
+	if len(sub.RegistryPrefixes) > 0 {
+		prefix, _, _ := strings.Cut(rec.MessageID, ".")
+		if !slices.Contains(sub.RegistryPrefixes, prefix) {
+			return false
+		}
+	}
+	if len(sub.ResourceTypes) > 0 && !slices.Contains(sub.ResourceTypes, rec.resourceType) {
+		return false
+	}
+	return true
+}
+
+// deliver POSTs queued events to the subscription destination, retrying
+// failed deliveries, until the subscription is removed.
+func (es *eventService) deliver(sub *subscription) {
+	for {
+		select {
+		case <-sub.done:
+			return
+		case payload := <-sub.queue:
+			es.mu.Lock()
+			attempts, interval := es.retryAttempts, es.retryInterval
+			es.mu.Unlock()
+
+			for attempt := 0; ; attempt++ {
+				err := es.post(sub.Destination, payload)
+				if err == nil {
+					break
+				}
+				if attempt >= attempts {
+					log.Printf("EventService: delivery to %s failed after %d retries: %v", sub.Destination, attempts, err)
+					break
+				}
+				select {
+				case <-sub.done:
+					return
+				case <-time.After(interval):
+				}
+			}
+		}
+	}
+}
+
+func (es *eventService) post(dest string, payload []byte) error {
+	resp, err := es.client.Post(dest, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("destination returned %s", resp.Status)
+	}
+	return nil
+}
+
+func newEventPayload(id, context string, rec EventRecord) Event {
+	return Event{
+		ODataType: "#Event.v1_4_0.Event",
+		ID:        id,
+		Name:      "Event",
+		Context:   context,
+		Events:    []EventRecord{rec},
+	}
+}
+
+// machineEventRecord translates a machine event into a Redfish event record.
+func machineEventRecord(e machine.Event) (EventRecord, bool) {
+	system := &ODataID{ODataID: "/redfish/v1/Systems/1"}
+	media := &ODataID{ODataID: managerVirtualMediaPath + "/" + e.Media}
+	rec := EventRecord{
+		EventType:      "Alert",
+		EventTimestamp: e.Time.UTC().Format(time.RFC3339),
+		Severity:       "OK",
+	}
+
+	switch e.Type {
+	case machine.EventPowerStateChanged:
+		rec.OriginOfCondition = system
+		rec.resourceType = "ComputerSystem"
+		rec.MessageArgs = []string{system.ODataID}
+		switch e.PowerState {
+		case machine.PowerOn:
+			rec.MessageID = "ResourceEvent.1.3.ResourcePoweredOn"
+			rec.Message = "The resource '" + system.ODataID + "' has powered on."
+		case machine.PowerOff:
+			rec.MessageID = "ResourceEvent.1.3.ResourcePoweredOff"
+			rec.Message = "The resource '" + system.ODataID + "' has powered off."
+		case machine.PowerPoweringOn:
+			rec.MessageID = "ResourceEvent.1.3.ResourcePoweringOn"
+			rec.Message = "The resource '" + system.ODataID + "' is powering on."
+		case machine.PowerPoweringOff:
+			rec.MessageID = "ResourceEvent.1.3.ResourcePoweringOff"
+			rec.Message = "The resource '" + system.ODataID + "' is powering off."
+		case machine.PowerPaused:
+			rec.MessageID = "ResourceEvent.1.3.ResourcePaused"
+			rec.Message = "The resource '" + system.ODataID + "' has been paused."
+		case machine.PowerSuspended:
+			rec.MessageID = "ResourceEvent.1.0.ResourceChanged"
+			rec.Message = "One or more resource properties have changed."
+		}
+	case machine.EventBootOverrideConsumed:
+		rec.OriginOfCondition = system
+		rec.resourceType = "ComputerSystem"
+		rec.MessageID = "ResourceEvent.1.0.ResourceChanged"
+		rec.Message = "One or more resource properties have changed."
+	case machine.EventMediaInserted, machine.EventMediaEjected:
+		rec.OriginOfCondition = media
+		rec.resourceType = "VirtualMedia"
+		rec.MessageID = "ResourceEvent.1.0.ResourceChanged"
+		rec.Message = "One or more resource properties have changed."
+	default:
+		return EventRecord{}, false
+	}
+	return rec, true
+}
@@ -0,0 +1,316 @@
+package redfish
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+)
+
+const (
+	accountsPath = "/redfish/v1/AccountService/Accounts"
+	rolesPath    = "/redfish/v1/AccountService/Roles"
+)
+
+// roleDef maps a predefined Redfish role to an IPMI privilege level
+type roleDef struct {
+	ID         string
+	Privilege  uint8
+	Privileges []string
+}
+
+// roles are the predefined Redfish roles, from most to least privileged.
+var roles = []roleDef{
+	{"Administrator", bmc.PrivilegeAdministrator, []string{"Login", "ConfigureManager", "ConfigureUsers", "ConfigureComponents", "ConfigureSelf"}},
+	{"Operator", bmc.PrivilegeOperator, []string{"Login", "ConfigureComponents", "ConfigureSelf"}},
+	{"ReadOnly", bmc.PrivilegeUser, []string{"Login", "ConfigureSelf"}},
+	{"NoAccess", bmc.PrivilegeNoAccess, []string{}},
+}
+
+func findRole(id string) (roleDef, bool) {
+	for _, r := range roles {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return roleDef{}, false
+}
+
+// roleForPrivilege returns the role granted by an IPMI privilege limit.
+// Callback and unknown levels map to NoAccess.
+func roleForPrivilege(priv uint8) roleDef {
+	for _, r := range roles {
+		if r.Privilege == priv {
+			return r
+		}
+	}
+	return roles[len(roles)-1]
+}
+
+func (s *Server) handleGetAccountService(w http.ResponseWriter, r *http.Request) {
+	svc := AccountService{
+		ODataType:         "#AccountService.v1_5_0.AccountService",
+		ODataID:           "/redfish/v1/AccountService",
+		ID:                "AccountService",
+		Name:              "Account Service",
+		ServiceEnabled:    true,
+		MaxPasswordLength: bmc.MaxPasswordLength,
+		Accounts:          ODataID{ODataID: accountsPath},
+		Roles:             ODataID{ODataID: rolesPath},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(svc)
+}
+
+func (s *Server) handleAccountCollection(w http.ResponseWriter, r *http.Request) {
+	members := []ODataID{}
+	for id := uint8(1); id <= s.bmcState.MaxUsers(); id++ {
+		if name, _ := s.bmcState.GetUserName(id); name != "" {
+			members = append(members, ODataID{ODataID: accountsPath + "/" + strconv.Itoa(int(id))})
+		}
+	}
+	col := AccountCollection{
+		ODataType:    "#ManagerAccountCollection.ManagerAccountCollection",
+		ODataID:      accountsPath,
+		Name:         "Accounts Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.accountID(r)
+	if !ok {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Account not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.accountResource(userID))
+}
+
+func (s *Server) handleCreateAccount(w http.ResponseWriter, r *http.Request) {
+	var req CreateAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedJSON", "Invalid request body")
+		return
+	}
+	if req.UserName == "" || req.Password == "" || req.RoleID == "" {
+		writeError(w, http.StatusBadRequest, "PropertyMissing", "UserName, Password and RoleId are required")
+		return
+	}
+	if !validateAccountFields(w, &req.UserName, &req.Password) {
+		return
+	}
+	role, ok := findRole(req.RoleID)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "PropertyValueNotInList", "invalid RoleId: "+req.RoleID)
+		return
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	userID, err := s.bmcState.CreateUser(req.UserName, req.Password, bmc.UserAccess{
+		PrivilegeLimit: role.Privilege,
+		Enabled:        enabled,
+		IPMIMessaging:  true,
+		LinkAuth:       true,
+	})
+	switch {
+	case errors.Is(err, bmc.ErrUserExists):
+		writeError(w, http.StatusConflict, "ResourceAlreadyExists", "UserName already exists: "+req.UserName)
+		return
+	case errors.Is(err, bmc.ErrNoFreeUserSlot):
+		writeError(w, http.StatusBadRequest, "CreateLimitReachedForResource", "No free account slots")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	w.Header().Set("Location", accountsPath+"/"+strconv.Itoa(int(userID)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s.accountResource(userID))
+}
+
+func (s *Server) handlePatchAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.accountID(r)
+	if !ok {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Account not found")
+		return
+	}
+
+	var req PatchAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedJSON", "Invalid request body")
+		return
+	}
+	if !validateAccountFields(w, req.UserName, req.Password) {
+		return
+	}
+	// Without ConfigureUsers, callers may only change their own password
+	if !s.hasPrivilege(r, privConfigureUsers) {
+		caller, _ := callerName(r)
+		name, _ := s.bmcState.GetUserName(userID)
+		if name != caller || req.UserName != nil || req.RoleID != nil || req.Enabled != nil {
+			writeInsufficientPrivilege(w)
+			return
+		}
+	}
+	var role roleDef
+	if req.RoleID != nil {
+		if role, ok = findRole(*req.RoleID); !ok {
+			writeError(w, http.StatusBadRequest, "PropertyValueNotInList", "invalid RoleId: "+*req.RoleID)
+			return
+		}
+	}
+
+	oldName, _ := s.bmcState.GetUserName(userID)
+	if req.UserName != nil && *req.UserName != oldName {
+		if _, taken := s.bmcState.LookupUserByName(*req.UserName); taken {
+			writeError(w, http.StatusConflict, "ResourceAlreadyExists", "UserName already exists: "+*req.UserName)
+			return
+		}
+	}
+	// The access change is the only one that can be refused, so it is
+	// applied first and nothing changes when it is.
+	if req.RoleID != nil || req.Enabled != nil {
+		access, _ := s.bmcState.GetUserAccess(0, userID)
+		if req.RoleID != nil {
+			access.PrivilegeLimit = role.Privilege
+		}
+		if req.Enabled != nil {
+			access.Enabled = *req.Enabled
+		}
+		if err := s.bmcState.SetUserAccess(0, userID, access); err != nil {
+			writeAccountError(w, err)
+			return
+		}
+	}
+	if req.UserName != nil && *req.UserName != oldName {
+		s.bmcState.SetUserName(userID, *req.UserName)
+	}
+	if req.Password != nil {
+		s.bmcState.SetUserPassword(userID, *req.Password)
+	}
+
+	// Existing sessions were authenticated with the old credentials or role
+	s.sessions.DeleteUser(oldName)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.accountResource(userID))
+}
+
+func (s *Server) handleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := s.accountID(r)
+	if !ok {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Account not found")
+		return
+	}
+	name, _ := s.bmcState.GetUserName(userID)
+	if err := s.bmcState.DeleteUser(userID); err != nil {
+		writeAccountError(w, err)
+		return
+	}
+	s.sessions.DeleteUser(name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAccountError writes the response for a refused account change.
+func writeAccountError(w http.ResponseWriter, err error) {
+	if errors.Is(err, bmc.ErrLastAdministrator) {
+		writeError(w, http.StatusConflict, "ResourceInUse",
+			"The change would leave no enabled Administrator account")
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+}
+
+func (s *Server) handleRoleCollection(w http.ResponseWriter, r *http.Request) {
+	members := make([]ODataID, len(roles))
+	for i, role := range roles {
+		members[i] = ODataID{ODataID: rolesPath + "/" + role.ID}
+	}
+	col := RoleCollection{
+		ODataType:    "#RoleCollection.RoleCollection",
+		ODataID:      rolesPath,
+		Name:         "Roles Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetRole(w http.ResponseWriter, r *http.Request) {
+	role, ok := findRole(mux.Vars(r)["rid"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Role not found")
+		return
+	}
+	res := Role{
+		ODataType:          "#Role.v1_2_1.Role",
+		ODataID:            rolesPath + "/" + role.ID,
+		ID:                 role.ID,
+		Name:               role.ID + " User Role",
+		RoleID:             role.ID,
+		IsPredefined:       true,
+		AssignedPrivileges: role.Privileges,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// accountID returns the user slot named by the {aid} route variable if it
+// holds an account.
+func (s *Server) accountID(r *http.Request) (uint8, bool) {
+	n, err := strconv.Atoi(mux.Vars(r)["aid"])
+	if err != nil || n < 1 || n > int(s.bmcState.MaxUsers()) {
+		return 0, false
+	}
+	name, _ := s.bmcState.GetUserName(uint8(n))
+	return uint8(n), name != ""
+}
+
+func (s *Server) accountResource(userID uint8) ManagerAccount {
+	name, _ := s.bmcState.GetUserName(userID)
+	access, _ := s.bmcState.GetUserAccess(0, userID)
+	_, locked := s.bmcState.AuthLockedOut(name, "")
+	role := roleForPrivilege(access.PrivilegeLimit)
+	id := strconv.Itoa(int(userID))
+	return ManagerAccount{
+		ODataType: "#ManagerAccount.v1_4_0.ManagerAccount",
+		ODataID:   accountsPath + "/" + id,
+		ID:        id,
+		Name:      "User Account",
+		UserName:  name,
+		RoleID:    role.ID,
+		Enabled:   access.Enabled,
+		Locked:    locked,
+		Links:     ManagerAccountLinks{Role: ODataID{ODataID: rolesPath + "/" + role.ID}},
+	}
+}
+
+// validateAccountFields checks the user name and password against the
+// limits of the IPMI user table. It writes an error response and returns
+// false if a field is invalid. nil fields are not checked.
+func validateAccountFields(w http.ResponseWriter, user, pass *string) bool {
+	if user != nil && (*user == "" || len(*user) > bmc.MaxUserNameLength) {
+		writeError(w, http.StatusBadRequest, "PropertyValueFormatError",
+			"UserName must be 1-16 bytes")
+		return false
+	}
+	if pass != nil && (*pass == "" || len(*pass) > bmc.MaxPasswordLength) {
+		writeError(w, http.StatusBadRequest, "PropertyValueFormatError",
+			"Password must be 1-20 bytes")
+		return false
+	}
+	return true
+}
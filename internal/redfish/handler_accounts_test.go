@@ -0,0 +1,186 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func TestAccountService_CreateAndUse(t *testing.T) {
+	state := bmc.NewState("admin", "password")
+	srv := NewServer(newMockMachine(qmp.StatusRunning), state, "admin", "password", "")
+
+	w := doRequest(srv, "POST", "/redfish/v1/AccountService/Accounts",
+		`{"UserName":"operator","Password":"secret","RoleId":"Operator"}`, "admin", "password")
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/redfish/v1/AccountService/Accounts/3", w.Header().Get("Location"))
+
+	var acct ManagerAccount
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &acct))
+	assert.Equal(t, "operator", acct.UserName)
+	assert.Equal(t, "Operator", acct.RoleID)
+	assert.True(t, acct.Enabled)
+	assert.Nil(t, acct.Password)
+
+	// The account lives in the IPMI user table
+	userID, ok := state.LookupUserByName("operator")
+	require.True(t, ok)
+	assert.True(t, state.CheckPassword(userID, "secret"))
+	access, _ := state.GetUserAccess(1, userID)
+	assert.Equal(t, bmc.PrivilegeOperator, access.PrivilegeLimit)
+
+	// and can authenticate to Redfish
+	w = doRequest(srv, "GET", "/redfish/v1/Systems/1", "", "operator", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Duplicate user names are rejected
+	w = doRequest(srv, "POST", "/redfish/v1/AccountService/Accounts",
+		`{"UserName":"operator","Password":"x","RoleId":"ReadOnly"}`, "admin", "password")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = doRequest(srv, "POST", "/redfish/v1/AccountService/Accounts",
+		`{"UserName":"bob","Password":"x","RoleId":"Superuser"}`, "admin", "password")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAccountService_IPMIPasswordChangeAppliesToRedfish(t *testing.T) {
+	state := bmc.NewState("admin", "password")
+	srv := NewServer(newMockMachine(qmp.StatusRunning), state, "admin", "password", "")
+
+	// e.g. ipmitool user set password 2 newpass
+	require.NoError(t, state.SetUserPassword(2, "newpass"))
+
+	assert.Equal(t, http.StatusUnauthorized, doRequest(srv, "GET", "/redfish/v1", "", "admin", "password").Code)
+	assert.Equal(t, http.StatusOK, doRequest(srv, "GET", "/redfish/v1", "", "admin", "newpass").Code)
+}
+
+func TestAccountService_IPMIChangesEndSessions(t *testing.T) {
+	tests := map[string]func(state *bmc.State, userID uint8){
+		// ipmitool user disable 3
+		"disable": func(state *bmc.State, userID uint8) {
+			require.NoError(t, state.SetUserAccess(1, userID, bmc.UserAccess{PrivilegeLimit: bmc.PrivilegeOperator}))
+		},
+		"password": func(state *bmc.State, userID uint8) {
+			require.NoError(t, state.SetUserPassword(userID, "changed"))
+		},
+		"delete": func(state *bmc.State, userID uint8) {
+			require.NoError(t, state.DeleteUser(userID))
+		},
+		// The name moves to another slot, which must not inherit the session
+		"rename": func(state *bmc.State, userID uint8) {
+			require.NoError(t, state.SetUserName(userID, "renamed"))
+			_, err := state.CreateUser("operator", "other", bmc.UserAccess{PrivilegeLimit: bmc.PrivilegeOperator, Enabled: true})
+			require.NoError(t, err)
+		},
+	}
+	for name, change := range tests {
+		t.Run(name, func(t *testing.T) {
+			state := bmc.NewState("admin", "password")
+			userID, err := state.CreateUser("operator", "secret", bmc.UserAccess{PrivilegeLimit: bmc.PrivilegeOperator, Enabled: true})
+			require.NoError(t, err)
+			srv := NewServer(newMockMachine(qmp.StatusRunning), state, "admin", "password", "")
+			token, _ := login(t, srv, "operator", "secret")
+
+			get := func() int {
+				req := httptest.NewRequest("GET", "/redfish/v1/Systems/1", nil)
+				req.Header.Set("X-Auth-Token", token)
+				w := httptest.NewRecorder()
+				srv.ServeHTTP(w, req)
+				return w.Code
+			}
+			require.Equal(t, http.StatusOK, get())
+
+			change(state, userID)
+			assert.Equal(t, http.StatusUnauthorized, get())
+		})
+	}
+}
+
+func TestAccountService_PatchAndDelete(t *testing.T) {
+	state := bmc.NewState("admin", "password")
+	srv := NewServer(newMockMachine(qmp.StatusRunning), state, "admin", "password", "")
+	userID, err := state.CreateUser("viewer", "viewpass", bmc.UserAccess{PrivilegeLimit: bmc.PrivilegeUser, Enabled: true})
+	require.NoError(t, err)
+	path := "/redfish/v1/AccountService/Accounts/3"
+	require.Equal(t, uint8(3), userID)
+
+	w := doRequest(srv, "GET", path, "", "admin", "password")
+	require.Equal(t, http.StatusOK, w.Code)
+	var acct ManagerAccount
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &acct))
+	assert.Equal(t, "ReadOnly", acct.RoleID)
+
+	w = doRequest(srv, "PATCH", path, `{"RoleId":"Administrator","Password":"changed"}`, "admin", "password")
+	require.Equal(t, http.StatusOK, w.Code)
+	access, _ := state.GetUserAccess(1, userID)
+	assert.Equal(t, bmc.PrivilegeAdministrator, access.PrivilegeLimit)
+	assert.True(t, state.CheckPassword(userID, "changed"))
+
+	// Disabled accounts cannot log in
+	w = doRequest(srv, "PATCH", path, `{"Enabled":false}`, "admin", "password")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, doRequest(srv, "GET", "/redfish/v1", "", "viewer", "changed").Code)
+
+	w = doRequest(srv, "PATCH", path, `{"Password":"this-password-is-far-too-long"}`, "admin", "password")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = doRequest(srv, "DELETE", path, "", "admin", "password")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	_, ok := state.LookupUserByName("viewer")
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", path, "", "admin", "password").Code)
+}
+
+func TestAccountService_LastAdministrator(t *testing.T) {
+	state := bmc.NewState("admin", "password")
+	srv := NewServer(newMockMachine(qmp.StatusRunning), state, "admin", "password", "")
+	path := "/redfish/v1/AccountService/Accounts/2"
+
+	w := doRequest(srv, "DELETE", path, "", "admin", "password")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "ResourceInUse")
+
+	w = doRequest(srv, "PATCH", path, `{"Enabled":false}`, "admin", "password")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// Nothing in a refused PATCH is applied
+	w = doRequest(srv, "PATCH", path, `{"RoleId":"Operator","UserName":"root","Password":"changed"}`, "admin", "password")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	_, ok := state.LookupUserByName("admin")
+	assert.True(t, ok)
+	assert.True(t, state.CheckPassword(2, "password"))
+	access, _ := state.GetUserAccess(1, 2)
+	assert.Equal(t, bmc.PrivilegeAdministrator, access.PrivilegeLimit)
+	assert.True(t, access.Enabled)
+
+	// Once another Administrator exists the account can be demoted
+	_, err := state.CreateUser("root", "secret", bmc.UserAccess{PrivilegeLimit: bmc.PrivilegeAdministrator, Enabled: true})
+	require.NoError(t, err)
+	w = doRequest(srv, "PATCH", path, `{"RoleId":"ReadOnly"}`, "admin", "password")
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestAccountService_Collections(t *testing.T) {
+	srv := newTestServer(newMockMachine(qmp.StatusRunning))
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/redfish/v1/AccountService/Accounts", nil))
+	var accounts AccountCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &accounts))
+	require.Equal(t, 1, accounts.MembersCount, "only the default admin; slot 1 is the null user")
+	assert.Equal(t, "/redfish/v1/AccountService/Accounts/2", accounts.Members[0].ODataID)
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/redfish/v1/AccountService/Roles/Operator", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var role Role
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &role))
+	assert.Contains(t, role.AssignedPrivileges, "ConfigureComponents")
+	assert.NotContains(t, role.AssignedPrivileges, "ConfigureUsers")
+}
@@ -2,17 +2,27 @@ package redfish
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"github.com/tjst-t/qemu-bmc/internal/machine"
 )
 
-var validResetTypes = map[string]bool{
-	"On":               true,
-	"ForceOff":         true,
-	"GracefulShutdown": true,
-	"ForceRestart":     true,
-	"GracefulRestart":  true,
+// resetTypes are the supported ResetType values in the order they are
+// advertised.
+var resetTypes = []string{
+	"On", "ForceOff", "GracefulShutdown", "ForceRestart", "GracefulRestart",
+	"PowerCycle", "PushPowerButton", "Nmi", "Pause", "Resume", "Suspend",
 }
 
+var validResetTypes = func() map[string]bool {
+	valid := make(map[string]bool)
+	for _, t := range resetTypes {
+		valid[t] = true
+	}
+	return valid
+}()
+
 func (s *Server) handleResetAction(w http.ResponseWriter, r *http.Request) {
 	var req ResetRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -26,10 +36,23 @@ func (s *Server) handleResetAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.machine.Reset(req.ResetType); err != nil {
-		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
-		return
+	// Graceful resets can take minutes in process mode; slow resets are
+	// finished in the background and tracked as a task.
+	op := func(progress func(int)) error {
+		return s.machine.Reset(req.ResetType)
 	}
+	s.runAsTask(w, "Reset "+req.ResetType, http.StatusNoContent, op, writeResetError, func() {
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
 
-	w.WriteHeader(http.StatusNoContent)
+func writeResetError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, machine.ErrNotRunning):
+		writeError(w, http.StatusConflict, "ResourceInStandby", err.Error())
+	case errors.Is(err, machine.ErrGuestAgentUnavailable):
+		writeError(w, http.StatusNotImplemented, "ActionNotSupported", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+	}
 }
@@ -1,6 +1,7 @@
 package redfish
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -8,6 +9,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
 	"github.com/tjst-t/qemu-bmc/internal/qmp"
 )
 
@@ -23,13 +26,19 @@ func TestResetAction(t *testing.T) {
 		{"GracefulShutdown", "GracefulShutdown", http.StatusNoContent, "GracefulShutdown"},
 		{"ForceRestart", "ForceRestart", http.StatusNoContent, "ForceRestart"},
 		{"GracefulRestart", "GracefulRestart", http.StatusNoContent, "GracefulRestart"},
+		{"PowerCycle", "PowerCycle", http.StatusNoContent, "PowerCycle"},
+		{"PushPowerButton", "PushPowerButton", http.StatusNoContent, "PushPowerButton"},
+		{"Nmi", "Nmi", http.StatusNoContent, "Nmi"},
+		{"Pause", "Pause", http.StatusNoContent, "Pause"},
+		{"Resume", "Resume", http.StatusNoContent, "Resume"},
+		{"Suspend", "Suspend", http.StatusNoContent, "Suspend"},
 		{"InvalidType returns 400", "InvalidType", http.StatusBadRequest, ""},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mock := newMockMachine(qmp.StatusRunning)
-			srv := NewServer(mock, "", "", "")
+			srv := NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 
 			body := `{"ResetType":"` + tt.resetType + `"}`
 			req := httptest.NewRequest("POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", strings.NewReader(body))
@@ -46,3 +55,22 @@ func TestResetAction(t *testing.T) {
 		})
 	}
 }
+
+func TestResetAction_Errors(t *testing.T) {
+	tests := []struct {
+		err            error
+		expectedStatus int
+	}{
+		{machine.ErrNotRunning, http.StatusConflict},
+		{machine.ErrGuestAgentUnavailable, http.StatusNotImplemented},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		mock := newMockMachine(qmp.StatusRunning)
+		mock.resetErr = tt.err
+		srv := newTestServer(mock)
+
+		w := doRequest(srv, "POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"Nmi"}`)
+		assert.Equal(t, tt.expectedStatus, w.Code, tt.err.Error())
+	}
+}
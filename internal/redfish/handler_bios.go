@@ -0,0 +1,327 @@
+package redfish
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+)
+
+const (
+	biosPath             = "/redfish/v1/Systems/1/Bios"
+	biosSettingsPath     = biosPath + "/Settings"
+	registriesPath       = "/redfish/v1/Registries"
+	biosRegistryFilePath = registriesPath + "/BiosAttributeRegistry"
+	biosRegistryPath     = biosRegistryFilePath + "/Registry"
+	biosRegistryID       = "BiosAttributeRegistry.1.0.0"
+)
+
+// biosRegistryAttributes describes the BIOS attributes. Each maps to a
+// machine.BiosSettings field in biosAttributes and setBiosAttribute.
+var biosRegistryAttributes = []AttributeRegistryAttribute{
+	{
+		AttributeName: "BootMode",
+		DisplayName:   "Boot Mode",
+		HelpText:      "Firmware the VM boots with: SeaBIOS (Bios) or OVMF (Uefi).",
+		Type:          "Enumeration",
+		ResetRequired: true,
+		Value: []AttributeRegistryValue{
+			{ValueName: machine.BiosBootModeLegacy, ValueDisplayName: "Legacy BIOS (SeaBIOS)"},
+			{ValueName: machine.BiosBootModeUEFI, ValueDisplayName: "UEFI (OVMF)"},
+		},
+	},
+	{
+		AttributeName: "BootMenuTimeout",
+		DisplayName:   "Boot Menu Timeout",
+		HelpText:      "Seconds the firmware boot menu waits for a key press. 0 disables the menu.",
+		Type:          "Integer",
+		ResetRequired: true,
+		LowerBound:    intPtr(0),
+		UpperBound:    intPtr(machine.MaxBootMenuTimeout),
+	},
+	{
+		AttributeName: "SecureBoot",
+		DisplayName:   "Secure Boot",
+		HelpText:      "UEFI Secure Boot with the Microsoft keys enrolled. Requires BootMode Uefi; changing it resets the UEFI variables.",
+		Type:          "Enumeration",
+		ResetRequired: true,
+		Value: []AttributeRegistryValue{
+			{ValueName: "Enabled", ValueDisplayName: "Enabled"},
+			{ValueName: "Disabled", ValueDisplayName: "Disabled"},
+		},
+	},
+	{
+		AttributeName: "NumaNodes",
+		DisplayName:   "NUMA Nodes",
+		HelpText:      "Number of NUMA nodes the memory and CPUs are split evenly over.",
+		Type:          "Integer",
+		ResetRequired: true,
+		LowerBound:    intPtr(1),
+		UpperBound:    intPtr(machine.MaxNumaNodes),
+	},
+	smbiosAttribute("SystemManufacturer", "System Manufacturer"),
+	smbiosAttribute("SystemProductName", "System Product Name"),
+	smbiosAttribute("SystemVersion", "System Version"),
+	smbiosAttribute("SystemSerialNumber", "System Serial Number"),
+	smbiosAttribute("SystemSKU", "System SKU"),
+	smbiosAttribute("SystemFamily", "System Family"),
+}
+
+func smbiosAttribute(name, displayName string) AttributeRegistryAttribute {
+	return AttributeRegistryAttribute{
+		AttributeName: name,
+		DisplayName:   displayName,
+		HelpText:      "SMBIOS type 1 string. Empty for the QEMU default.",
+		Type:          "String",
+		ResetRequired: true,
+		MaxLength:     intPtr(machine.MaxSMBIOSLength),
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// biosAttributes returns the Attributes object for s
+func biosAttributes(s machine.BiosSettings) map[string]interface{} {
+	secureBoot := "Disabled"
+	if s.SecureBoot {
+		secureBoot = "Enabled"
+	}
+	return map[string]interface{}{
+		"BootMode":           s.BootMode,
+		"BootMenuTimeout":    s.BootMenuTimeout,
+		"SecureBoot":         secureBoot,
+		"NumaNodes":          s.NumaNodes,
+		"SystemManufacturer": s.SystemManufacturer,
+		"SystemProductName":  s.SystemProductName,
+		"SystemVersion":      s.SystemVersion,
+		"SystemSerialNumber": s.SystemSerialNumber,
+		"SystemSKU":          s.SystemSKU,
+		"SystemFamily":       s.SystemFamily,
+	}
+}
+
+// setBiosAttribute checks value against the registry and sets it in s. It
+// returns the Redfish error code and message when value is rejected.
+func setBiosAttribute(s *machine.BiosSettings, name string, value interface{}) (string, string) {
+	var attr *AttributeRegistryAttribute
+	for i := range biosRegistryAttributes {
+		if biosRegistryAttributes[i].AttributeName == name {
+			attr = &biosRegistryAttributes[i]
+		}
+	}
+	if attr == nil {
+		return "PropertyUnknown", "unknown BIOS attribute " + name
+	}
+
+	var str string
+	var num int
+	switch attr.Type {
+	case "Enumeration", "String":
+		v, ok := value.(string)
+		if !ok {
+			return "PropertyValueTypeError", name + " must be a string"
+		}
+		str = v
+		if attr.MaxLength != nil && len(str) > *attr.MaxLength {
+			return "PropertyValueError", fmt.Sprintf("%s is limited to %d characters", name, *attr.MaxLength)
+		}
+		if attr.Type == "Enumeration" && !hasAttributeValue(attr.Value, str) {
+			return "PropertyValueNotInList", fmt.Sprintf("%q is not a valid value of %s", str, name)
+		}
+	case "Integer":
+		v, ok := value.(float64)
+		if !ok || v != float64(int(v)) {
+			return "PropertyValueTypeError", name + " must be an integer"
+		}
+		num = int(v)
+		if num < *attr.LowerBound || num > *attr.UpperBound {
+			return "PropertyValueError", fmt.Sprintf("%s must be %d-%d", name, *attr.LowerBound, *attr.UpperBound)
+		}
+	}
+
+	switch name {
+	case "BootMode":
+		s.BootMode = str
+	case "BootMenuTimeout":
+		s.BootMenuTimeout = num
+	case "SecureBoot":
+		s.SecureBoot = str == "Enabled"
+	case "NumaNodes":
+		s.NumaNodes = num
+	case "SystemManufacturer":
+		s.SystemManufacturer = str
+	case "SystemProductName":
+		s.SystemProductName = str
+	case "SystemVersion":
+		s.SystemVersion = str
+	case "SystemSerialNumber":
+		s.SystemSerialNumber = str
+	case "SystemSKU":
+		s.SystemSKU = str
+	case "SystemFamily":
+		s.SystemFamily = str
+	}
+	return "", ""
+}
+
+func hasAttributeValue(values []AttributeRegistryValue, v string) bool {
+	for _, val := range values {
+		if val.ValueName == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) handleGetBios(w http.ResponseWriter, r *http.Request) {
+	current, err := s.machine.GetBios()
+	if err != nil {
+		writeBiosError(w, err)
+		return
+	}
+
+	bios := Bios{
+		ODataType:         "#Bios.v1_1_0.Bios",
+		ODataID:           biosPath,
+		ID:                "Bios",
+		Name:              "BIOS Configuration Current Settings",
+		AttributeRegistry: biosRegistryID,
+		Attributes:        biosAttributes(current),
+		Settings: &RedfishSettings{
+			ODataType:           "#Settings.v1_3_0.Settings",
+			SettingsObject:      ODataID{ODataID: biosSettingsPath},
+			SupportedApplyTimes: []string{"OnReset"},
+		},
+		Actions: &BiosActions{
+			ResetBios: ActionTarget{Target: biosPath + "/Actions/Bios.ResetBios"},
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bios)
+}
+
+func (s *Server) handleGetBiosSettings(w http.ResponseWriter, r *http.Request) {
+	pending, err := s.machine.GetPendingBios()
+	if err != nil {
+		writeBiosError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(biosSettingsResource(pending))
+}
+
+// handlePatchBiosSettings merges attributes into the pending BIOS settings.
+// They are applied at the next power on.
+func (s *Server) handlePatchBiosSettings(w http.ResponseWriter, r *http.Request) {
+	var req PatchBiosRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedJSON", "invalid request body")
+		return
+	}
+	if len(req.Attributes) == 0 {
+		writeError(w, http.StatusBadRequest, "PropertyMissing", "no attributes provided")
+		return
+	}
+
+	settings, err := s.machine.GetPendingBios()
+	if err != nil {
+		writeBiosError(w, err)
+		return
+	}
+	for name, value := range req.Attributes {
+		if code, msg := setBiosAttribute(&settings, name, value); code != "" {
+			writeError(w, http.StatusBadRequest, code, msg)
+			return
+		}
+	}
+	if err := s.machine.SetPendingBios(settings); err != nil {
+		writeBiosError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(biosSettingsResource(settings))
+}
+
+// handleResetBios restores the startup BIOS settings from the next power on.
+func (s *Server) handleResetBios(w http.ResponseWriter, r *http.Request) {
+	if err := s.machine.ResetBios(); err != nil {
+		writeBiosError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleChangeBiosPassword refuses Bios.ChangePassword: neither SeaBIOS nor
+// OVMF can be given a password from outside the guest.
+func (s *Server) handleChangeBiosPassword(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotImplemented, "ActionNotSupported", "BIOS passwords are not supported")
+}
+
+func writeBiosError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, machine.ErrNotSupported):
+		writeError(w, http.StatusNotImplemented, "ActionNotSupported", "BIOS settings are only available in process mode")
+	case errors.Is(err, machine.ErrInvalidBiosSetting):
+		writeError(w, http.StatusBadRequest, "PropertyValueConflict", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+	}
+}
+
+func biosSettingsResource(settings machine.BiosSettings) Bios {
+	return Bios{
+		ODataType:         "#Bios.v1_1_0.Bios",
+		ODataID:           biosSettingsPath,
+		ID:                "Settings",
+		Name:              "BIOS Configuration Pending Settings",
+		AttributeRegistry: biosRegistryID,
+		Attributes:        biosAttributes(settings),
+	}
+}
+
+func (s *Server) handleRegistryCollection(w http.ResponseWriter, r *http.Request) {
+	col := RegistryCollection{
+		ODataType:    "#MessageRegistryFileCollection.MessageRegistryFileCollection",
+		ODataID:      registriesPath,
+		Name:         "Registry File Collection",
+		MembersCount: 1,
+		Members:      []ODataID{{ODataID: biosRegistryFilePath}},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetBiosRegistryFile(w http.ResponseWriter, r *http.Request) {
+	file := MessageRegistryFile{
+		ODataType: "#MessageRegistryFile.v1_1_0.MessageRegistryFile",
+		ODataID:   biosRegistryFilePath,
+		ID:        "BiosAttributeRegistry",
+		Name:      "BIOS Attribute Registry File",
+		Languages: []string{"en"},
+		Registry:  biosRegistryID,
+		Location:  []RegistryLocation{{Language: "en", URI: biosRegistryPath}},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(file)
+}
+
+func (s *Server) handleGetBiosRegistry(w http.ResponseWriter, r *http.Request) {
+	registry := AttributeRegistry{
+		ODataType:        "#AttributeRegistry.v1_3_0.AttributeRegistry",
+		ODataID:          biosRegistryPath,
+		ID:               biosRegistryID,
+		Name:             "QEMU BIOS Attribute Registry",
+		Language:         "en",
+		OwningEntity:     "qemu-bmc",
+		RegistryVersion:  "1.0.0",
+		SupportedSystems: []SupportedSystem{{ProductName: "QEMU Virtual Machine", SystemID: "1"}},
+		RegistryEntries:  AttributeRegistryEntries{Attributes: biosRegistryAttributes},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(registry)
+}
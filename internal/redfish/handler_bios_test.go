@@ -0,0 +1,163 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func newBiosTestServer() (*Server, *mockMachine) {
+	mock := newMockMachine(qmp.StatusShutdown)
+	mock.bios = &machine.BiosSettings{
+		BootMode:           machine.BiosBootModeLegacy,
+		NumaNodes:          1,
+		SystemManufacturer: "QEMU",
+	}
+	return newTestServer(mock), mock
+}
+
+func TestGetBios(t *testing.T) {
+	srv, _ := newBiosTestServer()
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1", "")
+	var system ComputerSystem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &system))
+	assert.Equal(t, "/redfish/v1/Systems/1/Bios", system.Bios.ODataID)
+
+	w = doRequest(srv, "GET", system.Bios.ODataID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var bios Bios
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &bios))
+	assert.Equal(t, "#Bios.v1_1_0.Bios", bios.ODataType)
+	assert.Equal(t, "BiosAttributeRegistry.1.0.0", bios.AttributeRegistry)
+	assert.Equal(t, "Bios", bios.Attributes["BootMode"])
+	assert.Equal(t, "Disabled", bios.Attributes["SecureBoot"])
+	assert.Equal(t, float64(1), bios.Attributes["NumaNodes"])
+	assert.Equal(t, "QEMU", bios.Attributes["SystemManufacturer"])
+	require.NotNil(t, bios.Settings)
+	assert.Equal(t, "/redfish/v1/Systems/1/Bios/Settings", bios.Settings.SettingsObject.ODataID)
+	require.NotNil(t, bios.Actions)
+	assert.Equal(t, "/redfish/v1/Systems/1/Bios/Actions/Bios.ResetBios", bios.Actions.ResetBios.Target)
+}
+
+func TestGetBios_LegacyMode(t *testing.T) {
+	srv := newTestServer(newMockMachine(qmp.StatusRunning))
+	assert.Equal(t, http.StatusNotImplemented, doRequest(srv, "GET", "/redfish/v1/Systems/1/Bios", "").Code)
+}
+
+func TestPatchBiosSettings(t *testing.T) {
+	srv, mock := newBiosTestServer()
+
+	w := doRequest(srv, "PATCH", "/redfish/v1/Systems/1/Bios/Settings",
+		`{"Attributes":{"BootMode":"Uefi","SecureBoot":"Enabled","BootMenuTimeout":5,"SystemSerialNumber":"SN-1"}}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var settings Bios
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+	assert.Equal(t, "Settings", settings.ID)
+	assert.Equal(t, "Uefi", settings.Attributes["BootMode"])
+	assert.Equal(t, float64(5), settings.Attributes["BootMenuTimeout"])
+
+	require.NotNil(t, mock.pendingBios)
+	assert.Equal(t, machine.BiosSettings{
+		BootMode:           machine.BiosBootModeUEFI,
+		BootMenuTimeout:    5,
+		SecureBoot:         true,
+		NumaNodes:          1,
+		SystemManufacturer: "QEMU",
+		SystemSerialNumber: "SN-1",
+	}, *mock.pendingBios)
+
+	// The current settings are unchanged until the next power on
+	w = doRequest(srv, "GET", "/redfish/v1/Systems/1/Bios", "")
+	var bios Bios
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &bios))
+	assert.Equal(t, "Bios", bios.Attributes["BootMode"])
+
+	w = doRequest(srv, "GET", "/redfish/v1/Systems/1/Bios/Settings", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+	assert.Equal(t, "SN-1", settings.Attributes["SystemSerialNumber"])
+}
+
+func TestPatchBiosSettings_Invalid(t *testing.T) {
+	srv, _ := newBiosTestServer()
+
+	tests := []struct {
+		body string
+		code string
+	}{
+		{`{"Attributes":{"Overclock":"On"}}`, "PropertyUnknown"},
+		{`{"Attributes":{"BootMode":"Efi"}}`, "PropertyValueNotInList"},
+		{`{"Attributes":{"BootMenuTimeout":"5"}}`, "PropertyValueTypeError"},
+		{`{"Attributes":{"NumaNodes":1.5}}`, "PropertyValueTypeError"},
+		{`{"Attributes":{"NumaNodes":9}}`, "PropertyValueError"},
+		{`{"Attributes":{"SystemSKU":"` + strings.Repeat("x", 65) + `"}}`, "PropertyValueError"},
+		{`{"Attributes":{"SecureBoot":"Enabled"}}`, "PropertyValueConflict"},
+		{`{"Attributes":{}}`, "PropertyMissing"},
+		{`not json`, "MalformedJSON"},
+	}
+	for _, tt := range tests {
+		w := doRequest(srv, "PATCH", "/redfish/v1/Systems/1/Bios/Settings", tt.body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, tt.body)
+		var e RedfishError
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &e))
+		assert.Equal(t, tt.code, e.Error.Code, tt.body)
+	}
+}
+
+func TestResetBiosAction(t *testing.T) {
+	srv, mock := newBiosTestServer()
+	w := doRequest(srv, "POST", "/redfish/v1/Systems/1/Bios/Actions/Bios.ResetBios", `{}`)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Contains(t, mock.Calls(), "ResetBios")
+}
+
+func TestChangeBiosPasswordAction(t *testing.T) {
+	srv, _ := newBiosTestServer()
+	path := "/redfish/v1/Systems/1/Bios/Actions/Bios.ChangePassword"
+
+	w := doRequest(srv, "POST", path, `{"PasswordName":"AdminPassword","OldPassword":"","NewPassword":"secret"}`)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	assert.Contains(t, w.Body.String(), "ActionNotSupported")
+
+	// and is not advertised
+	w = doRequest(srv, "GET", "/redfish/v1/Systems/1/Bios", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "Bios.ChangePassword")
+}
+
+func TestBiosAttributeRegistry(t *testing.T) {
+	srv, _ := newBiosTestServer()
+
+	w := doRequest(srv, "GET", "/redfish/v1/Registries", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var col RegistryCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	require.Equal(t, 1, col.MembersCount)
+
+	w = doRequest(srv, "GET", col.Members[0].ODataID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var file MessageRegistryFile
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &file))
+	assert.Equal(t, "BiosAttributeRegistry.1.0.0", file.Registry)
+	require.Len(t, file.Location, 1)
+
+	w = doRequest(srv, "GET", file.Location[0].URI, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var registry AttributeRegistry
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &registry))
+	assert.Equal(t, "BiosAttributeRegistry.1.0.0", registry.ID)
+
+	// Every attribute of the Bios resource is described
+	attrs := biosAttributes(machine.BiosSettings{})
+	assert.Len(t, registry.RegistryEntries.Attributes, len(attrs))
+	for _, a := range registry.RegistryEntries.Attributes {
+		assert.Contains(t, attrs, a.AttributeName)
+		assert.True(t, a.ResetRequired)
+	}
+}
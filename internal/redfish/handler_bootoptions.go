@@ -0,0 +1,71 @@
+package redfish
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+)
+
+const bootOptionsPath = "/redfish/v1/Systems/1/BootOptions"
+
+func (s *Server) handleBootOptionCollection(w http.ResponseWriter, r *http.Request) {
+	opts, err := s.machine.GetBootOptions()
+	if err != nil {
+		writeBootOptionError(w, err)
+		return
+	}
+
+	members := make([]ODataID, len(opts))
+	for i, o := range opts {
+		members[i] = ODataID{ODataID: bootOptionsPath + "/" + o.Reference}
+	}
+	col := BootOptionCollection{
+		ODataType:    "#BootOptionCollection.BootOptionCollection",
+		ODataID:      bootOptionsPath,
+		Name:         "Boot Option Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetBootOption(w http.ResponseWriter, r *http.Request) {
+	opts, err := s.machine.GetBootOptions()
+	if err != nil {
+		writeBootOptionError(w, err)
+		return
+	}
+
+	ref := mux.Vars(r)["option"]
+	for _, o := range opts {
+		if o.Reference != ref {
+			continue
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(BootOption{
+			ODataType:           "#BootOption.v1_0_4.BootOption",
+			ODataID:             bootOptionsPath + "/" + o.Reference,
+			ID:                  o.Reference,
+			Name:                "Boot Option " + o.Reference,
+			BootOptionReference: o.Reference,
+			BootOptionEnabled:   true,
+			DisplayName:         o.DisplayName,
+			Alias:               o.Alias,
+			UefiDevicePath:      o.UefiDevicePath,
+		})
+		return
+	}
+	writeError(w, http.StatusNotFound, "ResourceNotFound", "BootOption not found")
+}
+
+func writeBootOptionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, machine.ErrNotSupported) {
+		writeError(w, http.StatusNotImplemented, "ActionNotSupported", err.Error())
+		return
+	}
+	writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+}
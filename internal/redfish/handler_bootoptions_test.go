@@ -0,0 +1,112 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func newBootOptionsTestServer() (*Server, *mockMachine) {
+	mock := newMockMachine(qmp.StatusRunning)
+	mock.bootOptions = []machine.BootOption{
+		{Reference: "Boot0000", Alias: "Hdd", Driver: "virtio-blk-pci", DisplayName: "Hard disk (virtio-blk-pci)"},
+		{
+			Reference: "Boot0001", Alias: "Pxe", Driver: "virtio-net-pci", DeviceID: "nic0",
+			DisplayName: "Network (nic0)", UefiDevicePath: "PciRoot(0x0)/Pci(0x3,0x0)/MAC(525400123456,0x1)",
+		},
+	}
+	mock.bootOrder = []string{"Boot0000", "Boot0001"}
+	return newTestServer(mock), mock
+}
+
+func TestBootOptions(t *testing.T) {
+	srv, _ := newBootOptionsTestServer()
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1", "")
+	var system ComputerSystem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &system))
+	assert.Equal(t, []string{"Boot0000", "Boot0001"}, system.Boot.BootOrder)
+	require.NotNil(t, system.Boot.BootOptions)
+
+	w = doRequest(srv, "GET", system.Boot.BootOptions.ODataID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var col BootOptionCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	require.Equal(t, 2, col.MembersCount)
+
+	w = doRequest(srv, "GET", col.Members[1].ODataID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var opt BootOption
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &opt))
+	assert.Equal(t, "Boot0001", opt.BootOptionReference)
+	assert.Equal(t, "Pxe", opt.Alias)
+	assert.Equal(t, "PciRoot(0x0)/Pci(0x3,0x0)/MAC(525400123456,0x1)", opt.UefiDevicePath)
+	assert.True(t, opt.BootOptionEnabled)
+
+	w = doRequest(srv, "GET", "/redfish/v1/Systems/1/BootOptions/Boot0009", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestBootOptions_LegacyMode(t *testing.T) {
+	srv := newTestServer(newMockMachine(qmp.StatusRunning))
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1", "")
+	var system ComputerSystem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &system))
+	assert.Nil(t, system.Boot.BootOptions)
+	assert.Nil(t, system.Boot.BootOrder)
+
+	w = doRequest(srv, "GET", "/redfish/v1/Systems/1/BootOptions", "")
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+}
+
+func TestPatchBootOrder(t *testing.T) {
+	srv, mock := newBootOptionsTestServer()
+
+	w := doRequest(srv, "PATCH", "/redfish/v1/Systems/1", `{"Boot":{"BootOrder":["Boot0001","Boot0000"]}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var system ComputerSystem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &system))
+	assert.Equal(t, []string{"Boot0001", "Boot0000"}, system.Boot.BootOrder)
+	assert.Equal(t, "Disabled", mock.GetBootOverride().Enabled)
+
+	w = doRequest(srv, "PATCH", "/redfish/v1/Systems/1", `{"Boot":{"BootOrder":["Boot0007"]}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "PropertyValueNotInList")
+
+	// An invalid override fails the whole PATCH
+	w = doRequest(srv, "PATCH", "/redfish/v1/Systems/1",
+		`{"Boot":{"BootOrder":["Boot0000","Boot0001"],"BootSourceOverrideTarget":"Tape"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	order, _ := mock.GetBootOrder()
+	assert.Equal(t, []string{"Boot0001", "Boot0000"}, order)
+
+	// and an invalid boot order keeps the override
+	w = doRequest(srv, "PATCH", "/redfish/v1/Systems/1",
+		`{"Boot":{"BootOrder":["Boot0007"],"BootSourceOverrideEnabled":"Once","BootSourceOverrideTarget":"Pxe"}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "Disabled", mock.GetBootOverride().Enabled)
+}
+
+func TestPatchBootOrder_ChangesETag(t *testing.T) {
+	srv, _ := newBootOptionsTestServer()
+
+	etag := doRequest(srv, "GET", "/redfish/v1/Systems/1", "").Header().Get("ETag")
+	w := doRequest(srv, "PATCH", "/redfish/v1/Systems/1", `{"Boot":{"BootOrder":["Boot0001","Boot0000"]}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+
+	// A client holding the old ETag cannot overwrite the new order
+	req := httptest.NewRequest("PATCH", "/redfish/v1/Systems/1", strings.NewReader(`{"Boot":{"BootOrder":["Boot0000"]}}`))
+	req.Header.Set("If-Match", etag)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
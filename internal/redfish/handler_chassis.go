@@ -19,12 +19,21 @@ func (s *Server) handleChassisCollection(w http.ResponseWriter, r *http.Request)
 
 func (s *Server) handleGetChassis(w http.ResponseWriter, r *http.Request) {
 	chassis := Chassis{
-		ODataType:    "#Chassis.v1_0_0.Chassis",
-		ODataID:      "/redfish/v1/Chassis/1",
+		ODataType:    "#Chassis.v1_9_0.Chassis",
+		ODataID:      chassisPath,
 		ODataContext: "/redfish/v1/$metadata#Chassis.Chassis",
 		ID:           "1",
 		Name:         "QEMU Virtual Machine Chassis",
 		ChassisType:  "RackMount",
+		Power:        ODataID{ODataID: chassisPath + "/Power"},
+		Thermal:      ODataID{ODataID: chassisPath + "/Thermal"},
+		Sensors:      ODataID{ODataID: chassisSensorsPath},
+	}
+	if env, err := s.machine.GetEnvironment(); err == nil {
+		status := systemStatus(env.PowerState)
+		status.Health = worstHealth(chassisSensors(env))
+		chassis.PowerState = redfishPowerState(env.PowerState)
+		chassis.Status = &status
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(chassis)
@@ -8,12 +8,13 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
 	"github.com/tjst-t/qemu-bmc/internal/qmp"
 )
 
 func TestGetChassisCollection(t *testing.T) {
 	mock := newMockMachine(qmp.StatusRunning)
-	srv := NewServer(mock, "", "", "")
+	srv := NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 
 	req := httptest.NewRequest("GET", "/redfish/v1/Chassis", nil)
 	w := httptest.NewRecorder()
@@ -29,7 +30,7 @@ func TestGetChassisCollection(t *testing.T) {
 
 func TestGetChassis(t *testing.T) {
 	mock := newMockMachine(qmp.StatusRunning)
-	srv := NewServer(mock, "", "", "")
+	srv := NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 
 	req := httptest.NewRequest("GET", "/redfish/v1/Chassis/1", nil)
 	w := httptest.NewRecorder()
@@ -39,6 +40,6 @@ func TestGetChassis(t *testing.T) {
 	var chassis Chassis
 	err := json.Unmarshal(w.Body.Bytes(), &chassis)
 	require.NoError(t, err)
-	assert.Equal(t, "#Chassis.v1_0_0.Chassis", chassis.ODataType)
+	assert.Equal(t, "#Chassis.v1_9_0.Chassis", chassis.ODataType)
 	assert.Equal(t, "RackMount", chassis.ChassisType)
 }
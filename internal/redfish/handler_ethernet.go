@@ -0,0 +1,154 @@
+package redfish
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+)
+
+const ethernetInterfacesPath = "/redfish/v1/Systems/1/EthernetInterfaces"
+
+func (s *Server) handleEthernetInterfaceCollection(w http.ResponseWriter, r *http.Request) {
+	nics, err := s.machine.GetNICs()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	members := make([]ODataID, len(nics))
+	for i, n := range nics {
+		members[i] = ODataID{ODataID: ethernetInterfacesPath + "/" + n.ID}
+	}
+	col := EthernetInterfaceCollection{
+		ODataType:    "#EthernetInterfaceCollection.EthernetInterfaceCollection",
+		ODataID:      ethernetInterfacesPath,
+		Name:         "Ethernet Interface Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetEthernetInterface(w http.ResponseWriter, r *http.Request) {
+	nic, ok := s.findNIC(w, mux.Vars(r)["nic"])
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ethernetInterfaceResource(nic))
+}
+
+// handlePatchEthernetInterface changes the MAC address of a NIC, which
+// takes effect at the next power on, and connects or disconnects its link
+// through InterfaceEnabled.
+func (s *Server) handlePatchEthernetInterface(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["nic"]
+	var req PatchEthernetInterfaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedJSON", "invalid request body")
+		return
+	}
+	if req.MACAddress == nil && req.InterfaceEnabled == nil {
+		writeError(w, http.StatusBadRequest, "PropertyMissing", "no patchable properties provided")
+		return
+	}
+
+	if req.MACAddress != nil {
+		if err := s.machine.SetNICMAC(id, *req.MACAddress); err != nil {
+			writeNICError(w, err)
+			return
+		}
+	}
+	if req.InterfaceEnabled != nil {
+		if err := s.machine.SetNICLink(id, *req.InterfaceEnabled); err != nil {
+			writeNICError(w, err)
+			return
+		}
+	}
+
+	nic, ok := s.findNIC(w, id)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ethernetInterfaceResource(nic))
+}
+
+func writeNICError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, machine.ErrNICNotFound):
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "EthernetInterface not found")
+	case errors.Is(err, machine.ErrInvalidMAC):
+		writeError(w, http.StatusBadRequest, "PropertyValueFormatError", err.Error())
+	case errors.Is(err, machine.ErrNotSupported), errors.Is(err, machine.ErrNoNICArgument):
+		writeError(w, http.StatusBadRequest, "PropertyNotWritable", err.Error())
+	case errors.Is(err, machine.ErrNotRunning):
+		writeError(w, http.StatusConflict, "ResourceInStandby", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+	}
+}
+
+// findNIC looks up a NIC by ID, writing the error response if there is none.
+func (s *Server) findNIC(w http.ResponseWriter, id string) (machine.NIC, bool) {
+	nics, err := s.machine.GetNICs()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return machine.NIC{}, false
+	}
+	for _, n := range nics {
+		if n.ID == id {
+			return n, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "ResourceNotFound", "EthernetInterface not found")
+	return machine.NIC{}, false
+}
+
+func ethernetInterfaceResource(nic machine.NIC) EthernetInterface {
+	res := EthernetInterface{
+		ODataType:        "#EthernetInterface.v1_4_1.EthernetInterface",
+		ODataID:          ethernetInterfacesPath + "/" + nic.ID,
+		ID:               nic.ID,
+		Name:             "Ethernet Interface " + nic.ID,
+		Description:      nic.Model,
+		MACAddress:       nic.MAC,
+		InterfaceEnabled: !nic.LinkDisabled,
+		Status:           Status{State: "Enabled", Health: "OK"},
+		IPv4Addresses:    []IPv4Address{},
+		IPv6Addresses:    []IPv6Address{},
+	}
+	// LinkStatus is left out when the link state is unknown
+	switch {
+	case nic.LinkDisabled:
+		res.LinkStatus = "LinkDown"
+		res.Status.State = "Disabled"
+	case nic.Link == machine.LinkUp:
+		res.LinkStatus = "LinkUp"
+	case nic.Link == machine.LinkDown:
+		res.LinkStatus = "LinkDown"
+		res.Status.State = "StandbyOffline"
+	}
+	for _, a := range nic.Addresses {
+		if a.IPv6 {
+			res.IPv6Addresses = append(res.IPv6Addresses, IPv6Address{Address: a.Address, PrefixLength: a.Prefix})
+		} else {
+			res.IPv4Addresses = append(res.IPv4Addresses, IPv4Address{
+				Address:    a.Address,
+				SubnetMask: net.IP(net.CIDRMask(a.Prefix, 32)).String(),
+			})
+		}
+	}
+	if nic.Model != "" || nic.PendingMAC != "" {
+		res.Oem = &EthernetInterfaceOem{QemuBmc: EthernetInterfaceOemQemuBmc{
+			Model:             nic.Model,
+			PendingMACAddress: nic.PendingMAC,
+		}}
+	}
+	return res
+}
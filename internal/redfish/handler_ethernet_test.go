@@ -0,0 +1,95 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func newEthernetTestServer() *Server {
+	mock := newMockMachine(qmp.StatusRunning)
+	mock.nics = []machine.NIC{
+		{
+			ID: "nic0", Model: "virtio-net-pci", MAC: "52:54:00:12:34:56", Link: machine.LinkUp,
+			Addresses: []machine.IPAddress{
+				{Address: "192.0.2.10", Prefix: 24},
+				{Address: "2001:db8::10", Prefix: 64, IPv6: true},
+			},
+		},
+		{ID: "nic1", Model: "e1000", MAC: "52:54:00:12:34:57", Link: machine.LinkDown},
+		{ID: "nic2", Model: "e1000", MAC: "52:54:00:12:34:58"},
+	}
+	return newTestServer(mock)
+}
+
+func TestEthernetInterfaces(t *testing.T) {
+	srv := newEthernetTestServer()
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1/EthernetInterfaces", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var col EthernetInterfaceCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	require.Equal(t, 3, col.MembersCount)
+	assert.Equal(t, "/redfish/v1/Systems/1/EthernetInterfaces/nic0", col.Members[0].ODataID)
+
+	w = doRequest(srv, "GET", col.Members[0].ODataID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var nic EthernetInterface
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nic))
+	assert.Equal(t, "52:54:00:12:34:56", nic.MACAddress)
+	assert.Equal(t, "LinkUp", nic.LinkStatus)
+	assert.Equal(t, []IPv4Address{{Address: "192.0.2.10", SubnetMask: "255.255.255.0"}}, nic.IPv4Addresses)
+	assert.Equal(t, []IPv6Address{{Address: "2001:db8::10", PrefixLength: 64}}, nic.IPv6Addresses)
+	require.NotNil(t, nic.Oem)
+	assert.Equal(t, "virtio-net-pci", nic.Oem.QemuBmc.Model)
+
+	w = doRequest(srv, "GET", "/redfish/v1/Systems/1/EthernetInterfaces/nic1", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nic))
+	assert.Equal(t, "LinkDown", nic.LinkStatus)
+	assert.Equal(t, "StandbyOffline", nic.Status.State)
+	assert.Empty(t, nic.IPv4Addresses)
+
+	// An unknown link state is left out
+	w = doRequest(srv, "GET", "/redfish/v1/Systems/1/EthernetInterfaces/nic2", "")
+	assert.NotContains(t, w.Body.String(), "LinkStatus")
+
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", "/redfish/v1/Systems/1/EthernetInterfaces/nic9", "").Code)
+}
+
+func TestPatchEthernetInterface(t *testing.T) {
+	srv := newEthernetTestServer()
+
+	patch := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w
+	}
+
+	w := patch("/redfish/v1/Systems/1/EthernetInterfaces/nic0", `{"MACAddress":"52:54:00:aa:bb:cc"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var nic EthernetInterface
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nic))
+	assert.Equal(t, "52:54:00:12:34:56", nic.MACAddress)
+	assert.Equal(t, "52:54:00:aa:bb:cc", nic.Oem.QemuBmc.PendingMACAddress)
+
+	assert.Equal(t, http.StatusBadRequest, patch("/redfish/v1/Systems/1/EthernetInterfaces/nic0", `{"MACAddress":"ff:ff:ff:ff:ff:ff"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, patch("/redfish/v1/Systems/1/EthernetInterfaces/nic0", `{}`).Code)
+	assert.Equal(t, http.StatusNotFound, patch("/redfish/v1/Systems/1/EthernetInterfaces/nic9", `{"MACAddress":"52:54:00:aa:bb:cc"}`).Code)
+
+	// Disabling the interface takes its link down
+	w = patch("/redfish/v1/Systems/1/EthernetInterfaces/nic0", `{"InterfaceEnabled":false}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	nic = EthernetInterface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nic))
+	assert.False(t, nic.InterfaceEnabled)
+	assert.Equal(t, "LinkDown", nic.LinkStatus)
+	assert.Equal(t, "Disabled", nic.Status.State)
+}
@@ -0,0 +1,217 @@
+package redfish
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tjst-t/qemu-bmc/internal/eventlog"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+)
+
+const (
+	subscriptionsPath = "/redfish/v1/EventService/Subscriptions"
+	ssePath           = "/redfish/v1/EventService/SSE"
+)
+
+// ForwardMachineEvents publishes machine events to event subscribers and
+// logs them in the BMC event log until events is closed.
+func (s *Server) ForwardMachineEvents(events <-chan machine.Event) {
+	for e := range events {
+		if rec, ok := machineEventRecord(e); ok {
+			s.events.Publish(rec, false)
+		}
+		if msg, ok := machineEventMessage(e); ok {
+			s.eventLog.Add(eventlog.Entry{Created: e.Time, Message: msg})
+		}
+	}
+}
+
+// machineEventMessage describes a machine event for the BMC event log.
+func machineEventMessage(e machine.Event) (string, bool) {
+	switch e.Type {
+	case machine.EventPowerStateChanged:
+		return "Power state changed to " + string(e.PowerState), true
+	case machine.EventBootOverrideConsumed:
+		return fmt.Sprintf("Boot source override %s (%s) used", e.BootOverride.Target, e.BootOverride.Enabled), true
+	case machine.EventMediaInserted:
+		return "Virtual media " + e.Media + ": inserted " + e.Image, true
+	case machine.EventMediaEjected:
+		return "Virtual media " + e.Media + ": ejected", true
+	}
+	return "", false
+}
+
+func (s *Server) handleGetEventService(w http.ResponseWriter, r *http.Request) {
+	s.events.mu.Lock()
+	attempts, interval := s.events.retryAttempts, s.events.retryInterval
+	s.events.mu.Unlock()
+
+	svc := EventService{
+		ODataType:                    "#EventService.v1_7_0.EventService",
+		ODataID:                      "/redfish/v1/EventService",
+		ID:                           "EventService",
+		Name:                         "Event Service",
+		ServiceEnabled:               true,
+		DeliveryRetryAttempts:        attempts,
+		DeliveryRetryIntervalSeconds: int(interval / time.Second),
+		EventFormatTypes:             []string{"Event"},
+		RegistryPrefixes:             []string{"ResourceEvent"},
+		ResourceTypes:                []string{"ComputerSystem", "VirtualMedia"},
+		ServerSentEventURI:           ssePath,
+		Subscriptions:                ODataID{ODataID: subscriptionsPath},
+		Actions: EventServiceActions{
+			SubmitTestEvent: ActionTarget{Target: "/redfish/v1/EventService/Actions/EventService.SubmitTestEvent"},
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(svc)
+}
+
+func (s *Server) handleSubscriptionCollection(w http.ResponseWriter, r *http.Request) {
+	ids := s.events.List()
+	members := make([]ODataID, len(ids))
+	for i, id := range ids {
+		members[i] = ODataID{ODataID: subscriptionsPath + "/" + id}
+	}
+	col := EventDestinationCollection{
+		ODataType:    "#EventDestinationCollection.EventDestinationCollection",
+		ODataID:      subscriptionsPath,
+		Name:         "Event Subscriptions Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleCreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var req CreateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedJSON", "Invalid request body")
+		return
+	}
+	if req.Destination == "" {
+		writeError(w, http.StatusBadRequest, "PropertyMissing", "Destination is required")
+		return
+	}
+	if u, err := url.Parse(req.Destination); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		writeError(w, http.StatusBadRequest, "PropertyValueFormatError", "Destination must be an http or https URI")
+		return
+	}
+	if req.Protocol != "" && req.Protocol != "Redfish" {
+		writeError(w, http.StatusBadRequest, "PropertyValueNotInList", "Protocol must be Redfish")
+		return
+	}
+
+	dest := s.events.Subscribe(EventDestination{
+		Destination:      req.Destination,
+		Context:          req.Context,
+		RegistryPrefixes: req.RegistryPrefixes,
+		ResourceTypes:    req.ResourceTypes,
+	})
+
+	w.Header().Set("Location", subscriptionsPath+"/"+dest.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(subscriptionResource(dest))
+}
+
+func (s *Server) handleGetSubscription(w http.ResponseWriter, r *http.Request) {
+	dest, ok := s.events.Get(mux.Vars(r)["subid"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Subscription not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptionResource(dest))
+}
+
+func (s *Server) handleDeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	if !s.events.Unsubscribe(mux.Vars(r)["subid"]) {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Subscription not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSubmitTestEvent(w http.ResponseWriter, r *http.Request) {
+	var req SubmitTestEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedJSON", "Invalid request body")
+		return
+	}
+
+	rec := EventRecord{
+		EventType:      req.EventType,
+		EventID:        req.EventID,
+		EventTimestamp: req.EventTimestamp,
+		Severity:       req.Severity,
+		Message:        req.Message,
+		MessageID:      req.MessageID,
+		MessageArgs:    req.MessageArgs,
+	}
+	if rec.EventType == "" {
+		rec.EventType = "Alert"
+	}
+	if rec.Severity == "" {
+		rec.Severity = "OK"
+	}
+	if rec.MessageID == "" {
+		rec.MessageID = "Base.1.0.Success"
+		rec.Message = "Successfully Completed Request"
+	}
+	if req.OriginOfCondition != "" {
+		rec.OriginOfCondition = &ODataID{ODataID: req.OriginOfCondition}
+	}
+	s.events.Publish(rec, true)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEventStream serves events as Server-Sent Events until the client
+// disconnects.
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "InternalError", "Streaming not supported")
+		return
+	}
+
+	events, closeStream := s.events.OpenStream()
+	defer closeStream()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev := <-events:
+			fmt.Fprintf(w, "id: %s\ndata: %s\n\n", ev.ID, ev.Payload)
+			flusher.Flush()
+		}
+	}
+}
+
+func subscriptionResource(dest EventDestination) EventDestination {
+	dest.ODataType = "#EventDestination.v1_7_0.EventDestination"
+	dest.ODataID = subscriptionsPath + "/" + dest.ID
+	dest.Name = "Event Subscription " + dest.ID
+	dest.Protocol = "Redfish"
+	dest.SubscriptionType = "RedfishEvent"
+	dest.EventFormatType = "Event"
+	if dest.RegistryPrefixes == nil {
+		dest.RegistryPrefixes = []string{}
+	}
+	if dest.ResourceTypes == nil {
+		dest.ResourceTypes = []string{}
+	}
+	return dest
+}
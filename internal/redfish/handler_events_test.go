@@ -0,0 +1,204 @@
+package redfish
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+// eventSink is an HTTP event destination that records received events.
+// It fails the first failures requests with 500.
+type eventSink struct {
+	*httptest.Server
+	mu       sync.Mutex
+	failures int
+	events   chan Event
+}
+
+func newEventSink(t *testing.T, failures int) *eventSink {
+	sink := &eventSink{failures: failures, events: make(chan Event, 16)}
+	sink.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sink.mu.Lock()
+		fail := sink.failures > 0
+		sink.failures--
+		sink.mu.Unlock()
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var ev Event
+		json.NewDecoder(r.Body).Decode(&ev)
+		sink.events <- ev
+	}))
+	t.Cleanup(sink.Close)
+	return sink
+}
+
+func (sink *eventSink) next(t *testing.T) Event {
+	t.Helper()
+	select {
+	case ev := <-sink.events:
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event delivery")
+		return Event{}
+	}
+}
+
+func subscribe(t *testing.T, srv *Server, body string) string {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/redfish/v1/EventService/Subscriptions", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	return w.Header().Get("Location")
+}
+
+func TestEventService_Subscriptions(t *testing.T) {
+	srv := newTestServer(newMockMachine(qmp.StatusRunning))
+
+	location := subscribe(t, srv, `{"Destination":"http://198.51.100.1/events","Context":"ironic","RegistryPrefixes":["ResourceEvent"]}`)
+	assert.Equal(t, "/redfish/v1/EventService/Subscriptions/1", location)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", location, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var dest EventDestination
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &dest))
+	assert.Equal(t, "ironic", dest.Context)
+	assert.Equal(t, []string{"ResourceEvent"}, dest.RegistryPrefixes)
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("POST", "/redfish/v1/EventService/Subscriptions", strings.NewReader(`{"Destination":"ftp://x"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("DELETE", location, nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/redfish/v1/EventService/Subscriptions", nil))
+	var col EventDestinationCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	assert.Equal(t, 0, col.MembersCount)
+}
+
+func TestEventService_PushMachineEvents(t *testing.T) {
+	srv := newTestServer(newMockMachine(qmp.StatusRunning))
+	srv.events.retryInterval = 10 * time.Millisecond
+	sink := newEventSink(t, 1) // first attempt fails and is retried
+	subscribe(t, srv, `{"Destination":"`+sink.URL+`","Context":"ctx-1"}`)
+
+	events := make(chan machine.Event, 1)
+	go srv.ForwardMachineEvents(events)
+	events <- machine.Event{Type: machine.EventPowerStateChanged, PowerState: machine.PowerOff, Time: time.Now()}
+	close(events)
+
+	ev := sink.next(t)
+	assert.Equal(t, "ctx-1", ev.Context)
+	require.Len(t, ev.Events, 1)
+	assert.Equal(t, "ResourceEvent.1.3.ResourcePoweredOff", ev.Events[0].MessageID)
+	assert.Equal(t, "/redfish/v1/Systems/1", ev.Events[0].OriginOfCondition.ODataID)
+}
+
+func TestMachineEventRecord_PowerStates(t *testing.T) {
+	for ps, id := range map[machine.PowerState]string{
+		machine.PowerOn:          "ResourceEvent.1.3.ResourcePoweredOn",
+		machine.PowerOff:         "ResourceEvent.1.3.ResourcePoweredOff",
+		machine.PowerPoweringOn:  "ResourceEvent.1.3.ResourcePoweringOn",
+		machine.PowerPoweringOff: "ResourceEvent.1.3.ResourcePoweringOff",
+		machine.PowerPaused:      "ResourceEvent.1.3.ResourcePaused",
+	} {
+		rec, ok := machineEventRecord(machine.Event{Type: machine.EventPowerStateChanged, PowerState: ps})
+		require.True(t, ok)
+		assert.Equal(t, id, rec.MessageID)
+	}
+}
+
+func TestEventService_Filters(t *testing.T) {
+	srv := newTestServer(newMockMachine(qmp.StatusRunning))
+	mediaOnly := newEventSink(t, 0)
+	baseOnly := newEventSink(t, 0)
+	subscribe(t, srv, `{"Destination":"`+mediaOnly.URL+`","ResourceTypes":["VirtualMedia"]}`)
+	subscribe(t, srv, `{"Destination":"`+baseOnly.URL+`","RegistryPrefixes":["Base"]}`)
+
+	for _, e := range []machine.Event{
+		{Type: machine.EventPowerStateChanged, PowerState: machine.PowerOn},
+		{Type: machine.EventMediaInserted, Media: "CD1", Image: "http://x/a.iso"},
+	} {
+		rec, ok := machineEventRecord(e)
+		require.True(t, ok)
+		srv.events.Publish(rec, false)
+	}
+
+	ev := mediaOnly.next(t)
+	assert.Equal(t, "/redfish/v1/Managers/1/VirtualMedia/CD1", ev.Events[0].OriginOfCondition.ODataID)
+	select {
+	case ev := <-mediaOnly.events:
+		t.Fatalf("unexpected event %+v", ev)
+	case ev := <-baseOnly.events:
+		t.Fatalf("unexpected event %+v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestEventService_SubmitTestEvent(t *testing.T) {
+	srv := newTestServer(newMockMachine(qmp.StatusRunning))
+	sink := newEventSink(t, 0)
+	subscribe(t, srv, `{"Destination":"`+sink.URL+`","RegistryPrefixes":["ResourceEvent"]}`)
+
+	body := `{"MessageId":"Base.1.0.Success","Message":"hello","Severity":"OK"}`
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("POST", "/redfish/v1/EventService/Actions/EventService.SubmitTestEvent", strings.NewReader(body)))
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	ev := sink.next(t)
+	assert.Equal(t, "hello", ev.Events[0].Message, "test events bypass filters")
+}
+
+func TestEventService_SSE(t *testing.T) {
+	srv := NewServer(newMockMachine(qmp.StatusRunning), bmc.NewState("admin", "password"), "admin", "password", "")
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/redfish/v1/EventService/SSE", nil)
+	req.SetBasicAuth("admin", "password")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// The stream is registered once headers are flushed
+	require.Eventually(t, func() bool {
+		srv.events.mu.Lock()
+		defer srv.events.mu.Unlock()
+		return len(srv.events.sse) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	rec, _ := machineEventRecord(machine.Event{Type: machine.EventBootOverrideConsumed})
+	srv.events.Publish(rec, false)
+
+	reader := bufio.NewReader(resp.Body)
+	idLine, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(idLine, "id: "))
+	dataLine, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(dataLine, "data: "))
+
+	var ev Event
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(dataLine, "data: ")), &ev))
+	assert.Equal(t, "ResourceEvent.1.0.ResourceChanged", ev.Events[0].MessageID)
+}
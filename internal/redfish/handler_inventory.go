@@ -0,0 +1,189 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+)
+
+const (
+	processorsPath = "/redfish/v1/Systems/1/Processors"
+	memoryPath     = "/redfish/v1/Systems/1/Memory"
+	memoryID       = "DIMM0"
+)
+
+// archToRedfish maps QEMU targets to Redfish ProcessorArchitecture and
+// InstructionSet values.
+var archToRedfish = map[string][2]string{
+	"x86_64":  {"x86", "x86-64"},
+	"i386":    {"x86", "x86"},
+	"aarch64": {"ARM", "ARM-A64"},
+	"arm":     {"ARM", "ARM-A32"},
+	"ppc64":   {"Power", "PowerISA"},
+	"mips64":  {"MIPS", "MIPS64"},
+	"mips":    {"MIPS", "MIPS32"},
+	"riscv64": {"RISCV", "RV64"},
+	"riscv32": {"RISCV", "RV32"},
+}
+
+// socket groups the logical CPUs of one processor socket
+type socket struct {
+	ID      int
+	Cores   int
+	Threads int
+}
+
+// sockets groups the inventory CPUs by socket, in socket order.
+func sockets(inv machine.Inventory) []socket {
+	bySocket := make(map[int]*socket)
+	cores := make(map[[3]int]bool)
+	for _, c := range inv.CPUs {
+		sk, ok := bySocket[c.Socket]
+		if !ok {
+			sk = &socket{ID: c.Socket}
+			bySocket[c.Socket] = sk
+		}
+		sk.Threads++
+		if key := [3]int{c.Socket, c.Die, c.Core}; !cores[key] {
+			cores[key] = true
+			sk.Cores++
+		}
+	}
+
+	out := make([]socket, 0, len(bySocket))
+	for _, sk := range bySocket {
+		out = append(out, *sk)
+	}
+	slices.SortFunc(out, func(a, b socket) int { return a.ID - b.ID })
+	return out
+}
+
+// inventoryStatus is the status reported for processors and memory: present
+// and enabled while the system is on or paused, standby otherwise.
+func inventoryStatus(ps machine.PowerState) Status {
+	if ps == machine.PowerOn || ps == machine.PowerPaused {
+		return Status{State: "Enabled", Health: "OK"}
+	}
+	return Status{State: "StandbyOffline", Health: "OK"}
+}
+
+func processorSummary(inv machine.Inventory, status Status) *ProcessorSummary {
+	summary := &ProcessorSummary{
+		LogicalProcessorCount: len(inv.CPUs),
+		Model:                 inv.CPUModel,
+		Status:                status,
+	}
+	for _, sk := range sockets(inv) {
+		summary.Count++
+		summary.CoreCount += sk.Cores
+	}
+	return summary
+}
+
+func (s *Server) currentInventoryStatus() Status {
+	ps, err := s.machine.GetPowerState()
+	if err != nil {
+		return Status{State: "Absent"}
+	}
+	return inventoryStatus(ps)
+}
+
+func (s *Server) handleProcessorCollection(w http.ResponseWriter, r *http.Request) {
+	members := []ODataID{}
+	if inv, err := s.machine.GetInventory(); err == nil {
+		for _, sk := range sockets(inv) {
+			members = append(members, ODataID{ODataID: processorsPath + "/CPU" + strconv.Itoa(sk.ID)})
+		}
+	}
+
+	col := ProcessorCollection{
+		ODataType:    "#ProcessorCollection.ProcessorCollection",
+		ODataID:      processorsPath,
+		Name:         "Processors Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetProcessor(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["cpu"]
+	inv, err := s.machine.GetInventory()
+	var sk *socket
+	if err == nil {
+		for _, candidate := range sockets(inv) {
+			if "CPU"+strconv.Itoa(candidate.ID) == id {
+				sk = &candidate
+				break
+			}
+		}
+	}
+	if sk == nil {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Processor not found")
+		return
+	}
+
+	arch, ok := archToRedfish[inv.Arch]
+	if !ok {
+		arch = [2]string{"OEM", "OEM"}
+	}
+	proc := Processor{
+		ODataType:             "#Processor.v1_0_0.Processor",
+		ODataID:               processorsPath + "/" + id,
+		ID:                    id,
+		Name:                  "Processor",
+		Socket:                id,
+		ProcessorType:         "CPU",
+		ProcessorArchitecture: arch[0],
+		InstructionSet:        arch[1],
+		Model:                 inv.CPUModel,
+		TotalCores:            sk.Cores,
+		TotalThreads:          sk.Threads,
+		Status:                s.currentInventoryStatus(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(proc)
+}
+
+func (s *Server) handleMemoryCollection(w http.ResponseWriter, r *http.Request) {
+	members := []ODataID{}
+	if _, err := s.machine.GetInventory(); err == nil {
+		members = append(members, ODataID{ODataID: memoryPath + "/" + memoryID})
+	}
+
+	col := MemoryCollection{
+		ODataType:    "#MemoryCollection.MemoryCollection",
+		ODataID:      memoryPath,
+		Name:         "Memory Module Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetMemory(w http.ResponseWriter, r *http.Request) {
+	inv, err := s.machine.GetInventory()
+	if err != nil || mux.Vars(r)["mem"] != memoryID {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Memory not found")
+		return
+	}
+
+	mem := Memory{
+		ODataType:        "#Memory.v1_0_0.Memory",
+		ODataID:          memoryPath + "/" + memoryID,
+		ID:               memoryID,
+		Name:             "System Memory",
+		MemoryType:       "DRAM",
+		MemoryDeviceType: "DDR4",
+		CapacityMiB:      int(inv.MemoryBytes >> 20),
+		Status:           s.currentInventoryStatus(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mem)
+}
@@ -0,0 +1,103 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func newInventoryTestServer(status qmp.Status) *Server {
+	mock := newMockMachine(status)
+	mock.inventory = machine.Inventory{
+		MachineType: "pc-q35-8.2",
+		Arch:        "x86_64",
+		CPUModel:    "qemu64",
+		CPUs:        machine.TopologyCPUs(8, 2, 2),
+		MemoryBytes: 4 << 30,
+	}
+	return newTestServer(mock)
+}
+
+func TestGetSystem_Summaries(t *testing.T) {
+	srv := newInventoryTestServer(qmp.StatusRunning)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var system ComputerSystem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &system))
+	require.NotNil(t, system.ProcessorSummary)
+	assert.Equal(t, 2, system.ProcessorSummary.Count)
+	assert.Equal(t, 4, system.ProcessorSummary.CoreCount)
+	assert.Equal(t, 8, system.ProcessorSummary.LogicalProcessorCount)
+	assert.Equal(t, "qemu64", system.ProcessorSummary.Model)
+	assert.Equal(t, "Enabled", system.ProcessorSummary.Status.State)
+	require.NotNil(t, system.MemorySummary)
+	assert.Equal(t, 4.0, system.MemorySummary.TotalSystemMemoryGiB)
+	assert.Equal(t, "/redfish/v1/Systems/1/Processors", system.Processors.ODataID)
+	assert.Equal(t, "/redfish/v1/Systems/1/Memory", system.Memory.ODataID)
+}
+
+func TestGetSystem_NoInventory(t *testing.T) {
+	srv := newTestServer(newMockMachine(qmp.StatusShutdown))
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "ProcessorSummary")
+	assert.NotContains(t, w.Body.String(), "MemorySummary")
+
+	w = doRequest(srv, "GET", "/redfish/v1/Systems/1/Processors", "")
+	var col ProcessorCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	assert.Equal(t, 0, col.MembersCount)
+	assert.NotNil(t, col.Members)
+}
+
+func TestProcessors(t *testing.T) {
+	srv := newInventoryTestServer(qmp.StatusShutdown)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1/Processors", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var col ProcessorCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	require.Equal(t, 2, col.MembersCount)
+	assert.Equal(t, "/redfish/v1/Systems/1/Processors/CPU1", col.Members[1].ODataID)
+
+	w = doRequest(srv, "GET", "/redfish/v1/Systems/1/Processors/CPU1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var proc Processor
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &proc))
+	assert.Equal(t, "CPU1", proc.ID)
+	assert.Equal(t, "x86", proc.ProcessorArchitecture)
+	assert.Equal(t, "x86-64", proc.InstructionSet)
+	assert.Equal(t, 2, proc.TotalCores)
+	assert.Equal(t, 4, proc.TotalThreads)
+	assert.Equal(t, "StandbyOffline", proc.Status.State)
+
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", "/redfish/v1/Systems/1/Processors/CPU2", "").Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", "/redfish/v1/Systems/1/Processors/1", "").Code)
+}
+
+func TestMemory(t *testing.T) {
+	srv := newInventoryTestServer(qmp.StatusRunning)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1/Memory", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var col MemoryCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	require.Equal(t, 1, col.MembersCount)
+
+	w = doRequest(srv, "GET", col.Members[0].ODataID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var mem Memory
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &mem))
+	assert.Equal(t, 4096, mem.CapacityMiB)
+	assert.Equal(t, "Enabled", mem.Status.State)
+
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", "/redfish/v1/Systems/1/Memory/DIMM9", "").Code)
+}
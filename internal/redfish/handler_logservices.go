@@ -0,0 +1,460 @@
+package redfish
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+	"github.com/tjst-t/qemu-bmc/internal/eventlog"
+)
+
+const (
+	systemLogServicesPath  = "/redfish/v1/Systems/1/LogServices"
+	managerLogServicesPath = "/redfish/v1/Managers/1/LogServices"
+
+	// logPageSize is the most entries returned at once; the rest are
+	// linked by Members@odata.nextLink
+	logPageSize = 100
+
+	maxEventLogEntries = 1000
+)
+
+// logService is a log exposed as a Redfish LogService
+type logService struct {
+	id           string
+	name         string
+	description  string
+	logEntryType string // LogService.LogEntryType
+	maxRecords   int
+	entries      func(path string) []LogEntry // oldest first, path is the Entries collection
+	clear        func()
+}
+
+// SetProcessLog exposes the log of the QEMU process as the QemuLog log
+// service of the system.
+func (s *Server) SetProcessLog(log *eventlog.Log) {
+	s.processLog = log
+}
+
+// logServices returns the LogServices collection of the system or manager
+// addressed by r, and its log services.
+func (s *Server) logServices(r *http.Request) (string, []logService) {
+	if strings.HasPrefix(r.URL.Path, "/redfish/v1/Managers/") {
+		return managerLogServicesPath, []logService{{
+			id:           "EventLog",
+			name:         "BMC Event Log",
+			description:  "Changes made through the BMC and the machine events they caused",
+			logEntryType: "Event",
+			maxRecords:   s.eventLog.Max(),
+			entries: func(path string) []LogEntry {
+				return eventLogEntries(path, "Event", s.eventLog.Entries())
+			},
+			clear: s.eventLog.Clear,
+		}}
+	}
+
+	services := []logService{{
+		id:           "SEL",
+		name:         "System Event Log",
+		description:  "IPMI System Event Log",
+		logEntryType: "SEL",
+		maxRecords:   bmc.MaxSELEntries,
+		entries: func(path string) []LogEntry {
+			sel := s.bmcState.SELEntries()
+			entries := make([]LogEntry, len(sel))
+			for i, e := range sel {
+				entries[i] = selLogEntry(path, e)
+			}
+			return entries
+		},
+		clear: s.bmcState.ClearSEL,
+	}}
+	if s.processLog != nil {
+		services = append(services, logService{
+			id:           "QemuLog",
+			name:         "QEMU Log",
+			description:  "QEMU starts, exits and console output",
+			logEntryType: "OEM",
+			maxRecords:   s.processLog.Max(),
+			entries: func(path string) []LogEntry {
+				return eventLogEntries(path, "Oem", s.processLog.Entries())
+			},
+			clear: s.processLog.Clear,
+		})
+	}
+	return systemLogServicesPath, services
+}
+
+// logService returns the log service addressed by r and its path, or false
+// after writing the error response.
+func (s *Server) logService(w http.ResponseWriter, r *http.Request) (string, logService, bool) {
+	path, services := s.logServices(r)
+	id := mux.Vars(r)["log"]
+	for _, svc := range services {
+		if svc.id == id {
+			return path + "/" + id, svc, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "ResourceNotFound", "log service "+id+" not found")
+	return "", logService{}, false
+}
+
+func (s *Server) handleLogServiceCollection(w http.ResponseWriter, r *http.Request) {
+	path, services := s.logServices(r)
+	members := make([]ODataID, len(services))
+	for i, svc := range services {
+		members[i] = ODataID{ODataID: path + "/" + svc.id}
+	}
+	col := LogServiceCollection{
+		ODataType:    "#LogServiceCollection.LogServiceCollection",
+		ODataID:      path,
+		Name:         "Log Service Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetLogService(w http.ResponseWriter, r *http.Request) {
+	path, svc, ok := s.logService(w, r)
+	if !ok {
+		return
+	}
+	res := LogService{
+		ODataType:          "#LogService.v1_1_0.LogService",
+		ODataID:            path,
+		ID:                 svc.id,
+		Name:               svc.name,
+		Description:        svc.description,
+		ServiceEnabled:     true,
+		MaxNumberOfRecords: svc.maxRecords,
+		OverWritePolicy:    "WrapsWhenFull",
+		LogEntryType:       svc.logEntryType,
+		DateTime:           time.Now().UTC().Format(time.RFC3339),
+		Entries:            ODataID{ODataID: path + "/Entries"},
+		Actions: LogServiceActions{
+			ClearLog: ActionTarget{Target: path + "/Actions/LogService.ClearLog"},
+		},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// handleLogEntryCollection returns the entries matching $filter, a page at
+// a time as selected by $skip and $top.
+func (s *Server) handleLogEntryCollection(w http.ResponseWriter, r *http.Request) {
+	path, svc, ok := s.logService(w, r)
+	if !ok {
+		return
+	}
+	path += "/Entries"
+	query := r.URL.Query()
+
+	entries := svc.entries(path)
+	if expr := query.Get("$filter"); expr != "" {
+		match, err := parseLogFilter(expr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "QueryParameterValueFormatError", err.Error())
+			return
+		}
+		entries = slices.DeleteFunc(entries, func(e LogEntry) bool { return !match(e) })
+	}
+	total := len(entries)
+
+	skip, err := queryCount(query, "$skip", 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "QueryParameterValueFormatError", err.Error())
+		return
+	}
+	top, err := queryCount(query, "$top", total)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "QueryParameterValueFormatError", err.Error())
+		return
+	}
+	entries = entries[min(skip, total):]
+	entries = entries[:min(top, len(entries))]
+
+	col := LogEntryCollection{
+		ODataType:    "#LogEntryCollection.LogEntryCollection",
+		ODataID:      path,
+		Name:         "Log Entry Collection",
+		MembersCount: total,
+		Members:      entries,
+	}
+	if len(entries) > logPageSize {
+		col.Members = entries[:logPageSize]
+		next := url.Values{"$skip": {strconv.Itoa(skip + logPageSize)}}
+		if query.Has("$top") {
+			next.Set("$top", strconv.Itoa(len(entries)-logPageSize))
+		}
+		if query.Has("$filter") {
+			next.Set("$filter", query.Get("$filter"))
+		}
+		col.NextLink = path + "?" + next.Encode()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetLogEntry(w http.ResponseWriter, r *http.Request) {
+	path, svc, ok := s.logService(w, r)
+	if !ok {
+		return
+	}
+	id := mux.Vars(r)["entry"]
+	for _, e := range svc.entries(path + "/Entries") {
+		if e.ID == id {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(e)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "ResourceNotFound", "log entry "+id+" not found")
+}
+
+func (s *Server) handleClearLog(w http.ResponseWriter, r *http.Request) {
+	_, svc, ok := s.logService(w, r)
+	if !ok {
+		return
+	}
+	svc.clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// queryCount parses a non-negative integer query parameter, def if absent.
+func queryCount(query url.Values, name string, def int) (int, error) {
+	if !query.Has(name) {
+		return def, nil
+	}
+	n, err := strconv.Atoi(query.Get(name))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	return n, nil
+}
+
+func eventLogEntries(path, entryType string, log []eventlog.Entry) []LogEntry {
+	entries := make([]LogEntry, len(log))
+	for i, e := range log {
+		id := strconv.FormatUint(e.ID, 10)
+		entries[i] = LogEntry{
+			ODataType:   "#LogEntry.v1_4_0.LogEntry",
+			ODataID:     path + "/" + id,
+			ID:          id,
+			Name:        "Log Entry " + id,
+			EntryType:   entryType,
+			Severity:    e.Severity,
+			Created:     e.Created.UTC().Format(time.RFC3339),
+			Message:     e.Message,
+			MessageID:   e.MessageID,
+			MessageArgs: e.MessageArgs,
+		}
+		if entryType == "Oem" {
+			entries[i].OemRecordFormat = "QemuBmc"
+		}
+	}
+	return entries
+}
+
+// selEvent describes a SEL event offset of a sensor type
+type selEvent struct {
+	message  string
+	severity string
+}
+
+// selSensorTypes are the Redfish SensorType names of the sensor types the
+// BMC logs, and the messages of their event offsets.
+var selSensorTypes = map[uint8]struct {
+	name   string
+	events map[uint8]selEvent
+}{
+	bmc.SensorTypeSystemACPIPowerState: {"System ACPI Power State", map[uint8]selEvent{
+		bmc.ACPIPowerStateWorking: {"System powered on (S0/G0 working)", eventlog.SeverityOK},
+		bmc.ACPIPowerStateSoftOff: {"System powered off (S5/G2 soft-off)", eventlog.SeverityOK},
+	}},
+	bmc.SensorTypeSessionAudit: {"Session Audit", map[uint8]selEvent{
+		bmc.SessionAuditActivated:          {"Session activated", eventlog.SeverityOK},
+		bmc.SessionAuditDeactivated:        {"Session deactivated", eventlog.SeverityOK},
+		bmc.SessionAuditInvalidCredentials: {"Invalid username or password", eventlog.SeverityWarning},
+		bmc.SessionAuditLockout:            {"User disabled after repeated invalid passwords", eventlog.SeverityWarning},
+	}},
+}
+
+func selLogEntry(path string, e bmc.SELEntry) LogEntry {
+	id := strconv.Itoa(int(e.RecordID))
+	sensorNumber := int(e.SensorNumber)
+	entry := LogEntry{
+		ODataType:    "#LogEntry.v1_4_0.LogEntry",
+		ODataID:      path + "/" + id,
+		ID:           id,
+		Name:         "SEL Entry " + id,
+		EntryType:    "SEL",
+		Severity:     eventlog.SeverityOK,
+		Created:      e.Timestamp.UTC().Format(time.RFC3339),
+		SensorNumber: &sensorNumber,
+		EntryCode:    "Assert",
+		GeneratorID:  fmt.Sprintf("0x%04X", e.GeneratorID),
+	}
+
+	offset := e.EventData[0] & 0x0F
+	entry.Message = fmt.Sprintf("Sensor type 0x%02X: event offset 0x%02X", e.SensorType, offset)
+	if sensor, ok := selSensorTypes[e.SensorType]; ok {
+		entry.SensorType = sensor.name
+		entry.Message = fmt.Sprintf("%s: event offset 0x%02X", sensor.name, offset)
+		if ev, ok := sensor.events[offset]; ok {
+			entry.Message = sensor.name + ": " + ev.message
+			entry.Severity = ev.severity
+		}
+	}
+	if e.SensorType == bmc.SensorTypeSessionAudit && e.EventData[1] != 0xFF {
+		entry.Message += fmt.Sprintf(" (user ID %d)", e.EventData[1])
+	}
+	return entry
+}
+
+// logFilterProperties are the LogEntry properties $filter can compare
+var logFilterProperties = map[string]func(LogEntry) string{
+	"Id":        func(e LogEntry) string { return e.ID },
+	"EntryType": func(e LogEntry) string { return e.EntryType },
+	"Severity":  func(e LogEntry) string { return e.Severity },
+	"Created":   func(e LogEntry) string { return e.Created },
+	"MessageId": func(e LogEntry) string { return e.MessageID },
+}
+
+// logFilterOps maps $filter comparison operators to their test of
+// a comparison result
+var logFilterOps = map[string]func(int) bool{
+	"eq": func(c int) bool { return c == 0 },
+	"ne": func(c int) bool { return c != 0 },
+	"gt": func(c int) bool { return c > 0 },
+	"ge": func(c int) bool { return c >= 0 },
+	"lt": func(c int) bool { return c < 0 },
+	"le": func(c int) bool { return c <= 0 },
+}
+
+// parseLogFilter parses the subset of $filter that is useful on logs:
+// comparisons of a property with a literal joined by "and", such as
+// "Severity eq 'Critical' and Created ge '2026-01-02T03:04:05Z'". Id
+// compares as a number and Created as a time.
+func parseLogFilter(expr string) (func(LogEntry) bool, error) {
+	tokens, err := filterTokens(expr)
+	if err != nil {
+		return nil, err
+	}
+	var conds []func(LogEntry) bool
+	for {
+		if len(tokens) < 3 {
+			return nil, errors.New("$filter must compare a property with a value, e.g. Severity eq 'Critical'")
+		}
+		prop, op, value := tokens[0], tokens[1], tokens[2]
+		cond, err := logFilterCondition(prop.text, op.text, value.text)
+		if err == nil && (prop.quoted || op.quoted) {
+			err = errors.New("property and operator must not be quoted")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid $filter condition %s %s %s: %v", prop.text, op.text, value.text, err)
+		}
+		conds = append(conds, cond)
+		tokens = tokens[3:]
+		if len(tokens) == 0 {
+			break
+		}
+		if tokens[0].quoted || tokens[0].text != "and" {
+			return nil, fmt.Errorf("unsupported $filter operator %q, only and is supported", tokens[0].text)
+		}
+		tokens = tokens[1:]
+	}
+	return func(e LogEntry) bool {
+		for _, cond := range conds {
+			if !cond(e) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func logFilterCondition(prop, op, value string) (func(LogEntry) bool, error) {
+	get, ok := logFilterProperties[prop]
+	if !ok {
+		return nil, errors.New("unsupported property")
+	}
+	test, ok := logFilterOps[op]
+	if !ok {
+		return nil, errors.New("unsupported operator")
+	}
+	switch prop {
+	case "Id":
+		want, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, errors.New("Id must be a number")
+		}
+		return func(e LogEntry) bool {
+			id, _ := strconv.ParseUint(get(e), 10, 64)
+			return test(cmp.Compare(id, want))
+		}, nil
+	case "Created":
+		want, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, errors.New("Created must be an RFC 3339 date and time")
+		}
+		return func(e LogEntry) bool {
+			created, _ := time.Parse(time.RFC3339, get(e))
+			return test(created.Compare(want))
+		}, nil
+	}
+	return func(e LogEntry) bool { return test(strings.Compare(get(e), value)) }, nil
+}
+
+// filterToken is a word or a quoted string literal of $filter
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+// filterTokens splits a $filter expression into words and 'quoted'
+// literals, in which a doubled quote stands for a quote.
+func filterTokens(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expr); {
+		switch {
+		case expr[i] == ' ':
+			i++
+		case expr[i] == '\'':
+			var text strings.Builder
+			for i++; ; i++ {
+				if i == len(expr) {
+					return nil, errors.New("unterminated string literal in $filter")
+				}
+				if expr[i] == '\'' {
+					if i+1 < len(expr) && expr[i+1] == '\'' {
+						i++
+					} else {
+						break
+					}
+				}
+				text.WriteByte(expr[i])
+			}
+			tokens = append(tokens, filterToken{text: text.String(), quoted: true})
+			i++
+		default:
+			end := strings.IndexAny(expr[i:], " '")
+			if end < 0 {
+				end = len(expr) - i
+			}
+			tokens = append(tokens, filterToken{text: expr[i : i+end]})
+			i += end
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,250 @@
+package redfish
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+	"github.com/tjst-t/qemu-bmc/internal/eventlog"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func newLogTestServer() *Server {
+	return newTestServer(newMockMachine(qmp.StatusRunning))
+}
+
+func getLogEntries(t *testing.T, srv *Server, path string) LogEntryCollection {
+	t.Helper()
+	w := doRequest(srv, "GET", path, "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var col LogEntryCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	return col
+}
+
+func TestLogServices_Collections(t *testing.T) {
+	srv := newLogTestServer()
+
+	var system ComputerSystem
+	require.NoError(t, json.Unmarshal(doRequest(srv, "GET", "/redfish/v1/Systems/1", "").Body.Bytes(), &system))
+	assert.Equal(t, systemLogServicesPath, system.LogServices.ODataID)
+	var mgr Manager
+	require.NoError(t, json.Unmarshal(doRequest(srv, "GET", "/redfish/v1/Managers/1", "").Body.Bytes(), &mgr))
+	assert.Equal(t, managerLogServicesPath, mgr.LogServices.ODataID)
+
+	var col LogServiceCollection
+	require.NoError(t, json.Unmarshal(doRequest(srv, "GET", systemLogServicesPath, "").Body.Bytes(), &col))
+	assert.Equal(t, []ODataID{{ODataID: systemLogServicesPath + "/SEL"}}, col.Members, "no QEMU log in legacy mode")
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", systemLogServicesPath+"/QemuLog", "").Code)
+
+	srv.SetProcessLog(eventlog.New(10))
+	require.NoError(t, json.Unmarshal(doRequest(srv, "GET", systemLogServicesPath, "").Body.Bytes(), &col))
+	assert.Equal(t, 2, col.MembersCount)
+	assert.Equal(t, systemLogServicesPath+"/QemuLog", col.Members[1].ODataID)
+
+	require.NoError(t, json.Unmarshal(doRequest(srv, "GET", managerLogServicesPath, "").Body.Bytes(), &col))
+	assert.Equal(t, []ODataID{{ODataID: managerLogServicesPath + "/EventLog"}}, col.Members)
+
+	var svc LogService
+	w := doRequest(srv, "GET", systemLogServicesPath+"/SEL", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &svc))
+	assert.Equal(t, "SEL", svc.LogEntryType)
+	assert.Equal(t, bmc.MaxSELEntries, svc.MaxNumberOfRecords)
+	assert.Equal(t, systemLogServicesPath+"/SEL/Entries", svc.Entries.ODataID)
+	assert.Equal(t, systemLogServicesPath+"/SEL/Actions/LogService.ClearLog", svc.Actions.ClearLog.Target)
+
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", managerLogServicesPath+"/SEL", "").Code)
+}
+
+func TestLogServices_SEL(t *testing.T) {
+	srv := newLogTestServer()
+	srv.bmcState.AddPowerStateEntry(bmc.ACPIPowerStateWorking)
+	srv.bmcState.AddSELEntry(bmc.SELEntry{
+		SensorType: bmc.SensorTypeSessionAudit,
+		EventType:  0x6F,
+		EventData:  [3]byte{bmc.SessionAuditLockout, 3, 0xFF},
+	})
+	srv.bmcState.AddSELEntry(bmc.SELEntry{SensorType: 0x07, EventData: [3]byte{0x01}})
+	path := systemLogServicesPath + "/SEL/Entries"
+
+	col := getLogEntries(t, srv, path)
+	require.Equal(t, 3, col.MembersCount)
+	power, audit, other := col.Members[0], col.Members[1], col.Members[2]
+	assert.Equal(t, path+"/1", power.ODataID)
+	assert.Equal(t, "SEL", power.EntryType)
+	assert.Equal(t, "System ACPI Power State", power.SensorType)
+	assert.Equal(t, "System ACPI Power State: System powered on (S0/G0 working)", power.Message)
+	assert.Equal(t, "0x0020", power.GeneratorID)
+	assert.Equal(t, "Session Audit: User disabled after repeated invalid passwords (user ID 3)", audit.Message)
+	assert.Equal(t, "Warning", audit.Severity)
+	assert.Equal(t, "Sensor type 0x07: event offset 0x01", other.Message)
+
+	var entry LogEntry
+	w := doRequest(srv, "GET", path+"/2", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry))
+	assert.Equal(t, audit, entry)
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", path+"/99", "").Code)
+
+	w = doRequest(srv, "POST", systemLogServicesPath+"/SEL/Actions/LogService.ClearLog", "{}")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, srv.bmcState.SELEntries())
+}
+
+func TestLogServices_QemuLog(t *testing.T) {
+	srv := newLogTestServer()
+	log := eventlog.New(10)
+	log.Add(eventlog.Entry{Message: "QEMU started (pid 42): qemu-system-x86_64"})
+	log.Add(eventlog.Entry{Severity: eventlog.SeverityWarning, Message: "qemu-system-x86_64: Could not open 'disk.qcow2'"})
+	log.Add(eventlog.Entry{Severity: eventlog.SeverityCritical, Message: "QEMU exited unexpectedly: exit status 1"})
+	srv.SetProcessLog(log)
+	path := systemLogServicesPath + "/QemuLog/Entries"
+
+	col := getLogEntries(t, srv, path)
+	require.Equal(t, 3, col.MembersCount)
+	assert.Equal(t, "Oem", col.Members[2].EntryType)
+	assert.Equal(t, "QemuBmc", col.Members[2].OemRecordFormat)
+	assert.Equal(t, "Critical", col.Members[2].Severity)
+
+	col = getLogEntries(t, srv, path+"?$filter="+url.QueryEscape("Severity ne 'OK'"))
+	require.Equal(t, 2, col.MembersCount)
+	assert.Equal(t, "qemu-system-x86_64: Could not open 'disk.qcow2'", col.Members[0].Message)
+
+	require.Equal(t, http.StatusNoContent, doRequest(srv, "POST", systemLogServicesPath+"/QemuLog/Actions/LogService.ClearLog", "").Code)
+	assert.Empty(t, log.Entries())
+}
+
+func TestLogServices_Filter(t *testing.T) {
+	srv := newLogTestServer()
+	log := eventlog.New(10)
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, severity := range []string{"OK", "Warning", "Critical", "OK"} {
+		log.Add(eventlog.Entry{Severity: severity, Created: start.Add(time.Duration(i) * time.Hour), Message: fmt.Sprint("entry ", i+1)})
+	}
+	srv.SetProcessLog(log)
+	path := systemLogServicesPath + "/QemuLog/Entries"
+	ids := func(filter string) []string {
+		t.Helper()
+		col := getLogEntries(t, srv, path+"?$filter="+url.QueryEscape(filter))
+		ids := []string{}
+		for _, e := range col.Members {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"3"}, ids("Severity eq 'Critical'"))
+	assert.Equal(t, []string{"2", "3", "4"}, ids("Created ge '2026-03-01T13:00:00Z'"))
+	assert.Equal(t, []string{"2", "3"}, ids("Created gt '2026-03-01T22:30:00+10:00' and Id lt 4"))
+	assert.Equal(t, []string{"4"}, ids("Id gt 3 and Severity eq 'OK'"))
+	assert.Equal(t, []string{}, ids("MessageId eq 'it''s'"))
+
+	for _, filter := range []string{
+		"Severity",
+		"Severity eq 'OK' or Id eq 1",
+		"Message eq 'entry 1'",
+		"Severity like 'OK'",
+		"Created ge 'yesterday'",
+		"Id eq one",
+		"Severity eq 'OK",
+		"'Severity' eq 'OK'",
+	} {
+		w := doRequest(srv, "GET", path+"?$filter="+url.QueryEscape(filter), "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, filter)
+	}
+}
+
+func TestLogServices_Paging(t *testing.T) {
+	srv := newLogTestServer()
+	log := eventlog.New(1000)
+	for i := 0; i < 250; i++ {
+		log.Add(eventlog.Entry{Message: fmt.Sprint("line ", i+1)})
+	}
+	srv.SetProcessLog(log)
+	path := systemLogServicesPath + "/QemuLog/Entries"
+
+	var pages [][]LogEntry
+	next := path
+	for next != "" {
+		col := getLogEntries(t, srv, next)
+		assert.Equal(t, 250, col.MembersCount)
+		pages = append(pages, col.Members)
+		next = col.NextLink
+	}
+	require.Len(t, pages, 3)
+	assert.Len(t, pages[0], logPageSize)
+	assert.Equal(t, "101", pages[1][0].ID)
+	assert.Len(t, pages[2], 50)
+
+	col := getLogEntries(t, srv, path+"?$skip=10&$top=5")
+	require.Len(t, col.Members, 5)
+	assert.Equal(t, "11", col.Members[0].ID)
+	assert.Empty(t, col.NextLink)
+
+	// $top larger than a page continues with the rest of $top
+	col = getLogEntries(t, srv, path+"?$top=120")
+	require.Len(t, col.Members, logPageSize)
+	col = getLogEntries(t, srv, col.NextLink)
+	require.Len(t, col.Members, 20)
+	assert.Equal(t, "120", col.Members[19].ID)
+	assert.Empty(t, col.NextLink)
+
+	// Filters are kept across pages
+	col = getLogEntries(t, srv, path+"?$filter="+url.QueryEscape("Id gt 50"))
+	assert.Equal(t, 200, col.MembersCount)
+	col = getLogEntries(t, srv, col.NextLink)
+	assert.Equal(t, "151", col.Members[0].ID)
+
+	assert.Empty(t, getLogEntries(t, srv, path+"?$skip=300").Members)
+	assert.Equal(t, http.StatusBadRequest, doRequest(srv, "GET", path+"?$top=-1", "").Code)
+	assert.Equal(t, http.StatusBadRequest, doRequest(srv, "GET", path+"?$skip=x", "").Code)
+}
+
+func TestLogServices_EventLog(t *testing.T) {
+	srv, _ := newAuthzTestServer(t)
+	path := managerLogServicesPath + "/EventLog/Entries"
+
+	w := doRequest(srv, "POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"ForceOff"}`, "operator", "secret")
+	require.Equal(t, http.StatusNoContent, w.Code)
+	doRequest(srv, "POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"On"}`, "monitor", "secret")
+	doRequest(srv, "POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"On"}`, "operator", "wrong")
+	doRequest(srv, "POST", sessionsPath, `{"UserName":"admin","Password":"password"}`)
+	doRequest(srv, "GET", "/redfish/v1/Systems/1", "", "admin", "password")
+
+	events := make(chan machine.Event, 1)
+	events <- machine.Event{Type: machine.EventMediaInserted, Media: "CD1", Image: "http://x/boot.iso", Time: time.Now()}
+	close(events)
+	srv.ForwardMachineEvents(events)
+
+	w = doRequest(srv, "GET", path, "", "monitor", "secret")
+	require.Equal(t, http.StatusOK, w.Code)
+	var col LogEntryCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	require.Equal(t, 5, col.MembersCount, "reads are not logged")
+	msg := func(i int) string { return col.Members[i].Message }
+	assert.Equal(t, "POST /redfish/v1/Systems/1/Actions/ComputerSystem.Reset by operator from 192.0.2.1: 204 No Content", msg(0))
+	assert.Equal(t, "OK", col.Members[0].Severity)
+	assert.Equal(t, "POST /redfish/v1/Systems/1/Actions/ComputerSystem.Reset by monitor from 192.0.2.1: 403 Forbidden", msg(1))
+	assert.Equal(t, "Warning", col.Members[1].Severity)
+	assert.Equal(t, "POST /redfish/v1/Systems/1/Actions/ComputerSystem.Reset by operator from 192.0.2.1: 401 Unauthorized", msg(2))
+	assert.Equal(t, "POST "+sessionsPath+" by admin from 192.0.2.1: 201 Created", msg(3))
+	assert.Equal(t, "Virtual media CD1: inserted http://x/boot.iso", msg(4))
+	assert.Equal(t, "Event", col.Members[4].EntryType)
+
+	// Clearing the event log needs ConfigureManager, and is logged
+	clear := managerLogServicesPath + "/EventLog/Actions/LogService.ClearLog"
+	assert.Equal(t, http.StatusForbidden, doRequest(srv, "POST", clear, "", "operator", "secret").Code)
+	require.Equal(t, http.StatusNoContent, doRequest(srv, "POST", clear, "", "admin", "password").Code)
+	w = doRequest(srv, "GET", path, "", "monitor", "secret")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	require.Equal(t, 1, col.MembersCount)
+	assert.Equal(t, "POST "+clear+" by admin from 192.0.2.1: 204 No Content", msg(0))
+}
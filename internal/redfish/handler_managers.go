@@ -2,7 +2,6 @@ package redfish
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 )
 
@@ -26,80 +25,12 @@ func (s *Server) handleGetManager(w http.ResponseWriter, r *http.Request) {
 		ID:           "1",
 		Name:         "QEMU BMC",
 		ManagerType:  "BMC",
-		VirtualMedia: ODataID{ODataID: "/redfish/v1/Managers/1/VirtualMedia"},
+		VirtualMedia: ODataID{ODataID: managerVirtualMediaPath},
+		LogServices:  ODataID{ODataID: managerLogServicesPath},
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(mgr)
-}
-
-func (s *Server) handleVirtualMediaCollection(w http.ResponseWriter, r *http.Request) {
-	col := VirtualMediaCollection{
-		ODataType:    "#VirtualMediaCollection.VirtualMediaCollection",
-		ODataID:      "/redfish/v1/Managers/1/VirtualMedia",
-		Name:         "Virtual Media Collection",
-		MembersCount: 1,
-		Members:      []ODataID{{ODataID: "/redfish/v1/Managers/1/VirtualMedia/CD1"}},
+	if s.imageCache != nil {
+		mgr.Oem = &ManagerOem{QemuBmc: ManagerOemQemuBmc{MediaImages: ODataID{ODataID: mediaImagesPath}}}
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(col)
-}
-
-func (s *Server) handleGetVirtualMedia(w http.ResponseWriter, r *http.Request) {
-	vm := VirtualMedia{
-		ODataType:    "#VirtualMedia.v1_2_0.VirtualMedia",
-		ODataID:      "/redfish/v1/Managers/1/VirtualMedia/CD1",
-		ODataContext: "/redfish/v1/$metadata#VirtualMedia.VirtualMedia",
-		ID:           "CD1",
-		Name:         "Virtual CD",
-		MediaTypes:   []string{"CD", "DVD"},
-		Image:        s.currentMedia,
-		Inserted:     s.currentMedia != "",
-		ConnectedVia: func() string {
-			if s.currentMedia != "" {
-				return "URI"
-			}
-			return "NotConnected"
-		}(),
-		Actions: VirtualMediaActions{
-			InsertMedia: VirtualMediaAction{Target: "/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.InsertMedia"},
-			EjectMedia:  VirtualMediaAction{Target: "/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.EjectMedia"},
-		},
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(vm)
-}
-
-func (s *Server) handleInsertMedia(w http.ResponseWriter, r *http.Request) {
-	var req InsertMediaRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "MalformedJSON", "Invalid request body")
-		return
-	}
-
-	if req.Image == "" {
-		writeError(w, http.StatusBadRequest, "PropertyMissing", "Image URL is required")
-		return
-	}
-
-	// Track media state in BMC. QMP insertion is best-effort since
-	// the URL may not be accessible until boot time.
-	if err := s.machine.InsertMedia(req.Image); err != nil {
-		log.Printf("VirtualMedia: QMP insert failed (non-fatal): %v", err)
-	}
-
-	s.currentMedia = req.Image
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
-}
-
-func (s *Server) handleEjectMedia(w http.ResponseWriter, r *http.Request) {
-	if err := s.machine.EjectMedia(); err != nil {
-		log.Printf("VirtualMedia: QMP eject failed (non-fatal): %v", err)
-	}
-
-	s.currentMedia = ""
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	json.NewEncoder(w).Encode(mgr)
 }
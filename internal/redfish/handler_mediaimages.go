@@ -0,0 +1,194 @@
+package redfish
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tjst-t/qemu-bmc/internal/imagecache"
+)
+
+// mediaImagesPath is the OEM collection of images uploaded to the BMC. The
+// URI of an image, with or without scheme and host, is accepted as Image
+// by InsertMedia.
+const mediaImagesPath = "/redfish/v1/Managers/1/Oem/QemuBmc/MediaImages"
+
+// SetImageCache enables image uploads into cache.
+func (s *Server) SetImageCache(cache *imagecache.Cache) {
+	s.imageCache = cache
+}
+
+func (s *Server) handleMediaImageCollection(w http.ResponseWriter, r *http.Request) {
+	if !s.imageCacheEnabled(w) {
+		return
+	}
+	uploads, err := s.imageCache.Uploads()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	members := make([]ODataID, len(uploads))
+	for i, u := range uploads {
+		members[i] = ODataID{ODataID: mediaImagesPath + "/" + u.Name}
+	}
+	col := MediaImageCollection{
+		ODataType:    "#QemuBmcMediaImageCollection.QemuBmcMediaImageCollection",
+		ODataID:      mediaImagesPath,
+		Name:         "Uploaded Media Images",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetMediaImage(w http.ResponseWriter, r *http.Request) {
+	if !s.imageCacheEnabled(w) {
+		return
+	}
+	u, ok := s.imageCache.Lookup(mux.Vars(r)["image"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "media image "+mux.Vars(r)["image"]+" not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(mediaImageResource(u))
+}
+
+// handlePutMediaImage uploads the request body as an image.
+func (s *Server) handlePutMediaImage(w http.ResponseWriter, r *http.Request) {
+	if !s.imageCacheEnabled(w) {
+		return
+	}
+	u, err := s.imageCache.Store(mux.Vars(r)["image"], r.Body, r.ContentLength)
+	s.writeUploaded(w, u, err)
+}
+
+// handleUploadMediaImage uploads the first file of a multipart/form-data
+// request, named after the file.
+func (s *Server) handleUploadMediaImage(w http.ResponseWriter, r *http.Request) {
+	if !s.imageCacheEnabled(w) {
+		return
+	}
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusUnsupportedMediaType, "UnsupportedMediaType", "multipart/form-data upload expected")
+		return
+	}
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "PropertyMissing", "no file in the upload")
+			return
+		}
+		if part.FileName() == "" {
+			continue
+		}
+		u, err := s.imageCache.Store(uploadName(part.FileName()), part, -1)
+		s.writeUploaded(w, u, err)
+		return
+	}
+}
+
+func (s *Server) handleDeleteMediaImage(w http.ResponseWriter, r *http.Request) {
+	if !s.imageCacheEnabled(w) {
+		return
+	}
+	if err := s.imageCache.Delete(mux.Vars(r)["image"]); err != nil {
+		writeImageError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) writeUploaded(w http.ResponseWriter, u imagecache.Upload, err error) {
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+	res := mediaImageResource(u)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", res.ODataID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+// uploadedFile returns the file of an uploaded image given as the Image
+// of InsertMedia, "" if image is not an uploaded image, or false after
+// writing the error response if there is no such upload.
+func (s *Server) uploadedFile(w http.ResponseWriter, r *http.Request, image string) (string, bool) {
+	u, err := url.Parse(image)
+	if err != nil || (u.Host != "" && u.Host != r.Host) {
+		return "", true
+	}
+	name, ok := strings.CutPrefix(u.Path, mediaImagesPath+"/")
+	if !ok {
+		return "", true
+	}
+	if s.imageCache != nil {
+		if upload, ok := s.imageCache.Lookup(name); ok {
+			return upload.Path, true
+		}
+	}
+	writeError(w, http.StatusBadRequest, "ActionParameterValueError", "media image "+name+" not found")
+	return "", false
+}
+
+func (s *Server) imageCacheEnabled(w http.ResponseWriter) bool {
+	if s.imageCache == nil {
+		writeError(w, http.StatusNotImplemented, "ActionNotSupported", "the media cache is disabled")
+		return false
+	}
+	return true
+}
+
+func mediaImageResource(u imagecache.Upload) MediaImage {
+	return MediaImage{
+		ODataType: "#QemuBmcMediaImage.v1_0_0.QemuBmcMediaImage",
+		ODataID:   mediaImagesPath + "/" + u.Name,
+		ID:        u.Name,
+		Name:      u.Name,
+		SizeBytes: u.Size,
+		SHA256:    u.SHA256,
+		Created:   u.Uploaded.Format(time.RFC3339),
+	}
+}
+
+// uploadName turns the file name of a browser upload into an image name,
+// as imageName in the noVNC media overlay does.
+func uploadName(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+	name = strings.TrimLeft(name, "._-")
+	if len(name) > imagecache.MaxNameLength {
+		name = name[:imagecache.MaxNameLength]
+	}
+	if name == "" {
+		return "image.iso"
+	}
+	return name
+}
+
+func writeImageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, imagecache.ErrNotFound):
+		writeError(w, http.StatusNotFound, "ResourceNotFound", err.Error())
+	case errors.Is(err, imagecache.ErrInvalidName):
+		writeError(w, http.StatusBadRequest, "PropertyValueFormatError", err.Error())
+	case errors.Is(err, imagecache.ErrTooLarge):
+		writeError(w, http.StatusInsufficientStorage, "InsufficientStorage", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+	}
+}
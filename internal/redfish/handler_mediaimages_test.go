@@ -0,0 +1,155 @@
+package redfish
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/imagecache"
+)
+
+func newImageTestServer(t *testing.T, maxSize int64) (*Server, *mockMachine, *imagecache.Cache) {
+	t.Helper()
+	srv, mock := newMediaTestServer()
+	cache, err := imagecache.New(t.TempDir(), maxSize)
+	require.NoError(t, err)
+	srv.SetImageCache(cache)
+	return srv, mock, cache
+}
+
+func uploadMultipart(t *testing.T, srv *Server, filename, content string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	require.NoError(t, mw.WriteField("comment", "rescue"))
+	fw, err := mw.CreateFormFile("image", filename)
+	require.NoError(t, err)
+	fw.Write([]byte(content))
+	require.NoError(t, mw.Close())
+
+	req := httptest.NewRequest("POST", mediaImagesPath, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	return w
+}
+
+func TestMediaImages_Disabled(t *testing.T) {
+	srv, _ := newMediaTestServer()
+	assert.Equal(t, http.StatusNotImplemented, doRequest(srv, "GET", mediaImagesPath, "").Code)
+	assert.Equal(t, http.StatusNotImplemented, doRequest(srv, "PUT", mediaImagesPath+"/a.iso", "image").Code)
+
+	var mgr Manager
+	require.NoError(t, json.Unmarshal(doRequest(srv, "GET", "/redfish/v1/Managers/1", "").Body.Bytes(), &mgr))
+	assert.Nil(t, mgr.Oem)
+}
+
+func TestMediaImages_Upload(t *testing.T) {
+	srv, _, _ := newImageTestServer(t, 0)
+
+	w := doRequest(srv, "PUT", mediaImagesPath+"/rescue.iso", "rescue image")
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, mediaImagesPath+"/rescue.iso", w.Header().Get("Location"))
+	var img MediaImage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &img))
+	assert.Equal(t, "rescue.iso", img.ID)
+	assert.Equal(t, int64(12), img.SizeBytes)
+	assert.Len(t, img.SHA256, 64)
+
+	w = uploadMultipart(t, srv, `C:\Users\ops\Ubuntu 24.04.iso`, "ubuntu image")
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, mediaImagesPath+"/Ubuntu_24.04.iso", w.Header().Get("Location"))
+
+	var col MediaImageCollection
+	require.NoError(t, json.Unmarshal(doRequest(srv, "GET", mediaImagesPath, "").Body.Bytes(), &col))
+	assert.Equal(t, 2, col.MembersCount)
+	assert.Equal(t, mediaImagesPath+"/Ubuntu_24.04.iso", col.Members[0].ODataID)
+	assert.Equal(t, mediaImagesPath+"/rescue.iso", col.Members[1].ODataID)
+
+	require.Equal(t, http.StatusOK, doRequest(srv, "GET", mediaImagesPath+"/rescue.iso", "").Code)
+	assert.Equal(t, http.StatusNoContent, doRequest(srv, "DELETE", mediaImagesPath+"/rescue.iso", "").Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", mediaImagesPath+"/rescue.iso", "").Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "DELETE", mediaImagesPath+"/rescue.iso", "").Code)
+
+	var mgr Manager
+	require.NoError(t, json.Unmarshal(doRequest(srv, "GET", "/redfish/v1/Managers/1", "").Body.Bytes(), &mgr))
+	require.NotNil(t, mgr.Oem)
+	assert.Equal(t, mediaImagesPath, mgr.Oem.QemuBmc.MediaImages.ODataID)
+}
+
+func TestMediaImages_UploadErrors(t *testing.T) {
+	srv, _, _ := newImageTestServer(t, 8)
+
+	assert.Equal(t, http.StatusInsufficientStorage, doRequest(srv, "PUT", mediaImagesPath+"/big.iso", "larger than the cache").Code)
+	assert.Equal(t, http.StatusBadRequest, doRequest(srv, "PUT", mediaImagesPath+"/.hidden", "x").Code)
+	assert.Equal(t, http.StatusUnsupportedMediaType, doRequest(srv, "POST", mediaImagesPath, "raw").Code)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("comment", "no file")
+	mw.Close()
+	req := httptest.NewRequest("POST", mediaImagesPath, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUploadName(t *testing.T) {
+	tests := map[string]string{
+		"rescue.iso":                   "rescue.iso",
+		`C:\Users\me\My Disk.iso`:      "My_Disk.iso",
+		"/tmp/日本語 ディスク.iso":            "iso",
+		"/tmp/disk 日本語.iso":            "disk____.iso",
+		".hidden.iso":                  "hidden.iso",
+		"_-.boot.iso":                  "boot.iso",
+		"...":                          "image.iso",
+		strings.Repeat("a", 300):       strings.Repeat("a", imagecache.MaxNameLength),
+		"-" + strings.Repeat("b", 260): strings.Repeat("b", imagecache.MaxNameLength),
+	}
+	cache, err := imagecache.New(t.TempDir(), 0)
+	require.NoError(t, err)
+	for filename, want := range tests {
+		name := uploadName(filename)
+		assert.Equal(t, want, name, filename)
+		_, err := cache.Store(name, strings.NewReader("image"), 5)
+		assert.NoError(t, err, filename)
+	}
+}
+
+func TestInsertMedia_UploadedImage(t *testing.T) {
+	srv, mock, cache := newImageTestServer(t, 0)
+	require.Equal(t, http.StatusCreated, doRequest(srv, "PUT", mediaImagesPath+"/rescue.iso", "rescue image").Code)
+	upload, ok := cache.Lookup("rescue.iso")
+	require.True(t, ok)
+	insert := managerVirtualMediaPath + "/CD1/Actions/VirtualMedia.InsertMedia"
+
+	image := mediaImagesPath + "/rescue.iso"
+	w := doRequest(srv, "POST", insert, `{"Image":"`+image+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, upload.Path, mock.lastInsert.File)
+	assert.Equal(t, image, mock.LastInsertedMedia())
+
+	// An absolute URL of this BMC works as well
+	w = doRequest(srv, "POST", insert, `{"Image":"https://example.com`+image+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, upload.Path, mock.lastInsert.File)
+
+	w = doRequest(srv, "PATCH", managerVirtualMediaPath+"/USB1", `{"Image":"`+image+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, upload.Path, mock.lastInsert.File)
+
+	w = doRequest(srv, "POST", insert, `{"Image":"`+mediaImagesPath+`/missing.iso"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Other URLs are not uploads
+	w = doRequest(srv, "POST", insert, `{"Image":"https://images.example.org`+image+`"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, mock.lastInsert.File)
+}
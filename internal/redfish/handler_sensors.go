@@ -0,0 +1,288 @@
+package redfish
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+)
+
+const (
+	chassisPath        = "/redfish/v1/Chassis/1"
+	chassisSensorsPath = chassisPath + "/Sensors"
+
+	// chassisPowerCapacityWatts is the rating of the simulated power supply
+	chassisPowerCapacityWatts = 800
+)
+
+// healthOrder lists the Health values from best to worst
+var healthOrder = []string{"OK", "Warning", "Critical"}
+
+// limits are the thresholds of a sensor, 0 where there is none
+type limits struct {
+	upperCaution, upperCritical, upperFatal float64
+	lowerCaution, lowerCritical             float64
+}
+
+// Thresholds of the simulated sensors, in degrees Celsius and RPM
+var (
+	inletLimits = limits{upperCaution: 35, upperCritical: 40, upperFatal: 45}
+	cpuLimits   = limits{upperCaution: 80, upperCritical: 90, upperFatal: 100}
+	boardLimits = limits{upperCaution: 60, upperCritical: 70}
+	fanLimits   = limits{lowerCaution: 1500, lowerCritical: 1000}
+)
+
+// health rates a reading: Critical past a critical or fatal threshold,
+// Warning past a caution threshold.
+func (l limits) health(reading float64) string {
+	switch {
+	case l.upperCritical > 0 && reading >= l.upperCritical,
+		l.lowerCritical > 0 && reading <= l.lowerCritical:
+		return "Critical"
+	case l.upperCaution > 0 && reading >= l.upperCaution,
+		l.lowerCaution > 0 && reading <= l.lowerCaution:
+		return "Warning"
+	}
+	return "OK"
+}
+
+func (l limits) thresholds() *SensorThresholds {
+	t := SensorThresholds{
+		UpperCaution:  threshold(l.upperCaution),
+		UpperCritical: threshold(l.upperCritical),
+		UpperFatal:    threshold(l.upperFatal),
+		LowerCaution:  threshold(l.lowerCaution),
+		LowerCritical: threshold(l.lowerCritical),
+	}
+	if t == (SensorThresholds{}) {
+		return nil
+	}
+	return &t
+}
+
+func threshold(v float64) *Threshold {
+	if v == 0 {
+		return nil
+	}
+	return &Threshold{Reading: v}
+}
+
+// optionalLimit is a legacy Thermal threshold, omitted if there is none
+func optionalLimit(v float64) *float64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+// chassisSensor is a reading of the simulated chassis environment
+type chassisSensor struct {
+	id          string
+	name        string
+	readingType string // Sensor.ReadingType
+	units       string
+	context     string // PhysicalContext
+	reading     float64
+	state       string // Status.State; only Enabled sensors are rated
+	limits      limits
+}
+
+func (c chassisSensor) status() Status {
+	if c.state != "Enabled" {
+		return Status{State: c.state, Health: "OK"}
+	}
+	return Status{State: c.state, Health: c.limits.health(c.reading)}
+}
+
+func (c chassisSensor) sensor() Sensor {
+	sensor := Sensor{
+		ODataType:       "#Sensor.v1_2_0.Sensor",
+		ODataID:         chassisSensorsPath + "/" + c.id,
+		ID:              c.id,
+		Name:            c.name,
+		ReadingType:     c.readingType,
+		ReadingUnits:    c.units,
+		PhysicalContext: c.context,
+		Thresholds:      c.limits.thresholds(),
+		Status:          c.status(),
+	}
+	if c.state == "Enabled" {
+		reading := c.reading
+		sensor.Reading = &reading
+	}
+	return sensor
+}
+
+// chassisSensors lists the sensors of the chassis in env: temperatures,
+// fans, then power and CPU utilization.
+func chassisSensors(env machine.Environment) []chassisSensor {
+	powered := "Enabled"
+	if !env.Powered() {
+		powered = "StandbyOffline"
+	}
+	sensors := []chassisSensor{
+		{id: "InletTemp", name: "Inlet Temp", readingType: "Temperature", units: "Cel", context: "Intake",
+			reading: round1(env.InletCelsius), state: "Enabled", limits: inletLimits},
+		{id: "CPUTemp", name: "CPU Temp", readingType: "Temperature", units: "Cel", context: "CPU",
+			reading: round1(env.CPUCelsius), state: "Enabled", limits: cpuLimits},
+		{id: "BoardTemp", name: "System Board Temp", readingType: "Temperature", units: "Cel", context: "SystemBoard",
+			reading: round1(env.BoardCelsius), state: "Enabled", limits: boardLimits},
+	}
+	for i, rpm := range env.FanRPMs {
+		n := strconv.Itoa(i + 1)
+		sensors = append(sensors, chassisSensor{id: "Fan" + n, name: "System Fan " + n, readingType: "Rotational",
+			units: "RPM", context: "Fan", reading: float64(rpm), state: powered, limits: fanLimits})
+	}
+
+	// Without KVM statistics the load of a running VM is unknown
+	load := powered
+	if env.Powered() && !env.LoadMeasured && env.PowerState != machine.PowerPaused {
+		load = "UnavailableOffline"
+	}
+	return append(sensors,
+		chassisSensor{id: "TotalPower", name: "Total Power", readingType: "Power", units: "W", context: "Chassis",
+			reading: round1(env.PowerWatts), state: "Enabled"},
+		chassisSensor{id: "CPUUtilization", name: "CPU Utilization", readingType: "Percent", units: "%", context: "CPU",
+			reading: round1(env.CPULoad * 100), state: load},
+	)
+}
+
+// worstHealth is the rolled up health of sensors.
+func worstHealth(sensors []chassisSensor) string {
+	worst := 0
+	for _, c := range sensors {
+		worst = max(worst, slices.Index(healthOrder, c.status().Health))
+	}
+	return healthOrder[worst]
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// environment returns the simulated chassis environment, writing an error
+// response if the machine state cannot be read.
+func (s *Server) environment(w http.ResponseWriter) (machine.Environment, bool) {
+	env, err := s.machine.GetEnvironment()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return machine.Environment{}, false
+	}
+	return env, true
+}
+
+func (s *Server) handleGetThermal(w http.ResponseWriter, r *http.Request) {
+	env, ok := s.environment(w)
+	if !ok {
+		return
+	}
+	sensors := chassisSensors(env)
+
+	thermal := Thermal{
+		ODataType:    "#Thermal.v1_7_0.Thermal",
+		ODataID:      chassisPath + "/Thermal",
+		ID:           "Thermal",
+		Name:         "Thermal",
+		Status:       Status{State: "Enabled", Health: worstHealth(sensors)},
+		Temperatures: []Temperature{},
+		Fans:         []Fan{},
+	}
+	for i, c := range sensors {
+		switch c.readingType {
+		case "Temperature":
+			id := strconv.Itoa(len(thermal.Temperatures))
+			thermal.Temperatures = append(thermal.Temperatures, Temperature{
+				ODataID:                   thermal.ODataID + "#/Temperatures/" + id,
+				MemberID:                  id,
+				Name:                      c.name,
+				SensorNumber:              i + 1,
+				ReadingCelsius:            c.reading,
+				UpperThresholdNonCritical: optionalLimit(c.limits.upperCaution),
+				UpperThresholdCritical:    optionalLimit(c.limits.upperCritical),
+				UpperThresholdFatal:       optionalLimit(c.limits.upperFatal),
+				PhysicalContext:           c.context,
+				Status:                    c.status(),
+			})
+		case "Rotational":
+			id := strconv.Itoa(len(thermal.Fans))
+			thermal.Fans = append(thermal.Fans, Fan{
+				ODataID:                   thermal.ODataID + "#/Fans/" + id,
+				MemberID:                  id,
+				Name:                      c.name,
+				SensorNumber:              i + 1,
+				Reading:                   c.reading,
+				ReadingUnits:              c.units,
+				LowerThresholdNonCritical: optionalLimit(c.limits.lowerCaution),
+				LowerThresholdCritical:    optionalLimit(c.limits.lowerCritical),
+				PhysicalContext:           c.context,
+				Status:                    c.status(),
+			})
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(thermal)
+}
+
+func (s *Server) handleGetPower(w http.ResponseWriter, r *http.Request) {
+	env, ok := s.environment(w)
+	if !ok {
+		return
+	}
+	power := Power{
+		ODataType: "#Power.v1_5_0.Power",
+		ODataID:   chassisPath + "/Power",
+		ID:        "Power",
+		Name:      "Power",
+		PowerControl: []PowerControl{{
+			ODataID:            chassisPath + "/Power#/PowerControl/0",
+			MemberID:           "0",
+			Name:               "System Power Control",
+			PowerConsumedWatts: round1(env.PowerWatts),
+			PowerCapacityWatts: chassisPowerCapacityWatts,
+			PhysicalContext:    "Chassis",
+			Status:             Status{State: "Enabled", Health: "OK"},
+		}},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(power)
+}
+
+func (s *Server) handleSensorCollection(w http.ResponseWriter, r *http.Request) {
+	env, ok := s.environment(w)
+	if !ok {
+		return
+	}
+	members := []ODataID{}
+	for _, c := range chassisSensors(env) {
+		members = append(members, ODataID{ODataID: chassisSensorsPath + "/" + c.id})
+	}
+	col := SensorCollection{
+		ODataType:    "#SensorCollection.SensorCollection",
+		ODataID:      chassisSensorsPath,
+		Name:         "Sensors Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetSensor(w http.ResponseWriter, r *http.Request) {
+	env, ok := s.environment(w)
+	if !ok {
+		return
+	}
+	id := mux.Vars(r)["sensor"]
+	for _, c := range chassisSensors(env) {
+		if c.id == id {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(c.sensor())
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "ResourceNotFound", "Sensor not found")
+}
@@ -0,0 +1,171 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func newSensorTestServer(status qmp.Status, env machine.Environment) *Server {
+	mock := newMockMachine(status)
+	mock.environment = env
+	return newTestServer(mock)
+}
+
+// hotEnvironment is a VM under full load in a warm room
+var hotEnvironment = machine.Environment{
+	VCPUs:        4,
+	CPULoad:      0.9,
+	LoadMeasured: true,
+	InletCelsius: 36,
+	CPUCelsius:   94,
+	BoardCelsius: 50,
+	FanRPMs:      []int{12000, 900},
+	PowerWatts:   95.04,
+}
+
+func TestGetChassis_Telemetry(t *testing.T) {
+	srv := newSensorTestServer(qmp.StatusRunning, hotEnvironment)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Chassis/1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var chassis Chassis
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &chassis))
+	assert.Equal(t, "/redfish/v1/Chassis/1/Power", chassis.Power.ODataID)
+	assert.Equal(t, "/redfish/v1/Chassis/1/Thermal", chassis.Thermal.ODataID)
+	assert.Equal(t, "/redfish/v1/Chassis/1/Sensors", chassis.Sensors.ODataID)
+	assert.Equal(t, "On", chassis.PowerState)
+	require.NotNil(t, chassis.Status)
+	assert.Equal(t, Status{State: "Enabled", Health: "Critical"}, *chassis.Status)
+}
+
+func TestGetChassis_Suspended(t *testing.T) {
+	srv := newSensorTestServer(qmp.StatusSuspended, machine.Environment{
+		InletCelsius: 22, CPUCelsius: 22, BoardCelsius: 24, FanRPMs: []int{0, 0}, PowerWatts: 10,
+	})
+
+	w := doRequest(srv, "GET", "/redfish/v1/Chassis/1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var chassis Chassis
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &chassis))
+	assert.Equal(t, "On", chassis.PowerState)
+	require.NotNil(t, chassis.Status)
+	assert.Equal(t, "StandbyOffline", chassis.Status.State)
+}
+
+func TestGetThermal(t *testing.T) {
+	srv := newSensorTestServer(qmp.StatusRunning, hotEnvironment)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Chassis/1/Thermal", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var thermal Thermal
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &thermal))
+	assert.Equal(t, "Critical", thermal.Status.Health)
+
+	require.Len(t, thermal.Temperatures, 3)
+	inlet := thermal.Temperatures[0]
+	assert.Equal(t, "Inlet Temp", inlet.Name)
+	assert.Equal(t, 36.0, inlet.ReadingCelsius)
+	assert.Equal(t, "Warning", inlet.Status.Health)
+	cpu := thermal.Temperatures[1]
+	assert.Equal(t, "/redfish/v1/Chassis/1/Thermal#/Temperatures/1", cpu.ODataID)
+	assert.Equal(t, "Critical", cpu.Status.Health)
+	require.NotNil(t, cpu.UpperThresholdCritical)
+	assert.Equal(t, 90.0, *cpu.UpperThresholdCritical)
+	board := thermal.Temperatures[2]
+	assert.Equal(t, "OK", board.Status.Health)
+	assert.Nil(t, board.UpperThresholdFatal)
+
+	require.Len(t, thermal.Fans, 2)
+	assert.Equal(t, 12000.0, thermal.Fans[0].Reading)
+	assert.Equal(t, "RPM", thermal.Fans[0].ReadingUnits)
+	assert.Equal(t, "OK", thermal.Fans[0].Status.Health)
+	assert.Equal(t, "Critical", thermal.Fans[1].Status.Health)
+	assert.Equal(t, 5, thermal.Fans[1].SensorNumber)
+}
+
+func TestGetThermal_Off(t *testing.T) {
+	srv := newSensorTestServer(qmp.StatusShutdown, machine.Environment{
+		InletCelsius: 22, CPUCelsius: 22, BoardCelsius: 24, FanRPMs: []int{0, 0}, PowerWatts: 10,
+	})
+
+	w := doRequest(srv, "GET", "/redfish/v1/Chassis/1/Thermal", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var thermal Thermal
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &thermal))
+	assert.Equal(t, "OK", thermal.Status.Health)
+	require.Len(t, thermal.Fans, 2)
+	// Stopped fans are not below their thresholds
+	assert.Equal(t, Status{State: "StandbyOffline", Health: "OK"}, thermal.Fans[0].Status)
+}
+
+func TestGetPower(t *testing.T) {
+	srv := newSensorTestServer(qmp.StatusRunning, hotEnvironment)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Chassis/1/Power", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var power Power
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &power))
+	require.Len(t, power.PowerControl, 1)
+	assert.Equal(t, 95.0, power.PowerControl[0].PowerConsumedWatts)
+	assert.Equal(t, float64(chassisPowerCapacityWatts), power.PowerControl[0].PowerCapacityWatts)
+}
+
+func TestSensorCollection(t *testing.T) {
+	srv := newSensorTestServer(qmp.StatusRunning, hotEnvironment)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Chassis/1/Sensors", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var col SensorCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	assert.Equal(t, 7, col.MembersCount)
+	assert.Contains(t, col.Members, ODataID{ODataID: "/redfish/v1/Chassis/1/Sensors/CPUTemp"})
+	assert.Contains(t, col.Members, ODataID{ODataID: "/redfish/v1/Chassis/1/Sensors/TotalPower"})
+}
+
+func TestGetSensor(t *testing.T) {
+	srv := newSensorTestServer(qmp.StatusRunning, hotEnvironment)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Chassis/1/Sensors/CPUTemp", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var sensor Sensor
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sensor))
+	assert.Equal(t, "Temperature", sensor.ReadingType)
+	assert.Equal(t, "Cel", sensor.ReadingUnits)
+	require.NotNil(t, sensor.Reading)
+	assert.Equal(t, 94.0, *sensor.Reading)
+	require.NotNil(t, sensor.Thresholds)
+	assert.Equal(t, &Threshold{Reading: 80}, sensor.Thresholds.UpperCaution)
+	assert.Equal(t, &Threshold{Reading: 100}, sensor.Thresholds.UpperFatal)
+	assert.Nil(t, sensor.Thresholds.LowerCaution)
+	assert.Equal(t, "Critical", sensor.Status.Health)
+
+	w = doRequest(srv, "GET", "/redfish/v1/Chassis/1/Sensors/CPUUtilization", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var load Sensor
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &load))
+	require.NotNil(t, load.Reading)
+	assert.Equal(t, 90.0, *load.Reading)
+	assert.Nil(t, load.Thresholds)
+
+	w = doRequest(srv, "GET", "/redfish/v1/Chassis/1/Sensors/Nope", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetSensor_LoadUnmeasured(t *testing.T) {
+	srv := newSensorTestServer(qmp.StatusRunning, machine.Environment{
+		InletCelsius: 22, CPUCelsius: 35, BoardCelsius: 27, FanRPMs: []int{2000, 2000}, PowerWatts: 58,
+	})
+
+	w := doRequest(srv, "GET", "/redfish/v1/Chassis/1/Sensors/CPUUtilization", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"Reading":null`)
+	var sensor Sensor
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sensor))
+	assert.Equal(t, "UnavailableOffline", sensor.Status.State)
+}
@@ -15,6 +15,14 @@ func (s *Server) handleServiceRoot(w http.ResponseWriter, r *http.Request) {
 		Systems:        ODataID{ODataID: "/redfish/v1/Systems"},
 		Managers:       ODataID{ODataID: "/redfish/v1/Managers"},
 		Chassis:        ODataID{ODataID: "/redfish/v1/Chassis"},
+		SessionService: ODataID{ODataID: "/redfish/v1/SessionService"},
+		AccountService: ODataID{ODataID: "/redfish/v1/AccountService"},
+		EventService:   ODataID{ODataID: "/redfish/v1/EventService"},
+		TaskService:    ODataID{ODataID: "/redfish/v1/TaskService"},
+		Registries:     ODataID{ODataID: registriesPath},
+		Links: ServiceRootLinks{
+			Sessions: ODataID{ODataID: "/redfish/v1/SessionService/Sessions"},
+		},
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,192 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const sessionsPath = "/redfish/v1/SessionService/Sessions"
+
+func (s *Server) handleGetSessionService(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.sessionService())
+}
+
+func (s *Server) handlePatchSessionService(w http.ResponseWriter, r *http.Request) {
+	var req PatchSessionServiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedJSON", "Invalid request body")
+		return
+	}
+
+	if req.SessionTimeout != nil {
+		if *req.SessionTimeout < minSessionTimeout || *req.SessionTimeout > maxSessionTimeout {
+			writeError(w, http.StatusBadRequest, "PropertyValueOutOfRange",
+				"SessionTimeout must be between 30 and 86400 seconds")
+			return
+		}
+		s.sessions.SetTimeout(time.Duration(*req.SessionTimeout) * time.Second)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.sessionService())
+}
+
+func (s *Server) sessionService() SessionService {
+	return SessionService{
+		ODataType:      "#SessionService.v1_1_8.SessionService",
+		ODataID:        "/redfish/v1/SessionService",
+		ID:             "SessionService",
+		Name:           "Session Service",
+		ServiceEnabled: true,
+		SessionTimeout: int(s.sessions.Timeout() / time.Second),
+		Sessions:       ODataID{ODataID: sessionsPath},
+	}
+}
+
+func (s *Server) handleSessionCollection(w http.ResponseWriter, r *http.Request) {
+	ids := s.sessions.List()
+	members := make([]ODataID, len(ids))
+	for i, id := range ids {
+		members[i] = ODataID{ODataID: sessionsPath + "/" + id}
+	}
+	col := SessionCollection{
+		ODataType:    "#SessionCollection.SessionCollection",
+		ODataID:      sessionsPath,
+		Name:         "Session Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	var req CreateSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedJSON", "Invalid request body")
+		return
+	}
+	auditUser(r, req.UserName)
+	if req.UserName == "" || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "PropertyMissing", "UserName and Password are required")
+		return
+	}
+	if !s.checkCredentials(req.UserName, req.Password, remoteHost(r)) {
+		writeError(w, http.StatusUnauthorized, "NoValidSession", "Invalid username or password")
+		return
+	}
+	if s.authEnabled() && !slices.Contains(s.userRole(req.UserName).Privileges, privLogin) {
+		writeInsufficientPrivilege(w)
+		return
+	}
+
+	userID, _ := s.bmcState.LookupUserByName(req.UserName)
+	sess, err := s.sessions.Create(req.UserName, userID, s.bmcState.UserGeneration(userID))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	location := sessionsPath + "/" + sess.ID
+	w.Header().Set("X-Auth-Token", sess.Token)
+	w.Header().Set("Location", location)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(sessionResource(sess))
+}
+
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.sessions.Get(mux.Vars(r)["sid"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Session not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sessionResource(sess))
+}
+
+func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["sid"]
+	sess, ok := s.sessions.Get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Session not found")
+		return
+	}
+	// Logging out is always allowed; ending other users' sessions is not
+	if caller, _ := callerName(r); sess.UserName != caller && !s.hasPrivilege(r, privConfigureManager) {
+		writeInsufficientPrivilege(w)
+		return
+	}
+	if !s.sessions.Delete(id) {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Session not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func sessionResource(sess session) Session {
+	return Session{
+		ODataType:   "#Session.v1_3_0.Session",
+		ODataID:     sessionsPath + "/" + sess.ID,
+		ID:          sess.ID,
+		Name:        "User Session",
+		UserName:    sess.UserName,
+		CreatedTime: sess.Created.UTC().Format(time.RFC3339),
+	}
+}
+
+// checkCredentials validates a username and password against the shared BMC
+// user table, honouring account enablement and authentication lockout. Any
+// credentials are accepted when authentication is disabled.
+func (s *Server) checkCredentials(user, pass, source string) bool {
+	if !s.authEnabled() {
+		return true
+	}
+	if _, locked := s.bmcState.AuthLockedOut(user, source); locked {
+		return false
+	}
+	userID, ok := s.bmcState.LookupUserByName(user)
+	if ok {
+		access, _ := s.bmcState.GetUserAccess(0, userID)
+		ok = access.Enabled && s.bmcState.CheckPassword(userID, pass)
+	}
+	if !ok {
+		s.bmcState.RecordAuthFailure(user, source)
+		return false
+	}
+	s.bmcState.RecordAuthSuccess(user, source)
+	return true
+}
+
+// sessionCurrent reports whether the user slot sess logged in to is still
+// enabled and unchanged. Sessions of a user renamed, deleted, disabled or
+// given a new password or privilege, over Redfish or IPMI, are no longer
+// valid.
+func (s *Server) sessionCurrent(sess session) bool {
+	if s.bmcState.UserGeneration(sess.UserID) != sess.Generation {
+		return false
+	}
+	name, _ := s.bmcState.GetUserName(sess.UserID)
+	access, _ := s.bmcState.GetUserAccess(0, sess.UserID)
+	return name == sess.UserName && access.Enabled
+}
+
+// authEnabled reports whether requests must be authenticated.
+func (s *Server) authEnabled() bool {
+	return s.user != "" && s.pass != ""
+}
+
+// remoteHost returns the host part of the request's remote address.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,128 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+// login creates a session and returns its token and location.
+func login(t *testing.T, srv *Server, user, pass string) (string, string) {
+	t.Helper()
+	body := `{"UserName":"` + user + `","Password":"` + pass + `"}`
+	w := doRequest(srv, "POST", "/redfish/v1/SessionService/Sessions", body)
+	require.Equal(t, http.StatusCreated, w.Code)
+	return w.Header().Get("X-Auth-Token"), w.Header().Get("Location")
+}
+
+func TestCreateSession(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	srv := NewServer(mock, bmc.NewState("admin", "password"), "admin", "password", "")
+
+	t.Run("valid credentials return token and location", func(t *testing.T) {
+		w := doRequest(srv, "POST", "/redfish/v1/SessionService/Sessions", `{"UserName":"admin","Password":"password"}`)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.NotEmpty(t, w.Header().Get("X-Auth-Token"))
+		location := w.Header().Get("Location")
+		assert.True(t, strings.HasPrefix(location, "/redfish/v1/SessionService/Sessions/"))
+
+		var sess Session
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sess))
+		assert.Equal(t, location, sess.ODataID)
+		assert.Equal(t, "admin", sess.UserName)
+		assert.NotContains(t, w.Body.String(), "password")
+	})
+
+	t.Run("wrong password returns 401", func(t *testing.T) {
+		w := doRequest(srv, "POST", "/redfish/v1/SessionService/Sessions", `{"UserName":"admin","Password":"wrong"}`)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, w.Header().Get("X-Auth-Token"))
+	})
+}
+
+func TestSessionTokenAuth(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	srv := NewServer(mock, bmc.NewState("admin", "password"), "admin", "password", "")
+	token, location := login(t, srv, "admin", "password")
+
+	req := httptest.NewRequest("GET", "/redfish/v1/Systems/1", nil)
+	req.Header.Set("X-Auth-Token", token)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The session is listed in the collection
+	req = httptest.NewRequest("GET", "/redfish/v1/SessionService/Sessions", nil)
+	req.Header.Set("X-Auth-Token", token)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	var col SessionCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	require.Equal(t, 1, col.MembersCount)
+	assert.Equal(t, location, col.Members[0].ODataID)
+
+	// Logout
+	req = httptest.NewRequest("DELETE", location, nil)
+	req.Header.Set("X-Auth-Token", token)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	// The token is no longer valid
+	req = httptest.NewRequest("GET", "/redfish/v1/Systems/1", nil)
+	req.Header.Set("X-Auth-Token", token)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	srv := NewServer(mock, bmc.NewState("admin", "password"), "admin", "password", "")
+	now := time.Now()
+	srv.sessions.now = func() time.Time { return now }
+	srv.SetSessionTimeout(time.Minute)
+
+	token, _ := login(t, srv, "admin", "password")
+
+	get := func() int {
+		req := httptest.NewRequest("GET", "/redfish/v1", nil)
+		req.Header.Set("X-Auth-Token", token)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	now = now.Add(50 * time.Second)
+	assert.Equal(t, http.StatusOK, get(), "use refreshes the idle timer")
+	now = now.Add(50 * time.Second)
+	assert.Equal(t, http.StatusOK, get())
+	now = now.Add(61 * time.Second)
+	assert.Equal(t, http.StatusUnauthorized, get(), "session expired")
+}
+
+func TestSessionService(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, "PATCH", "/redfish/v1/SessionService", `{"SessionTimeout":600}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var svc SessionService
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &svc))
+	assert.Equal(t, 600, svc.SessionTimeout)
+	assert.Equal(t, "/redfish/v1/SessionService/Sessions", svc.Sessions.ODataID)
+
+	w = doRequest(srv, "PATCH", "/redfish/v1/SessionService", `{"SessionTimeout":5}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
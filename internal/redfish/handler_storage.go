@@ -0,0 +1,249 @@
+package redfish
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+)
+
+const (
+	storagePath = "/redfish/v1/Systems/1/Storage"
+	drivesPath  = storagePath + "/1/Drives"
+	volumesPath = storagePath + "/1/Volumes"
+)
+
+// drives returns the VM's block devices. A powered-off VM has none; other
+// errors are reported to the caller.
+func (s *Server) drives() ([]machine.Drive, error) {
+	drives, err := s.machine.GetDrives()
+	if errors.Is(err, machine.ErrNotRunning) {
+		return nil, nil
+	}
+	return drives, err
+}
+
+// isVolume reports whether a drive is presented as a volume: fixed disks
+// with an image attached.
+func isVolume(d machine.Drive) bool {
+	return d.Inserted && !d.Removable
+}
+
+func findDrive(drives []machine.Drive, id string) (machine.Drive, bool) {
+	for _, d := range drives {
+		if d.ID == id {
+			return d, true
+		}
+	}
+	return machine.Drive{}, false
+}
+
+func (s *Server) handleStorageCollection(w http.ResponseWriter, r *http.Request) {
+	col := StorageCollection{
+		ODataType:    "#StorageCollection.StorageCollection",
+		ODataID:      storagePath,
+		Name:         "Storage Collection",
+		MembersCount: 1,
+		Members:      []ODataID{{ODataID: storagePath + "/1"}},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetStorage(w http.ResponseWriter, r *http.Request) {
+	drives, err := s.drives()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	members := make([]ODataID, len(drives))
+	for i, d := range drives {
+		members[i] = ODataID{ODataID: drivesPath + "/" + d.ID}
+	}
+	storage := Storage{
+		ODataType:   "#Storage.v1_7_0.Storage",
+		ODataID:     storagePath + "/1",
+		ID:          "1",
+		Name:        "QEMU Block Storage",
+		Drives:      members,
+		DrivesCount: len(members),
+		Volumes:     ODataID{ODataID: volumesPath},
+		Status:      s.currentInventoryStatus(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(storage)
+}
+
+func (s *Server) handleGetDrive(w http.ResponseWriter, r *http.Request) {
+	drives, err := s.drives()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	d, ok := findDrive(drives, mux.Vars(r)["drive"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Drive not found")
+		return
+	}
+
+	drive := Drive{
+		ODataType:     "#Drive.v1_7_0.Drive",
+		ODataID:       drivesPath + "/" + d.ID,
+		ID:            d.ID,
+		Name:          d.ID,
+		CapacityBytes: d.CapacityBytes,
+		Status:        Status{State: "Enabled", Health: "OK"},
+		Links:         DriveLinks{Volumes: []ODataID{}},
+		Oem:           storageOem(d),
+	}
+	if !d.Removable {
+		drive.MediaType = "HDD"
+	}
+	if !d.Inserted {
+		drive.Status = Status{State: "Absent"}
+	}
+	if isVolume(d) {
+		drive.Links.Volumes = append(drive.Links.Volumes, ODataID{ODataID: volumesPath + "/" + d.ID})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(drive)
+}
+
+func (s *Server) handleVolumeCollection(w http.ResponseWriter, r *http.Request) {
+	drives, err := s.drives()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+
+	members := []ODataID{}
+	for _, d := range drives {
+		if isVolume(d) {
+			members = append(members, ODataID{ODataID: volumesPath + "/" + d.ID})
+		}
+	}
+	col := VolumeCollection{
+		ODataType:    "#VolumeCollection.VolumeCollection",
+		ODataID:      volumesPath,
+		Name:         "Volume Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetVolume(w http.ResponseWriter, r *http.Request) {
+	drives, err := s.drives()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return
+	}
+	d, ok := findDrive(drives, mux.Vars(r)["volume"])
+	if !ok || !isVolume(d) {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Volume not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(volumeResource(d))
+}
+
+// handleCreateVolume creates a qcow2 image in the volume directory and
+// hot-plugs it into the VM.
+func (s *Server) handleCreateVolume(w http.ResponseWriter, r *http.Request) {
+	var req CreateVolumeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedJSON", "invalid request body")
+		return
+	}
+	if req.Name == "" || req.CapacityBytes <= 0 {
+		writeError(w, http.StatusBadRequest, "PropertyMissing", "Name and CapacityBytes are required")
+		return
+	}
+
+	var id string
+	op := func(progress func(int)) error {
+		var err error
+		id, err = s.machine.CreateVolume(req.Name, req.CapacityBytes)
+		return err
+	}
+	created := func() string { return volumesPath + "/" + id }
+	s.runAsCreateTask(w, "Create volume "+req.Name, op, created, writeVolumeError, func() {
+		vol := Volume{ODataID: volumesPath + "/" + id}
+		if drives, err := s.drives(); err == nil {
+			if d, ok := findDrive(drives, id); ok {
+				vol = volumeResource(d)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", vol.ODataID)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(vol)
+	})
+}
+
+// handleDeleteVolume unplugs and deletes a volume created through Redfish.
+// Disks given on the QEMU command line cannot be deleted.
+func (s *Server) handleDeleteVolume(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["volume"]
+	op := func(progress func(int)) error {
+		return s.machine.DeleteVolume(id)
+	}
+	s.runAsTask(w, "Delete volume "+id, http.StatusNoContent, op, writeVolumeError, func() {
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+func writeVolumeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, machine.ErrVolumesDisabled):
+		writeError(w, http.StatusNotImplemented, "ActionNotSupported", err.Error())
+	case errors.Is(err, machine.ErrInvalidVolume):
+		writeError(w, http.StatusBadRequest, "PropertyValueError", err.Error())
+	case errors.Is(err, machine.ErrVolumeExists):
+		writeError(w, http.StatusConflict, "ResourceAlreadyExists", err.Error())
+	case errors.Is(err, machine.ErrVolumeNotFound):
+		writeError(w, http.StatusNotFound, "ResourceNotFound", err.Error())
+	case errors.Is(err, machine.ErrNotRunning):
+		writeError(w, http.StatusConflict, "ResourceInStandby", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+	}
+}
+
+func volumeResource(d machine.Drive) Volume {
+	access := []string{"Read"}
+	if !d.ReadOnly {
+		access = append(access, "Write")
+	}
+	return Volume{
+		ODataType:          "#Volume.v1_4_0.Volume",
+		ODataID:            volumesPath + "/" + d.ID,
+		ID:                 d.ID,
+		Name:               d.ID,
+		CapacityBytes:      d.CapacityBytes,
+		VolumeType:         "RawDevice",
+		AccessCapabilities: access,
+		Status:             Status{State: "Enabled", Health: "OK"},
+		Links:              VolumeLinks{Drives: []ODataID{{ODataID: drivesPath + "/" + d.ID}}},
+		Oem:                storageOem(d),
+	}
+}
+
+func storageOem(d machine.Drive) *StorageOem {
+	return &StorageOem{QemuBmc: StorageOemQemuBmc{
+		File:            d.File,
+		Format:          d.Format,
+		BackingFile:     d.BackingFile,
+		ReadOnly:        d.ReadOnly,
+		Removable:       d.Removable,
+		ReadBytes:       d.ReadBytes,
+		WriteBytes:      d.WriteBytes,
+		ReadOperations:  d.ReadOperations,
+		WriteOperations: d.WriteOperations,
+	}}
+}
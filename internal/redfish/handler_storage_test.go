@@ -0,0 +1,183 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func newStorageTestServer(status qmp.Status) (*Server, *mockMachine) {
+	mock := newMockMachine(status)
+	mock.drives = []machine.Drive{
+		{
+			ID: "disk0", File: "/vm/disk.qcow2", Format: "qcow2", BackingFile: "/vm/base.qcow2",
+			CapacityBytes: 10 << 30, Inserted: true, WriteBytes: 1024,
+		},
+		{ID: "ide0-cd0", Removable: true},
+	}
+	return newTestServer(mock), mock
+}
+
+func TestStorage(t *testing.T) {
+	srv, _ := newStorageTestServer(qmp.StatusRunning)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1/Storage", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var col StorageCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	require.Equal(t, 1, col.MembersCount)
+
+	w = doRequest(srv, "GET", col.Members[0].ODataID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var storage Storage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &storage))
+	assert.Equal(t, 2, storage.DrivesCount)
+	assert.Equal(t, "/redfish/v1/Systems/1/Storage/1/Drives/disk0", storage.Drives[0].ODataID)
+	assert.Equal(t, "/redfish/v1/Systems/1/Storage/1/Volumes", storage.Volumes.ODataID)
+}
+
+func TestStorage_PoweredOff(t *testing.T) {
+	srv, _ := newStorageTestServer(qmp.StatusShutdown)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1/Storage/1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var storage Storage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &storage))
+	assert.Equal(t, 0, storage.DrivesCount)
+	assert.Equal(t, "StandbyOffline", storage.Status.State)
+}
+
+func TestDrive(t *testing.T) {
+	srv, _ := newStorageTestServer(qmp.StatusRunning)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1/Storage/1/Drives/disk0", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var drive Drive
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &drive))
+	assert.Equal(t, int64(10<<30), drive.CapacityBytes)
+	assert.Equal(t, "HDD", drive.MediaType)
+	require.Len(t, drive.Links.Volumes, 1)
+	require.NotNil(t, drive.Oem)
+	assert.Equal(t, "qcow2", drive.Oem.QemuBmc.Format)
+	assert.Equal(t, "/vm/base.qcow2", drive.Oem.QemuBmc.BackingFile)
+	assert.Equal(t, int64(1024), drive.Oem.QemuBmc.WriteBytes)
+
+	w = doRequest(srv, "GET", "/redfish/v1/Systems/1/Storage/1/Drives/ide0-cd0", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &drive))
+	assert.Equal(t, "Absent", drive.Status.State)
+	assert.Empty(t, drive.Links.Volumes)
+
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", "/redfish/v1/Systems/1/Storage/1/Drives/nope", "").Code)
+}
+
+func TestVolumes(t *testing.T) {
+	srv, _ := newStorageTestServer(qmp.StatusRunning)
+
+	w := doRequest(srv, "GET", "/redfish/v1/Systems/1/Storage/1/Volumes", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var col VolumeCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	require.Equal(t, 1, col.MembersCount, "removable drives are not volumes")
+
+	w = doRequest(srv, "GET", col.Members[0].ODataID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var vol Volume
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &vol))
+	assert.Equal(t, int64(10<<30), vol.CapacityBytes)
+	assert.Equal(t, []string{"Read", "Write"}, vol.AccessCapabilities)
+	assert.Equal(t, "/vm/disk.qcow2", vol.Oem.QemuBmc.File)
+
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", "/redfish/v1/Systems/1/Storage/1/Volumes/ide0-cd0", "").Code)
+}
+
+func TestCreateDeleteVolume(t *testing.T) {
+	srv, mock := newStorageTestServer(qmp.StatusRunning)
+
+	req := httptest.NewRequest("POST", "/redfish/v1/Systems/1/Storage/1/Volumes",
+		strings.NewReader(`{"Name":"data","CapacityBytes":1073741824}`))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/redfish/v1/Systems/1/Storage/1/Volumes/rfvol-data", w.Header().Get("Location"))
+
+	var vol Volume
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &vol))
+	assert.Equal(t, int64(1<<30), vol.CapacityBytes)
+
+	req = httptest.NewRequest("DELETE", "/redfish/v1/Systems/1/Storage/1/Volumes/rfvol-data", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []string{"CreateVolume", "DeleteVolume"}, mock.Calls())
+
+	req = httptest.NewRequest("DELETE", "/redfish/v1/Systems/1/Storage/1/Volumes/disk0", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCreateVolume_Async(t *testing.T) {
+	srv, mock := newStorageTestServer(qmp.StatusRunning)
+	srv.taskWait = 10 * time.Millisecond
+	mock.volumeBlock = make(chan struct{})
+
+	req := httptest.NewRequest("POST", "/redfish/v1/Systems/1/Storage/1/Volumes",
+		strings.NewReader(`{"Name":"data","CapacityBytes":1073741824}`))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	monitor := w.Header().Get("Location")
+	var tk Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tk))
+	assert.Nil(t, tk.Payload)
+
+	close(mock.volumeBlock)
+	task, ok := srv.tasks.Get(tk.ID)
+	require.True(t, ok)
+	require.True(t, task.Wait(time.Second))
+
+	// The finished task points to the new volume
+	w = doRequest(srv, "GET", monitor, "")
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/redfish/v1/Systems/1/Storage/1/Volumes/rfvol-data", w.Header().Get("Location"))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tk))
+	require.NotNil(t, tk.Payload)
+	assert.Equal(t, []string{"Location: /redfish/v1/Systems/1/Storage/1/Volumes/rfvol-data"}, tk.Payload.HTTPHeaders)
+
+	w = doRequest(srv, "GET", tk.ODataID, "")
+	assert.Contains(t, w.Body.String(), `"HttpHeaders":["Location: /redfish/v1/Systems/1/Storage/1/Volumes/rfvol-data"]`)
+}
+
+func TestCreateVolume_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		err    error
+		status int
+	}{
+		{"missing size", `{"Name":"data"}`, nil, http.StatusBadRequest},
+		{"disabled", `{"Name":"data","CapacityBytes":1}`, machine.ErrVolumesDisabled, http.StatusNotImplemented},
+		{"exists", `{"Name":"data","CapacityBytes":1}`, machine.ErrVolumeExists, http.StatusConflict},
+		{"powered off", `{"Name":"data","CapacityBytes":1}`, machine.ErrNotRunning, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, mock := newStorageTestServer(qmp.StatusRunning)
+			mock.volumeErr = tt.err
+
+			req := httptest.NewRequest("POST", "/redfish/v1/Systems/1/Storage/1/Volumes", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
@@ -3,11 +3,13 @@ package redfish
 import (
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
 	"github.com/tjst-t/qemu-bmc/internal/machine"
-	"github.com/tjst-t/qemu-bmc/internal/qmp"
 )
 
 func (s *Server) handleSystemCollection(w http.ResponseWriter, r *http.Request) {
@@ -26,16 +28,17 @@ func (s *Server) handleSystemCollection(w http.ResponseWriter, r *http.Request)
 }
 
 func (s *Server) handleGetSystem(w http.ResponseWriter, r *http.Request) {
-	status, err := s.machine.GetQMPStatus()
+	ps, err := s.machine.GetPowerState()
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "InternalError", "failed to get system status")
 		return
 	}
 
-	powerState := mapQMPStatusToRedfish(status)
+	powerState := redfishPowerState(ps)
 	boot := s.machine.GetBootOverride()
 
-	etag := generateETag(powerState, boot)
+	bootOrder, bootOrderErr := s.machine.GetBootOrder()
+	etag := generateETag(string(ps), boot, bootOrder)
 
 	system := ComputerSystem{
 		ODataType: "#ComputerSystem.v1_5_0.ComputerSystem",
@@ -43,19 +46,47 @@ func (s *Server) handleGetSystem(w http.ResponseWriter, r *http.Request) {
 		ODataEtag: etag,
 		ID:        "1",
 		Name:      "QEMU Virtual Machine",
+		HostName:  s.bmcState.GetSystemInfoString(bmc.SysInfoSystemName),
 		PowerState: powerState,
+		Status:     systemStatus(ps),
 		Boot: BootSource{
 			BootSourceOverrideEnabled: boot.Enabled,
 			BootSourceOverrideTarget:  boot.Target,
 			BootSourceOverrideMode:    boot.Mode,
-			AllowableValues:           []string{"None", "Pxe", "Hdd", "Cd", "BiosSetup"},
+			AllowableValues: []string{
+				"None", "Pxe", "Hdd", "Cd", "Usb", "Floppy", "BiosSetup",
+				"UefiShell", "UefiHttp", "UefiTarget",
+			},
+			HttpBootUri:                  boot.HttpBootURI,
+			UefiTargetBootSourceOverride: boot.UefiTarget,
 		},
 		Actions: ComputerSystemActions{
 			Reset: ResetAction{
 				Target:          "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset",
-				AllowableValues: []string{"On", "ForceOff", "GracefulShutdown", "ForceRestart", "GracefulRestart"},
+				AllowableValues: resetTypes,
 			},
 		},
+		Oem:        s.systemOem(),
+		Processors: ODataID{ODataID: processorsPath},
+		Memory:     ODataID{ODataID: memoryPath},
+		Storage:    ODataID{ODataID: storagePath},
+
+		EthernetInterfaces: ODataID{ODataID: ethernetInterfacesPath},
+		Bios:               ODataID{ODataID: biosPath},
+		VirtualMedia:       ODataID{ODataID: systemVirtualMediaPath},
+		LogServices:        ODataID{ODataID: systemLogServicesPath},
+	}
+	if inv, err := s.machine.GetInventory(); err == nil {
+		status := inventoryStatus(ps)
+		system.ProcessorSummary = processorSummary(inv, status)
+		system.MemorySummary = &MemorySummary{
+			TotalSystemMemoryGiB: float64(inv.MemoryBytes) / (1 << 30),
+			Status:               status,
+		}
+	}
+	if bootOrderErr == nil {
+		system.Boot.BootOrder = bootOrder
+		system.Boot.BootOptions = &ODataID{ODataID: bootOptionsPath}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -68,14 +99,14 @@ func (s *Server) handlePatchSystem(w http.ResponseWriter, r *http.Request) {
 	ifMatch := r.Header.Get("If-Match")
 	if ifMatch != "" {
 		// Get current state to compute the current ETag
-		status, err := s.machine.GetQMPStatus()
+		ps, err := s.machine.GetPowerState()
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, "InternalError", "failed to get system status")
 			return
 		}
-		powerState := mapQMPStatusToRedfish(status)
 		boot := s.machine.GetBootOverride()
-		currentETag := generateETag(powerState, boot)
+		bootOrder, _ := s.machine.GetBootOrder()
+		currentETag := generateETag(string(ps), boot, bootOrder)
 
 		if ifMatch != currentETag {
 			writeError(w, http.StatusPreconditionFailed, "PreconditionFailed", "ETag mismatch")
@@ -106,29 +137,94 @@ func (s *Server) handlePatchSystem(w http.ResponseWriter, r *http.Request) {
 	if req.Boot.BootSourceOverrideMode != "" {
 		current.Mode = req.Boot.BootSourceOverrideMode
 	}
+	if req.Boot.HttpBootUri != nil {
+		current.HttpBootURI = *req.Boot.HttpBootUri
+	}
+	if req.Boot.UefiTargetBootSourceOverride != nil {
+		current.UefiTarget = *req.Boot.UefiTargetBootSourceOverride
+	}
+
+	// Validate everything before applying anything
+	if err := s.machine.ValidateBootOverride(current); err != nil {
+		writeError(w, http.StatusBadRequest, "PropertyValueError", err.Error())
+		return
+	}
+	if req.Boot.BootOrder != nil {
+		if err := s.machine.ValidateBootOrder(req.Boot.BootOrder); err != nil {
+			writeBootOrderError(w, err)
+			return
+		}
+	}
 
 	if err := s.machine.SetBootOverride(current); err != nil {
 		writeError(w, http.StatusBadRequest, "PropertyValueError", err.Error())
 		return
 	}
+	if req.Boot.BootOrder != nil {
+		if err := s.machine.SetBootOrder(req.Boot.BootOrder); err != nil {
+			writeBootOrderError(w, err)
+			return
+		}
+	}
 
 	// Return the updated system
 	s.handleGetSystem(w, r)
 }
 
-// mapQMPStatusToRedfish converts QMP status to Redfish PowerState
-func mapQMPStatusToRedfish(status qmp.Status) string {
-	switch status {
-	case qmp.StatusRunning:
-		return "On"
+func writeBootOrderError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, machine.ErrBootOptionNotFound):
+		writeError(w, http.StatusBadRequest, "PropertyValueNotInList", err.Error())
+	case errors.Is(err, machine.ErrNotSupported):
+		writeError(w, http.StatusBadRequest, "PropertyNotWritable", err.Error())
+	default:
+		writeError(w, http.StatusBadRequest, "PropertyValueError", err.Error())
+	}
+}
+
+// systemOem returns the guest-reported system info, or nil if the guest has
+// not published any.
+func (s *Server) systemOem() *ComputerSystemOem {
+	info := ComputerSystemOemQemuBmc{
+		FirmwareVersion: s.bmcState.GetSystemInfoString(bmc.SysInfoFirmwareVersion),
+		PrimaryOSName:   s.bmcState.GetSystemInfoString(bmc.SysInfoPrimaryOSName),
+		OSName:          s.bmcState.GetSystemInfoString(bmc.SysInfoOSName),
+		OSVersion:       s.bmcState.GetSystemInfoString(bmc.SysInfoOSVersion),
+	}
+	if info == (ComputerSystemOemQemuBmc{}) {
+		return nil
+	}
+	return &ComputerSystemOem{QemuBmc: info}
+}
+
+// redfishPowerState is the Redfish PowerState of ps. Redfish has no
+// suspended power state; a suspended system is On with a StandbyOffline
+// status.
+func redfishPowerState(ps machine.PowerState) string {
+	if ps == machine.PowerSuspended {
+		return string(machine.PowerOn)
+	}
+	return string(ps)
+}
+
+// systemStatus is the Status of the system in power state ps.
+func systemStatus(ps machine.PowerState) Status {
+	switch ps {
+	case machine.PowerOn:
+		return Status{State: "Enabled", Health: "OK"}
+	case machine.PowerPoweringOn:
+		return Status{State: "Starting", Health: "OK"}
+	case machine.PowerPaused:
+		return Status{State: "Quiesced", Health: "OK"}
 	default:
-		return "Off"
+		return Status{State: "StandbyOffline", Health: "OK"}
 	}
 }
 
 // generateETag creates an ETag based on the system state
-func generateETag(powerState string, boot machine.BootOverride) string {
-	data := fmt.Sprintf("%s-%s-%s-%s", powerState, boot.Enabled, boot.Target, boot.Mode)
+func generateETag(powerState string, boot machine.BootOverride, bootOrder []string) string {
+	data := fmt.Sprintf("%s-%s-%s-%s-%s-%s-%s", powerState, boot.Enabled, boot.Target, boot.Mode,
+		boot.HttpBootURI, boot.UefiTarget, strings.Join(bootOrder, ","))
 	hash := sha256.Sum256([]byte(data))
 	return fmt.Sprintf(`"%x"`, hash[:8])
 }
@@ -144,3 +240,24 @@ func writeError(w http.ResponseWriter, statusCode int, code, message string) {
 		},
 	})
 }
+
+// writeBaseError writes a Redfish error response carrying a message from
+// the Base message registry
+func writeBaseError(w http.ResponseWriter, statusCode int, messageID, message, resolution string) {
+	id := "Base.1.0." + messageID
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(RedfishError{
+		Error: RedfishErrorBody{
+			Code:    id,
+			Message: message,
+			ExtendedInfo: []MessageInfo{{
+				ODataType:  "#Message.v1_0_0.Message",
+				MessageID:  id,
+				Message:    message,
+				Severity:   "Critical",
+				Resolution: resolution,
+			}},
+		},
+	})
+}
@@ -9,13 +9,14 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
 	"github.com/tjst-t/qemu-bmc/internal/machine"
 	"github.com/tjst-t/qemu-bmc/internal/qmp"
 )
 
 func TestGetSystems(t *testing.T) {
 	mock := newMockMachine(qmp.StatusRunning)
-	srv := NewServer(mock, "", "", "")
+	srv := NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 
 	req := httptest.NewRequest("GET", "/redfish/v1/Systems", nil)
 	w := httptest.NewRecorder()
@@ -34,19 +35,23 @@ func TestGetSystems(t *testing.T) {
 
 func TestGetSystem_PowerState(t *testing.T) {
 	tests := []struct {
-		name           string
-		qmpStatus      qmp.Status
-		expectedPower  string
+		powerState         machine.PowerState
+		expectedPowerState string
+		expectedState      string
 	}{
-		{"running maps to On", qmp.StatusRunning, "On"},
-		{"shutdown maps to Off", qmp.StatusShutdown, "Off"},
-		{"paused maps to Off", qmp.StatusPaused, "Off"},
+		{machine.PowerOn, "On", "Enabled"},
+		{machine.PowerOff, "Off", "StandbyOffline"},
+		{machine.PowerPoweringOn, "PoweringOn", "Starting"},
+		{machine.PowerPoweringOff, "PoweringOff", "StandbyOffline"},
+		{machine.PowerPaused, "Paused", "Quiesced"},
+		{machine.PowerSuspended, "On", "StandbyOffline"},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			mock := newMockMachine(tt.qmpStatus)
-			srv := NewServer(mock, "", "", "")
+		t.Run(string(tt.powerState), func(t *testing.T) {
+			mock := newMockMachine(qmp.StatusRunning)
+			mock.powerState = tt.powerState
+			srv := NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 
 			req := httptest.NewRequest("GET", "/redfish/v1/Systems/1", nil)
 			w := httptest.NewRecorder()
@@ -58,14 +63,15 @@ func TestGetSystem_PowerState(t *testing.T) {
 			err := json.Unmarshal(w.Body.Bytes(), &system)
 			require.NoError(t, err)
 
-			assert.Equal(t, tt.expectedPower, system.PowerState)
+			assert.Equal(t, tt.expectedPowerState, system.PowerState)
+			assert.Equal(t, tt.expectedState, system.Status.State)
 		})
 	}
 }
 
 func TestGetSystem_ETag(t *testing.T) {
 	mock := newMockMachine(qmp.StatusRunning)
-	srv := NewServer(mock, "", "", "")
+	srv := NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 
 	req := httptest.NewRequest("GET", "/redfish/v1/Systems/1", nil)
 	w := httptest.NewRecorder()
@@ -91,7 +97,7 @@ func TestGetSystem_BootOverride(t *testing.T) {
 		Target:  "Pxe",
 		Mode:    "UEFI",
 	}
-	srv := NewServer(mock, "", "", "")
+	srv := NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 
 	req := httptest.NewRequest("GET", "/redfish/v1/Systems/1", nil)
 	w := httptest.NewRecorder()
@@ -109,12 +115,43 @@ func TestGetSystem_BootOverride(t *testing.T) {
 	assert.Contains(t, system.Boot.AllowableValues, "Pxe")
 	assert.Contains(t, system.Boot.AllowableValues, "Hdd")
 	assert.Contains(t, system.Boot.AllowableValues, "Cd")
+	assert.Contains(t, system.Actions.Reset.AllowableValues, "Nmi")
+	assert.Contains(t, system.Actions.Reset.AllowableValues, "PushPowerButton")
+}
+
+func TestGetSystem_GuestSystemInfo(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	state := bmc.NewState("admin", "password")
+	srv := NewServer(mock, state, "", "", "")
+
+	// Nothing published yet: no HostName and no Oem block
+	req := httptest.NewRequest("GET", "/redfish/v1/Systems/1", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+	assert.NotContains(t, raw, "HostName")
+	assert.NotContains(t, raw, "Oem")
+
+	// Guest publishes its hostname and OS through System Info Parameters
+	state.SetSystemInfoString(bmc.SysInfoSystemName, "node01")
+	state.SetSystemInfoString(bmc.SysInfoOSName, "Linux")
+	state.SetSystemInfoString(bmc.SysInfoOSVersion, "6.8.0-45-generic")
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/redfish/v1/Systems/1", nil))
+	var system ComputerSystem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &system))
+	assert.Equal(t, "node01", system.HostName)
+	require.NotNil(t, system.Oem)
+	assert.Equal(t, "Linux", system.Oem.QemuBmc.OSName)
+	assert.Equal(t, "6.8.0-45-generic", system.Oem.QemuBmc.OSVersion)
 }
 
 func TestPatchBootDevice(t *testing.T) {
 	t.Run("PXE Once returns 200", func(t *testing.T) {
 		mock := newMockMachine(qmp.StatusRunning)
-		srv := NewServer(mock, "", "", "")
+		srv := NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 
 		body := `{"Boot":{"BootSourceOverrideTarget":"Pxe","BootSourceOverrideEnabled":"Once"}}`
 		req := httptest.NewRequest("PATCH", "/redfish/v1/Systems/1", strings.NewReader(body))
@@ -138,7 +175,7 @@ func TestPatchBootDevice(t *testing.T) {
 
 	t.Run("ETag mismatch returns 412", func(t *testing.T) {
 		mock := newMockMachine(qmp.StatusRunning)
-		srv := NewServer(mock, "", "", "")
+		srv := NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 
 		body := `{"Boot":{"BootSourceOverrideTarget":"Pxe","BootSourceOverrideEnabled":"Once"}}`
 		req := httptest.NewRequest("PATCH", "/redfish/v1/Systems/1", strings.NewReader(body))
@@ -152,7 +189,7 @@ func TestPatchBootDevice(t *testing.T) {
 
 	t.Run("No ETag returns 200", func(t *testing.T) {
 		mock := newMockMachine(qmp.StatusRunning)
-		srv := NewServer(mock, "", "", "")
+		srv := NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 
 		body := `{"Boot":{"BootSourceOverrideTarget":"Hdd","BootSourceOverrideEnabled":"Continuous"}}`
 		req := httptest.NewRequest("PATCH", "/redfish/v1/Systems/1", strings.NewReader(body))
@@ -170,3 +207,25 @@ func TestPatchBootDevice(t *testing.T) {
 		assert.Equal(t, "Continuous", system.Boot.BootSourceOverrideEnabled)
 	})
 }
+
+func TestPatchSystem_HttpBootUri(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	srv := newTestServer(mock)
+
+	w := doRequest(srv, "PATCH", "/redfish/v1/Systems/1",
+		`{"Boot":{"BootSourceOverrideEnabled":"Once","BootSourceOverrideTarget":"UefiHttp","HttpBootUri":"http://192.0.2.1/boot.ipxe"}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var system ComputerSystem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &system))
+	assert.Equal(t, "UefiHttp", system.Boot.BootSourceOverrideTarget)
+	assert.Equal(t, "http://192.0.2.1/boot.ipxe", system.Boot.HttpBootUri)
+	assert.Contains(t, system.Boot.AllowableValues, "UefiTarget")
+
+	w = doRequest(srv, "PATCH", "/redfish/v1/Systems/1",
+		`{"Boot":{"BootSourceOverrideTarget":"UefiTarget","UefiTargetBootSourceOverride":"PciRoot(0x0)/Pci(0x3,0x0)"}}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	b := mock.GetBootOverride()
+	assert.Equal(t, "PciRoot(0x0)/Pci(0x3,0x0)", b.UefiTarget)
+	assert.Equal(t, "http://192.0.2.1/boot.ipxe", b.HttpBootURI)
+}
@@ -0,0 +1,162 @@
+package redfish
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	tasksPath        = "/redfish/v1/TaskService/Tasks"
+	taskMonitorsPath = "/redfish/v1/TaskService/TaskMonitors"
+)
+
+// errorWriter writes the error response for a failed operation.
+type errorWriter func(w http.ResponseWriter, err error)
+
+// runAsTask runs op as a task. If it finishes within the task wait, respond
+// writes the result as for a synchronous request, or writeErr the error;
+// otherwise the client gets 202 Accepted with a task monitor to poll, which
+// reports an error through writeErr as well.
+func (s *Server) runAsTask(w http.ResponseWriter, name string, successCode int, op taskOp, writeErr errorWriter, respond func()) {
+	s.awaitTask(w, s.tasks.Start(name, successCode, op, nil, writeErr), respond)
+}
+
+// runAsCreateTask is runAsTask for an op that creates the resource at the
+// URI created returns. The task monitor of a finished task points to it.
+func (s *Server) runAsCreateTask(w http.ResponseWriter, name string, op taskOp, created func() string, writeErr errorWriter, respond func()) {
+	s.awaitTask(w, s.tasks.Start(name, http.StatusCreated, op, created, writeErr), respond)
+}
+
+func (s *Server) awaitTask(w http.ResponseWriter, t *task, respond func()) {
+	if !t.Wait(s.taskWait) {
+		writeTaskAccepted(w, t)
+		return
+	}
+	if err := t.Err(); err != nil {
+		t.writeErr(w, err)
+		return
+	}
+	respond()
+}
+
+func writeTaskAccepted(w http.ResponseWriter, t *task) {
+	w.Header().Set("Location", taskMonitorsPath+"/"+t.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(taskResource(t))
+}
+
+func (s *Server) handleGetTaskService(w http.ResponseWriter, r *http.Request) {
+	svc := TaskService{
+		ODataType:                    "#TaskService.v1_1_4.TaskService",
+		ODataID:                      "/redfish/v1/TaskService",
+		ID:                           "TaskService",
+		Name:                         "Task Service",
+		ServiceEnabled:               true,
+		CompletedTaskOverWritePolicy: "Oldest",
+		Tasks:                        ODataID{ODataID: tasksPath},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(svc)
+}
+
+func (s *Server) handleTaskCollection(w http.ResponseWriter, r *http.Request) {
+	ids := s.tasks.List()
+	members := make([]ODataID, len(ids))
+	for i, id := range ids {
+		members[i] = ODataID{ODataID: tasksPath + "/" + id}
+	}
+	col := TaskCollection{
+		ODataType:    "#TaskCollection.TaskCollection",
+		ODataID:      tasksPath,
+		Name:         "Task Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+func (s *Server) handleGetTask(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.tasks.Get(mux.Vars(r)["tid"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Task not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(taskResource(t))
+}
+
+// handleTaskMonitor returns 202 while the task runs and the operation's
+// final response once it has finished.
+func (s *Server) handleTaskMonitor(w http.ResponseWriter, r *http.Request) {
+	t, ok := s.tasks.Get(mux.Vars(r)["tid"])
+	if !ok {
+		writeError(w, http.StatusNotFound, "ResourceNotFound", "Task not found")
+		return
+	}
+	if !t.finished() {
+		writeTaskAccepted(w, t)
+		return
+	}
+	if err := t.Err(); err != nil {
+		t.writeErr(w, err)
+		return
+	}
+	if t.successCode == http.StatusNoContent {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if location := t.Location(); location != "" {
+		w.Header().Set("Location", location)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(t.successCode)
+	json.NewEncoder(w).Encode(taskResource(t))
+}
+
+func taskResource(t *task) Task {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	res := Task{
+		ODataType:       "#Task.v1_4_3.Task",
+		ODataID:         tasksPath + "/" + t.ID,
+		ID:              t.ID,
+		Name:            t.Name,
+		TaskState:       t.state,
+		TaskStatus:      "OK",
+		PercentComplete: t.percent,
+		StartTime:       t.startTime.UTC().Format(time.RFC3339),
+		TaskMonitor:     taskMonitorsPath + "/" + t.ID,
+		Messages:        []MessageInfo{},
+	}
+	if !t.endTime.IsZero() {
+		res.EndTime = t.endTime.UTC().Format(time.RFC3339)
+	}
+	if t.location != "" {
+		res.Payload = &TaskPayload{HTTPHeaders: []string{"Location: " + t.location}}
+	}
+	switch t.state {
+	case taskStateCompleted:
+		res.Messages = append(res.Messages, MessageInfo{
+			ODataType: "#Message.v1_0_0.Message",
+			MessageID: "Base.1.0.Success",
+			Message:   "Successfully Completed Request",
+			Severity:  "OK",
+		})
+	case taskStateException:
+		res.TaskStatus = "Critical"
+		res.Messages = append(res.Messages, MessageInfo{
+			ODataType:  "#Message.v1_0_0.Message",
+			MessageID:  "Base.1.0.InternalError",
+			Message:    t.err.Error(),
+			Severity:   "Critical",
+			Resolution: "Resubmit the request. If the problem persists, check the BMC log.",
+		})
+	}
+	return res
+}
@@ -0,0 +1,171 @@
+package redfish
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func newTaskTestServer(mock *mockMachine) *Server {
+	srv := newTestServer(mock)
+	srv.taskWait = 10 * time.Millisecond
+	return srv
+}
+
+func postReset(srv *Server, resetType string) *httptest.ResponseRecorder {
+	return doRequest(srv, "POST", "/redfish/v1/Systems/1/Actions/ComputerSystem.Reset", `{"ResetType":"`+resetType+`"}`)
+}
+
+func TestTaskService(t *testing.T) {
+	srv := newTaskTestServer(newMockMachine(qmp.StatusRunning))
+
+	w := doRequest(srv, "GET", "/redfish/v1/TaskService", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var svc TaskService
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &svc))
+	assert.Equal(t, "#TaskService.v1_1_4.TaskService", svc.ODataType)
+	assert.True(t, svc.ServiceEnabled)
+	assert.Equal(t, "/redfish/v1/TaskService/Tasks", svc.Tasks.ODataID)
+}
+
+func TestResetAction_FastResetIsSynchronous(t *testing.T) {
+	srv := newTaskTestServer(newMockMachine(qmp.StatusRunning))
+
+	w := postReset(srv, "ForceOff")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Location"))
+}
+
+func TestResetAction_SlowResetReturnsTask(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	mock.resetBlock = make(chan struct{})
+	srv := newTaskTestServer(mock)
+
+	w := postReset(srv, "GracefulShutdown")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	monitor := w.Header().Get("Location")
+	assert.True(t, strings.HasPrefix(monitor, "/redfish/v1/TaskService/TaskMonitors/"))
+
+	var tk Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tk))
+	assert.Equal(t, "Running", tk.TaskState)
+	assert.Equal(t, monitor, tk.TaskMonitor)
+
+	// Still running: the monitor keeps answering 202
+	w = doRequest(srv, "GET", monitor, "")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	// The task is listed in the collection
+	w = doRequest(srv, "GET", "/redfish/v1/TaskService/Tasks", "")
+	var col TaskCollection
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+	require.Equal(t, 1, col.MembersCount)
+	assert.Equal(t, tk.ODataID, col.Members[0].ODataID)
+
+	close(mock.resetBlock)
+	task, ok := srv.tasks.Get(tk.ID)
+	require.True(t, ok)
+	require.True(t, task.Wait(time.Second))
+
+	w = doRequest(srv, "GET", monitor, "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []string{"GracefulShutdown"}, mock.Calls())
+
+	w = doRequest(srv, "GET", tk.ODataID, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tk))
+	assert.Equal(t, "Completed", tk.TaskState)
+	assert.Equal(t, "OK", tk.TaskStatus)
+	assert.Equal(t, 100, tk.PercentComplete)
+	assert.NotEmpty(t, tk.EndTime)
+}
+
+func TestResetAction_FailedTask(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	mock.resetBlock = make(chan struct{})
+	mock.resetErr = errors.New("guest did not shut down")
+	srv := newTaskTestServer(mock)
+
+	w := postReset(srv, "GracefulShutdown")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	monitor := w.Header().Get("Location")
+
+	close(mock.resetBlock)
+	id := monitor[strings.LastIndex(monitor, "/")+1:]
+	task, ok := srv.tasks.Get(id)
+	require.True(t, ok)
+	require.True(t, task.Wait(time.Second))
+
+	w = doRequest(srv, "GET", monitor, "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "guest did not shut down")
+
+	w = doRequest(srv, "GET", "/redfish/v1/TaskService/Tasks/"+id, "")
+	var tk Task
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tk))
+	assert.Equal(t, "Exception", tk.TaskState)
+	assert.Equal(t, "Critical", tk.TaskStatus)
+	require.Len(t, tk.Messages, 1)
+	assert.Equal(t, "Base.1.0.InternalError", tk.Messages[0].MessageID)
+}
+
+func TestTaskNotFound(t *testing.T) {
+	srv := newTaskTestServer(newMockMachine(qmp.StatusRunning))
+
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", "/redfish/v1/TaskService/Tasks/99", "").Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "GET", "/redfish/v1/TaskService/TaskMonitors/99", "").Code)
+}
+
+func TestTaskStore_PrunesOldestFinished(t *testing.T) {
+	ts := newTaskStore()
+	for i := 0; i < maxTasks+5; i++ {
+		tk := ts.Start("noop", http.StatusNoContent, func(func(int)) error { return nil }, nil, writeResetError)
+		require.True(t, tk.Wait(time.Second))
+	}
+	ids := ts.List()
+	assert.Len(t, ids, maxTasks)
+	_, ok := ts.Get("1")
+	assert.False(t, ok)
+}
+
+func TestResetAction_FailedTaskStatusMatchesSynchronous(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		code int
+	}{
+		"not running":    {machine.ErrNotRunning, http.StatusConflict},
+		"no guest agent": {machine.ErrGuestAgentUnavailable, http.StatusNotImplemented},
+		"other failure":  {errors.New("boom"), http.StatusInternalServerError},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mock := newMockMachine(qmp.StatusRunning)
+			mock.resetErr = tt.err
+			srv := newTaskTestServer(mock)
+			assert.Equal(t, tt.code, postReset(srv, "GracefulShutdown").Code, "synchronous")
+
+			mock.resetBlock = make(chan struct{})
+			w := postReset(srv, "GracefulShutdown")
+			require.Equal(t, http.StatusAccepted, w.Code)
+			monitor := w.Header().Get("Location")
+			close(mock.resetBlock)
+			task, ok := srv.tasks.Get(monitor[strings.LastIndex(monitor, "/")+1:])
+			require.True(t, ok)
+			require.True(t, task.Wait(time.Second))
+
+			w = doRequest(srv, "GET", monitor, "")
+			assert.Equal(t, tt.code, w.Code, "task monitor")
+			assert.Contains(t, w.Body.String(), tt.err.Error())
+		})
+	}
+}
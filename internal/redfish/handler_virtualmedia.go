@@ -0,0 +1,304 @@
+package redfish
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/tjst-t/qemu-bmc/internal/imagecache"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+)
+
+const (
+	managerVirtualMediaPath = "/redfish/v1/Managers/1/VirtualMedia"
+	systemVirtualMediaPath  = "/redfish/v1/Systems/1/VirtualMedia"
+)
+
+// transferProtocols maps image URL schemes to TransferProtocolType. Images
+// without a scheme are paths on the QEMU host.
+var transferProtocols = map[string]string{
+	"http":  "HTTP",
+	"https": "HTTPS",
+	"ftp":   "FTP",
+	"nfs":   "NFS",
+}
+
+// virtualMediaPath returns the collection a request addresses: the
+// manager's, or the system's of newer schema versions.
+func virtualMediaPath(r *http.Request) string {
+	if strings.HasPrefix(r.URL.Path, "/redfish/v1/Systems/") {
+		return systemVirtualMediaPath
+	}
+	return managerVirtualMediaPath
+}
+
+func (s *Server) handleVirtualMediaCollection(w http.ResponseWriter, r *http.Request) {
+	base := virtualMediaPath(r)
+	slots := s.machine.MediaSlots()
+	members := make([]ODataID, len(slots))
+	for i, slot := range slots {
+		members[i] = ODataID{ODataID: base + "/" + slot.ID}
+	}
+	col := VirtualMediaCollection{
+		ODataType:    "#VirtualMediaCollection.VirtualMediaCollection",
+		ODataID:      base,
+		Name:         "Virtual Media Collection",
+		MembersCount: len(members),
+		Members:      members,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(col)
+}
+
+// handleGetVirtualMedia reports a virtual media slot as QEMU sees it:
+// Inserted is false while the guest has the tray open.
+func (s *Server) handleGetVirtualMedia(w http.ResponseWriter, r *http.Request) {
+	media, ok := s.findMedia(w, mux.Vars(r)["vmid"])
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(virtualMediaResource(virtualMediaPath(r), media))
+}
+
+func (s *Server) handleInsertMedia(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["vmid"]
+	var req InsertMediaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedJSON", "Invalid request body")
+		return
+	}
+
+	if req.Image == "" {
+		writeError(w, http.StatusBadRequest, "PropertyMissing", "Image URL is required")
+		return
+	}
+	if !validTransferProtocol(req.Image, req.TransferProtocolType) {
+		writeError(w, http.StatusBadRequest, "ActionParameterValueError",
+			"TransferProtocolType "+req.TransferProtocolType+" does not match the Image")
+		return
+	}
+	file, ok := s.uploadedFile(w, r, req.Image)
+	if !ok {
+		return
+	}
+	opts := machine.InsertOptions{
+		WriteProtected: req.WriteProtected == nil || *req.WriteProtected,
+		UserName:       req.UserName,
+		Password:       req.Password,
+		File:           file,
+	}
+	if req.Oem != nil {
+		if !setVerification(w, &opts, req.Oem.QemuBmc) {
+			return
+		}
+	}
+
+	// Downloading the image can be slow, so it runs as a task.
+	op := func(progress func(int)) error {
+		opts.Progress = progress
+		return s.machine.InsertMedia(id, req.Image, opts)
+	}
+	s.runAsTask(w, "Insert virtual media "+id, http.StatusOK, op, writeMediaError, func() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	})
+}
+
+func (s *Server) handleEjectMedia(w http.ResponseWriter, r *http.Request) {
+	if err := s.machine.EjectMedia(mux.Vars(r)["vmid"]); err != nil {
+		writeMediaError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handlePatchVirtualMedia inserts or ejects media by setting Image, or
+// Inserted to false, as newer VirtualMedia schema versions allow.
+func (s *Server) handlePatchVirtualMedia(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["vmid"]
+	var req PatchVirtualMediaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "MalformedJSON", "Invalid request body")
+		return
+	}
+	if req.Image == nil && req.Inserted == nil && req.WriteProtected == nil {
+		writeError(w, http.StatusBadRequest, "PropertyMissing", "no patchable properties provided")
+		return
+	}
+	current, ok := s.findMedia(w, id)
+	if !ok {
+		return
+	}
+
+	image := current.Image
+	if req.Image != nil {
+		image = ""
+		if err := json.Unmarshal(req.Image, &image); err != nil && string(req.Image) != "null" {
+			writeError(w, http.StatusBadRequest, "PropertyValueTypeError", "Image must be a string or null")
+			return
+		}
+	}
+	var protocol string
+	if req.TransferProtocolType != nil {
+		protocol = *req.TransferProtocolType
+	}
+	if image != "" && !validTransferProtocol(image, protocol) {
+		writeError(w, http.StatusBadRequest, "PropertyValueConflict",
+			"TransferProtocolType "+protocol+" does not match the Image")
+		return
+	}
+	eject := image == "" || (req.Inserted != nil && !*req.Inserted)
+	var file string
+	if !eject {
+		if file, ok = s.uploadedFile(w, r, image); !ok {
+			return
+		}
+	}
+	opts := machine.InsertOptions{
+		WriteProtected: req.WriteProtected == nil || *req.WriteProtected,
+		UserName:       req.UserName,
+		Password:       req.Password,
+		File:           file,
+	}
+
+	op := func(progress func(int)) error {
+		if eject {
+			return s.machine.EjectMedia(id)
+		}
+		opts.Progress = progress
+		return s.machine.InsertMedia(id, image, opts)
+	}
+	base := virtualMediaPath(r)
+	s.runAsTask(w, "Update virtual media "+id, http.StatusOK, op, writeMediaError, func() {
+		media, ok := s.findMedia(w, id)
+		if !ok {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(virtualMediaResource(base, media))
+	})
+}
+
+// setVerification validates the checksum and signature to verify the image
+// against, writing the error response if they are malformed.
+func setVerification(w http.ResponseWriter, opts *machine.InsertOptions, p InsertMediaOemQemuBmc) bool {
+	if p.SHA256 != "" {
+		if sum, err := hex.DecodeString(p.SHA256); err != nil || len(sum) != sha256.Size {
+			writeError(w, http.StatusBadRequest, "ActionParameterValueFormatError", "SHA256 must be 64 hex digits")
+			return false
+		}
+		opts.SHA256 = p.SHA256
+	}
+	if p.Signature != "" {
+		sig, err := base64.StdEncoding.DecodeString(p.Signature)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "ActionParameterValueFormatError", "Signature must be base64")
+			return false
+		}
+		opts.Signature = sig
+	}
+	return true
+}
+
+// findMedia looks up a virtual media slot, writing the error response if
+// there is none.
+func (s *Server) findMedia(w http.ResponseWriter, id string) (machine.Media, bool) {
+	all, err := s.machine.GetMedia()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+		return machine.Media{}, false
+	}
+	for _, media := range all {
+		if media.ID == id {
+			return media, true
+		}
+	}
+	writeError(w, http.StatusNotFound, "ResourceNotFound", "VirtualMedia "+id+" not found")
+	return machine.Media{}, false
+}
+
+func virtualMediaResource(base string, media machine.Media) VirtualMedia {
+	odataID := base + "/" + media.ID
+	vm := VirtualMedia{
+		ODataType:            "#VirtualMedia.v1_3_0.VirtualMedia",
+		ODataID:              odataID,
+		ODataContext:         "/redfish/v1/$metadata#VirtualMedia.VirtualMedia",
+		ID:                   media.ID,
+		Image:                media.Image,
+		Inserted:             media.Inserted,
+		WriteProtected:       media.WriteProtected,
+		TransferProtocolType: transferProtocol(media.Image),
+		ConnectedVia:         "NotConnected",
+		Actions: VirtualMediaActions{
+			InsertMedia: VirtualMediaAction{Target: odataID + "/Actions/VirtualMedia.InsertMedia"},
+			EjectMedia:  VirtualMediaAction{Target: odataID + "/Actions/VirtualMedia.EjectMedia"},
+		},
+	}
+	if media.Image != "" {
+		vm.ConnectedVia = "URI"
+	}
+	if v := media.Verification; v != nil {
+		vm.Oem = &VirtualMediaOem{QemuBmc: VirtualMediaOemQemuBmc{ImageVerification: ImageVerification{
+			SHA256:            v.SHA256,
+			ChecksumVerified:  v.ChecksumVerified,
+			SignatureVerified: v.SignatureVerified,
+			Signer:            v.Signer,
+		}}}
+	}
+	switch media.Type {
+	case machine.MediaCD:
+		vm.Name, vm.MediaTypes = "Virtual CD", []string{"CD", "DVD"}
+	case machine.MediaUSBStick:
+		vm.Name, vm.MediaTypes = "Virtual USB Stick", []string{"USBStick"}
+	case machine.MediaFloppy:
+		vm.Name, vm.MediaTypes = "Virtual Floppy", []string{"Floppy"}
+	}
+	return vm
+}
+
+// transferProtocol returns the TransferProtocolType of an image, "" for a
+// path on the QEMU host.
+func transferProtocol(image string) string {
+	scheme, _, ok := strings.Cut(image, "://")
+	if !ok {
+		return ""
+	}
+	return transferProtocols[strings.ToLower(scheme)]
+}
+
+// validTransferProtocol checks a requested TransferProtocolType against
+// the image; "OEM" stands for a path on the QEMU host.
+func validTransferProtocol(image, protocol string) bool {
+	if protocol == "" {
+		return true
+	}
+	if p := transferProtocol(image); p != "" {
+		return p == protocol
+	}
+	return protocol == "OEM" && !strings.Contains(image, "://")
+}
+
+func writeMediaError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, machine.ErrMediaNotFound):
+		writeError(w, http.StatusNotFound, "ResourceNotFound", err.Error())
+	case errors.Is(err, machine.ErrNoMediaDevice):
+		writeError(w, http.StatusNotImplemented, "ActionNotSupported", err.Error())
+	case errors.Is(err, imagecache.ErrVerification):
+		writeError(w, http.StatusBadRequest, "ActionParameterValueError", err.Error())
+	case errors.Is(err, imagecache.ErrTooLarge):
+		writeError(w, http.StatusInsufficientStorage, "InsufficientStorage", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "InternalError", err.Error())
+	}
+}
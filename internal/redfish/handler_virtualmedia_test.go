@@ -0,0 +1,294 @@
+package redfish
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/imagecache"
+	"github.com/tjst-t/qemu-bmc/internal/machine"
+	"github.com/tjst-t/qemu-bmc/internal/qmp"
+)
+
+func TestGetVirtualMediaCollection(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest("GET", "/redfish/v1/Managers/1/VirtualMedia", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var col VirtualMediaCollection
+	err := json.Unmarshal(w.Body.Bytes(), &col)
+	require.NoError(t, err)
+	assert.Equal(t, 1, col.MembersCount)
+}
+
+func TestGetVirtualMedia_NotInserted(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest("GET", "/redfish/v1/Managers/1/VirtualMedia/CD1", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var vm VirtualMedia
+	err := json.Unmarshal(w.Body.Bytes(), &vm)
+	require.NoError(t, err)
+	assert.False(t, vm.Inserted)
+	assert.Empty(t, vm.Image)
+}
+
+func TestInsertVirtualMedia(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	srv := newTestServer(mock)
+
+	body := `{"Image": "http://example.com/boot.iso", "Inserted": true}`
+	req := httptest.NewRequest("POST",
+		"/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.InsertMedia",
+		strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "http://example.com/boot.iso", mock.LastInsertedMedia())
+}
+
+func TestInsertVirtualMedia_EmptyImage(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	srv := newTestServer(mock)
+
+	body := `{"Image": "", "Inserted": true}`
+	req := httptest.NewRequest("POST",
+		"/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.InsertMedia",
+		strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestEjectVirtualMedia(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	mock.lastMedia = "http://example.com/boot.iso"
+	srv := newTestServer(mock)
+
+	req := httptest.NewRequest("POST",
+		"/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.EjectMedia",
+		nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestVirtualMedia_InsertThenGet(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	srv := newTestServer(mock)
+
+	// Insert
+	body := `{"Image": "http://example.com/boot.iso", "Inserted": true}`
+	insertReq := httptest.NewRequest("POST",
+		"/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.InsertMedia",
+		strings.NewReader(body))
+	insertReq.Header.Set("Content-Type", "application/json")
+	w1 := httptest.NewRecorder()
+	srv.ServeHTTP(w1, insertReq)
+	assert.Equal(t, http.StatusOK, w1.Code)
+
+	// Get - should show inserted
+	getReq := httptest.NewRequest("GET", "/redfish/v1/Managers/1/VirtualMedia/CD1", nil)
+	w2 := httptest.NewRecorder()
+	srv.ServeHTTP(w2, getReq)
+
+	var vm VirtualMedia
+	json.Unmarshal(w2.Body.Bytes(), &vm)
+	assert.True(t, vm.Inserted)
+	assert.True(t, vm.WriteProtected)
+	assert.Equal(t, "http://example.com/boot.iso", vm.Image)
+	assert.Equal(t, "URI", vm.ConnectedVia)
+}
+
+func TestVirtualMedia_Errors(t *testing.T) {
+	mock := newMockMachine(qmp.StatusRunning)
+	srv := newTestServer(mock)
+	insert := "/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.InsertMedia"
+	eject := "/redfish/v1/Managers/1/VirtualMedia/CD1/Actions/VirtualMedia.EjectMedia"
+
+	mock.mediaErr = errors.New("inserting http://x/a.iso: QMP error: GenericError: Could not open")
+	w := doRequest(srv, "POST", insert, `{"Image":"http://x/a.iso"}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Could not open")
+
+	mock.mediaErr = machine.ErrNoMediaDevice
+	w = doRequest(srv, "POST", insert, `{"Image":"http://x/a.iso"}`)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+	w = doRequest(srv, "POST", eject, `{}`)
+	assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+	var vm VirtualMedia
+	w = doRequest(srv, "GET", "/redfish/v1/Managers/1/VirtualMedia/CD1", "")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &vm))
+	assert.False(t, vm.Inserted)
+	assert.Equal(t, "NotConnected", vm.ConnectedVia)
+}
+
+func newMediaTestServer() (*Server, *mockMachine) {
+	mock := newMockMachine(qmp.StatusRunning)
+	mock.mediaSlots = []machine.MediaSlot{
+		{ID: "CD1", Type: machine.MediaCD},
+		{ID: "USB1", Type: machine.MediaUSBStick},
+		{ID: "Floppy1", Type: machine.MediaFloppy},
+	}
+	return newTestServer(mock), mock
+}
+
+func getVirtualMedia(t *testing.T, srv *Server, path string) VirtualMedia {
+	t.Helper()
+	w := doRequest(srv, "GET", path, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var vm VirtualMedia
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &vm))
+	return vm
+}
+
+func TestVirtualMedia_Slots(t *testing.T) {
+	srv, _ := newMediaTestServer()
+
+	for _, base := range []string{managerVirtualMediaPath, systemVirtualMediaPath} {
+		w := doRequest(srv, "GET", base, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var col VirtualMediaCollection
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &col))
+		assert.Equal(t, base, col.ODataID)
+		assert.Equal(t, []ODataID{{ODataID: base + "/CD1"}, {ODataID: base + "/USB1"}, {ODataID: base + "/Floppy1"}}, col.Members)
+	}
+
+	usb := getVirtualMedia(t, srv, systemVirtualMediaPath+"/USB1")
+	assert.Equal(t, systemVirtualMediaPath+"/USB1", usb.ODataID)
+	assert.Equal(t, []string{"USBStick"}, usb.MediaTypes)
+	assert.Equal(t, systemVirtualMediaPath+"/USB1/Actions/VirtualMedia.InsertMedia", usb.Actions.InsertMedia.Target)
+	assert.Equal(t, []string{"Floppy"}, getVirtualMedia(t, srv, managerVirtualMediaPath+"/Floppy1").MediaTypes)
+
+	w := doRequest(srv, "GET", managerVirtualMediaPath+"/CD9", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var system ComputerSystem
+	require.NoError(t, json.Unmarshal(doRequest(srv, "GET", "/redfish/v1/Systems/1", "").Body.Bytes(), &system))
+	assert.Equal(t, systemVirtualMediaPath, system.VirtualMedia.ODataID)
+}
+
+func TestInsertVirtualMedia_WriteProtectedAndProtocol(t *testing.T) {
+	srv, mock := newMediaTestServer()
+	insert := systemVirtualMediaPath + "/USB1/Actions/VirtualMedia.InsertMedia"
+
+	w := doRequest(srv, "POST", insert, `{"Image":"https://example.com/ks.img","WriteProtected":false,"TransferProtocolType":"HTTPS"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	usb := getVirtualMedia(t, srv, systemVirtualMediaPath+"/USB1")
+	assert.True(t, usb.Inserted)
+	assert.False(t, usb.WriteProtected)
+	assert.Equal(t, "HTTPS", usb.TransferProtocolType)
+
+	w = doRequest(srv, "POST", insert, `{"Image":"https://example.com/ks.img","TransferProtocolType":"NFS"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, "POST", insert, `{"Image":"/images/ks.img","TransferProtocolType":"OEM"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, mock.media["USB1"].WriteProtected, "WriteProtected defaults to true")
+	assert.Empty(t, getVirtualMedia(t, srv, systemVirtualMediaPath+"/USB1").TransferProtocolType)
+
+	w = doRequest(srv, "POST", systemVirtualMediaPath+"/CD9/Actions/VirtualMedia.InsertMedia", `{"Image":"/a.iso"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestInsertVirtualMedia_Download(t *testing.T) {
+	srv, mock := newMediaTestServer()
+	insert := managerVirtualMediaPath + "/CD1/Actions/VirtualMedia.InsertMedia"
+
+	w := doRequest(srv, "POST", insert, `{"Image":"https://example.com/boot.iso","UserName":"deploy","Password":"secret"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "deploy", mock.lastInsert.UserName)
+	assert.Equal(t, "secret", mock.lastInsert.Password)
+	assert.NotNil(t, mock.lastInsert.Progress, "download progress goes to the task")
+
+	w = doRequest(srv, "PATCH", managerVirtualMediaPath+"/CD1", `{"Image":"https://example.com/other.iso","UserName":"ops","Password":"pw"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "ops", mock.lastInsert.UserName)
+
+	mock.mediaErr = fmt.Errorf("downloading https://example.com/huge.iso: %w", imagecache.ErrTooLarge)
+	w = doRequest(srv, "POST", insert, `{"Image":"https://example.com/huge.iso"}`)
+	assert.Equal(t, http.StatusInsufficientStorage, w.Code)
+}
+
+func TestPatchVirtualMedia(t *testing.T) {
+	srv, mock := newMediaTestServer()
+	path := systemVirtualMediaPath + "/Floppy1"
+
+	w := doRequest(srv, "PATCH", path, `{"Image":"http://example.com/dos.img","Inserted":true,"WriteProtected":false}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	var vm VirtualMedia
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &vm))
+	assert.True(t, vm.Inserted)
+	assert.False(t, vm.WriteProtected)
+	assert.Equal(t, "HTTP", vm.TransferProtocolType)
+	assert.Equal(t, "http://example.com/dos.img", mock.LastInsertedMedia())
+
+	// Changing only WriteProtected re-inserts the current image
+	w = doRequest(srv, "PATCH", path, `{"WriteProtected":true}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, mock.media["Floppy1"].WriteProtected)
+	assert.Equal(t, "http://example.com/dos.img", mock.media["Floppy1"].Image)
+
+	w = doRequest(srv, "PATCH", path, `{"Image":null}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, getVirtualMedia(t, srv, path).Inserted)
+
+	require.Equal(t, http.StatusOK, doRequest(srv, "PATCH", path, `{"Image":"/images/dos.img"}`).Code)
+	w = doRequest(srv, "PATCH", path, `{"Inserted":false}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, mock.calls, "EjectMedia")
+	assert.False(t, getVirtualMedia(t, srv, path).Inserted)
+
+	assert.Equal(t, http.StatusBadRequest, doRequest(srv, "PATCH", path, `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, doRequest(srv, "PATCH", path, `{"Image":42}`).Code)
+	assert.Equal(t, http.StatusNotFound, doRequest(srv, "PATCH", systemVirtualMediaPath+"/USB9", `{"Image":"/a.img"}`).Code)
+}
+
+func TestInsertVirtualMedia_Verification(t *testing.T) {
+	srv, mock := newMediaTestServer()
+	insert := managerVirtualMediaPath + "/CD1/Actions/VirtualMedia.InsertMedia"
+	sum := strings.Repeat("ab", 32)
+
+	w := doRequest(srv, "POST", insert, `{"Image":"/images/boot.iso","Oem":{"QemuBmc":{"SHA256":"`+sum+`","Signature":"c2lnbmF0dXJl"}}}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, sum, mock.lastInsert.SHA256)
+	assert.Equal(t, []byte("signature"), mock.lastInsert.Signature)
+
+	w = doRequest(srv, "POST", insert, `{"Image":"/images/boot.iso","Oem":{"QemuBmc":{"SHA256":"abc"}}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = doRequest(srv, "POST", insert, `{"Image":"/images/boot.iso","Oem":{"QemuBmc":{"Signature":"not base64!"}}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mock.mediaErr = fmt.Errorf("%w: SHA-256 is 00, expected ab", imagecache.ErrVerification)
+	w = doRequest(srv, "POST", insert, `{"Image":"/images/boot.iso","Oem":{"QemuBmc":{"SHA256":"`+sum+`"}}}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "expected ab")
+	mock.mediaErr = nil
+
+	// The result is reported with the inserted image
+	assert.Nil(t, getVirtualMedia(t, srv, managerVirtualMediaPath+"/CD1").Oem)
+	mock.media["CD1"] = machine.Media{ID: "CD1", Type: machine.MediaCD, Image: "/images/boot.iso", Inserted: true,
+		Verification: &imagecache.Verification{SHA256: sum, ChecksumVerified: true, SignatureVerified: true, Signer: "cafe"}}
+	vm := getVirtualMedia(t, srv, managerVirtualMediaPath+"/CD1")
+	require.NotNil(t, vm.Oem)
+	assert.Equal(t, ImageVerification{SHA256: sum, ChecksumVerified: true, SignatureVerified: true, Signer: "cafe"}, vm.Oem.QemuBmc.ImageVerification)
+}
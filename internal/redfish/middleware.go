@@ -1,24 +1,129 @@
 package redfish
 
 import (
-	"crypto/subtle"
+	"context"
+	"fmt"
 	"net/http"
+	"slices"
 	"strings"
+
+	"github.com/tjst-t/qemu-bmc/internal/eventlog"
 )
 
-func (s *Server) basicAuthMiddleware(next http.Handler) http.Handler {
+type contextKey int
+
+const (
+	contextKeyUser contextKey = iota
+	contextKeyAudit
+)
+
+// auditRecord collects what the BMC event log records about a request
+type auditRecord struct {
+	user string
+}
+
+// statusRecorder remembers the status code of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// auditMiddleware logs requests that change state in the BMC event log with
+// the caller, the source address and the response status.
+func (s *Server) auditMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		user, pass, ok := r.BasicAuth()
-		if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(s.user)) != 1 ||
-			subtle.ConstantTimeCompare([]byte(pass), []byte(s.pass)) != 1 {
-			w.Header().Set("WWW-Authenticate", `Basic realm="Redfish"`)
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
 			return
 		}
-		next.ServeHTTP(w, r)
+
+		audit := &auditRecord{}
+		audit.user, _, _ = r.BasicAuth()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), contextKeyAudit, audit)))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		msg := r.Method + " " + r.URL.Path
+		if audit.user != "" {
+			msg += " by " + audit.user
+		}
+		msg += fmt.Sprintf(" from %s: %d %s", remoteHost(r), rec.status, http.StatusText(rec.status))
+		severity := eventlog.SeverityOK
+		switch {
+		case rec.status >= 500:
+			severity = eventlog.SeverityCritical
+		case rec.status >= 400:
+			severity = eventlog.SeverityWarning
+		}
+		s.eventLog.Add(eventlog.Entry{Severity: severity, Message: msg})
 	})
 }
 
+// auditUser records the user a request acts as for the BMC event log.
+func auditUser(r *http.Request, user string) {
+	if audit, ok := r.Context().Value(contextKeyAudit).(*auditRecord); ok {
+		audit.user = user
+	}
+}
+
+// authMiddleware accepts either a session token (X-Auth-Token) or HTTP Basic
+// credentials and requires the Login privilege. Creating a session (login)
+// needs neither.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && strings.TrimSuffix(r.URL.Path, "/") == sessionsPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var user string
+		sess, ok := s.sessions.Authenticate(r.Header.Get("X-Auth-Token"))
+		if ok && !s.sessionCurrent(sess) {
+			s.sessions.Delete(sess.ID)
+			ok = false
+		}
+		if ok {
+			user = sess.UserName
+		} else {
+			var pass string
+			user, pass, ok = r.BasicAuth()
+			if !ok || !s.checkCredentials(user, pass, remoteHost(r)) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="Redfish"`)
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if !slices.Contains(s.userRole(user).Privileges, privLogin) {
+			writeInsufficientPrivilege(w)
+			return
+		}
+		next.ServeHTTP(w, withUser(r, user))
+	})
+}
+
+// withUser records the authenticated user name in the request context.
+func withUser(r *http.Request, user string) *http.Request {
+	auditUser(r, user)
+	return r.WithContext(context.WithValue(r.Context(), contextKeyUser, user))
+}
+
 func (s *Server) trailingSlashMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/" && strings.HasSuffix(r.URL.Path, "/") {
@@ -6,12 +6,13 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
 	"github.com/tjst-t/qemu-bmc/internal/qmp"
 )
 
 func TestBasicAuth(t *testing.T) {
 	mock := newMockMachine(qmp.StatusRunning)
-	srv := NewServer(mock, "admin", "password", "")
+	srv := NewServer(mock, bmc.NewState("admin", "password"), "admin", "password", "")
 
 	t.Run("valid credentials returns 200", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/redfish/v1", nil)
@@ -42,7 +43,7 @@ func TestBasicAuth(t *testing.T) {
 
 func TestTrailingSlash(t *testing.T) {
 	mock := newMockMachine(qmp.StatusRunning)
-	srv := NewServer(mock, "", "", "")
+	srv := NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 
 	t.Run("without trailing slash returns 200", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/redfish/v1/Systems", nil)
@@ -2,69 +2,258 @@ package redfish
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
+	"github.com/tjst-t/qemu-bmc/internal/eventlog"
+	"github.com/tjst-t/qemu-bmc/internal/imagecache"
 	"github.com/tjst-t/qemu-bmc/internal/machine"
 	"github.com/tjst-t/qemu-bmc/internal/novnc"
-	"github.com/tjst-t/qemu-bmc/internal/qmp"
 )
 
 // MachineInterface defines what the Redfish server needs from the machine layer
 type MachineInterface interface {
 	GetPowerState() (machine.PowerState, error)
-	GetQMPStatus() (qmp.Status, error)
 	Reset(resetType string) error
 	GetBootOverride() machine.BootOverride
 	SetBootOverride(override machine.BootOverride) error
-	InsertMedia(image string) error
-	EjectMedia() error
+	ValidateBootOverride(override machine.BootOverride) error
+	MediaSlots() []machine.MediaSlot
+	GetMedia() ([]machine.Media, error)
+	InsertMedia(id, image string, opts machine.InsertOptions) error
+	EjectMedia(id string) error
+	GetInventory() (machine.Inventory, error)
+	GetDrives() ([]machine.Drive, error)
+	CreateVolume(name string, sizeBytes int64) (string, error)
+	DeleteVolume(id string) error
+	GetNICs() ([]machine.NIC, error)
+	SetNICMAC(id, mac string) error
+	SetNICLink(id string, up bool) error
+	GetBios() (machine.BiosSettings, error)
+	GetPendingBios() (machine.BiosSettings, error)
+	SetPendingBios(s machine.BiosSettings) error
+	ResetBios() error
+	GetBootOptions() ([]machine.BootOption, error)
+	GetBootOrder() ([]string, error)
+	SetBootOrder(refs []string) error
+	ValidateBootOrder(refs []string) error
+	GetEnvironment() (machine.Environment, error)
 }
 
 // Server is the Redfish HTTP server
 type Server struct {
 	router       *mux.Router
 	machine      MachineInterface
+	bmcState     *bmc.State
 	user         string
 	pass         string
-	currentMedia string
 	novncHandler *novnc.Handler
+	sessions     *sessionStore
+	events       *eventService
+	tasks        *taskStore
+	taskWait     time.Duration
+	imageCache   *imagecache.Cache // nil = image uploads disabled
+	eventLog     *eventlog.Log     // changes made through the BMC and machine events
+	processLog   *eventlog.Log     // QEMU output and exits, nil in legacy mode
 }
 
-// NewServer creates a new Redfish server
-func NewServer(m MachineInterface, user, pass, vncAddr string) *Server {
+// NewServer creates a new Redfish server. Authentication is enabled when
+// user and pass are non-empty; credentials are then checked against the
+// user table in state, shared with IPMI.
+func NewServer(m MachineInterface, state *bmc.State, user, pass, vncAddr string) *Server {
 	s := &Server{
 		router:       mux.NewRouter(),
 		machine:      m,
+		bmcState:     state,
 		user:         user,
 		pass:         pass,
 		novncHandler: novnc.NewHandler(vncAddr),
+		sessions:     newSessionStore(DefaultSessionTimeout),
+		events:       newEventService(),
+		tasks:        newTaskStore(),
+		taskWait:     defaultTaskWait,
+		eventLog:     eventlog.New(maxEventLogEntries),
 	}
 	s.setupRoutes()
 	return s
 }
 
+// SetSessionTimeout sets the idle timeout of Redfish sessions.
+func (s *Server) SetSessionTimeout(d time.Duration) {
+	s.sessions.SetTimeout(d)
+}
+
 func (s *Server) setupRoutes() {
-	// Apply middleware
+	// Apply middleware. Every authenticated request needs the Login
+	// privilege; routes that change state additionally require the
+	// privilege given to requirePrivilege. Virtual media and the console
+	// control what the system runs, so they need ConfigureComponents.
+	// Requests that change state are logged in the BMC event log, whether
+	// or not they are authorized.
 	s.router.Use(s.trailingSlashMiddleware)
-	if s.user != "" && s.pass != "" {
-		s.router.Use(s.basicAuthMiddleware)
+	s.router.Use(s.auditMiddleware)
+	if s.authEnabled() {
+		s.router.Use(s.authMiddleware)
 	}
 
 	// Service Root
 	s.router.HandleFunc("/redfish/v1", s.handleServiceRoot).Methods("GET")
 	s.router.HandleFunc("/redfish/v1/", s.handleServiceRoot).Methods("GET")
 
+	// SessionService
+	s.router.HandleFunc("/redfish/v1/SessionService", s.handleGetSessionService).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/SessionService/", s.handleGetSessionService).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/SessionService", s.requirePrivilege(privConfigureManager, s.handlePatchSessionService)).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/SessionService/", s.requirePrivilege(privConfigureManager, s.handlePatchSessionService)).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/SessionService/Sessions", s.handleSessionCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/SessionService/Sessions/", s.handleSessionCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/SessionService/Sessions", s.handleCreateSession).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/SessionService/Sessions/", s.handleCreateSession).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/SessionService/Sessions/{sid}", s.handleGetSession).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/SessionService/Sessions/{sid}/", s.handleGetSession).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/SessionService/Sessions/{sid}", s.handleDeleteSession).Methods("DELETE")
+	s.router.HandleFunc("/redfish/v1/SessionService/Sessions/{sid}/", s.handleDeleteSession).Methods("DELETE")
+
+	// AccountService
+	s.router.HandleFunc("/redfish/v1/AccountService", s.handleGetAccountService).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/AccountService/", s.handleGetAccountService).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/AccountService/Accounts", s.handleAccountCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/AccountService/Accounts/", s.handleAccountCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/AccountService/Accounts", s.requirePrivilege(privConfigureUsers, s.handleCreateAccount)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/AccountService/Accounts/", s.requirePrivilege(privConfigureUsers, s.handleCreateAccount)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/AccountService/Accounts/{aid}", s.handleGetAccount).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/AccountService/Accounts/{aid}/", s.handleGetAccount).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/AccountService/Accounts/{aid}", s.requirePrivilege(privConfigureSelf, s.handlePatchAccount)).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/AccountService/Accounts/{aid}/", s.requirePrivilege(privConfigureSelf, s.handlePatchAccount)).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/AccountService/Accounts/{aid}", s.requirePrivilege(privConfigureUsers, s.handleDeleteAccount)).Methods("DELETE")
+	s.router.HandleFunc("/redfish/v1/AccountService/Accounts/{aid}/", s.requirePrivilege(privConfigureUsers, s.handleDeleteAccount)).Methods("DELETE")
+	s.router.HandleFunc("/redfish/v1/AccountService/Roles", s.handleRoleCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/AccountService/Roles/", s.handleRoleCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/AccountService/Roles/{rid}", s.handleGetRole).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/AccountService/Roles/{rid}/", s.handleGetRole).Methods("GET")
+
+	// EventService
+	s.router.HandleFunc("/redfish/v1/EventService", s.handleGetEventService).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/EventService/", s.handleGetEventService).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/EventService/Subscriptions", s.handleSubscriptionCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/EventService/Subscriptions/", s.handleSubscriptionCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/EventService/Subscriptions", s.requirePrivilege(privConfigureComponents, s.handleCreateSubscription)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/EventService/Subscriptions/", s.requirePrivilege(privConfigureComponents, s.handleCreateSubscription)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/EventService/Subscriptions/{subid}", s.handleGetSubscription).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/EventService/Subscriptions/{subid}/", s.handleGetSubscription).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/EventService/Subscriptions/{subid}", s.requirePrivilege(privConfigureComponents, s.handleDeleteSubscription)).Methods("DELETE")
+	s.router.HandleFunc("/redfish/v1/EventService/Subscriptions/{subid}/", s.requirePrivilege(privConfigureComponents, s.handleDeleteSubscription)).Methods("DELETE")
+	s.router.HandleFunc("/redfish/v1/EventService/Actions/EventService.SubmitTestEvent", s.requirePrivilege(privConfigureManager, s.handleSubmitTestEvent)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/EventService/Actions/EventService.SubmitTestEvent/", s.requirePrivilege(privConfigureManager, s.handleSubmitTestEvent)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/EventService/SSE", s.handleEventStream).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/EventService/SSE/", s.handleEventStream).Methods("GET")
+
+	// TaskService
+	s.router.HandleFunc("/redfish/v1/TaskService", s.handleGetTaskService).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/TaskService/", s.handleGetTaskService).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/TaskService/Tasks", s.handleTaskCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/TaskService/Tasks/", s.handleTaskCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/TaskService/Tasks/{tid}", s.handleGetTask).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/TaskService/Tasks/{tid}/", s.handleGetTask).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/TaskService/TaskMonitors/{tid}", s.handleTaskMonitor).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/TaskService/TaskMonitors/{tid}/", s.handleTaskMonitor).Methods("GET")
+
+	// Registries
+	s.router.HandleFunc("/redfish/v1/Registries", s.handleRegistryCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Registries/", s.handleRegistryCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Registries/BiosAttributeRegistry", s.handleGetBiosRegistryFile).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Registries/BiosAttributeRegistry/", s.handleGetBiosRegistryFile).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Registries/BiosAttributeRegistry/Registry", s.handleGetBiosRegistry).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Registries/BiosAttributeRegistry/Registry/", s.handleGetBiosRegistry).Methods("GET")
+
 	// Systems
 	s.router.HandleFunc("/redfish/v1/Systems", s.handleSystemCollection).Methods("GET")
 	s.router.HandleFunc("/redfish/v1/Systems/", s.handleSystemCollection).Methods("GET")
 	s.router.HandleFunc("/redfish/v1/Systems/{id}", s.handleGetSystem).Methods("GET")
 	s.router.HandleFunc("/redfish/v1/Systems/{id}/", s.handleGetSystem).Methods("GET")
-	s.router.HandleFunc("/redfish/v1/Systems/{id}", s.handlePatchSystem).Methods("PATCH")
-	s.router.HandleFunc("/redfish/v1/Systems/{id}/", s.handlePatchSystem).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}", s.requirePrivilege(privConfigureComponents, s.handlePatchSystem)).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/", s.requirePrivilege(privConfigureComponents, s.handlePatchSystem)).Methods("PATCH")
+
+	// Inventory
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Processors", s.handleProcessorCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Processors/", s.handleProcessorCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Processors/{cpu}", s.handleGetProcessor).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Processors/{cpu}/", s.handleGetProcessor).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Memory", s.handleMemoryCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Memory/", s.handleMemoryCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Memory/{mem}", s.handleGetMemory).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Memory/{mem}/", s.handleGetMemory).Methods("GET")
+
+	// Storage
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage", s.handleStorageCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/", s.handleStorageCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/1", s.handleGetStorage).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/1/", s.handleGetStorage).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/1/Drives/{drive}", s.handleGetDrive).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/1/Drives/{drive}/", s.handleGetDrive).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/1/Volumes", s.handleVolumeCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/1/Volumes/", s.handleVolumeCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/1/Volumes", s.requirePrivilege(privConfigureComponents, s.handleCreateVolume)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/1/Volumes/", s.requirePrivilege(privConfigureComponents, s.handleCreateVolume)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/1/Volumes/{volume}", s.handleGetVolume).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/1/Volumes/{volume}/", s.handleGetVolume).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/1/Volumes/{volume}", s.requirePrivilege(privConfigureComponents, s.handleDeleteVolume)).Methods("DELETE")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Storage/1/Volumes/{volume}/", s.requirePrivilege(privConfigureComponents, s.handleDeleteVolume)).Methods("DELETE")
+
+	// EthernetInterfaces
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/EthernetInterfaces", s.handleEthernetInterfaceCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/EthernetInterfaces/", s.handleEthernetInterfaceCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/EthernetInterfaces/{nic}", s.handleGetEthernetInterface).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/EthernetInterfaces/{nic}/", s.handleGetEthernetInterface).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/EthernetInterfaces/{nic}", s.requirePrivilege(privConfigureComponents, s.handlePatchEthernetInterface)).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/EthernetInterfaces/{nic}/", s.requirePrivilege(privConfigureComponents, s.handlePatchEthernetInterface)).Methods("PATCH")
+
+	// BootOptions
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/BootOptions", s.handleBootOptionCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/BootOptions/", s.handleBootOptionCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/BootOptions/{option}", s.handleGetBootOption).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/BootOptions/{option}/", s.handleGetBootOption).Methods("GET")
+
+	// Bios
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Bios", s.handleGetBios).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Bios/", s.handleGetBios).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Bios/Settings", s.handleGetBiosSettings).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Bios/Settings/", s.handleGetBiosSettings).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Bios/Settings", s.requirePrivilege(privConfigureComponents, s.handlePatchBiosSettings)).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Bios/Settings/", s.requirePrivilege(privConfigureComponents, s.handlePatchBiosSettings)).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Bios/Actions/Bios.ResetBios", s.requirePrivilege(privConfigureComponents, s.handleResetBios)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Bios/Actions/Bios.ResetBios/", s.requirePrivilege(privConfigureComponents, s.handleResetBios)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Bios/Actions/Bios.ChangePassword", s.requirePrivilege(privConfigureComponents, s.handleChangeBiosPassword)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Bios/Actions/Bios.ChangePassword/", s.requirePrivilege(privConfigureComponents, s.handleChangeBiosPassword)).Methods("POST")
+
+	// VirtualMedia
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/VirtualMedia", s.handleVirtualMediaCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/VirtualMedia/", s.handleVirtualMediaCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/VirtualMedia/{vmid}", s.handleGetVirtualMedia).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/VirtualMedia/{vmid}/", s.handleGetVirtualMedia).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/VirtualMedia/{vmid}", s.requirePrivilege(privConfigureComponents, s.handlePatchVirtualMedia)).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/VirtualMedia/{vmid}/", s.requirePrivilege(privConfigureComponents, s.handlePatchVirtualMedia)).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/VirtualMedia/{vmid}/Actions/VirtualMedia.InsertMedia", s.requirePrivilege(privConfigureComponents, s.handleInsertMedia)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/VirtualMedia/{vmid}/Actions/VirtualMedia.InsertMedia/", s.requirePrivilege(privConfigureComponents, s.handleInsertMedia)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/VirtualMedia/{vmid}/Actions/VirtualMedia.EjectMedia", s.requirePrivilege(privConfigureComponents, s.handleEjectMedia)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/VirtualMedia/{vmid}/Actions/VirtualMedia.EjectMedia/", s.requirePrivilege(privConfigureComponents, s.handleEjectMedia)).Methods("POST")
+
+	// LogServices
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/LogServices", s.handleLogServiceCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/LogServices/", s.handleLogServiceCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/LogServices/{log}", s.handleGetLogService).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/LogServices/{log}/", s.handleGetLogService).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/LogServices/{log}/Entries", s.handleLogEntryCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/LogServices/{log}/Entries/", s.handleLogEntryCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/LogServices/{log}/Entries/{entry}", s.handleGetLogEntry).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/LogServices/{log}/Entries/{entry}/", s.handleGetLogEntry).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/LogServices/{log}/Actions/LogService.ClearLog", s.requirePrivilege(privConfigureComponents, s.handleClearLog)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/LogServices/{log}/Actions/LogService.ClearLog/", s.requirePrivilege(privConfigureComponents, s.handleClearLog)).Methods("POST")
 
 	// Actions
-	s.router.HandleFunc("/redfish/v1/Systems/{id}/Actions/ComputerSystem.Reset", s.handleResetAction).Methods("POST")
-	s.router.HandleFunc("/redfish/v1/Systems/{id}/Actions/ComputerSystem.Reset/", s.handleResetAction).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Actions/ComputerSystem.Reset", s.requirePrivilege(privConfigureComponents, s.handleResetAction)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Systems/{id}/Actions/ComputerSystem.Reset/", s.requirePrivilege(privConfigureComponents, s.handleResetAction)).Methods("POST")
 
 	// Managers
 	s.router.HandleFunc("/redfish/v1/Managers", s.handleManagerCollection).Methods("GET")
@@ -75,16 +264,49 @@ func (s *Server) setupRoutes() {
 	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/", s.handleVirtualMediaCollection).Methods("GET")
 	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/{vmid}", s.handleGetVirtualMedia).Methods("GET")
 	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/{vmid}/", s.handleGetVirtualMedia).Methods("GET")
-	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/{vmid}/Actions/VirtualMedia.InsertMedia", s.handleInsertMedia).Methods("POST")
-	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/{vmid}/Actions/VirtualMedia.InsertMedia/", s.handleInsertMedia).Methods("POST")
-	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/{vmid}/Actions/VirtualMedia.EjectMedia", s.handleEjectMedia).Methods("POST")
-	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/{vmid}/Actions/VirtualMedia.EjectMedia/", s.handleEjectMedia).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/{vmid}", s.requirePrivilege(privConfigureComponents, s.handlePatchVirtualMedia)).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/{vmid}/", s.requirePrivilege(privConfigureComponents, s.handlePatchVirtualMedia)).Methods("PATCH")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/{vmid}/Actions/VirtualMedia.InsertMedia", s.requirePrivilege(privConfigureComponents, s.handleInsertMedia)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/{vmid}/Actions/VirtualMedia.InsertMedia/", s.requirePrivilege(privConfigureComponents, s.handleInsertMedia)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/{vmid}/Actions/VirtualMedia.EjectMedia", s.requirePrivilege(privConfigureComponents, s.handleEjectMedia)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/VirtualMedia/{vmid}/Actions/VirtualMedia.EjectMedia/", s.requirePrivilege(privConfigureComponents, s.handleEjectMedia)).Methods("POST")
+
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/LogServices", s.handleLogServiceCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/LogServices/", s.handleLogServiceCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/LogServices/{log}", s.handleGetLogService).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/LogServices/{log}/", s.handleGetLogService).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/LogServices/{log}/Entries", s.handleLogEntryCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/LogServices/{log}/Entries/", s.handleLogEntryCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/LogServices/{log}/Entries/{entry}", s.handleGetLogEntry).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/LogServices/{log}/Entries/{entry}/", s.handleGetLogEntry).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/LogServices/{log}/Actions/LogService.ClearLog", s.requirePrivilege(privConfigureManager, s.handleClearLog)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/LogServices/{log}/Actions/LogService.ClearLog/", s.requirePrivilege(privConfigureManager, s.handleClearLog)).Methods("POST")
+
+	// Images uploaded for virtual media (OEM)
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/Oem/QemuBmc/MediaImages", s.handleMediaImageCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/Oem/QemuBmc/MediaImages/", s.handleMediaImageCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/Oem/QemuBmc/MediaImages", s.requirePrivilege(privConfigureComponents, s.handleUploadMediaImage)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/Oem/QemuBmc/MediaImages/", s.requirePrivilege(privConfigureComponents, s.handleUploadMediaImage)).Methods("POST")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/Oem/QemuBmc/MediaImages/{image}", s.handleGetMediaImage).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/Oem/QemuBmc/MediaImages/{image}/", s.handleGetMediaImage).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/Oem/QemuBmc/MediaImages/{image}", s.requirePrivilege(privConfigureComponents, s.handlePutMediaImage)).Methods("PUT")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/Oem/QemuBmc/MediaImages/{image}/", s.requirePrivilege(privConfigureComponents, s.handlePutMediaImage)).Methods("PUT")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/Oem/QemuBmc/MediaImages/{image}", s.requirePrivilege(privConfigureComponents, s.handleDeleteMediaImage)).Methods("DELETE")
+	s.router.HandleFunc("/redfish/v1/Managers/{id}/Oem/QemuBmc/MediaImages/{image}/", s.requirePrivilege(privConfigureComponents, s.handleDeleteMediaImage)).Methods("DELETE")
 
 	// Chassis
 	s.router.HandleFunc("/redfish/v1/Chassis", s.handleChassisCollection).Methods("GET")
 	s.router.HandleFunc("/redfish/v1/Chassis/", s.handleChassisCollection).Methods("GET")
 	s.router.HandleFunc("/redfish/v1/Chassis/{id}", s.handleGetChassis).Methods("GET")
 	s.router.HandleFunc("/redfish/v1/Chassis/{id}/", s.handleGetChassis).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Chassis/{id}/Power", s.handleGetPower).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Chassis/{id}/Power/", s.handleGetPower).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Chassis/{id}/Thermal", s.handleGetThermal).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Chassis/{id}/Thermal/", s.handleGetThermal).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Chassis/{id}/Sensors", s.handleSensorCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Chassis/{id}/Sensors/", s.handleSensorCollection).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Chassis/{id}/Sensors/{sensor}", s.handleGetSensor).Methods("GET")
+	s.router.HandleFunc("/redfish/v1/Chassis/{id}/Sensors/{sensor}/", s.handleGetSensor).Methods("GET")
 
 	// noVNC: redirect /novnc/ → /novnc/vnc.html, serve static files, and WebSocket proxy
 	s.router.HandleFunc("/novnc/", func(w http.ResponseWriter, r *http.Request) {
@@ -93,7 +315,7 @@ func (s *Server) setupRoutes() {
 	s.router.PathPrefix("/novnc/").Handler(
 		http.StripPrefix("/novnc/", s.novncHandler.ServeFiles()),
 	)
-	s.router.HandleFunc("/websockify", s.novncHandler.ServeWebSocket)
+	s.router.HandleFunc("/websockify", s.requirePrivilege(privConfigureComponents, s.novncHandler.ServeWebSocket))
 }
 
 // ServeHTTP implements the http.Handler interface
@@ -5,10 +5,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tjst-t/qemu-bmc/internal/bmc"
 	"github.com/tjst-t/qemu-bmc/internal/machine"
 	"github.com/tjst-t/qemu-bmc/internal/qmp"
 )
@@ -16,11 +19,26 @@ import (
 // mockMachine implements MachineInterface for testing
 type mockMachine struct {
 	powerState   machine.PowerState
-	qmpStatus    qmp.Status
 	bootOverride machine.BootOverride
 	calls        []string
-	lastMedia    string
+	lastMedia    string              // last inserted image
+	mediaSlots   []machine.MediaSlot // nil = CD1 only
+	lastInsert   machine.InsertOptions
+	media        map[string]machine.Media
+	mediaErr     error
 	resetErr     error
+	resetBlock   chan struct{}
+	inventory    machine.Inventory
+	drives       []machine.Drive
+	volumeErr    error
+	volumeBlock  chan struct{}
+	nics         []machine.NIC
+	bios         *machine.BiosSettings // nil = legacy mode
+	pendingBios  *machine.BiosSettings
+	bootOptions  []machine.BootOption // nil = legacy mode
+	bootOrder    []string
+	environment  machine.Environment // PowerState is taken from powerState
+	mu           sync.Mutex
 }
 
 func newMockMachine(status qmp.Status) *mockMachine {
@@ -28,12 +46,15 @@ func newMockMachine(status qmp.Status) *mockMachine {
 	switch status {
 	case qmp.StatusRunning:
 		ps = machine.PowerOn
+	case qmp.StatusPaused:
+		ps = machine.PowerPaused
+	case qmp.StatusSuspended:
+		ps = machine.PowerSuspended
 	default:
 		ps = machine.PowerOff
 	}
 	return &mockMachine{
 		powerState: ps,
-		qmpStatus:  status,
 		bootOverride: machine.BootOverride{
 			Enabled: "Disabled",
 			Target:  "None",
@@ -42,15 +63,33 @@ func newMockMachine(status qmp.Status) *mockMachine {
 	}
 }
 
-func (m *mockMachine) GetPowerState() (machine.PowerState, error) {
-	return m.powerState, nil
+// newTestServer returns a server for mock without authentication.
+func newTestServer(mock *mockMachine) *Server {
+	return NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 }
 
-func (m *mockMachine) GetQMPStatus() (qmp.Status, error) {
-	return m.qmpStatus, nil
+// doRequest sends a request with body to srv. Optional credentials, user
+// and password, are sent with basic authentication.
+func doRequest(srv *Server, method, path, body string, credentials ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if len(credentials) == 2 {
+		req.SetBasicAuth(credentials[0], credentials[1])
+	}
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	return w
+}
+
+func (m *mockMachine) GetPowerState() (machine.PowerState, error) {
+	return m.powerState, nil
 }
 
 func (m *mockMachine) Reset(resetType string) error {
+	if m.resetBlock != nil {
+		<-m.resetBlock
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.calls = append(m.calls, resetType)
 	return m.resetErr
 }
@@ -60,9 +99,18 @@ func (m *mockMachine) GetBootOverride() machine.BootOverride {
 }
 
 func (m *mockMachine) SetBootOverride(override machine.BootOverride) error {
+	if err := m.ValidateBootOverride(override); err != nil {
+		return err
+	}
+	m.bootOverride = override
+	return nil
+}
+
+func (m *mockMachine) ValidateBootOverride(override machine.BootOverride) error {
 	// Validate target
 	validTargets := map[string]bool{
-		"None": true, "Pxe": true, "Hdd": true, "Cd": true, "BiosSetup": true,
+		"None": true, "Pxe": true, "Hdd": true, "Cd": true, "Usb": true, "Floppy": true,
+		"BiosSetup": true, "UefiShell": true, "UefiHttp": true, "UefiTarget": true,
 	}
 	if !validTargets[override.Target] {
 		return fmt.Errorf("invalid boot target: %s", override.Target)
@@ -75,34 +123,271 @@ func (m *mockMachine) SetBootOverride(override machine.BootOverride) error {
 	if !validEnabled[override.Enabled] {
 		return fmt.Errorf("invalid boot enabled: %s", override.Enabled)
 	}
-
-	m.bootOverride = override
 	return nil
 }
 
-func (m *mockMachine) InsertMedia(image string) error {
+func (m *mockMachine) MediaSlots() []machine.MediaSlot {
+	if m.mediaSlots == nil {
+		return []machine.MediaSlot{{ID: "CD1", Type: machine.MediaCD}}
+	}
+	return m.mediaSlots
+}
+
+func (m *mockMachine) GetMedia() ([]machine.Media, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var all []machine.Media
+	for _, slot := range m.MediaSlots() {
+		media, ok := m.media[slot.ID]
+		if !ok {
+			media = machine.Media{ID: slot.ID, Type: slot.Type}
+		}
+		all = append(all, media)
+	}
+	return all, nil
+}
+
+func (m *mockMachine) mediaSlot(id string) (machine.MediaSlot, bool) {
+	for _, slot := range m.MediaSlots() {
+		if slot.ID == id {
+			return slot, true
+		}
+	}
+	return machine.MediaSlot{}, false
+}
+
+func (m *mockMachine) InsertMedia(id, image string, opts machine.InsertOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastInsert = opts
+	if opts.Progress != nil {
+		opts.Progress(100)
+	}
+	if m.mediaErr != nil {
+		return m.mediaErr
+	}
+	slot, ok := m.mediaSlot(id)
+	if !ok {
+		return machine.ErrMediaNotFound
+	}
+	if m.media == nil {
+		m.media = map[string]machine.Media{}
+	}
+	m.media[id] = machine.Media{ID: id, Type: slot.Type, Image: image, Inserted: true, WriteProtected: opts.WriteProtected}
 	m.lastMedia = image
 	m.calls = append(m.calls, "InsertMedia")
 	return nil
 }
 
-func (m *mockMachine) EjectMedia() error {
+func (m *mockMachine) EjectMedia(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.mediaErr != nil {
+		return m.mediaErr
+	}
+	if _, ok := m.mediaSlot(id); !ok {
+		return machine.ErrMediaNotFound
+	}
+	delete(m.media, id)
 	m.lastMedia = ""
 	m.calls = append(m.calls, "EjectMedia")
 	return nil
 }
 
+func (m *mockMachine) GetInventory() (machine.Inventory, error) {
+	if len(m.inventory.CPUs) == 0 {
+		return machine.Inventory{}, machine.ErrInventoryUnavailable
+	}
+	return m.inventory, nil
+}
+
+func (m *mockMachine) GetEnvironment() (machine.Environment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	env := m.environment
+	env.PowerState = m.powerState
+	return env, nil
+}
+
+func (m *mockMachine) GetDrives() ([]machine.Drive, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.powerState == machine.PowerOff {
+		return nil, machine.ErrNotRunning
+	}
+	return append([]machine.Drive(nil), m.drives...), nil
+}
+
+func (m *mockMachine) CreateVolume(name string, sizeBytes int64) (string, error) {
+	if m.volumeBlock != nil {
+		<-m.volumeBlock
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, "CreateVolume")
+	if m.volumeErr != nil {
+		return "", m.volumeErr
+	}
+	id := machine.VolumePrefix + name
+	m.drives = append(m.drives, machine.Drive{
+		ID: id, Format: "qcow2", CapacityBytes: sizeBytes, Inserted: true, Managed: true,
+	})
+	return id, nil
+}
+
+func (m *mockMachine) DeleteVolume(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, "DeleteVolume")
+	for i, d := range m.drives {
+		if d.ID == id && d.Managed {
+			m.drives = append(m.drives[:i], m.drives[i+1:]...)
+			return nil
+		}
+	}
+	return machine.ErrVolumeNotFound
+}
+
+func (m *mockMachine) GetNICs() ([]machine.NIC, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]machine.NIC(nil), m.nics...), nil
+}
+
+func (m *mockMachine) SetNICLink(id string, up bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.nics {
+		if m.nics[i].ID == id {
+			m.nics[i].LinkDisabled = !up
+			m.nics[i].Link = machine.LinkDown
+			if up {
+				m.nics[i].Link = machine.LinkUp
+			}
+			return nil
+		}
+	}
+	return machine.ErrNICNotFound
+}
+
+func (m *mockMachine) SetNICMAC(id, mac string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if mac == "ff:ff:ff:ff:ff:ff" {
+		return machine.ErrInvalidMAC
+	}
+	for i := range m.nics {
+		if m.nics[i].ID == id {
+			m.nics[i].PendingMAC = mac
+			return nil
+		}
+	}
+	return machine.ErrNICNotFound
+}
+
+func (m *mockMachine) GetBios() (machine.BiosSettings, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.bios == nil {
+		return machine.BiosSettings{}, machine.ErrNotSupported
+	}
+	return *m.bios, nil
+}
+
+func (m *mockMachine) GetPendingBios() (machine.BiosSettings, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.bios == nil {
+		return machine.BiosSettings{}, machine.ErrNotSupported
+	}
+	if m.pendingBios != nil {
+		return *m.pendingBios, nil
+	}
+	return *m.bios, nil
+}
+
+func (m *mockMachine) SetPendingBios(s machine.BiosSettings) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.bios == nil {
+		return machine.ErrNotSupported
+	}
+	if s.SecureBoot && s.BootMode != machine.BiosBootModeUEFI {
+		return machine.ErrInvalidBiosSetting
+	}
+	m.pendingBios = &s
+	return nil
+}
+
+func (m *mockMachine) ResetBios() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, "ResetBios")
+	m.pendingBios = nil
+	return nil
+}
+
+func (m *mockMachine) GetBootOptions() ([]machine.BootOption, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.bootOptions == nil {
+		return nil, machine.ErrNotSupported
+	}
+	return append([]machine.BootOption(nil), m.bootOptions...), nil
+}
+
+func (m *mockMachine) GetBootOrder() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.bootOptions == nil {
+		return nil, machine.ErrNotSupported
+	}
+	return append([]string(nil), m.bootOrder...), nil
+}
+
+func (m *mockMachine) SetBootOrder(refs []string) error {
+	if err := m.ValidateBootOrder(refs); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bootOrder = append([]string(nil), refs...)
+	return nil
+}
+
+func (m *mockMachine) ValidateBootOrder(refs []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.bootOptions == nil {
+		return machine.ErrNotSupported
+	}
+	for _, ref := range refs {
+		found := false
+		for _, o := range m.bootOptions {
+			found = found || o.Reference == ref
+		}
+		if !found {
+			return machine.ErrBootOptionNotFound
+		}
+	}
+	return nil
+}
+
 func (m *mockMachine) Calls() []string {
-	return m.calls
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.calls...)
 }
 
 func (m *mockMachine) LastInsertedMedia() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	return m.lastMedia
 }
 
 func TestServiceRoot(t *testing.T) {
 	mock := newMockMachine(qmp.StatusRunning)
-	srv := NewServer(mock, "", "", "")
+	srv := NewServer(mock, bmc.NewState("admin", "password"), "", "", "")
 
 	req := httptest.NewRequest("GET", "/redfish/v1", nil)
 	w := httptest.NewRecorder()
@@ -123,4 +408,9 @@ func TestServiceRoot(t *testing.T) {
 	assert.Equal(t, "/redfish/v1/Systems", root.Systems.ODataID)
 	assert.Equal(t, "/redfish/v1/Managers", root.Managers.ODataID)
 	assert.Equal(t, "/redfish/v1/Chassis", root.Chassis.ODataID)
+	assert.Equal(t, "/redfish/v1/SessionService", root.SessionService.ODataID)
+	assert.Equal(t, "/redfish/v1/AccountService", root.AccountService.ODataID)
+	assert.Equal(t, "/redfish/v1/TaskService", root.TaskService.ODataID)
+	assert.Equal(t, "/redfish/v1/Registries", root.Registries.ODataID)
+	assert.Equal(t, "/redfish/v1/SessionService/Sessions", root.Links.Sessions.ODataID)
 }
@@ -0,0 +1,180 @@
+package redfish
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultSessionTimeout is the idle timeout for Redfish sessions.
+const DefaultSessionTimeout = 30 * time.Minute
+
+// Bounds for SessionService.SessionTimeout (seconds), per the Redfish schema.
+const (
+	minSessionTimeout = 30
+	maxSessionTimeout = 86400
+)
+
+// session is an authenticated Redfish login session
+type session struct {
+	ID         string
+	Token      string
+	UserName   string
+	UserID     uint8  // user slot logged in to, 0 without authentication
+	Generation uint64 // bmc.State.UserGeneration of the slot at login
+	Created    time.Time
+	LastUsed   time.Time
+}
+
+// sessionStore holds the active Redfish sessions. Sessions expire after
+// being idle for longer than the timeout.
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*session // by ID
+	timeout  time.Duration
+	nextID   uint64
+	now      func() time.Time
+}
+
+func newSessionStore(timeout time.Duration) *sessionStore {
+	return &sessionStore{
+		sessions: make(map[string]*session),
+		timeout:  timeout,
+		now:      time.Now,
+	}
+}
+
+// Timeout returns the session idle timeout.
+func (st *sessionStore) Timeout() time.Duration {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.timeout
+}
+
+// SetTimeout changes the session idle timeout.
+func (st *sessionStore) SetTimeout(d time.Duration) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.timeout = d
+}
+
+// Create starts a new session for user, logged in to user slot userID at
+// generation, and returns it.
+func (st *sessionStore) Create(user string, userID uint8, generation uint64) (session, error) {
+	token, err := newSessionToken()
+	if err != nil {
+		return session{}, err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.pruneLocked()
+	st.nextID++
+	now := st.now()
+	sess := &session{
+		ID:         strconv.FormatUint(st.nextID, 10),
+		Token:      token,
+		UserName:   user,
+		UserID:     userID,
+		Generation: generation,
+		Created:    now,
+		LastUsed:   now,
+	}
+	st.sessions[sess.ID] = sess
+	return *sess, nil
+}
+
+// Authenticate returns the session owning token and marks it as used.
+// Expired sessions are removed.
+func (st *sessionStore) Authenticate(token string) (session, bool) {
+	if token == "" {
+		return session{}, false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.pruneLocked()
+	for _, sess := range st.sessions {
+		if subtle.ConstantTimeCompare([]byte(sess.Token), []byte(token)) == 1 {
+			sess.LastUsed = st.now()
+			return *sess, true
+		}
+	}
+	return session{}, false
+}
+
+// Get returns the session with the given ID.
+func (st *sessionStore) Get(id string) (session, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.pruneLocked()
+	sess, ok := st.sessions[id]
+	if !ok {
+		return session{}, false
+	}
+	return *sess, true
+}
+
+// Delete ends the session with the given ID. It returns false if no such
+// session exists.
+func (st *sessionStore) Delete(id string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.sessions[id]; !ok {
+		return false
+	}
+	delete(st.sessions, id)
+	return true
+}
+
+// DeleteUser ends all sessions of user.
+func (st *sessionStore) DeleteUser(user string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for id, sess := range st.sessions {
+		if sess.UserName == user {
+			delete(st.sessions, id)
+		}
+	}
+}
+
+// List returns the IDs of all active sessions in creation order.
+func (st *sessionStore) List() []string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.pruneLocked()
+	ids := make([]uint64, 0, len(st.sessions))
+	for id := range st.sessions {
+		n, _ := strconv.ParseUint(id, 10, 64)
+		ids = append(ids, n)
+	}
+	slices.Sort(ids)
+	out := make([]string, len(ids))
+	for i, n := range ids {
+		out[i] = strconv.FormatUint(n, 10)
+	}
+	return out
+}
+
+func (st *sessionStore) pruneLocked() {
+	if st.timeout <= 0 {
+		return
+	}
+	now := st.now()
+	for id, sess := range st.sessions {
+		if now.Sub(sess.LastUsed) > st.timeout {
+			delete(st.sessions, id)
+		}
+	}
+}
+
+func newSessionToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
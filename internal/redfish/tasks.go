@@ -0,0 +1,183 @@
+package redfish
+
+import (
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Task states and statuses (Redfish Task schema)
+const (
+	taskStateRunning   = "Running"
+	taskStateCompleted = "Completed"
+	taskStateException = "Exception"
+)
+
+// maxTasks bounds the task history; the oldest finished tasks are dropped.
+const maxTasks = 64
+
+// defaultTaskWait is how long an action waits for its task before replying
+// 202 Accepted with a task monitor instead of the final result.
+const defaultTaskWait = time.Second
+
+// taskOp is the work of a task. It may report progress in percent.
+type taskOp func(progress func(percent int)) error
+
+// task is a long-running operation started by a Redfish action
+type task struct {
+	ID          string
+	Name        string
+	successCode int // HTTP status returned by the task monitor on success
+	created     func() string
+	writeErr    errorWriter // writes the response for a failed op
+	done        chan struct{}
+
+	mu        sync.Mutex
+	state     string
+	percent   int
+	startTime time.Time
+	endTime   time.Time
+	err       error
+	location  string // URI of the resource the task created
+}
+
+// taskStore holds running and recently finished tasks
+type taskStore struct {
+	mu     sync.Mutex
+	tasks  map[string]*task
+	nextID uint64
+	now    func() time.Time
+}
+
+func newTaskStore() *taskStore {
+	return &taskStore{
+		tasks: make(map[string]*task),
+		now:   time.Now,
+	}
+}
+
+// Start runs op in the background as a new task. successCode is the status
+// the task monitor reports once op has succeeded. If op creates a resource,
+// created returns its URI once op has succeeded; otherwise it is nil.
+// writeErr writes the response for an error of op, as the request would
+// have been answered without a task.
+func (ts *taskStore) Start(name string, successCode int, op taskOp, created func() string, writeErr errorWriter) *task {
+	ts.mu.Lock()
+	ts.nextID++
+	t := &task{
+		ID:          strconv.FormatUint(ts.nextID, 10),
+		Name:        name,
+		successCode: successCode,
+		created:     created,
+		writeErr:    writeErr,
+		done:        make(chan struct{}),
+		state:       taskStateRunning,
+		startTime:   ts.now(),
+	}
+	ts.tasks[t.ID] = t
+	ts.pruneLocked()
+	ts.mu.Unlock()
+
+	go func() {
+		err := op(func(percent int) {
+			t.mu.Lock()
+			t.percent = percent
+			t.mu.Unlock()
+		})
+		var location string
+		if err == nil && t.created != nil {
+			location = t.created()
+		}
+		t.mu.Lock()
+		t.err = err
+		t.endTime = ts.now()
+		if err != nil {
+			t.state = taskStateException
+		} else {
+			t.state = taskStateCompleted
+			t.percent = 100
+			t.location = location
+		}
+		t.mu.Unlock()
+		close(t.done)
+	}()
+	return t
+}
+
+// Get returns the task with the given ID.
+func (ts *taskStore) Get(id string) (*task, bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	t, ok := ts.tasks[id]
+	return t, ok
+}
+
+// List returns the IDs of all tasks in creation order.
+func (ts *taskStore) List() []string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ids := make([]uint64, 0, len(ts.tasks))
+	for id := range ts.tasks {
+		n, _ := strconv.ParseUint(id, 10, 64)
+		ids = append(ids, n)
+	}
+	slices.Sort(ids)
+	out := make([]string, len(ids))
+	for i, n := range ids {
+		out[i] = strconv.FormatUint(n, 10)
+	}
+	return out
+}
+
+// pruneLocked drops the oldest finished tasks beyond maxTasks.
+func (ts *taskStore) pruneLocked() {
+	for len(ts.tasks) > maxTasks {
+		var oldest *task
+		for _, t := range ts.tasks {
+			if !t.finished() {
+				continue
+			}
+			if oldest == nil || t.startTime.Before(oldest.startTime) {
+				oldest = t
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		delete(ts.tasks, oldest.ID)
+	}
+}
+
+// Wait waits up to d for t to finish and reports whether it has.
+func (t *task) Wait(d time.Duration) bool {
+	select {
+	case <-t.done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+func (t *task) finished() bool {
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Location returns the URI of the resource a finished task created, or ""
+func (t *task) Location() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.location
+}
+
+// Err returns the error of a finished task.
+func (t *task) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
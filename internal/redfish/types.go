@@ -1,5 +1,7 @@
 package redfish
 
+import "encoding/json"
+
 // ODataID represents an OData reference
 type ODataID struct {
 	ODataID string `json:"@odata.id"`
@@ -7,15 +9,26 @@ type ODataID struct {
 
 // ServiceRoot is the Redfish service root
 type ServiceRoot struct {
-	ODataType      string  `json:"@odata.type"`
-	ODataID        string  `json:"@odata.id"`
-	ODataContext   string  `json:"@odata.context,omitempty"`
-	ID             string  `json:"Id"`
-	Name           string  `json:"Name"`
-	RedfishVersion string  `json:"RedfishVersion"`
-	Systems        ODataID `json:"Systems"`
-	Managers       ODataID `json:"Managers"`
-	Chassis        ODataID `json:"Chassis"`
+	ODataType      string           `json:"@odata.type"`
+	ODataID        string           `json:"@odata.id"`
+	ODataContext   string           `json:"@odata.context,omitempty"`
+	ID             string           `json:"Id"`
+	Name           string           `json:"Name"`
+	RedfishVersion string           `json:"RedfishVersion"`
+	Systems        ODataID          `json:"Systems"`
+	Managers       ODataID          `json:"Managers"`
+	Chassis        ODataID          `json:"Chassis"`
+	SessionService ODataID          `json:"SessionService"`
+	AccountService ODataID          `json:"AccountService"`
+	EventService   ODataID          `json:"EventService"`
+	TaskService    ODataID          `json:"TaskService"`
+	Registries     ODataID          `json:"Registries"`
+	Links          ServiceRootLinks `json:"Links"`
+}
+
+// ServiceRootLinks contains the service root links
+type ServiceRootLinks struct {
+	Sessions ODataID `json:"Sessions"`
 }
 
 // SystemCollection is a collection of computer systems
@@ -35,9 +48,58 @@ type ComputerSystem struct {
 	ODataEtag    string                `json:"@odata.etag,omitempty"`
 	ID           string                `json:"Id"`
 	Name         string                `json:"Name"`
+	HostName     string                `json:"HostName,omitempty"`
 	PowerState   string                `json:"PowerState"`
+	Status       Status                `json:"Status"`
 	Boot         BootSource            `json:"Boot"`
 	Actions      ComputerSystemActions `json:"Actions"`
+	Oem          *ComputerSystemOem    `json:"Oem,omitempty"`
+
+	ProcessorSummary *ProcessorSummary `json:"ProcessorSummary,omitempty"`
+	MemorySummary    *MemorySummary    `json:"MemorySummary,omitempty"`
+	Processors       ODataID           `json:"Processors"`
+	Memory           ODataID           `json:"Memory"`
+	Storage          ODataID           `json:"Storage"`
+
+	EthernetInterfaces ODataID `json:"EthernetInterfaces"`
+	Bios               ODataID `json:"Bios"`
+	VirtualMedia       ODataID `json:"VirtualMedia"`
+	LogServices        ODataID `json:"LogServices"`
+}
+
+// Status is the common Redfish resource status
+type Status struct {
+	State  string `json:"State"`
+	Health string `json:"Health,omitempty"`
+}
+
+// ProcessorSummary summarizes the processors of a system
+type ProcessorSummary struct {
+	Count                 int    `json:"Count"`
+	CoreCount             int    `json:"CoreCount"`
+	LogicalProcessorCount int    `json:"LogicalProcessorCount"`
+	Model                 string `json:"Model,omitempty"`
+	Status                Status `json:"Status"`
+}
+
+// MemorySummary summarizes the memory of a system
+type MemorySummary struct {
+	TotalSystemMemoryGiB float64 `json:"TotalSystemMemoryGiB"`
+	Status               Status  `json:"Status"`
+}
+
+// ComputerSystemOem holds qemu-bmc specific ComputerSystem properties
+type ComputerSystemOem struct {
+	QemuBmc ComputerSystemOemQemuBmc `json:"QemuBmc"`
+}
+
+// ComputerSystemOemQemuBmc reports the operating system information the guest
+// published through the IPMI System Info Parameters
+type ComputerSystemOemQemuBmc struct {
+	FirmwareVersion string `json:"FirmwareVersion,omitempty"`
+	PrimaryOSName   string `json:"PrimaryOperatingSystemName,omitempty"`
+	OSName          string `json:"OperatingSystemName,omitempty"`
+	OSVersion       string `json:"OperatingSystemVersion,omitempty"`
 }
 
 // BootSource represents boot source override
@@ -46,6 +108,11 @@ type BootSource struct {
 	BootSourceOverrideTarget  string   `json:"BootSourceOverrideTarget"`
 	BootSourceOverrideMode    string   `json:"BootSourceOverrideMode"`
 	AllowableValues           []string `json:"BootSourceOverrideTarget@Redfish.AllowableValues"`
+	BootOrder                 []string `json:"BootOrder,omitempty"`
+	BootOptions               *ODataID `json:"BootOptions,omitempty"`
+
+	HttpBootUri                  string `json:"HttpBootUri,omitempty"`
+	UefiTargetBootSourceOverride string `json:"UefiTargetBootSourceOverride,omitempty"`
 }
 
 // ComputerSystemActions contains available actions
@@ -71,9 +138,13 @@ type PatchSystemRequest struct {
 
 // PatchBootSource is the boot source in a patch request
 type PatchBootSource struct {
-	BootSourceOverrideEnabled string `json:"BootSourceOverrideEnabled,omitempty"`
-	BootSourceOverrideTarget  string `json:"BootSourceOverrideTarget,omitempty"`
-	BootSourceOverrideMode    string `json:"BootSourceOverrideMode,omitempty"`
+	BootSourceOverrideEnabled string   `json:"BootSourceOverrideEnabled,omitempty"`
+	BootSourceOverrideTarget  string   `json:"BootSourceOverrideTarget,omitempty"`
+	BootSourceOverrideMode    string   `json:"BootSourceOverrideMode,omitempty"`
+	BootOrder                 []string `json:"BootOrder,omitempty"`
+
+	HttpBootUri                  *string `json:"HttpBootUri,omitempty"`
+	UefiTargetBootSourceOverride *string `json:"UefiTargetBootSourceOverride,omitempty"`
 }
 
 // RedfishError is a Redfish error response
@@ -83,8 +154,18 @@ type RedfishError struct {
 
 // RedfishErrorBody is the body of a Redfish error
 type RedfishErrorBody struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code         string        `json:"code"`
+	Message      string        `json:"message"`
+	ExtendedInfo []MessageInfo `json:"@Message.ExtendedInfo,omitempty"`
+}
+
+// MessageInfo is a registry message in @Message.ExtendedInfo
+type MessageInfo struct {
+	ODataType  string `json:"@odata.type"`
+	MessageID  string `json:"MessageId"`
+	Message    string `json:"Message"`
+	Severity   string `json:"Severity"`
+	Resolution string `json:"Resolution,omitempty"`
 }
 
 // ManagerCollection is a collection of managers
@@ -98,13 +179,46 @@ type ManagerCollection struct {
 
 // Manager represents a BMC manager
 type Manager struct {
-	ODataType    string  `json:"@odata.type"`
-	ODataID      string  `json:"@odata.id"`
-	ODataContext string  `json:"@odata.context,omitempty"`
-	ID           string  `json:"Id"`
-	Name         string  `json:"Name"`
-	ManagerType  string  `json:"ManagerType"`
-	VirtualMedia ODataID `json:"VirtualMedia"`
+	ODataType    string      `json:"@odata.type"`
+	ODataID      string      `json:"@odata.id"`
+	ODataContext string      `json:"@odata.context,omitempty"`
+	ID           string      `json:"Id"`
+	Name         string      `json:"Name"`
+	ManagerType  string      `json:"ManagerType"`
+	VirtualMedia ODataID     `json:"VirtualMedia"`
+	LogServices  ODataID     `json:"LogServices"`
+	Oem          *ManagerOem `json:"Oem,omitempty"`
+}
+
+// ManagerOem holds qemu-bmc specific Manager properties
+type ManagerOem struct {
+	QemuBmc ManagerOemQemuBmc `json:"QemuBmc"`
+}
+
+// ManagerOemQemuBmc links the images uploaded for virtual media
+type ManagerOemQemuBmc struct {
+	MediaImages ODataID `json:"MediaImages"`
+}
+
+// MediaImageCollection is the collection of uploaded images
+type MediaImageCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// MediaImage is an image uploaded for virtual media. Its @odata.id is
+// accepted as the Image of InsertMedia.
+type MediaImage struct {
+	ODataType string `json:"@odata.type"`
+	ODataID   string `json:"@odata.id"`
+	ID        string `json:"Id"`
+	Name      string `json:"Name"`
+	SizeBytes int64  `json:"SizeBytes"`
+	SHA256    string `json:"SHA256"`
+	Created   string `json:"Created"`
 }
 
 // VirtualMediaCollection is a collection of virtual media
@@ -118,16 +232,38 @@ type VirtualMediaCollection struct {
 
 // VirtualMedia represents a virtual media resource
 type VirtualMedia struct {
-	ODataType    string              `json:"@odata.type"`
-	ODataID      string              `json:"@odata.id"`
-	ODataContext string              `json:"@odata.context,omitempty"`
-	ID           string              `json:"Id"`
-	Name         string              `json:"Name"`
-	MediaTypes   []string            `json:"MediaTypes"`
-	Image        string              `json:"Image,omitempty"`
-	Inserted     bool                `json:"Inserted"`
-	ConnectedVia string              `json:"ConnectedVia,omitempty"`
-	Actions      VirtualMediaActions `json:"Actions"`
+	ODataType            string              `json:"@odata.type"`
+	ODataID              string              `json:"@odata.id"`
+	ODataContext         string              `json:"@odata.context,omitempty"`
+	ID                   string              `json:"Id"`
+	Name                 string              `json:"Name"`
+	MediaTypes           []string            `json:"MediaTypes"`
+	Image                string              `json:"Image,omitempty"`
+	Inserted             bool                `json:"Inserted"`
+	WriteProtected       bool                `json:"WriteProtected"`
+	TransferProtocolType string              `json:"TransferProtocolType,omitempty"`
+	ConnectedVia         string              `json:"ConnectedVia,omitempty"`
+	Actions              VirtualMediaActions `json:"Actions"`
+	Oem                  *VirtualMediaOem    `json:"Oem,omitempty"`
+}
+
+// VirtualMediaOem holds qemu-bmc specific VirtualMedia properties
+type VirtualMediaOem struct {
+	QemuBmc VirtualMediaOemQemuBmc `json:"QemuBmc"`
+}
+
+// VirtualMediaOemQemuBmc reports how the inserted image was verified
+type VirtualMediaOemQemuBmc struct {
+	ImageVerification ImageVerification `json:"ImageVerification"`
+}
+
+// ImageVerification is the result of checking an image against the
+// checksum and signature given with InsertMedia
+type ImageVerification struct {
+	SHA256            string `json:"SHA256"`
+	ChecksumVerified  bool   `json:"ChecksumVerified"`
+	SignatureVerified bool   `json:"SignatureVerified"`
+	Signer            string `json:"Signer,omitempty"`
 }
 
 // VirtualMediaActions contains available actions for virtual media
@@ -143,8 +279,36 @@ type VirtualMediaAction struct {
 
 // InsertMediaRequest is the request body for inserting virtual media
 type InsertMediaRequest struct {
-	Image    string `json:"Image"`
-	Inserted bool   `json:"Inserted"`
+	Image                string          `json:"Image"`
+	Inserted             bool            `json:"Inserted"`
+	WriteProtected       *bool           `json:"WriteProtected,omitempty"` // default true
+	TransferProtocolType string          `json:"TransferProtocolType,omitempty"`
+	UserName             string          `json:"UserName,omitempty"` // for downloading Image
+	Password             string          `json:"Password,omitempty"`
+	Oem                  *InsertMediaOem `json:"Oem,omitempty"`
+}
+
+// InsertMediaOem holds the qemu-bmc specific InsertMedia parameters
+type InsertMediaOem struct {
+	QemuBmc InsertMediaOemQemuBmc `json:"QemuBmc"`
+}
+
+// InsertMediaOemQemuBmc is what the image is verified against before it is
+// inserted: its SHA-256 (hex) and a detached signature (base64)
+type InsertMediaOemQemuBmc struct {
+	SHA256    string `json:"SHA256,omitempty"`
+	Signature string `json:"Signature,omitempty"`
+}
+
+// PatchVirtualMediaRequest is the request body for PATCH on virtual media.
+// Image is raw so that null, which ejects, can be told from absent.
+type PatchVirtualMediaRequest struct {
+	Image                json.RawMessage `json:"Image,omitempty"`
+	Inserted             *bool           `json:"Inserted,omitempty"`
+	WriteProtected       *bool           `json:"WriteProtected,omitempty"`
+	TransferProtocolType *string         `json:"TransferProtocolType,omitempty"`
+	UserName             string          `json:"UserName,omitempty"`
+	Password             string          `json:"Password,omitempty"`
 }
 
 // ChassisCollection is a collection of chassis
@@ -158,10 +322,740 @@ type ChassisCollection struct {
 
 // Chassis represents a chassis resource
 type Chassis struct {
-	ODataType    string `json:"@odata.type"`
-	ODataID      string `json:"@odata.id"`
-	ODataContext string `json:"@odata.context,omitempty"`
-	ID           string `json:"Id"`
-	Name         string `json:"Name"`
-	ChassisType  string `json:"ChassisType"`
+	ODataType    string  `json:"@odata.type"`
+	ODataID      string  `json:"@odata.id"`
+	ODataContext string  `json:"@odata.context,omitempty"`
+	ID           string  `json:"Id"`
+	Name         string  `json:"Name"`
+	ChassisType  string  `json:"ChassisType"`
+	PowerState   string  `json:"PowerState,omitempty"`
+	Status       *Status `json:"Status,omitempty"`
+	Power        ODataID `json:"Power"`
+	Thermal      ODataID `json:"Thermal"`
+	Sensors      ODataID `json:"Sensors"`
+}
+
+// Thermal holds the temperatures and fans of a chassis
+type Thermal struct {
+	ODataType    string        `json:"@odata.type"`
+	ODataID      string        `json:"@odata.id"`
+	ID           string        `json:"Id"`
+	Name         string        `json:"Name"`
+	Status       Status        `json:"Status"`
+	Temperatures []Temperature `json:"Temperatures"`
+	Fans         []Fan         `json:"Fans"`
+}
+
+// Temperature is a temperature sensor of a Thermal resource. Thresholds
+// that do not apply are omitted.
+type Temperature struct {
+	ODataID                   string   `json:"@odata.id"`
+	MemberID                  string   `json:"MemberId"`
+	Name                      string   `json:"Name"`
+	SensorNumber              int      `json:"SensorNumber"`
+	ReadingCelsius            float64  `json:"ReadingCelsius"`
+	UpperThresholdNonCritical *float64 `json:"UpperThresholdNonCritical,omitempty"`
+	UpperThresholdCritical    *float64 `json:"UpperThresholdCritical,omitempty"`
+	UpperThresholdFatal       *float64 `json:"UpperThresholdFatal,omitempty"`
+	PhysicalContext           string   `json:"PhysicalContext"`
+	Status                    Status   `json:"Status"`
+}
+
+// Fan is a fan of a Thermal resource
+type Fan struct {
+	ODataID                   string   `json:"@odata.id"`
+	MemberID                  string   `json:"MemberId"`
+	Name                      string   `json:"Name"`
+	SensorNumber              int      `json:"SensorNumber"`
+	Reading                   float64  `json:"Reading"`
+	ReadingUnits              string   `json:"ReadingUnits"`
+	LowerThresholdNonCritical *float64 `json:"LowerThresholdNonCritical,omitempty"`
+	LowerThresholdCritical    *float64 `json:"LowerThresholdCritical,omitempty"`
+	PhysicalContext           string   `json:"PhysicalContext"`
+	Status                    Status   `json:"Status"`
+}
+
+// Power holds the power consumption of a chassis
+type Power struct {
+	ODataType    string         `json:"@odata.type"`
+	ODataID      string         `json:"@odata.id"`
+	ID           string         `json:"Id"`
+	Name         string         `json:"Name"`
+	PowerControl []PowerControl `json:"PowerControl"`
+}
+
+// PowerControl reports the power drawn by a chassis
+type PowerControl struct {
+	ODataID            string  `json:"@odata.id"`
+	MemberID           string  `json:"MemberId"`
+	Name               string  `json:"Name"`
+	PowerConsumedWatts float64 `json:"PowerConsumedWatts"`
+	PowerCapacityWatts float64 `json:"PowerCapacityWatts"`
+	PhysicalContext    string  `json:"PhysicalContext"`
+	Status             Status  `json:"Status"`
+}
+
+// SensorCollection is a collection of sensors
+type SensorCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// Sensor is a single sensor reading. Reading is null while the sensor
+// cannot be read.
+type Sensor struct {
+	ODataType       string            `json:"@odata.type"`
+	ODataID         string            `json:"@odata.id"`
+	ID              string            `json:"Id"`
+	Name            string            `json:"Name"`
+	ReadingType     string            `json:"ReadingType"`
+	Reading         *float64          `json:"Reading"`
+	ReadingUnits    string            `json:"ReadingUnits"`
+	PhysicalContext string            `json:"PhysicalContext"`
+	Thresholds      *SensorThresholds `json:"Thresholds,omitempty"`
+	Status          Status            `json:"Status"`
+}
+
+// SensorThresholds are the thresholds of a sensor, nil if they do not apply
+type SensorThresholds struct {
+	UpperCaution  *Threshold `json:"UpperCaution,omitempty"`
+	UpperCritical *Threshold `json:"UpperCritical,omitempty"`
+	UpperFatal    *Threshold `json:"UpperFatal,omitempty"`
+	LowerCaution  *Threshold `json:"LowerCaution,omitempty"`
+	LowerCritical *Threshold `json:"LowerCritical,omitempty"`
+}
+
+// Threshold is a sensor threshold
+type Threshold struct {
+	Reading float64 `json:"Reading"`
+}
+
+// SessionService represents the Redfish session service
+type SessionService struct {
+	ODataType      string  `json:"@odata.type"`
+	ODataID        string  `json:"@odata.id"`
+	ID             string  `json:"Id"`
+	Name           string  `json:"Name"`
+	ServiceEnabled bool    `json:"ServiceEnabled"`
+	SessionTimeout int     `json:"SessionTimeout"`
+	Sessions       ODataID `json:"Sessions"`
+}
+
+// PatchSessionServiceRequest is the request body for patching the session service
+type PatchSessionServiceRequest struct {
+	SessionTimeout *int `json:"SessionTimeout,omitempty"`
+}
+
+// SessionCollection is a collection of sessions
+type SessionCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// Session represents a Redfish login session
+type Session struct {
+	ODataType   string `json:"@odata.type"`
+	ODataID     string `json:"@odata.id"`
+	ID          string `json:"Id"`
+	Name        string `json:"Name"`
+	UserName    string `json:"UserName"`
+	CreatedTime string `json:"CreatedTime,omitempty"`
+}
+
+// CreateSessionRequest is the request body for creating a session
+type CreateSessionRequest struct {
+	UserName string `json:"UserName"`
+	Password string `json:"Password"`
+}
+
+// AccountService represents the Redfish account service
+type AccountService struct {
+	ODataType         string  `json:"@odata.type"`
+	ODataID           string  `json:"@odata.id"`
+	ID                string  `json:"Id"`
+	Name              string  `json:"Name"`
+	ServiceEnabled    bool    `json:"ServiceEnabled"`
+	MaxPasswordLength int     `json:"MaxPasswordLength"`
+	Accounts          ODataID `json:"Accounts"`
+	Roles             ODataID `json:"Roles"`
+}
+
+// AccountCollection is a collection of manager accounts
+type AccountCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// ManagerAccount represents a user account. The password is never returned.
+type ManagerAccount struct {
+	ODataType string              `json:"@odata.type"`
+	ODataID   string              `json:"@odata.id"`
+	ID        string              `json:"Id"`
+	Name      string              `json:"Name"`
+	UserName  string              `json:"UserName"`
+	RoleID    string              `json:"RoleId"`
+	Enabled   bool                `json:"Enabled"`
+	Locked    bool                `json:"Locked"`
+	Password  *string             `json:"Password"`
+	Links     ManagerAccountLinks `json:"Links"`
+}
+
+// ManagerAccountLinks contains the links of a manager account
+type ManagerAccountLinks struct {
+	Role ODataID `json:"Role"`
+}
+
+// CreateAccountRequest is the request body for creating an account
+type CreateAccountRequest struct {
+	UserName string `json:"UserName"`
+	Password string `json:"Password"`
+	RoleID   string `json:"RoleId"`
+	Enabled  *bool  `json:"Enabled,omitempty"`
+}
+
+// PatchAccountRequest is the request body for patching an account
+type PatchAccountRequest struct {
+	UserName *string `json:"UserName,omitempty"`
+	Password *string `json:"Password,omitempty"`
+	RoleID   *string `json:"RoleId,omitempty"`
+	Enabled  *bool   `json:"Enabled,omitempty"`
+}
+
+// RoleCollection is a collection of roles
+type RoleCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// Role represents a predefined Redfish role
+type Role struct {
+	ODataType          string   `json:"@odata.type"`
+	ODataID            string   `json:"@odata.id"`
+	ID                 string   `json:"Id"`
+	Name               string   `json:"Name"`
+	RoleID             string   `json:"RoleId"`
+	IsPredefined       bool     `json:"IsPredefined"`
+	AssignedPrivileges []string `json:"AssignedPrivileges"`
+}
+
+// EventService represents the Redfish event service
+type EventService struct {
+	ODataType                    string              `json:"@odata.type"`
+	ODataID                      string              `json:"@odata.id"`
+	ID                           string              `json:"Id"`
+	Name                         string              `json:"Name"`
+	ServiceEnabled               bool                `json:"ServiceEnabled"`
+	DeliveryRetryAttempts        int                 `json:"DeliveryRetryAttempts"`
+	DeliveryRetryIntervalSeconds int                 `json:"DeliveryRetryIntervalSeconds"`
+	EventFormatTypes             []string            `json:"EventFormatTypes"`
+	RegistryPrefixes             []string            `json:"RegistryPrefixes"`
+	ResourceTypes                []string            `json:"ResourceTypes"`
+	ServerSentEventURI           string              `json:"ServerSentEventUri"`
+	Subscriptions                ODataID             `json:"Subscriptions"`
+	Actions                      EventServiceActions `json:"Actions"`
+}
+
+// EventServiceActions contains available actions for the event service
+type EventServiceActions struct {
+	SubmitTestEvent ActionTarget `json:"#EventService.SubmitTestEvent"`
+}
+
+// ActionTarget describes an action without parameters to advertise
+type ActionTarget struct {
+	Target string `json:"target"`
+}
+
+// EventDestinationCollection is a collection of event subscriptions
+type EventDestinationCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// EventDestination represents an event subscription
+type EventDestination struct {
+	ODataType        string   `json:"@odata.type"`
+	ODataID          string   `json:"@odata.id"`
+	ID               string   `json:"Id"`
+	Name             string   `json:"Name"`
+	Destination      string   `json:"Destination"`
+	Context          string   `json:"Context"`
+	Protocol         string   `json:"Protocol"`
+	SubscriptionType string   `json:"SubscriptionType"`
+	EventFormatType  string   `json:"EventFormatType"`
+	RegistryPrefixes []string `json:"RegistryPrefixes"`
+	ResourceTypes    []string `json:"ResourceTypes"`
+}
+
+// CreateSubscriptionRequest is the request body for creating a subscription
+type CreateSubscriptionRequest struct {
+	Destination      string   `json:"Destination"`
+	Context          string   `json:"Context"`
+	Protocol         string   `json:"Protocol"`
+	RegistryPrefixes []string `json:"RegistryPrefixes"`
+	ResourceTypes    []string `json:"ResourceTypes"`
+}
+
+// Event is the payload delivered to event subscribers
+type Event struct {
+	ODataType string        `json:"@odata.type"`
+	ID        string        `json:"Id"`
+	Name      string        `json:"Name"`
+	Context   string        `json:"Context,omitempty"`
+	Events    []EventRecord `json:"Events"`
+}
+
+// EventRecord is a single event in an Event payload
+type EventRecord struct {
+	EventType         string   `json:"EventType"`
+	EventID           string   `json:"EventId"`
+	EventTimestamp    string   `json:"EventTimestamp"`
+	Severity          string   `json:"Severity"`
+	Message           string   `json:"Message"`
+	MessageID         string   `json:"MessageId"`
+	MessageArgs       []string `json:"MessageArgs,omitempty"`
+	OriginOfCondition *ODataID `json:"OriginOfCondition,omitempty"`
+
+	resourceType string // for ResourceTypes filtering; not serialized
+}
+
+// SubmitTestEventRequest is the request body for EventService.SubmitTestEvent
+type SubmitTestEventRequest struct {
+	EventType         string   `json:"EventType"`
+	EventID           string   `json:"EventId"`
+	EventTimestamp    string   `json:"EventTimestamp"`
+	Severity          string   `json:"Severity"`
+	Message           string   `json:"Message"`
+	MessageID         string   `json:"MessageId"`
+	MessageArgs       []string `json:"MessageArgs"`
+	OriginOfCondition string   `json:"OriginOfCondition"`
+}
+
+// LogServiceCollection is a collection of log services
+type LogServiceCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// LogService represents a log of the system or the manager
+type LogService struct {
+	ODataType          string            `json:"@odata.type"`
+	ODataID            string            `json:"@odata.id"`
+	ID                 string            `json:"Id"`
+	Name               string            `json:"Name"`
+	Description        string            `json:"Description"`
+	ServiceEnabled     bool              `json:"ServiceEnabled"`
+	MaxNumberOfRecords int               `json:"MaxNumberOfRecords"`
+	OverWritePolicy    string            `json:"OverWritePolicy"`
+	LogEntryType       string            `json:"LogEntryType"`
+	DateTime           string            `json:"DateTime"`
+	Entries            ODataID           `json:"Entries"`
+	Actions            LogServiceActions `json:"Actions"`
+}
+
+// LogServiceActions contains available actions for a log service
+type LogServiceActions struct {
+	ClearLog ActionTarget `json:"#LogService.ClearLog"`
+}
+
+// LogEntryCollection is a page of log entries, expanded
+type LogEntryCollection struct {
+	ODataType    string     `json:"@odata.type"`
+	ODataID      string     `json:"@odata.id"`
+	Name         string     `json:"Name"`
+	MembersCount int        `json:"Members@odata.count"`
+	Members      []LogEntry `json:"Members"`
+	NextLink     string     `json:"Members@odata.nextLink,omitempty"`
+}
+
+// LogEntry is a record of a log service
+type LogEntry struct {
+	ODataType       string   `json:"@odata.type"`
+	ODataID         string   `json:"@odata.id"`
+	ID              string   `json:"Id"`
+	Name            string   `json:"Name"`
+	EntryType       string   `json:"EntryType"`
+	OemRecordFormat string   `json:"OemRecordFormat,omitempty"`
+	Severity        string   `json:"Severity"`
+	Created         string   `json:"Created"`
+	Message         string   `json:"Message"`
+	MessageID       string   `json:"MessageId,omitempty"`
+	MessageArgs     []string `json:"MessageArgs,omitempty"`
+	SensorType      string   `json:"SensorType,omitempty"`
+	SensorNumber    *int     `json:"SensorNumber,omitempty"`
+	EntryCode       string   `json:"EntryCode,omitempty"`
+	GeneratorID     string   `json:"GeneratorId,omitempty"`
+}
+
+// TaskService represents the Redfish task service
+type TaskService struct {
+	ODataType                    string  `json:"@odata.type"`
+	ODataID                      string  `json:"@odata.id"`
+	ID                           string  `json:"Id"`
+	Name                         string  `json:"Name"`
+	ServiceEnabled               bool    `json:"ServiceEnabled"`
+	CompletedTaskOverWritePolicy string  `json:"CompletedTaskOverWritePolicy"`
+	Tasks                        ODataID `json:"Tasks"`
+}
+
+// TaskCollection is a collection of tasks
+type TaskCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// Task represents a long-running operation
+type Task struct {
+	ODataType       string        `json:"@odata.type"`
+	ODataID         string        `json:"@odata.id"`
+	ID              string        `json:"Id"`
+	Name            string        `json:"Name"`
+	TaskState       string        `json:"TaskState"`
+	TaskStatus      string        `json:"TaskStatus"`
+	PercentComplete int           `json:"PercentComplete"`
+	StartTime       string        `json:"StartTime"`
+	EndTime         string        `json:"EndTime,omitempty"`
+	TaskMonitor     string        `json:"TaskMonitor"`
+	Messages        []MessageInfo `json:"Messages"`
+	Payload         *TaskPayload  `json:"Payload,omitempty"`
+}
+
+// TaskPayload describes the response of a finished task
+type TaskPayload struct {
+	HTTPHeaders []string `json:"HttpHeaders"`
+}
+
+// ProcessorCollection is a collection of processors
+type ProcessorCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// Processor represents one CPU socket of the VM
+type Processor struct {
+	ODataType             string `json:"@odata.type"`
+	ODataID               string `json:"@odata.id"`
+	ID                    string `json:"Id"`
+	Name                  string `json:"Name"`
+	Socket                string `json:"Socket"`
+	ProcessorType         string `json:"ProcessorType"`
+	ProcessorArchitecture string `json:"ProcessorArchitecture"`
+	InstructionSet        string `json:"InstructionSet"`
+	Model                 string `json:"Model,omitempty"`
+	TotalCores            int    `json:"TotalCores"`
+	TotalThreads          int    `json:"TotalThreads"`
+	Status                Status `json:"Status"`
+}
+
+// MemoryCollection is a collection of memory devices
+type MemoryCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// Memory represents the VM's RAM as a single memory device
+type Memory struct {
+	ODataType        string `json:"@odata.type"`
+	ODataID          string `json:"@odata.id"`
+	ID               string `json:"Id"`
+	Name             string `json:"Name"`
+	MemoryType       string `json:"MemoryType"`
+	MemoryDeviceType string `json:"MemoryDeviceType"`
+	CapacityMiB      int    `json:"CapacityMiB"`
+	Status           Status `json:"Status"`
+}
+
+// StorageCollection is a collection of storage subsystems
+type StorageCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// Storage represents the QEMU block layer as a storage subsystem
+type Storage struct {
+	ODataType   string    `json:"@odata.type"`
+	ODataID     string    `json:"@odata.id"`
+	ID          string    `json:"Id"`
+	Name        string    `json:"Name"`
+	Drives      []ODataID `json:"Drives"`
+	DrivesCount int       `json:"Drives@odata.count"`
+	Volumes     ODataID   `json:"Volumes"`
+	Status      Status    `json:"Status"`
+}
+
+// Drive represents a block device of the VM
+type Drive struct {
+	ODataType     string      `json:"@odata.type"`
+	ODataID       string      `json:"@odata.id"`
+	ID            string      `json:"Id"`
+	Name          string      `json:"Name"`
+	CapacityBytes int64       `json:"CapacityBytes"`
+	MediaType     string      `json:"MediaType,omitempty"`
+	Status        Status      `json:"Status"`
+	Links         DriveLinks  `json:"Links"`
+	Oem           *StorageOem `json:"Oem,omitempty"`
+}
+
+// DriveLinks links a drive to the volumes on it
+type DriveLinks struct {
+	Volumes []ODataID `json:"Volumes"`
+}
+
+// VolumeCollection is a collection of volumes
+type VolumeCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// Volume represents a disk image attached to the VM
+type Volume struct {
+	ODataType          string      `json:"@odata.type"`
+	ODataID            string      `json:"@odata.id"`
+	ID                 string      `json:"Id"`
+	Name               string      `json:"Name"`
+	CapacityBytes      int64       `json:"CapacityBytes"`
+	VolumeType         string      `json:"VolumeType"`
+	AccessCapabilities []string    `json:"AccessCapabilities"`
+	Status             Status      `json:"Status"`
+	Links              VolumeLinks `json:"Links"`
+	Oem                *StorageOem `json:"Oem,omitempty"`
+}
+
+// VolumeLinks links a volume to its drives
+type VolumeLinks struct {
+	Drives []ODataID `json:"Drives"`
+}
+
+// StorageOem holds qemu-bmc specific Drive and Volume properties
+type StorageOem struct {
+	QemuBmc StorageOemQemuBmc `json:"QemuBmc"`
+}
+
+// StorageOemQemuBmc reports the image behind a drive or volume
+type StorageOemQemuBmc struct {
+	File            string `json:"File,omitempty"`
+	Format          string `json:"Format,omitempty"`
+	BackingFile     string `json:"BackingFile,omitempty"`
+	ReadOnly        bool   `json:"ReadOnly"`
+	Removable       bool   `json:"Removable"`
+	ReadBytes       int64  `json:"ReadBytes"`
+	WriteBytes      int64  `json:"WriteBytes"`
+	ReadOperations  int64  `json:"ReadOperations"`
+	WriteOperations int64  `json:"WriteOperations"`
+}
+
+// CreateVolumeRequest is the request body for creating a volume
+type CreateVolumeRequest struct {
+	Name          string `json:"Name"`
+	CapacityBytes int64  `json:"CapacityBytes"`
+}
+
+// EthernetInterfaceCollection is a collection of network interfaces
+type EthernetInterfaceCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// EthernetInterface represents a NIC of the VM
+type EthernetInterface struct {
+	ODataType        string                `json:"@odata.type"`
+	ODataID          string                `json:"@odata.id"`
+	ID               string                `json:"Id"`
+	Name             string                `json:"Name"`
+	Description      string                `json:"Description,omitempty"`
+	MACAddress       string                `json:"MACAddress,omitempty"`
+	LinkStatus       string                `json:"LinkStatus,omitempty"`
+	InterfaceEnabled bool                  `json:"InterfaceEnabled"`
+	Status           Status                `json:"Status"`
+	IPv4Addresses    []IPv4Address         `json:"IPv4Addresses"`
+	IPv6Addresses    []IPv6Address         `json:"IPv6Addresses"`
+	Oem              *EthernetInterfaceOem `json:"Oem,omitempty"`
+}
+
+// IPv4Address is an IPv4 address of an interface
+type IPv4Address struct {
+	Address    string `json:"Address"`
+	SubnetMask string `json:"SubnetMask"`
+}
+
+// IPv6Address is an IPv6 address of an interface
+type IPv6Address struct {
+	Address      string `json:"Address"`
+	PrefixLength int    `json:"PrefixLength"`
+}
+
+// EthernetInterfaceOem holds qemu-bmc specific EthernetInterface properties
+type EthernetInterfaceOem struct {
+	QemuBmc EthernetInterfaceOemQemuBmc `json:"QemuBmc"`
+}
+
+// EthernetInterfaceOemQemuBmc reports the NIC model and a MAC address
+// change waiting for the next power on
+type EthernetInterfaceOemQemuBmc struct {
+	Model             string `json:"Model,omitempty"`
+	PendingMACAddress string `json:"PendingMACAddress,omitempty"`
+}
+
+// PatchEthernetInterfaceRequest is the request body for PATCH on an interface
+type PatchEthernetInterfaceRequest struct {
+	MACAddress       *string `json:"MACAddress"`
+	InterfaceEnabled *bool   `json:"InterfaceEnabled"`
+}
+
+// Bios is the BIOS resource of a system, for both the current and the
+// pending (Settings) attributes
+type Bios struct {
+	ODataType         string                 `json:"@odata.type"`
+	ODataID           string                 `json:"@odata.id"`
+	ID                string                 `json:"Id"`
+	Name              string                 `json:"Name"`
+	AttributeRegistry string                 `json:"AttributeRegistry"`
+	Attributes        map[string]interface{} `json:"Attributes"`
+	Settings          *RedfishSettings       `json:"@Redfish.Settings,omitempty"`
+	Actions           *BiosActions           `json:"Actions,omitempty"`
+}
+
+// RedfishSettings links a resource to the settings object holding its
+// pending changes
+type RedfishSettings struct {
+	ODataType           string   `json:"@odata.type"`
+	SettingsObject      ODataID  `json:"SettingsObject"`
+	SupportedApplyTimes []string `json:"SupportedApplyTimes"`
+}
+
+// BiosActions lists the actions of the Bios resource
+type BiosActions struct {
+	ResetBios ActionTarget `json:"#Bios.ResetBios"`
+}
+
+// PatchBiosRequest is the request body for PATCH on Bios/Settings
+type PatchBiosRequest struct {
+	Attributes map[string]interface{} `json:"Attributes"`
+}
+
+// RegistryCollection is the collection of registry files
+type RegistryCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// MessageRegistryFile locates a registry
+type MessageRegistryFile struct {
+	ODataType string             `json:"@odata.type"`
+	ODataID   string             `json:"@odata.id"`
+	ID        string             `json:"Id"`
+	Name      string             `json:"Name"`
+	Languages []string           `json:"Languages"`
+	Registry  string             `json:"Registry"`
+	Location  []RegistryLocation `json:"Location"`
+}
+
+// RegistryLocation is where a registry is served in one language
+type RegistryLocation struct {
+	Language string `json:"Language"`
+	URI      string `json:"Uri"`
+}
+
+// AttributeRegistry describes the attributes of the Bios resource
+type AttributeRegistry struct {
+	ODataType        string                   `json:"@odata.type"`
+	ODataID          string                   `json:"@odata.id"`
+	ID               string                   `json:"Id"`
+	Name             string                   `json:"Name"`
+	Language         string                   `json:"Language"`
+	OwningEntity     string                   `json:"OwningEntity"`
+	RegistryVersion  string                   `json:"RegistryVersion"`
+	SupportedSystems []SupportedSystem        `json:"SupportedSystems"`
+	RegistryEntries  AttributeRegistryEntries `json:"RegistryEntries"`
+}
+
+// SupportedSystem names a system an attribute registry applies to
+type SupportedSystem struct {
+	ProductName string `json:"ProductName"`
+	SystemID    string `json:"SystemId"`
+}
+
+// AttributeRegistryEntries holds the attributes of a registry
+type AttributeRegistryEntries struct {
+	Attributes []AttributeRegistryAttribute `json:"Attributes"`
+}
+
+// AttributeRegistryAttribute describes one BIOS attribute
+type AttributeRegistryAttribute struct {
+	AttributeName string                   `json:"AttributeName"`
+	DisplayName   string                   `json:"DisplayName"`
+	HelpText      string                   `json:"HelpText"`
+	Type          string                   `json:"Type"`
+	ReadOnly      bool                     `json:"ReadOnly"`
+	ResetRequired bool                     `json:"ResetRequired"`
+	Value         []AttributeRegistryValue `json:"Value,omitempty"`
+	LowerBound    *int                     `json:"LowerBound,omitempty"`
+	UpperBound    *int                     `json:"UpperBound,omitempty"`
+	MaxLength     *int                     `json:"MaxLength,omitempty"`
+}
+
+// AttributeRegistryValue is an allowed value of an enumeration attribute
+type AttributeRegistryValue struct {
+	ValueName        string `json:"ValueName"`
+	ValueDisplayName string `json:"ValueDisplayName"`
+}
+
+// BootOptionCollection lists the boot options of a system
+type BootOptionCollection struct {
+	ODataType    string    `json:"@odata.type"`
+	ODataID      string    `json:"@odata.id"`
+	Name         string    `json:"Name"`
+	MembersCount int       `json:"Members@odata.count"`
+	Members      []ODataID `json:"Members"`
+}
+
+// BootOption is a device the system can boot from
+type BootOption struct {
+	ODataType           string `json:"@odata.type"`
+	ODataID             string `json:"@odata.id"`
+	ID                  string `json:"Id"`
+	Name                string `json:"Name"`
+	BootOptionReference string `json:"BootOptionReference"`
+	BootOptionEnabled   bool   `json:"BootOptionEnabled"`
+	DisplayName         string `json:"DisplayName"`
+	Alias               string `json:"Alias"`
+	UefiDevicePath      string `json:"UefiDevicePath,omitempty"`
 }